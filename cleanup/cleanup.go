@@ -0,0 +1,199 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package cleanup - Process wide registry of cleanup functions.
+
+Components that leave state behind (temp files, lock files, partial
+downloads) register a cleanup function. Registered functions run once,
+either when the program calls Run on its normal exit path or when the
+process receives SIGINT or SIGTERM.
+
+	func main() {
+		defer cleanup.Run()
+		h := cleanup.Register(func() error { return os.Remove(tmp) }, cleanup.Name("tmp file"))
+		...
+		h.Run() // Clean up early, it won't run again.
+	}
+
+Functions run by descending priority and, within the same priority, in
+reverse registration order.
+*/
+package cleanup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Logger - Custom lib logger
+var Logger = log.New(ioutil.Discard, "cleanup ", log.LstdFlags)
+
+// ErrTimeout - The cleanup function didn't finish within its timeout.
+var ErrTimeout = fmt.Errorf("cleanup timed out")
+
+// DefaultTimeout - Timeout for functions registered without the Timeout option.
+// 0 means no timeout.
+var DefaultTimeout = 30 * time.Second
+
+// ExitFunc - Called with 128+signal number after a signal triggered cleanup.
+var ExitFunc = os.Exit
+
+// Option - Register option.
+type Option func(*Handle)
+
+// Name - Name used in logs and errors.
+func Name(name string) Option {
+	return func(h *Handle) {
+		h.name = name
+	}
+}
+
+// Priority - Functions with a higher priority run first, the default is 0.
+func Priority(p int) Option {
+	return func(h *Handle) {
+		h.priority = p
+	}
+}
+
+// Timeout - Maximum time to wait for the function, 0 means no timeout.
+func Timeout(d time.Duration) Option {
+	return func(h *Handle) {
+		h.timeout = d
+	}
+}
+
+// Handle - Registered cleanup function.
+type Handle struct {
+	fn       func() error
+	name     string
+	priority int
+	timeout  time.Duration
+	seq      int
+	once     sync.Once
+	err      error
+}
+
+var registry = struct {
+	sync.Mutex
+	handles map[*Handle]bool
+	seq     int
+	signals bool
+}{handles: map[*Handle]bool{}}
+
+// Register - Registers fn to run once on Run or on termination signals.
+// The signal handler is installed on the first call.
+func Register(fn func() error, opts ...Option) *Handle {
+	h := &Handle{fn: fn, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(h)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.seq++
+	h.seq = registry.seq
+	if h.name == "" {
+		h.name = fmt.Sprintf("cleanup #%d", h.seq)
+	}
+	registry.handles[h] = true
+	if !registry.signals {
+		registry.signals = true
+		handleSignals()
+	}
+	return h
+}
+
+// Unregister - Removes the function from the registry without running it.
+func (h *Handle) Unregister() {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.handles, h)
+}
+
+// Run - Runs the function now, if it hasn't run yet, and unregisters it.
+func (h *Handle) Run() error {
+	h.once.Do(func() {
+		h.Unregister()
+		Logger.Printf("running %s", h.name)
+		h.err = runWithTimeout(h)
+	})
+	return h.err
+}
+
+func runWithTimeout(h *Handle) error {
+	if h.timeout <= 0 {
+		return h.fn()
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- h.fn()
+	}()
+	timer := time.NewTimer(h.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-errc:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%w: %s after %s", ErrTimeout, h.name, h.timeout)
+	}
+}
+
+// Run - Runs all registered functions in order.
+// Errors from all functions are combined into the returned error.
+func Run() error {
+	registry.Lock()
+	handles := []*Handle{}
+	for h := range registry.handles {
+		handles = append(handles, h)
+	}
+	registry.Unlock()
+	sort.Slice(handles, func(i, j int) bool {
+		if handles[i].priority != handles[j].priority {
+			return handles[i].priority > handles[j].priority
+		}
+		return handles[i].seq > handles[j].seq
+	})
+	msgs := []string{}
+	for _, h := range handles {
+		err := h.Run()
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", h.name, err))
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("cleanup errors: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+func handleSignals() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-c
+		signal.Stop(c)
+		Logger.Printf("received %s, cleaning up", sig)
+		err := Run()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		}
+		code := 1
+		if s, ok := sig.(syscall.Signal); ok {
+			code = 128 + int(s)
+		}
+		ExitFunc(code)
+	}()
+}
@@ -0,0 +1,92 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package cleanup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	order := []string{}
+	add := func(name string) func() error {
+		return func() error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	Register(add("first"))
+	Register(add("second"))
+	Register(add("high"), Priority(10))
+	removed := Register(add("removed"))
+	removed.Unregister()
+	early := Register(add("early"))
+	early.Run()
+	Register(func() error { return fmt.Errorf("failed") }, Name("failing"))
+
+	err := Run()
+	if err == nil || !strings.Contains(err.Error(), "failing: failed") {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	expected := []string{"early", "high", "second", "first"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, order)
+	}
+	// Functions only run once.
+	err = Run()
+	if err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+	early.Run()
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, order)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	h := Register(func() error {
+		time.Sleep(time.Second)
+		return nil
+	}, Timeout(10*time.Millisecond))
+	err := h.Run()
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
+
+func TestSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("can't send SIGINT on windows")
+	}
+	exit := make(chan int, 1)
+	ExitFunc = func(code int) { exit <- code }
+	defer func() { ExitFunc = os.Exit }()
+	ran := make(chan bool, 1)
+	Register(func() error {
+		ran <- true
+		return nil
+	})
+	p, _ := os.FindProcess(os.Getpid())
+	p.Signal(os.Interrupt)
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Cleanup didn't run\n")
+	}
+	code := <-exit
+	if code != 130 {
+		t.Errorf("Unexpected exit code: %d\n", code)
+	}
+}
@@ -0,0 +1,253 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+	"github.com/DavidGamba/go-utils/hashdir"
+	"github.com/DavidGamba/go-utils/progress"
+
+	"github.com/DavidGamba/go-getoptions"
+)
+
+// BuildMetadata - Provides the metadata part of the version information.
+var BuildMetadata = "dev"
+
+const semVersion = "0.1.0"
+
+var logger = log.New(ioutil.Discard, "", log.LstdFlags)
+
+// events - Progress events, nil unless --progress-json is given.
+var events *progress.Reporter
+
+// errVerifyFailed - Some files didn't match the manifest, details have already been printed.
+var errVerifyFailed = fmt.Errorf("verification failed")
+
+// result - Hash of a manifest entry, also used as the JSON report.
+type result struct {
+	Path     string `json:"path"`
+	SHA256   string `json:"sha256,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func main() {
+	opt := getoptions.New()
+	opt.Self("", `Creates and verifies SHA256SUMS style checksum manifests.
+
+    Source: https://github.com/DavidGamba/go-utils`)
+	opt.Bool("help", false, opt.Alias("?"))
+	opt.Bool("debug", false)
+	opt.Bool("version", false, opt.Alias("V"))
+	opt.Bool("progress-json", false, opt.Description("Print progress events to STDERR as NDJSON, one JSON object per line."))
+	opt.SetRequireOrder()
+	opt.SetUnknownMode(getoptions.Pass)
+	opt.Command(createOptions().SetOption(opt.Option("help"), opt.Option("debug"), opt.Option("progress-json")).SetCommandFn(create))
+	opt.Command(verifyOptions().SetOption(opt.Option("help"), opt.Option("debug"), opt.Option("progress-json")).SetCommandFn(verify))
+	opt.Command(opt.HelpCommand(""))
+	remaining, err := opt.Parse(os.Args[1:])
+	if opt.Called("version") {
+		fmt.Printf("Version: %s+%s\n", semVersion, BuildMetadata)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if opt.Called("debug") {
+		logger.SetOutput(os.Stderr)
+	}
+	err = opt.Dispatch("help", remaining)
+	if err == errVerifyFailed {
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func createOptions() *getoptions.GetOpt {
+	opt := getoptions.NewCommand().Self("create", "Prints the checksum manifest, with paths relative to <dir>, of all the files under <dir>.")
+	opt.HelpSynopsisArgs("<dir>")
+	opt.String("output", "", opt.Alias("o"), opt.ArgName("file"), opt.Description("Write the manifest to file instead of STDOUT."))
+	opt.Int("jobs", runtime.NumCPU(), opt.Alias("j"), opt.ArgName("n"), opt.Description("Number of files hashed in parallel."))
+	opt.Bool("json", false, opt.Description("Print a JSON report instead of the manifest."))
+	return opt
+}
+
+func create(opt *getoptions.GetOpt, args []string) error {
+	remaining, err := opt.Parse(args)
+	if opt.Called("help") {
+		fmt.Fprintln(os.Stderr, opt.Help())
+		os.Exit(1)
+	}
+	if err != nil {
+		return err
+	}
+	if opt.Called("debug") {
+		logger.SetOutput(os.Stderr)
+	}
+	if opt.Called("progress-json") {
+		events = progress.New(os.Stderr)
+	}
+	if len(remaining) != 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: missing <dir>\n")
+		fmt.Fprintln(os.Stderr, opt.Help(getoptions.HelpSynopsis))
+		os.Exit(1)
+	}
+	dir := strings.TrimSuffix(remaining[0], string(os.PathSeparator))
+	sums, err := hashdir.HashTreeOptions(dir, crypto.SHA256, hashOptions(opt))
+	events.End()
+	if err != nil {
+		return err
+	}
+	output := opt.Value("output").(string)
+	for rel := range sums {
+		// Don't list the manifest being written.
+		if output != "" && sameFile(filepath.Join(dir, filepath.FromSlash(rel)), output) {
+			delete(sums, rel)
+		}
+	}
+
+	var out strings.Builder
+	if opt.Called("json") {
+		results := []result{}
+		for rel, sum := range sums {
+			results = append(results, result{Path: rel, SHA256: sum})
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		out.Write(data)
+		out.WriteString("\n")
+	} else {
+		err = hashdir.WriteManifest(&out, sums)
+		if err != nil {
+			return err
+		}
+	}
+	if output == "" {
+		fmt.Print(out.String())
+		return nil
+	}
+	return fileutils.WriteFileAtomic(output, []byte(out.String()), 0644)
+}
+
+// hashOptions - Hashes with the --jobs workers, reporting the progress
+// events.
+func hashOptions(opt *getoptions.GetOpt) hashdir.Options {
+	opts := hashdir.Options{Jobs: opt.Value("jobs").(int)}
+	if events != nil {
+		opts.Progress = events
+	}
+	return opts
+}
+
+func verifyOptions() *getoptions.GetOpt {
+	opt := getoptions.NewCommand().Self("verify", "Verifies the files listed in a checksum manifest, exits with status 1 if any file is missing or doesn't match.")
+	opt.HelpSynopsisArgs("<manifest>")
+	opt.String("dir", "", opt.Alias("C"), opt.ArgName("dir"), opt.Description("Base dir for the manifest paths, defaults to the manifest dir."))
+	opt.Int("jobs", runtime.NumCPU(), opt.Alias("j"), opt.ArgName("n"), opt.Description("Number of files hashed in parallel."))
+	opt.Bool("quiet", false, opt.Alias("q"), opt.Description("Don't print OK for each verified file."))
+	opt.Bool("json", false, opt.Description("Print a JSON report."))
+	return opt
+}
+
+func verify(opt *getoptions.GetOpt, args []string) error {
+	remaining, err := opt.Parse(args)
+	if opt.Called("help") {
+		fmt.Fprintln(os.Stderr, opt.Help())
+		os.Exit(1)
+	}
+	if err != nil {
+		return err
+	}
+	if opt.Called("debug") {
+		logger.SetOutput(os.Stderr)
+	}
+	if opt.Called("progress-json") {
+		events = progress.New(os.Stderr)
+	}
+	if len(remaining) != 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: missing <manifest>\n")
+		fmt.Fprintln(os.Stderr, opt.Help(getoptions.HelpSynopsis))
+		os.Exit(1)
+	}
+	manifest := remaining[0]
+	dir := opt.Value("dir").(string)
+	if dir == "" {
+		dir = filepath.Dir(manifest)
+	}
+	entries, err := hashdir.VerifyManifest(manifest, dir, crypto.SHA256, hashOptions(opt))
+	events.End()
+	if err != nil && !errors.Is(err, hashdir.ErrVerifyFailed) {
+		return err
+	}
+
+	results := []result{}
+	failed := 0
+	for _, e := range entries {
+		r := result{Path: e.Path, SHA256: e.Actual, Expected: e.Expected, Status: string(e.Status)}
+		if e.Err != nil {
+			r.Error = e.Err.Error()
+		}
+		if e.Status != hashdir.ManifestOK {
+			failed++
+		}
+		results = append(results, r)
+	}
+	if opt.Called("json") {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, r := range results {
+			if r.Status == "OK" && opt.Called("quiet") {
+				continue
+			}
+			fmt.Printf("%s: %s\n", r.Path, r.Status)
+		}
+	}
+	if failed > 0 {
+		if !opt.Called("json") {
+			fmt.Fprintf(os.Stderr, "WARNING: %d of %d files failed verification\n", failed, len(results))
+		}
+		return errVerifyFailed
+	}
+	return nil
+}
+
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
@@ -0,0 +1,208 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/DavidGamba/go-utils/completion"
+	"github.com/DavidGamba/go-utils/fileutils"
+	"github.com/DavidGamba/go-utils/hashdir"
+	"github.com/DavidGamba/go-utils/progress"
+	"github.com/DavidGamba/go-utils/sizeutils"
+
+	"github.com/DavidGamba/go-getoptions"
+)
+
+// BuildMetadata - Provides the metadata part of the version information.
+var BuildMetadata = "dev"
+
+const semVersion = "0.1.0"
+
+var logger = log.New(ioutil.Discard, "", log.LstdFlags)
+
+// events - Progress events, nil unless --progress-json is given.
+var events *progress.Reporter
+
+// report - Result of a sync, printed with --json.
+type report struct {
+	fileutils.SyncReport
+	Duration string `json:"duration,omitempty"`
+}
+
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
+
+func main() {
+	var excludes []string
+	opt := getoptions.New()
+	opt.Self("", `Synchronizes the contents of the dst dir with the src dir.
+
+    Files are copied when they are missing or their size or modification time
+    differ, or with --checksum, when their contents differ.
+    Modes and modification times are preserved. Symlinks are copied as symlinks.
+
+    With --delta, files that exist in dst are updated with a binary delta so
+    only their changed portions are transferred.
+
+    Interrupting the sync stops it once the change in progress is done,
+    running it again resumes from there. Interrupt twice to stop right away.
+
+    Source: https://github.com/DavidGamba/go-utils`)
+	opt.HelpSynopsisArgs("<src> <dst>")
+	opt.Bool("help", false, opt.Alias("?"))
+	opt.Bool("debug", false)
+	opt.Bool("version", false, opt.Alias("V"))
+	opt.String("completion", "", opt.ArgName("bash|zsh|fish"), opt.Description("Print the shell completion script, for example: source <(dirsync --completion zsh)"))
+	opt.Bool("delete", false, opt.Description("Delete files in dst that don't exist in src."))
+	opt.StringSliceVar(&excludes, "exclude", 1, 1, opt.Alias("e"), opt.ArgName("glob"), opt.Description("Skip files and dirs whose name matches the glob, in both src and dst. Globs with a '/' match the relative path and '**' any number of dirs."))
+	opt.Bool("dry-run", false, opt.Alias("n"), opt.Description("Print the changes without applying them."))
+	opt.Bool("checksum", false, opt.Alias("c"), opt.Description("Compare file contents instead of size and modification time."))
+	opt.String("checkpoint", "", opt.ArgName("file"), opt.Description("With --checksum, keep the file digests in the given file so resumed and repeated runs don't read unchanged files again."))
+	opt.Bool("delta", false, opt.Alias("d"), opt.Description("Update existing files transferring only their changed portions."))
+	opt.Bool("no-space-check", false, opt.Description("Don't check that dst has space for the files to copy before starting."))
+	opt.Bool("progress", false, opt.Alias("p"), opt.Description("Print each change as it is applied and a summary at the end."))
+	opt.Bool("json", false, opt.Description("Print the changes, and with --dry-run the planned changes, as a JSON report."))
+	opt.Bool("progress-json", false, opt.Description("Print progress events to STDERR as NDJSON, one JSON object per line."))
+	remaining, err := opt.Parse(os.Args[1:])
+	if opt.Called("help") {
+		fmt.Fprintln(os.Stderr, opt.Help())
+		os.Exit(1)
+	}
+	if opt.Called("version") {
+		fmt.Printf("Version: %s+%s\n", semVersion, BuildMetadata)
+		os.Exit(0)
+	}
+	if opt.Called("completion") {
+		script, err := completion.Script(opt.Value("completion").(string), "dirsync")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(script)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if opt.Called("debug") {
+		logger.SetOutput(os.Stderr)
+		fileutils.Logger.SetOutput(os.Stderr)
+	}
+	if opt.Called("progress-json") {
+		events = progress.New(os.Stderr)
+	}
+	if len(remaining) != 2 {
+		fmt.Fprintf(os.Stderr, "ERROR: missing src and dst dirs\n")
+		fmt.Fprintln(os.Stderr, opt.Help(getoptions.HelpSynopsis))
+		os.Exit(1)
+	}
+	src, dst := remaining[0], remaining[1]
+
+	opts := []fileutils.SyncOption{fileutils.SyncExclude(excludes...)}
+	if opt.Called("delete") {
+		opts = append(opts, fileutils.SyncDelete())
+	}
+	if opt.Called("dry-run") {
+		opts = append(opts, fileutils.SyncDryRun())
+	}
+	if opt.Called("checksum") {
+		opts = append(opts, fileutils.SyncChecksum(func(filename string) (string, error) {
+			return hashdir.HashFile(filename, crypto.SHA256)
+		}))
+	}
+	if opt.Called("checkpoint") {
+		checkpoint, err := fileutils.LoadProcessedCache(opt.Value("checkpoint").(string))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, fileutils.SyncCheckpoint(checkpoint))
+	}
+	if opt.Called("delta") {
+		opts = append(opts, fileutils.SyncDelta())
+	}
+	if !opt.Called("no-space-check") {
+		opts = append(opts, fileutils.SyncSpaceCheck())
+	}
+	printProgress := opt.Called("progress") && !opt.Called("json")
+	opts = append(opts, fileutils.SyncProgress(func(e fileutils.SyncEvent) {
+		path := e.Action.Path
+		switch {
+		case e.Err != nil:
+			events.Error(path, e.Err)
+		case e.Done:
+			events.Done(path, e.Transferred)
+		case e.Transferred > 0:
+			events.Progress(path, e.Transferred, e.Action.Size)
+		default:
+			if printProgress {
+				fmt.Printf("[%d/%d] %s %s\n", e.Index+1, e.Total, e.Action.Op, path)
+			}
+			events.Start(path, e.Action.Size)
+		}
+	}))
+
+	control := fileutils.NewControl()
+	opts = append(opts, fileutils.SyncControl(control))
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigc
+		// Restore the default handling so a second interrupt stops right away.
+		signal.Stop(sigc)
+		fmt.Fprintf(os.Stderr, "Stopping after the current change, interrupt again to stop now\n")
+		control.Cancel()
+	}()
+
+	start := time.Now()
+	r, err := fileutils.Sync(src, dst, opts...)
+	if err != nil {
+		events.Error("", err)
+		events.End()
+		if errors.Is(err, fileutils.ErrCanceled) {
+			fmt.Fprintf(os.Stderr, "Interrupted after %d of %d changes, run again to resume\n", r.Applied, len(r.Actions))
+			os.Exit(130)
+		}
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	events.End()
+	if opt.Called("dry-run") {
+		if opt.Called("json") {
+			printJSON(report{SyncReport: r})
+			os.Exit(0)
+		}
+		for _, a := range r.Actions {
+			fmt.Printf("%s %s\n", a.Op, a.Path)
+		}
+		os.Exit(0)
+	}
+	duration := time.Since(start).Round(time.Millisecond)
+	if opt.Called("json") {
+		printJSON(report{SyncReport: r, Duration: duration.String()})
+	} else if opt.Called("progress") {
+		fmt.Printf("%d changes, %s copied in %s\n", len(r.Actions), sizeutils.FormatSize(r.Copied), duration)
+	}
+}
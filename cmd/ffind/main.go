@@ -0,0 +1,306 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DavidGamba/go-utils/completion"
+	"github.com/DavidGamba/go-utils/fileutils"
+	"github.com/DavidGamba/go-utils/progress"
+	"github.com/DavidGamba/go-utils/sizeutils"
+
+	"github.com/DavidGamba/go-getoptions"
+)
+
+// BuildMetadata - Provides the metadata part of the version information.
+var BuildMetadata = "dev"
+
+const semVersion = "0.1.0"
+
+var logger = log.New(ioutil.Discard, "", log.LstdFlags)
+
+// events - Progress events, nil unless --progress-json is given.
+var events *progress.Reporter
+
+var vcsDirs = []string{".git", ".svn", ".hg", ".bzr", "CVS"}
+
+// sortKeys - The --sort values.
+var sortKeys = map[string]fileutils.SortKey{
+	"name":    fileutils.SortByName,
+	"numeric": fileutils.SortByNumeric,
+	"version": fileutils.SortByVersion,
+	"mtime":   fileutils.SortByModTime,
+	"size":    fileutils.SortBySize,
+}
+
+// filter - Predicates applied to each listed file, the name, hidden, VCS
+// and depth filters are applied while listing.
+type filter struct {
+	fileType string
+	newer    time.Time
+	older    time.Time
+	sizeSet  bool
+	sizeCmp  int
+	size     int64
+	cache    *fileutils.StatCache
+}
+
+func main() {
+	var f filter
+	var sortBy, newer, within, before, size string
+	var preview, maxDepth int
+	var names, excludes, exts []string
+	opt := getoptions.New()
+	opt.Self("", `Finds files in the given dirs, the current dir by default.
+
+    Hidden files and VCS dirs are skipped unless requested.
+
+    Source: https://github.com/DavidGamba/go-utils`)
+	opt.HelpSynopsisArgs("[<dir>...]")
+	opt.Bool("help", false, opt.Alias("?"))
+	opt.Bool("debug", false)
+	opt.Bool("version", false, opt.Alias("V"))
+	opt.String("completion", "", opt.ArgName("bash|zsh|fish"), opt.Description("Print the shell completion script, for example: source <(ffind --completion zsh)"))
+	opt.StringVar(&f.fileType, "type", "", opt.Alias("t"), opt.ArgName("f|d"), opt.Description("Only list files (f) or dirs (d)."))
+	opt.StringSliceVar(&names, "name", 1, 1, opt.Alias("n"), opt.ArgName("glob"), opt.Description("Only list entries whose name matches the glob, globs with a '/' match the relative path and '**' any number of dirs."))
+	opt.StringSliceVar(&excludes, "exclude", 1, 1, opt.Alias("e"), opt.ArgName("glob"), opt.Description("Skip entries whose name matches the glob, globs with a '/' match the relative path and '**' any number of dirs."))
+	opt.StringSliceVar(&exts, "ext", 1, 1, opt.ArgName("extension"), opt.Description("Only list files with the extension, compared ignoring case, dirs are still listed."))
+	opt.Bool("hidden", false, opt.Description("Include hidden files and dirs."))
+	opt.Bool("vcs", false, opt.Description("Include VCS dirs (.git, .svn, .hg, .bzr, CVS)."))
+	opt.IntVar(&maxDepth, "max-depth", 0, opt.Alias("d"), opt.ArgName("n"), opt.Description("Maximum depth, 1 lists only the dir contents. 0 means no limit."))
+	opt.StringVar(&newer, "newer", "", opt.ArgName("file"), opt.Description("Only list entries modified after the given file."))
+	opt.StringVar(&within, "changed-within", "", opt.ArgName("duration"), opt.Description(`Only list entries modified within the given duration.
+For example: --changed-within 2d, --changed-within 1h30m.`))
+	opt.StringVar(&before, "changed-before", "", opt.ArgName("duration"), opt.Description("Only list entries modified more than the given duration ago."))
+	opt.StringVar(&size, "size", "", opt.ArgName("[+-]size"), opt.Description(`Only list files bigger (+), smaller (-) or equal to the size.
+For example: --size +10MiB, --size=-1K.`))
+	opt.StringVar(&sortBy, "sort", "name", opt.ArgName("name|numeric|version|mtime|size"), opt.Description("Sort order within each dir, times and sizes oldest and smallest first."))
+	opt.Bool("reverse", false, opt.Alias("r"), opt.Description("Reverse the sort order."))
+	opt.Bool("print0", false, opt.Alias("0"), opt.Description("Separate entries with a NUL character, for use with 'xargs -0'."))
+	opt.IntVar(&preview, "preview", 0, opt.ArgName("bytes"), opt.Description("Print up to the given number of bytes from the start and from the end of each file, indented below it."))
+	opt.Bool("progress-json", false, opt.Description("Print progress events to STDERR as NDJSON, one JSON object per line."))
+	remaining, err := opt.Parse(os.Args[1:])
+	if opt.Called("help") {
+		fmt.Fprintln(os.Stderr, opt.Help())
+		os.Exit(1)
+	}
+	if opt.Called("version") {
+		fmt.Printf("Version: %s+%s\n", semVersion, BuildMetadata)
+		os.Exit(0)
+	}
+	if opt.Called("completion") {
+		script, err := completion.Script(opt.Value("completion").(string), "ffind")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(script)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if opt.Called("debug") {
+		logger.SetOutput(os.Stderr)
+	}
+	if opt.Called("progress-json") {
+		events = progress.New(os.Stderr)
+	}
+	if f.fileType != "" && f.fileType != "f" && f.fileType != "d" {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid type '%s', use f or d\n", f.fileType)
+		os.Exit(1)
+	}
+	sortKey, ok := sortKeys[sortBy]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid sort '%s', use name, numeric, version, mtime or size\n", sortBy)
+		os.Exit(1)
+	}
+	if newer != "" {
+		fInfo, err := os.Stat(newer)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		f.newer = fInfo.ModTime()
+	}
+	now := time.Now()
+	if within != "" {
+		d, err := sizeutils.ParseDuration(within)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		if t := now.Add(-d); t.After(f.newer) {
+			f.newer = t
+		}
+	}
+	if before != "" {
+		d, err := sizeutils.ParseDuration(before)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		f.older = now.Add(-d)
+	}
+	if size != "" {
+		f.sizeSet = true
+		switch size[0] {
+		case '+':
+			f.sizeCmp = 1
+			size = size[1:]
+		case '-':
+			f.sizeCmp = -1
+			size = size[1:]
+		}
+		f.size, err = sizeutils.ParseSize(size)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	dirs := remaining
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	separator := "\n"
+	if opt.Called("print0") {
+		separator = "\x00"
+	}
+	listOpts := []fileutils.ListOption{
+		fileutils.ListSortBy(sortKey),
+		fileutils.ListExtensions(exts...),
+		fileutils.ListInclude(names...),
+		fileutils.ListExclude(excludes...),
+		fileutils.ListMaxDepth(maxDepth),
+	}
+	if opt.Called("reverse") {
+		listOpts = append(listOpts, fileutils.ListReverse())
+	}
+	if !opt.Called("hidden") {
+		listOpts = append(listOpts, fileutils.ListSkipHidden())
+	}
+	if !opt.Called("vcs") {
+		listOpts = append(listOpts, fileutils.ListExclude(vcsDirs...))
+	}
+
+	exitCode := 0
+	for _, dir := range dirs {
+		// Keeps the root, "/", as is.
+		if len(dir) > 1 {
+			dir = strings.TrimSuffix(dir, string(os.PathSeparator))
+		}
+		events.Start(dir, 0)
+		// The filters reuse the dir entries read while listing.
+		f.cache = fileutils.NewStatCache()
+		for e := range fileutils.GetList(dir, append(listOpts, fileutils.ListStatCache(f.cache))...) {
+			if e.Error != nil {
+				events.Error(dir, e.Error)
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", e.Error)
+				exitCode = 1
+				continue
+			}
+			file := e.String
+			ok, err := f.match(file)
+			if err != nil {
+				events.Error(file, err)
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+				exitCode = 1
+				continue
+			}
+			if ok {
+				events.Done(file, 0)
+				fmt.Print(strings.TrimPrefix(file, "."+string(os.PathSeparator)) + separator)
+				if preview > 0 {
+					err := printPreview(f.cache, file, preview)
+					if err != nil {
+						events.Error(file, err)
+						fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+						exitCode = 1
+					}
+				}
+			}
+		}
+	}
+	events.End()
+	os.Exit(exitCode)
+}
+
+// printPreview - Prints the start and end of file, indented, see fileutils.Preview.
+// Dirs are skipped.
+func printPreview(cache *fileutils.StatCache, file string, size int) error {
+	fInfo, err := cache.Stat(file)
+	if err != nil {
+		return err
+	}
+	if fInfo.IsDir() {
+		return nil
+	}
+	p, err := fileutils.Preview(file, size, size)
+	if err != nil {
+		return err
+	}
+	indent := func(s string) {
+		s = strings.TrimSuffix(s, "\n")
+		if s == "" {
+			return
+		}
+		fmt.Printf("    %s\n", strings.ReplaceAll(s, "\n", "\n    "))
+	}
+	switch {
+	case p.Binary:
+		fmt.Printf("    (binary, %d bytes)\n", p.Size)
+	case p.Truncated:
+		indent(p.Head)
+		fmt.Printf("    ... (%d bytes)\n", p.Size)
+		indent(p.Tail)
+	default:
+		indent(p.Head)
+	}
+	return nil
+}
+
+// match - Reports whether file passes the type, time and size filters.
+func (f *filter) match(file string) (bool, error) {
+	if f.fileType == "" && f.newer.IsZero() && f.older.IsZero() && !f.sizeSet {
+		return true, nil
+	}
+	fInfo, err := f.cache.Stat(file)
+	if err != nil {
+		return false, err
+	}
+	if f.fileType == "f" && fInfo.IsDir() || f.fileType == "d" && !fInfo.IsDir() {
+		return false, nil
+	}
+	if !f.newer.IsZero() && !fInfo.ModTime().After(f.newer) {
+		return false, nil
+	}
+	if !f.older.IsZero() && !fInfo.ModTime().Before(f.older) {
+		return false, nil
+	}
+	if f.sizeSet {
+		if fInfo.IsDir() {
+			return false, nil
+		}
+		switch f.sizeCmp {
+		case 1:
+			return fInfo.Size() > f.size, nil
+		case -1:
+			return fInfo.Size() < f.size, nil
+		default:
+			return fInfo.Size() == f.size, nil
+		}
+	}
+	return true, nil
+}
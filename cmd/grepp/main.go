@@ -0,0 +1,439 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/DavidGamba/go-utils/completion"
+	"github.com/DavidGamba/go-utils/fileutils"
+	"github.com/DavidGamba/go-utils/progress"
+
+	"github.com/DavidGamba/go-getoptions"
+)
+
+// BuildMetadata - Provides the metadata part of the version information.
+var BuildMetadata = "dev"
+
+const semVersion = "0.1.0"
+
+var logger = log.New(ioutil.Discard, "", log.LstdFlags)
+
+// errQuit - Returned when the user quits at the confirmation prompt.
+var errQuit = errors.New("quit")
+
+// events - Progress events, nil unless --progress-json is given.
+var events *progress.Reporter
+
+const (
+	colorFile  = "\033[35m"
+	colorLine  = "\033[32m"
+	colorMatch = "\033[1;31m"
+	colorNew   = "\033[1;32m"
+	colorReset = "\033[0m"
+)
+
+type config struct {
+	re           *regexp.Regexp
+	replace      *string
+	confirm      bool
+	force        bool
+	context      int
+	color        bool
+	listOpts     []fileutils.ListOption
+	filesOnly    bool
+	applyAll     bool
+	quit         bool
+	stdin        *bufio.Reader
+	matchedFiles int
+
+	// json collects the results instead of printing them.
+	json    bool
+	results []result
+}
+
+// result - A matched line, or only the file with --files-with-matches,
+// printed with --json.
+type result struct {
+	Path        string `json:"path"`
+	Line        int    `json:"line,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+	Applied     bool   `json:"applied,omitempty"`
+}
+
+func main() {
+	var c config
+	var replace, color string
+	var includes, excludes []string
+	opt := getoptions.New()
+	opt.Self("", `Searches for a regular expression in files and optionally replaces its matches.
+
+    Binary files, hidden files and VCS dirs are skipped.
+    Replacements are previewed unless --confirm or --force are given.
+
+    Source: https://github.com/DavidGamba/go-utils`)
+	opt.HelpSynopsisArgs("<pattern> [<file|dir>...]")
+	opt.Bool("help", false, opt.Alias("?"))
+	opt.Bool("debug", false)
+	opt.Bool("version", false, opt.Alias("V"))
+	opt.String("completion", "", opt.ArgName("bash|zsh|fish"), opt.Description("Print the shell completion script, for example: source <(grepp --completion zsh)"))
+	opt.Bool("ignore-case", false, opt.Alias("i"), opt.Description("Case insensitive search."))
+	opt.IntVar(&c.context, "context", 0, opt.Alias("C"), opt.ArgName("n"), opt.Description("Print n lines of context around matches."))
+	opt.StringVar(&color, "color", "auto", opt.ArgName("auto|always|never"), opt.Description("Colorize the output."))
+	opt.StringSliceVar(&includes, "include", 1, 1, opt.ArgName("glob"), opt.Description("Only search files whose name matches the glob, globs with a '/' match the relative path and '**' any number of dirs."))
+	opt.StringSliceVar(&excludes, "exclude", 1, 1, opt.ArgName("glob"), opt.Description("Skip files and dirs whose name matches the glob, globs with a '/' match the relative path and '**' any number of dirs."))
+	opt.Bool("hidden", false, opt.Description("Search hidden files and dirs."))
+	opt.BoolVar(&c.filesOnly, "files-with-matches", false, opt.Alias("l"), opt.Description("Only print the names of files with matches."))
+	opt.StringVar(&replace, "replace", "", opt.Alias("r"), opt.ArgName("replacement"), opt.Description("Replacement, supports capture group references like $1."))
+	opt.BoolVar(&c.confirm, "confirm", false, opt.Alias("c"), opt.Description("Ask for confirmation before each replacement."))
+	opt.BoolVar(&c.force, "force", false, opt.Alias("f"), opt.Description("Apply all replacements without asking."))
+	opt.BoolVar(&c.json, "json", false, opt.Description("Print the matches, and the replacements, as a JSON report."))
+	opt.Bool("progress-json", false, opt.Description("Print progress events to STDERR as NDJSON, one JSON object per line."))
+	remaining, err := opt.Parse(os.Args[1:])
+	if opt.Called("help") {
+		fmt.Fprintln(os.Stderr, opt.Help())
+		os.Exit(1)
+	}
+	if opt.Called("version") {
+		fmt.Printf("Version: %s+%s\n", semVersion, BuildMetadata)
+		os.Exit(0)
+	}
+	if opt.Called("completion") {
+		script, err := completion.Script(opt.Value("completion").(string), "grepp")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(script)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if opt.Called("debug") {
+		logger.SetOutput(os.Stderr)
+	}
+	if opt.Called("progress-json") {
+		events = progress.New(os.Stderr)
+	}
+	if len(remaining) < 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: missing pattern\n")
+		fmt.Fprintln(os.Stderr, opt.Help(getoptions.HelpSynopsis))
+		os.Exit(1)
+	}
+	pattern := remaining[0]
+	if opt.Called("ignore-case") {
+		pattern = "(?i)" + pattern
+	}
+	c.re, err = regexp.Compile(pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if opt.Called("replace") {
+		c.replace = &replace
+	}
+	switch color {
+	case "always":
+		c.color = true
+	case "never":
+	case "auto":
+		if fInfo, err := os.Stdout.Stat(); err == nil {
+			c.color = fInfo.Mode()&os.ModeCharDevice != 0
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "ERROR: invalid color '%s'\n", color)
+		os.Exit(1)
+	}
+	c.stdin = bufio.NewReader(os.Stdin)
+	c.listOpts = []fileutils.ListOption{
+		fileutils.ListInclude(includes...),
+		fileutils.ListExclude(excludes...),
+		fileutils.ListExclude(".git", ".svn", ".hg"),
+	}
+	if !opt.Called("hidden") {
+		c.listOpts = append(c.listOpts, fileutils.ListSkipHidden())
+	}
+
+	paths := remaining[1:]
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	exitCode := 0
+	for _, path := range paths {
+		var err error
+		if c.replace != nil && !c.filesOnly {
+			err = c.replaceTree(path)
+		} else {
+			err = c.grep(path)
+		}
+		if err != nil {
+			exitCode = 2
+		}
+		if c.quit {
+			break
+		}
+	}
+	events.End()
+	if c.json {
+		if c.results == nil {
+			c.results = []result{}
+		}
+		data, err := json.MarshalIndent(c.results, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(2)
+		}
+		fmt.Println(string(data))
+	}
+	if exitCode == 0 && c.matchedFiles == 0 {
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// grep - Searches path and prints the matches.
+// Errors are printed and the search continues, the last one is returned.
+func (c *config) grep(path string) error {
+	var lastErr error
+	var f *fileMatches
+	for m := range fileutils.Grep(context.Background(), path, c.re, c.listOpts...) {
+		if m.Error != nil {
+			lastErr = c.report(m.Path, m.Error)
+			continue
+		}
+		if f == nil || f.path != m.Path {
+			f.finish()
+			f = c.newFileMatches(m.Path)
+		}
+		if c.filesOnly {
+			continue
+		}
+		if c.json {
+			c.results = append(c.results, result{Path: m.Path, Line: m.Line, Text: m.Text})
+			continue
+		}
+		f.print(m.Line, m.Text)
+	}
+	f.finish()
+	return lastErr
+}
+
+// replaceTree - Replaces the matches under path, printing each change and
+// asking whether to apply it.
+// Errors are printed and the replacement continues, the last one is
+// returned.
+func (c *config) replaceTree(path string) error {
+	var f *fileMatches
+	confirm := func(file string, line int, old, new string) (bool, error) {
+		if f == nil || f.path != file {
+			f.finish()
+			f = c.newFileMatches(file)
+		}
+		r := result{Path: file, Line: line, Text: old, Replacement: new}
+		if !c.json {
+			f.print(line, old)
+			c.printLine(file, line-1, "+", c.colorize(colorNew, new))
+		}
+		apply, err := c.ask()
+		r.Applied = apply
+		if c.json && err == nil {
+			c.results = append(c.results, r)
+		}
+		return apply, err
+	}
+	opts := []fileutils.ReplaceOption{
+		fileutils.ReplaceRegexp(),
+		fileutils.ReplaceList(c.listOpts...),
+		fileutils.ReplaceConfirm(confirm),
+	}
+	preview := !c.confirm && !c.force
+	if preview {
+		opts = append(opts, fileutils.ReplaceDryRun(ioutil.Discard))
+	}
+	changed, err := fileutils.ReplaceInTree(path, c.re.String(), *c.replace, opts...)
+	f.finish()
+	if !preview {
+		files := make([]string, 0, len(changed))
+		for file := range changed {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			fmt.Fprintf(os.Stderr, "%s: %d lines changed\n", file, changed[file])
+		}
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	var lastErr error
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, errQuit) {
+			lastErr = c.report(path, err)
+		}
+	}
+	return lastErr
+}
+
+// report - Prints err and returns it.
+func (c *config) report(path string, err error) error {
+	events.Error(path, err)
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+	return err
+}
+
+// fileMatches - Prints the matches found in a file with their context.
+type fileMatches struct {
+	c    *config
+	path string
+	size int64
+
+	// lines - The lines of the file, only read to print context.
+	lines []string
+
+	// printed - Index of the last line printed.
+	printed int
+
+	// end - Index of the last context line to print after the last match.
+	end int
+}
+
+func (c *config) newFileMatches(path string) *fileMatches {
+	c.matchedFiles++
+	f := &fileMatches{c: c, path: path, printed: -1, end: -1}
+	if events != nil {
+		if fInfo, err := os.Stat(path); err == nil {
+			f.size = fInfo.Size()
+		}
+	}
+	events.Start(path, f.size)
+	if c.filesOnly {
+		if c.json {
+			c.results = append(c.results, result{Path: path})
+		} else {
+			fmt.Println(c.colorize(colorFile, path))
+		}
+		return f
+	}
+	if c.context > 0 && !c.json {
+		for e := range fileutils.ReadLines(path, 1024*1024) {
+			if e.Error != nil {
+				c.report(path, e.Error)
+				break
+			}
+			f.lines = append(f.lines, e.String)
+		}
+	}
+	return f
+}
+
+// print - Prints the matched line, 1 based, with its context.
+func (f *fileMatches) print(line int, text string) {
+	c := f.c
+	i := line - 1
+	f.flush(i - 1)
+	start := i - c.context
+	if start <= f.printed {
+		start = f.printed + 1
+	}
+	if start < 0 {
+		start = 0
+	}
+	if f.printed >= 0 && start > f.printed+1 {
+		fmt.Println("--")
+	}
+	for j := start; j < i && j < len(f.lines); j++ {
+		c.printLine(f.path, j, "-", f.lines[j])
+	}
+	c.printLine(f.path, i, ":", c.highlight(text, colorMatch))
+	f.printed = i
+	f.end = i + c.context
+}
+
+// flush - Prints the context lines after the last match, up to index last.
+func (f *fileMatches) flush(last int) {
+	for j := f.printed + 1; j <= f.end && j <= last && j < len(f.lines); j++ {
+		f.c.printLine(f.path, j, "-", f.lines[j])
+		f.printed = j
+	}
+}
+
+// finish - Prints the remaining context.
+func (f *fileMatches) finish() {
+	if f == nil {
+		return
+	}
+	f.flush(len(f.lines) - 1)
+	events.Done(f.path, f.size)
+}
+
+// ask - Returns whether the replacement should be applied, errQuit when the
+// user quits.
+func (c *config) ask() (bool, error) {
+	if c.force || c.applyAll {
+		return true, nil
+	}
+	if !c.confirm {
+		return false, nil
+	}
+	for {
+		fmt.Fprint(os.Stderr, "Apply change? [y,n,a,q] ")
+		answer, err := c.stdin.ReadString('\n')
+		if err != nil {
+			return false, fmt.Errorf("failed to read answer: %w", err)
+		}
+		switch strings.TrimSpace(answer) {
+		case "y":
+			return true, nil
+		case "n":
+			return false, nil
+		case "a":
+			c.applyAll = true
+			return true, nil
+		case "q":
+			c.quit = true
+			return false, errQuit
+		}
+	}
+}
+
+func (c *config) printLine(file string, i int, sep, line string) {
+	fmt.Printf("%s%s%s%s%s", c.colorize(colorFile, file), sep, c.colorize(colorLine, fmt.Sprintf("%d", i+1)), sep, line)
+	if !strings.HasSuffix(line, "\n") {
+		fmt.Println()
+	}
+}
+
+func (c *config) colorize(color, s string) string {
+	if !c.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (c *config) highlight(line, color string) string {
+	if !c.color {
+		return line
+	}
+	return c.re.ReplaceAllStringFunc(line, func(m string) string {
+		return color + m + colorReset
+	})
+}
@@ -0,0 +1,151 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/DavidGamba/go-utils/progress"
+	"github.com/DavidGamba/go-utils/stringutils"
+
+	"github.com/DavidGamba/go-getoptions"
+)
+
+// BuildMetadata - Provides the metadata part of the version information.
+var BuildMetadata = "dev"
+
+const semVersion = "0.1.0"
+
+var logger = log.New(ioutil.Discard, "", log.LstdFlags)
+
+// events - Progress events, nil unless --progress-json is given.
+var events *progress.Reporter
+
+type line struct {
+	text string
+	key  string
+}
+
+func main() {
+	var field int
+	var delimiter string
+	opt := getoptions.New()
+	opt.Self("", `Sorts lines read from STDIN, comparing numbers numerically.
+
+    Lines, or the selected field, that are integers are compared by value
+    and sort before any other lines, which are compared lexicographically.
+
+    Source: https://github.com/DavidGamba/go-utils`)
+	opt.Bool("help", false, opt.Alias("?"))
+	opt.Bool("debug", false)
+	opt.Bool("version", false, opt.Alias("V"))
+	opt.Bool("reverse", false, opt.Alias("r"), opt.Description("Reverse the sort order."))
+	opt.Bool("version-sort", false, opt.Description("Compare the numbers within the lines too, like 'sort -V': img2.png before img10.png."))
+	opt.Bool("unique", false, opt.Alias("u"), opt.Description("Only print the first line of each run of lines with equal keys."))
+	opt.IntVar(&field, "field", 0, opt.Alias("k", "column"), opt.ArgName("n"), opt.Description("Sort by the n-th field, starting at 1. 0 uses the whole line."))
+	opt.StringVar(&delimiter, "delimiter", "", opt.Alias("t"), opt.ArgName("sep"), opt.Description("Field delimiter, defaults to runs of blanks."))
+	opt.Bool("progress-json", false, opt.Description("Print progress events to STDERR as NDJSON, one JSON object per line."))
+	remaining, err := opt.Parse(os.Args[1:])
+	if opt.Called("help") {
+		fmt.Fprintln(os.Stderr, opt.Help())
+		os.Exit(1)
+	}
+	if opt.Called("version") {
+		fmt.Printf("Version: %s+%s\n", semVersion, BuildMetadata)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if opt.Called("debug") {
+		logger.SetOutput(os.Stderr)
+	}
+	if opt.Called("progress-json") {
+		events = progress.New(os.Stderr)
+	}
+	if len(remaining) > 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: unexpected arguments %v, input is read from STDIN\n", remaining)
+		os.Exit(1)
+	}
+	if field < 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid field '%d'\n", field)
+		os.Exit(1)
+	}
+
+	lines := []line{}
+	var read int64
+	events.Start("-", 0)
+	scanner := bufio.NewScanner(events.Reader("-", 0, os.Stdin))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		read += int64(len(scanner.Bytes())) + 1
+		lines = append(lines, line{text: text, key: key(text, field, delimiter)})
+	}
+	if err := scanner.Err(); err != nil {
+		events.Error("-", err)
+		events.End()
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	events.Done("-", read)
+	events.End()
+	logger.Printf("read %d lines", len(lines))
+
+	less := stringutils.NaturalLess
+	if opt.Called("version-sort") {
+		less = stringutils.VersionLess
+	}
+	reverse := opt.Called("reverse")
+	sort.SliceStable(lines, func(i, j int) bool {
+		if reverse {
+			return less(lines[j].key, lines[i].key)
+		}
+		return less(lines[i].key, lines[j].key)
+	})
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for i, l := range lines {
+		if opt.Called("unique") && i > 0 && equal(less, lines[i-1].key, l.key) {
+			continue
+		}
+		fmt.Fprintln(w, l.text)
+	}
+}
+
+// key - Returns the part of the line used for sorting.
+// Fields are 1 based, a missing field sorts as the empty string.
+func key(text string, field int, delimiter string) string {
+	if field == 0 {
+		return strings.TrimSpace(text)
+	}
+	var fields []string
+	if delimiter == "" {
+		fields = strings.Fields(text)
+	} else {
+		fields = strings.Split(text, delimiter)
+	}
+	if field > len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[field-1])
+}
+
+// equal - Reports whether neither key sorts before the other, so with
+// stringutils.NaturalLess "01" and "1" are equal.
+func equal(less func(a, b string) bool, a, b string) bool {
+	return !less(a, b) && !less(b, a)
+}
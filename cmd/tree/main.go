@@ -0,0 +1,251 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+	"github.com/DavidGamba/go-utils/progress"
+	"github.com/DavidGamba/go-utils/sizeutils"
+
+	"github.com/DavidGamba/go-getoptions"
+)
+
+// BuildMetadata - Provides the metadata part of the version information.
+var BuildMetadata = "dev"
+
+const semVersion = "0.1.0"
+
+var logger = log.New(ioutil.Discard, "", log.LstdFlags)
+
+// events - Progress events, nil unless --progress-json is given.
+var events *progress.Reporter
+
+// node - Tree entry, also used as the JSON output.
+type node struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Size     int64     `json:"size"`
+	Mtime    time.Time `json:"mtime"`
+	Target   string    `json:"target,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Children []*node   `json:"children,omitempty"`
+}
+
+type config struct {
+	maxDepth int
+	ignores  []string
+	hidden   bool
+	dirsOnly bool
+	size     bool
+	mtime    bool
+	ascii    bool
+	dirs     int
+	files    int
+}
+
+func main() {
+	var c config
+	opt := getoptions.New()
+	opt.Self("", `Prints the contents of the given dirs, the current dir by default, as a tree.
+
+    Entries are sorted numerically when their names are integers.
+
+    Source: https://github.com/DavidGamba/go-utils`)
+	opt.HelpSynopsisArgs("[<dir>...]")
+	opt.Bool("help", false, opt.Alias("?"))
+	opt.Bool("debug", false)
+	opt.Bool("version", false, opt.Alias("V"))
+	opt.IntVar(&c.maxDepth, "max-depth", 0, opt.Alias("L"), opt.ArgName("n"), opt.Description("Maximum depth, 1 prints only the dir contents. 0 means no limit."))
+	opt.StringSliceVar(&c.ignores, "ignore", 1, 1, opt.Alias("I"), opt.ArgName("glob"), opt.Description("Skip entries whose name matches the glob."))
+	opt.BoolVar(&c.hidden, "hidden", false, opt.Alias("a"), opt.Description("Include hidden files and dirs."))
+	opt.BoolVar(&c.dirsOnly, "dirs-only", false, opt.Alias("d"), opt.Description("Only print dirs."))
+	opt.BoolVar(&c.size, "size", false, opt.Alias("s"), opt.Description("Print the size of each file."))
+	opt.BoolVar(&c.mtime, "mtime", false, opt.Alias("D"), opt.Description("Print the modification time of each entry."))
+	opt.BoolVar(&c.ascii, "ascii", false, opt.Description("Draw the tree with ASCII characters instead of Unicode box drawing ones."))
+	opt.Bool("json", false, opt.Description("Print the tree as JSON."))
+	opt.Bool("progress-json", false, opt.Description("Print progress events to STDERR as NDJSON, one JSON object per line."))
+	remaining, err := opt.Parse(os.Args[1:])
+	if opt.Called("help") {
+		fmt.Fprintln(os.Stderr, opt.Help())
+		os.Exit(1)
+	}
+	if opt.Called("version") {
+		fmt.Printf("Version: %s+%s\n", semVersion, BuildMetadata)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if opt.Called("debug") {
+		logger.SetOutput(os.Stderr)
+	}
+	if opt.Called("progress-json") {
+		events = progress.New(os.Stderr)
+	}
+	for _, pattern := range c.ignores {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: invalid glob '%s': %s\n", pattern, err)
+			os.Exit(1)
+		}
+	}
+	dirs := remaining
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	exitCode := 0
+	roots := []*node{}
+	for _, dir := range dirs {
+		fInfo, err := os.Stat(dir)
+		if err != nil {
+			events.Error(dir, err)
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			exitCode = 1
+			continue
+		}
+		root := newNode(dir, fInfo)
+		if fInfo.IsDir() {
+			c.build(root, dir, 1)
+		}
+		roots = append(roots, root)
+	}
+	events.End()
+
+	if opt.Called("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(roots)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		os.Exit(exitCode)
+	}
+	for _, root := range roots {
+		fmt.Println(c.label(root))
+		c.print(os.Stdout, root.Children, "")
+	}
+	if c.dirsOnly {
+		fmt.Printf("\n%d directories\n", c.dirs)
+	} else {
+		fmt.Printf("\n%d directories, %d files\n", c.dirs, c.files)
+	}
+	os.Exit(exitCode)
+}
+
+func newNode(path string, fInfo os.FileInfo) *node {
+	n := &node{Name: path, Size: fInfo.Size(), Mtime: fInfo.ModTime()}
+	switch {
+	case fInfo.IsDir():
+		n.Type = "dir"
+	case fInfo.Mode()&os.ModeSymlink != 0:
+		n.Type = "link"
+		n.Target, _ = os.Readlink(path)
+	default:
+		n.Type = "file"
+	}
+	return n
+}
+
+// build - Adds the contents of dir to n.
+// Symlinks are not followed.
+func (c *config) build(n *node, dir string, depth int) {
+	if c.maxDepth > 0 && depth > c.maxDepth {
+		return
+	}
+	events.Start(dir, 0)
+	fInfos, err := fileutils.ReadDirNumSort(dir, false)
+	if err != nil {
+		logger.Printf("%s: %s", dir, err)
+		events.Error(dir, err)
+		n.Error = err.Error()
+		return
+	}
+	events.Done(dir, 0)
+	for _, fInfo := range fInfos {
+		name := fInfo.Name()
+		if c.skip(name) || c.dirsOnly && !fInfo.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		child := newNode(path, fInfo)
+		child.Name = name
+		if fInfo.IsDir() {
+			c.dirs++
+			c.build(child, path, depth+1)
+		} else {
+			c.files++
+		}
+		n.Children = append(n.Children, child)
+	}
+}
+
+func (c *config) skip(name string) bool {
+	if !c.hidden && strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, pattern := range c.ignores {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *config) print(w io.Writer, children []*node, prefix string) {
+	for i, child := range children {
+		branch, indent := "├── ", "│   "
+		if c.ascii {
+			branch, indent = "|-- ", "|   "
+		}
+		if i == len(children)-1 {
+			branch, indent = "└── ", "    "
+			if c.ascii {
+				branch = "`-- "
+			}
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, c.label(child))
+		c.print(w, child.Children, prefix+indent)
+	}
+}
+
+func (c *config) label(n *node) string {
+	columns := []string{}
+	if c.size {
+		size := ""
+		if n.Type != "dir" {
+			size = sizeutils.FormatSize(n.Size)
+		}
+		columns = append(columns, fmt.Sprintf("%8s", size))
+	}
+	if c.mtime {
+		columns = append(columns, n.Mtime.Format("2006-01-02 15:04"))
+	}
+	label := n.Name
+	if n.Target != "" {
+		label += " -> " + n.Target
+	}
+	if n.Error != "" {
+		label += " [" + n.Error + "]"
+	}
+	if len(columns) == 0 {
+		return label
+	}
+	return "[" + strings.Join(columns, "  ") + "]  " + label
+}
@@ -0,0 +1,221 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/DavidGamba/go-utils/progress"
+	"github.com/DavidGamba/go-utils/yamlutils"
+
+	"github.com/DavidGamba/go-getoptions"
+)
+
+// BuildMetadata - Provides the metadata part of the version information.
+var BuildMetadata = "dev"
+
+const semVersion = "0.1.0"
+
+var logger = log.New(ioutil.Discard, "", log.LstdFlags)
+
+// events - Progress events, nil unless --progress-json is given.
+var events *progress.Reporter
+
+// Exit codes, following diff(1).
+const (
+	exitSame  = 0
+	exitDiff  = 1
+	exitError = 2
+)
+
+func main() {
+	var ignorePaths []string
+	var docA, docB int
+	opt := getoptions.New()
+	opt.Self("", `Compares the structure of two YAML files.
+
+    When a single file is given, two of its documents are compared, the first
+    and the second by default.
+    When two files are given, their documents are compared by position unless
+    --doc-a or --doc-b select specific documents.
+
+    Exit status is 0 when the inputs are equal, 1 when they differ and 2 on errors.
+
+    Source: https://github.com/DavidGamba/go-utils`)
+	opt.HelpSynopsisArgs("<file> [<file>]")
+	opt.Bool("help", false, opt.Alias("?"))
+	opt.Bool("debug", false)
+	opt.Bool("version", false, opt.Alias("V"))
+	opt.IntVar(&docA, "doc-a", 0, opt.ArgName("n"), opt.Description("Index of the document to compare from the first input, starting at 0."))
+	opt.IntVar(&docB, "doc-b", 0, opt.ArgName("n"), opt.Description("Index of the document to compare from the second input, starting at 0."))
+	opt.StringSliceVar(&ignorePaths, "ignore-paths", 1, 1, opt.Alias("i"), opt.ArgName("path"),
+		opt.Description(`Ignore differences at or under the path.
+Path elements are separated by / and can use glob patterns, for example: metadata/*/timestamp.`))
+	opt.Bool("quiet", false, opt.Alias("q"), opt.Description("Don't print the differences, only set the exit status."))
+	opt.Bool("json", false, opt.Description("Print the differences of each document as a JSON report."))
+	opt.Bool("progress-json", false, opt.Description("Print progress events to STDERR as NDJSON, one JSON object per line."))
+	remaining, err := opt.Parse(os.Args[1:])
+	if opt.Called("help") {
+		fmt.Fprintln(os.Stderr, opt.Help())
+		os.Exit(exitError)
+	}
+	if opt.Called("version") {
+		fmt.Printf("Version: %s+%s\n", semVersion, BuildMetadata)
+		os.Exit(exitSame)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(exitError)
+	}
+	if opt.Called("debug") {
+		logger.SetOutput(os.Stderr)
+		yamlutils.Logger.SetOutput(os.Stderr)
+	}
+	if opt.Called("progress-json") {
+		events = progress.New(os.Stderr)
+	}
+	if len(remaining) < 1 || len(remaining) > 2 {
+		fmt.Fprintf(os.Stderr, "ERROR: expected one or two files\n")
+		fmt.Fprintln(os.Stderr, opt.Help(getoptions.HelpSynopsis))
+		os.Exit(exitError)
+	}
+	for _, p := range ignorePaths {
+		if _, err := path.Match(p, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: invalid ignore path '%s': %s\n", p, err)
+			os.Exit(exitError)
+		}
+	}
+
+	docsA, err := readDocs(remaining[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: reading yaml file '%s': %s\n", remaining[0], err)
+		os.Exit(exitError)
+	}
+	docsB := docsA
+	if len(remaining) == 2 {
+		docsB, err = readDocs(remaining[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: reading yaml file '%s': %s\n", remaining[1], err)
+			os.Exit(exitError)
+		}
+	} else if !opt.Called("doc-b") {
+		docB = 1
+	}
+	events.End()
+
+	// pairs of document indexes to compare, -1 marks a missing document.
+	pairs := [][2]int{}
+	if len(remaining) == 2 && !opt.Called("doc-a") && !opt.Called("doc-b") {
+		for i := 0; i < len(docsA) || i < len(docsB); i++ {
+			a, b := i, i
+			if a >= len(docsA) {
+				a = -1
+			}
+			if b >= len(docsB) {
+				b = -1
+			}
+			pairs = append(pairs, [2]int{a, b})
+		}
+	} else {
+		if docA < 0 || docA >= len(docsA) {
+			fmt.Fprintf(os.Stderr, "ERROR: '%s' has no document %d\n", remaining[0], docA)
+			os.Exit(exitError)
+		}
+		if docB < 0 || docB >= len(docsB) {
+			fmt.Fprintf(os.Stderr, "ERROR: '%s' has no document %d\n", remaining[len(remaining)-1], docB)
+			os.Exit(exitError)
+		}
+		pairs = append(pairs, [2]int{docA, docB})
+	}
+
+	count := 0
+	reports := []documentReport{}
+	for _, pair := range pairs {
+		header := ""
+		if len(pairs) > 1 {
+			header = fmt.Sprintf("document %d: ", pair[0])
+		}
+		var diffs []yamlutils.Difference
+		switch {
+		case pair[0] == -1:
+			diffs = []yamlutils.Difference{{Path: []string{}, Type: yamlutils.DiffAdded, New: docsB[pair[1]].Tree}}
+			header = fmt.Sprintf("document %d: ", pair[1])
+		case pair[1] == -1:
+			diffs = []yamlutils.Difference{{Path: []string{}, Type: yamlutils.DiffRemoved, Old: docsA[pair[0]].Tree}}
+		default:
+			diffs = yamlutils.Diff(docsA[pair[0]].Tree, docsB[pair[1]].Tree)
+		}
+		r := documentReport{DocA: pair[0], DocB: pair[1], Differences: []yamlutils.Difference{}}
+		for _, d := range diffs {
+			if ignored(ignorePaths, d.Path) {
+				logger.Printf("ignoring: %s", d)
+				continue
+			}
+			count++
+			r.Differences = append(r.Differences, d)
+			if !opt.Called("quiet") && !opt.Called("json") {
+				fmt.Println(header + d.String())
+			}
+		}
+		if len(r.Differences) > 0 {
+			reports = append(reports, r)
+		}
+	}
+	if opt.Called("json") && !opt.Called("quiet") {
+		data, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Println(string(data))
+	}
+	if count > 0 {
+		os.Exit(exitDiff)
+	}
+	os.Exit(exitSame)
+}
+
+// readDocs - Reads the documents of file reporting progress events.
+func readDocs(file string) ([]*yamlutils.YML, error) {
+	events.Start(file, 0)
+	docs, err := yamlutils.NewListFromFile(file)
+	if err != nil {
+		events.Error(file, err)
+		events.End()
+		return nil, err
+	}
+	events.Done(file, 0)
+	return docs, nil
+}
+
+// documentReport - Differences between a pair of documents, printed with --json.
+// A document index of -1 marks a missing document.
+type documentReport struct {
+	DocA        int                    `json:"doc_a"`
+	DocB        int                    `json:"doc_b"`
+	Differences []yamlutils.Difference `json:"differences"`
+}
+
+// ignored - Reports whether the path, or any of its parents, matches one of the patterns.
+func ignored(patterns []string, p []string) bool {
+	for i := 1; i <= len(p); i++ {
+		prefix := strings.Join(p[:i], "/")
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.Trim(pattern, "/"), prefix); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
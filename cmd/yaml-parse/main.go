@@ -14,6 +14,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/DavidGamba/go-utils/completion"
+	"github.com/DavidGamba/go-utils/progress"
 	"github.com/DavidGamba/go-utils/yamlutils"
 
 	"github.com/DavidGamba/go-getoptions"
@@ -26,6 +28,9 @@ const semVersion = "0.5.0"
 
 var logger = log.New(ioutil.Discard, "", log.LstdFlags)
 
+// events - Progress events, nil unless --progress-json is given.
+var events *progress.Reporter
+
 func main() {
 	var file string
 	var include bool
@@ -38,15 +43,25 @@ func main() {
 	opt.Bool("help", false, opt.Alias("?"))
 	opt.Bool("debug", false)
 	opt.Bool("version", false, opt.Alias("V"))
+	opt.String("completion", "", opt.ArgName("bash|zsh|fish"), opt.Description("Print the shell completion script, for example: source <(yaml-parse --completion zsh)"))
 	opt.Bool("n", false, opt.Description("Remove trailing spaces."))
 	opt.Bool("silent", false, opt.Description("Don't print full context errors."))
 	opt.BoolVar(&include, "include", false, opt.Description("Include parent key if it is a map key."))
 	opt.StringVar(&file, "file", "", opt.Alias("f"), opt.ArgName("file"), opt.Description("YAML file to read."))
 	opt.StringVar(&add, "add", "", opt.ArgName("yaml/json input"), opt.Description("Child input to add at the current location."))
+	opt.Bool("progress-json", false, opt.Description("Print progress events to STDERR as NDJSON, one JSON object per line."))
 	opt.StringSliceVar(&keys, "key", 1, 99, opt.Alias("k"), opt.ArgName("key/index"),
 		opt.Description(`Key or index to descend to.
 Multiple keys allow to descend further.
 Indexes are positive integers.`))
+	if line := os.Getenv("COMP_LINE"); line != "" {
+		if candidates, ok := completeKey(line); ok {
+			if len(candidates) > 0 {
+				fmt.Println(strings.Join(candidates, "\n"))
+			}
+			os.Exit(0)
+		}
+	}
 	_, err := opt.Parse(os.Args[1:])
 	if opt.Called("help") {
 		fmt.Fprintln(os.Stderr, opt.Help())
@@ -56,6 +71,15 @@ Indexes are positive integers.`))
 		fmt.Printf("Version: %s+%s\n", semVersion, BuildMetadata)
 		os.Exit(0)
 	}
+	if opt.Called("completion") {
+		script, err := completion.Script(opt.Value("completion").(string), "yaml-parse")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(script)
+		os.Exit(0)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
 		os.Exit(1)
@@ -64,6 +88,9 @@ Indexes are positive integers.`))
 		logger.SetOutput(os.Stderr)
 		yamlutils.Logger.SetOutput(os.Stderr)
 	}
+	if opt.Called("progress-json") {
+		events = progress.New(os.Stderr)
+	}
 	var xpath []string
 	for _, k := range keys {
 		xpath = append(xpath, strings.Split(k, "/")...)
@@ -77,24 +104,33 @@ Indexes are positive integers.`))
 	var yml *yamlutils.YML
 	if !stdinIsDevice && !opt.Called("file") {
 		logger.Printf("Reading from stdin\n")
-		reader := os.Stdin
+		events.Start("-", 0)
+		reader := events.Reader("-", 0, os.Stdin)
 		yml, err = yamlutils.NewFromReader(reader)
 		if err != nil {
+			events.Error("-", err)
+			events.End()
 			fmt.Fprintf(os.Stderr, "ERROR: reading yaml from STDIN: %s\n", err)
 			os.Exit(1)
 		}
+		events.Done("-", 0)
 	} else {
 		logger.Printf("Reading from file: %s\n", file)
 		if !opt.Called("file") {
 			fmt.Fprintf(os.Stderr, "ERROR: missing argument '--file <file>'\n")
 			os.Exit(1)
 		}
+		events.Start(file, 0)
 		yml, err = yamlutils.NewFromFile(file)
 		if err != nil {
+			events.Error(file, err)
+			events.End()
 			fmt.Fprintf(os.Stderr, "ERROR: reading yaml file: %s\n", err)
 			os.Exit(1)
 		}
+		events.Done(file, 0)
 	}
+	events.End()
 
 	if opt.Called("add") {
 		str, err := yml.AddString(xpath, add)
@@ -125,3 +161,63 @@ Indexes are positive integers.`))
 	}
 	fmt.Printf(str)
 }
+
+// completeKey - Completes the --key argument at the end of the COMP_LINE with
+// the children of the --file YAML at the path given so far.
+// Returns false when the word being completed is not a --key argument, to
+// leave it to go-getoptions.
+func completeKey(line string) ([]string, bool) {
+	words := completion.Words(line)
+	current := words[len(words)-1]
+	var file string
+	var keys []string
+	inKey := false
+	for i := 1; i < len(words)-1; i++ {
+		w := words[i]
+		switch {
+		case w == "--key" || w == "-k":
+			inKey = true
+		case strings.HasPrefix(w, "--key="):
+			keys = append(keys, strings.Split(strings.TrimPrefix(w, "--key="), "/")...)
+			inKey = true
+		case w == "--file" || w == "-f":
+			if i+1 < len(words)-1 {
+				i++
+				file = words[i]
+			}
+			inKey = false
+		case strings.HasPrefix(w, "--file="):
+			file = strings.TrimPrefix(w, "--file=")
+			inKey = false
+		case strings.HasPrefix(w, "-"):
+			inKey = false
+		case inKey:
+			keys = append(keys, strings.Split(w, "/")...)
+		}
+	}
+	if !inKey || strings.HasPrefix(current, "-") {
+		return nil, false
+	}
+	if file == "" {
+		return nil, true
+	}
+	// Complete the last element of a key with '/' separated elements.
+	prefix := ""
+	if i := strings.LastIndex(current, "/"); i >= 0 {
+		prefix = current[:i+1]
+		keys = append(keys, strings.Split(current[:i], "/")...)
+	}
+	yml, err := yamlutils.NewFromFile(file)
+	if err != nil {
+		return nil, true
+	}
+	children, err := yml.Keys(keys)
+	if err != nil {
+		return nil, true
+	}
+	candidates := []string{}
+	for _, c := range completion.Filter(children, current[len(prefix):]) {
+		candidates = append(candidates, prefix+c)
+	}
+	return candidates, true
+}
@@ -0,0 +1,206 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package columnar - Converts streamed CSV and JSON lines records into a columnar format.
+
+Records are buffered into row groups and handed, column by column, to an
+Encoder. The package ships an encoder for a simple columnar binary format,
+see NewFileEncoder, and other formats, like Parquet, can be plugged in by
+implementing Encoder.
+*/
+package columnar
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/DavidGamba/go-utils/logutils"
+)
+
+// ErrUnknownColumn - A record has a field that is not one of the columns.
+var ErrUnknownColumn = fmt.Errorf("unknown column")
+
+// DefaultRowGroupSize - Rows buffered before they are handed to the Encoder.
+const DefaultRowGroupSize = 10000
+
+// Encoder - Writes row groups in a columnar format.
+type Encoder interface {
+	// WriteRowGroup - Writes a row group, data has one slice per column with
+	// one value per row.
+	WriteRowGroup(data [][]string) error
+
+	// Close - Writes any trailing metadata. It doesn't close the underlying writer.
+	Close() error
+}
+
+// Writer - Buffers records into row groups for an Encoder.
+type Writer struct {
+	enc          Encoder
+	columns      []string
+	index        map[string]int
+	rowGroupSize int
+	data         [][]string
+	rows         int
+}
+
+// NewWriter - Returns a Writer for records with the given columns.
+// rowGroupSize < 1 uses DefaultRowGroupSize.
+func NewWriter(enc Encoder, columns []string, rowGroupSize int) *Writer {
+	if rowGroupSize < 1 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	w := &Writer{enc: enc, columns: columns, index: map[string]int{}, rowGroupSize: rowGroupSize}
+	for i, c := range columns {
+		w.index[c] = i
+	}
+	w.reset()
+	return w
+}
+
+func (w *Writer) reset() {
+	w.data = make([][]string, len(w.columns))
+	for i := range w.data {
+		w.data[i] = make([]string, 0, w.rowGroupSize)
+	}
+	w.rows = 0
+}
+
+// Write - Adds a record with one value per column, in column order.
+func (w *Writer) Write(record []string) error {
+	if len(record) != len(w.columns) {
+		return fmt.Errorf("record has %d fields, expected %d", len(record), len(w.columns))
+	}
+	for i, v := range record {
+		w.data[i] = append(w.data[i], v)
+	}
+	return w.added()
+}
+
+// WriteMap - Adds a record by column name, missing columns are empty.
+func (w *Writer) WriteMap(record map[string]string) error {
+	for k := range record {
+		if _, ok := w.index[k]; !ok {
+			return fmt.Errorf("%w: '%s'", ErrUnknownColumn, k)
+		}
+	}
+	for i, c := range w.columns {
+		w.data[i] = append(w.data[i], record[c])
+	}
+	return w.added()
+}
+
+func (w *Writer) added() error {
+	w.rows++
+	if w.rows >= w.rowGroupSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush - Hands the buffered rows to the Encoder as a row group.
+func (w *Writer) Flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	err := w.enc.WriteRowGroup(w.data)
+	w.reset()
+	return err
+}
+
+// Close - Flushes and closes the Encoder.
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if err != nil {
+		return err
+	}
+	return w.enc.Close()
+}
+
+// ConvertCSV - Converts CSV read from r, whose first row is the header with
+// the column names, using the Encoder returned by newEnc for those columns.
+func ConvertCSV(r io.Reader, newEnc func(columns []string) (Encoder, error), rowGroupSize int) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+	enc, err := newEnc(header)
+	if err != nil {
+		return err
+	}
+	w := NewWriter(enc, header, rowGroupSize)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// ConvertJSONLines - Converts JSON lines read from r, nested objects are
+// flattened as in logutils.JSONLines.
+// When columns is empty, the sorted keys of the first record are used.
+// Records with keys that are not columns fail with ErrUnknownColumn.
+func ConvertJSONLines(r io.Reader, columns []string, newEnc func(columns []string) (Encoder, error), rowGroupSize int) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var w *Writer
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		record, err := logutils.ParseLine(logutils.JSONLines, line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if w == nil {
+			if len(columns) == 0 {
+				for k := range record {
+					columns = append(columns, k)
+				}
+				sort.Strings(columns)
+			}
+			enc, err := newEnc(columns)
+			if err != nil {
+				return err
+			}
+			w = NewWriter(enc, columns, rowGroupSize)
+		}
+		err = w.WriteMap(record)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if w == nil {
+		enc, err := newEnc(columns)
+		if err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return w.Close()
+}
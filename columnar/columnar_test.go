@@ -0,0 +1,116 @@
+package columnar
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type memEncoder struct {
+	groups [][][]string
+	closed bool
+}
+
+func (e *memEncoder) WriteRowGroup(data [][]string) error {
+	e.groups = append(e.groups, data)
+	return nil
+}
+
+func (e *memEncoder) Close() error {
+	e.closed = true
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	enc := &memEncoder{}
+	w := NewWriter(enc, []string{"a", "b"}, 2)
+	w.Write([]string{"1", "x"})
+	w.Write([]string{"2", "y"})
+	w.WriteMap(map[string]string{"b": "z"})
+	err := w.Write([]string{"1"})
+	if err == nil {
+		t.Errorf("Expected field count error\n")
+	}
+	err = w.WriteMap(map[string]string{"c": "z"})
+	if !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Expected ErrUnknownColumn, got: %v\n", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := [][][]string{
+		{{"1", "2"}, {"x", "y"}},
+		{{""}, {"z"}},
+	}
+	if !reflect.DeepEqual(enc.groups, expected) || !enc.closed {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, enc.groups)
+	}
+}
+
+func TestConvertCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := ConvertCSV(strings.NewReader("name,size\na,1\n\"b,c\",2\nd,3\n"), NewFileEncoderFunc(&buf), 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(r.Columns(), []string{"name", "size"}) || r.Rows() != 3 {
+		t.Errorf("Unexpected columns or rows: %q, %d\n", r.Columns(), r.Rows())
+	}
+	sizes, err := r.Column("size")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(sizes, []string{"1", "2", "3"}) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", []string{"1", "2", "3"}, sizes)
+	}
+
+	err = ConvertCSV(strings.NewReader(""), NewFileEncoderFunc(&buf), 2)
+	if err == nil {
+		t.Errorf("Expected missing header error\n")
+	}
+}
+
+func TestConvertJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	input := `{"level":"info","req":{"id":1}}
+
+{"level":"warn"}
+`
+	err := ConvertJSONLines(strings.NewReader(input), nil, NewFileEncoderFunc(&buf), 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(r.Columns(), []string{"level", "req.id"}) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", []string{"level", "req.id"}, r.Columns())
+	}
+	ids, _ := r.Column("req.id")
+	if !reflect.DeepEqual(ids, []string{"1", ""}) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", []string{"1", ""}, ids)
+	}
+
+	err = ConvertJSONLines(strings.NewReader(`{"a":1}`+"\n"+`{"b":2}`), nil, NewFileEncoderFunc(&buf), 0)
+	if !errors.Is(err, ErrUnknownColumn) || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("Expected ErrUnknownColumn on line 2, got: %v\n", err)
+	}
+
+	buf.Reset()
+	err = ConvertJSONLines(strings.NewReader(""), []string{"a"}, NewFileEncoderFunc(&buf), 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	r, err = NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || r.Rows() != 0 {
+		t.Errorf("Expected an empty file, got: %v\n", err)
+	}
+}
@@ -0,0 +1,334 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package columnar
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// File format, integers are unsigned varints unless noted otherwise:
+//
+//	file      = magic rowGroup* footer footerLen magic
+//	magic     = "CLMN1\n"
+//	rowGroup  = chunk per column
+//	chunk     = byteLen (valueLen value)*
+//	footer    = columnCount (nameLen name)* rowGroupCount (offset rows)*
+//	footerLen = uint32 little endian
+//
+// Each chunk is prefixed by its length so a reader can skip the columns it
+// doesn't need.
+
+// ErrInvalidFile - The data is not in the columnar file format.
+var ErrInvalidFile = fmt.Errorf("invalid columnar file")
+
+var magic = []byte("CLMN1\n")
+
+type rowGroupInfo struct {
+	offset int64
+	rows   int
+}
+
+// FileEncoder - Encoder for the package columnar file format.
+type FileEncoder struct {
+	w       *bufio.Writer
+	columns []string
+	offset  int64
+	groups  []rowGroupInfo
+	started bool
+}
+
+// NewFileEncoder - Returns an Encoder that writes the columns to w.
+func NewFileEncoder(w io.Writer, columns []string) *FileEncoder {
+	return &FileEncoder{w: bufio.NewWriter(w), columns: columns}
+}
+
+// NewFileEncoderFunc - Returns a constructor for ConvertCSV and ConvertJSONLines.
+func NewFileEncoderFunc(w io.Writer) func(columns []string) (Encoder, error) {
+	return func(columns []string) (Encoder, error) {
+		return NewFileEncoder(w, columns), nil
+	}
+}
+
+func (e *FileEncoder) write(b []byte) error {
+	n, err := e.w.Write(b)
+	e.offset += int64(n)
+	return err
+}
+
+func (e *FileEncoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	return e.write(magic)
+}
+
+// WriteRowGroup - Writes one chunk per column.
+func (e *FileEncoder) WriteRowGroup(data [][]string) error {
+	if len(data) != len(e.columns) {
+		return fmt.Errorf("row group has %d columns, expected %d", len(data), len(e.columns))
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	err := e.start()
+	if err != nil {
+		return err
+	}
+	rows := -1
+	info := rowGroupInfo{offset: e.offset}
+	var chunk bytes.Buffer
+	for _, values := range data {
+		if rows >= 0 && len(values) != rows {
+			return fmt.Errorf("columns with different row counts: %d and %d", rows, len(values))
+		}
+		rows = len(values)
+		chunk.Reset()
+		for _, v := range values {
+			putUvarint(&chunk, uint64(len(v)))
+			chunk.WriteString(v)
+		}
+		var prefix bytes.Buffer
+		putUvarint(&prefix, uint64(chunk.Len()))
+		err := e.write(prefix.Bytes())
+		if err != nil {
+			return err
+		}
+		err = e.write(chunk.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	info.rows = rows
+	e.groups = append(e.groups, info)
+	return nil
+}
+
+// Close - Writes the footer and flushes.
+func (e *FileEncoder) Close() error {
+	err := e.start()
+	if err != nil {
+		return err
+	}
+	var footer bytes.Buffer
+	putUvarint(&footer, uint64(len(e.columns)))
+	for _, c := range e.columns {
+		putUvarint(&footer, uint64(len(c)))
+		footer.WriteString(c)
+	}
+	putUvarint(&footer, uint64(len(e.groups)))
+	for _, g := range e.groups {
+		putUvarint(&footer, uint64(g.offset))
+		putUvarint(&footer, uint64(g.rows))
+	}
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(footer.Len()))
+	for _, b := range [][]byte{footer.Bytes(), size, magic} {
+		err := e.write(b)
+		if err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+func putUvarint(b *bytes.Buffer, v uint64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	b.Write(buf[:binary.PutUvarint(buf, v)])
+}
+
+// Reader - Reads files written by FileEncoder.
+type Reader struct {
+	r       io.ReaderAt
+	columns []string
+	groups  []rowGroupInfo
+
+	// dataEnd - Offset of the footer, where the last row group ends.
+	dataEnd int64
+}
+
+// NewReader - Reads the footer of the size bytes file in r.
+// The footer is checked against the file size, a corrupt one returns
+// ErrInvalidFile.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	tail := int64(4 + len(magic))
+	if size < int64(len(magic))+tail {
+		return nil, ErrInvalidFile
+	}
+	buf := make([]byte, tail)
+	_, err := r.ReadAt(buf, size-tail)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(buf[4:], magic) {
+		return nil, ErrInvalidFile
+	}
+	footerLen := int64(binary.LittleEndian.Uint32(buf[:4]))
+	if footerLen > size-tail-int64(len(magic)) {
+		return nil, ErrInvalidFile
+	}
+	footer := make([]byte, footerLen)
+	_, err = r.ReadAt(footer, size-tail-footerLen)
+	if err != nil {
+		return nil, err
+	}
+	br := bytes.NewReader(footer)
+	rd := &Reader{r: r, dataEnd: size - tail - footerLen}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+	// Each name takes at least its length byte.
+	if n > uint64(br.Len()) {
+		return nil, fmt.Errorf("%w: %d columns in a %d bytes footer", ErrInvalidFile, n, footerLen)
+	}
+	for i := uint64(0); i < n; i++ {
+		name, err := readString(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+		}
+		rd.columns = append(rd.columns, name)
+	}
+	n, err = binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+	// Each group takes at least an offset and a rows byte.
+	if n > uint64(br.Len()/2) {
+		return nil, fmt.Errorf("%w: %d row groups in a %d bytes footer", ErrInvalidFile, n, footerLen)
+	}
+	rows := make([]uint64, n)
+	for i := range rows {
+		offset, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+		}
+		rows[i], err = binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+		}
+		start := int64(len(magic))
+		if i > 0 {
+			start = rd.groups[i-1].offset + 1
+		}
+		if offset < uint64(start) || offset >= uint64(rd.dataEnd) {
+			return nil, fmt.Errorf("%w: row group offset %d out of range", ErrInvalidFile, offset)
+		}
+		rd.groups = append(rd.groups, rowGroupInfo{offset: int64(offset)})
+	}
+	// Each row takes at least its length byte in every chunk of the group.
+	for i := range rd.groups {
+		if rows[i] > uint64(rd.groupEnd(i)-rd.groups[i].offset) {
+			return nil, fmt.Errorf("%w: %d rows in row group %d", ErrInvalidFile, rows[i], i)
+		}
+		rd.groups[i].rows = int(rows[i])
+	}
+	return rd, nil
+}
+
+// groupEnd - Offset where row group i ends.
+func (rd *Reader) groupEnd(i int) int64 {
+	if i+1 < len(rd.groups) {
+		return rd.groups[i+1].offset
+	}
+	return rd.dataEnd
+}
+
+// Columns - Column names.
+func (rd *Reader) Columns() []string {
+	return rd.columns
+}
+
+// Rows - Number of rows.
+func (rd *Reader) Rows() int {
+	n := 0
+	for _, g := range rd.groups {
+		n += g.rows
+	}
+	return n
+}
+
+// Column - Returns all the values of the named column, only its chunks are read.
+func (rd *Reader) Column(name string) ([]string, error) {
+	index := -1
+	for i, c := range rd.columns {
+		if c == name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownColumn, name)
+	}
+	values := make([]string, 0, rd.Rows())
+	for gi, g := range rd.groups {
+		offset := g.offset
+		end := rd.groupEnd(gi)
+		for i := 0; ; i++ {
+			length, n, err := rd.uvarintAt(offset)
+			if err != nil {
+				return nil, err
+			}
+			offset += int64(n)
+			if offset > end || length > uint64(end-offset) {
+				return nil, fmt.Errorf("%w: chunk of %d bytes past the end of row group %d", ErrInvalidFile, length, gi)
+			}
+			if i < index {
+				offset += int64(length)
+				continue
+			}
+			chunk := make([]byte, length)
+			_, err = rd.r.ReadAt(chunk, offset)
+			if err != nil {
+				return nil, err
+			}
+			br := bytes.NewReader(chunk)
+			for j := 0; j < g.rows; j++ {
+				v, err := readString(br)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+				}
+				values = append(values, v)
+			}
+			break
+		}
+	}
+	return values, nil
+}
+
+// uvarintAt - Reads a uvarint at offset and returns it with its size.
+func (rd *Reader) uvarintAt(offset int64) (uint64, int, error) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n, err := rd.r.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return 0, 0, err
+	}
+	v, size := binary.Uvarint(buf[:n])
+	if size <= 0 {
+		return 0, 0, ErrInvalidFile
+	}
+	return v, size, nil
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return string(b), err
+}
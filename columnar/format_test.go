@@ -0,0 +1,128 @@
+package columnar
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewFileEncoder(&buf, []string{"a", "b", "c"})
+	long := strings.Repeat("x", 300)
+	groups := [][][]string{
+		{{"1", "2"}, {"", long}, {"ñ", "c"}},
+		{{"3"}, {"b"}, {""}},
+	}
+	for _, g := range groups {
+		err := enc.WriteRowGroup(g)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	}
+	err := enc.WriteRowGroup([][]string{{"1"}, {"1", "2"}, {"1"}})
+	if err == nil {
+		t.Errorf("Expected row count error\n")
+	}
+	err = enc.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	data := buf.Bytes()
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if r.Rows() != 3 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 3, r.Rows())
+	}
+	for name, expected := range map[string][]string{
+		"a": {"1", "2", "3"},
+		"b": {"", long, "b"},
+		"c": {"ñ", "c", ""},
+	} {
+		got, err := r.Column(name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+		}
+	}
+	_, err = r.Column("d")
+	if !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Expected ErrUnknownColumn, got: %v\n", err)
+	}
+
+	_, err = NewReader(bytes.NewReader(data[:len(data)-1]), int64(len(data)-1))
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Expected ErrInvalidFile, got: %v\n", err)
+	}
+	_, err = NewReader(bytes.NewReader([]byte("x")), 1)
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Expected ErrInvalidFile, got: %v\n", err)
+	}
+}
+
+func TestReaderInvalidFooter(t *testing.T) {
+	// file - Builds a file with the given row group bytes and footer.
+	file := func(body []byte, footer ...uint64) []byte {
+		var b bytes.Buffer
+		b.Write(magic)
+		b.Write(body)
+		var f bytes.Buffer
+		for _, v := range footer {
+			putUvarint(&f, v)
+		}
+		b.Write(f.Bytes())
+		b.Write([]byte{byte(f.Len()), 0, 0, 0})
+		b.Write(magic)
+		return b.Bytes()
+	}
+	// One column named "a" and one row group with the value "x".
+	body := []byte{2, 1, 'x'}
+	cases := []struct {
+		name   string
+		data   []byte
+		column bool
+	}{
+		{"huge row count", file(body, 1, 1, 'a', 1, 6, 1<<63), false},
+		{"more rows than bytes", file(body, 1, 1, 'a', 1, 6, 4), false},
+		{"huge column count", file(body, 1<<40), false},
+		{"huge row group count", file(body, 1, 1, 'a', 1<<40), false},
+		{"offset in footer", file(body, 1, 1, 'a', 1, 9, 1), false},
+		{"offset in magic", file(body, 1, 1, 'a', 1, 2, 1), false},
+		{"offsets out of order", file(body, 1, 1, 'a', 2, 7, 1, 6, 1), false},
+		{"chunk past row group", file([]byte{9, 1, 'x'}, 1, 1, 'a', 1, 6, 1), true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(c.data), int64(len(c.data)))
+			if c.column {
+				if err != nil {
+					t.Fatalf("Unexpected error: %s\n", err)
+				}
+				_, err = r.Column("a")
+			}
+			if !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("Expected ErrInvalidFile, got: %v\n", err)
+			}
+		})
+	}
+
+	data := file(body, 1, 1, 'a', 1, 6, 1)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got, err := r.Column("a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", []string{"x"}, got)
+	}
+}
@@ -0,0 +1,116 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package completion - Shell completion scripts for CLIs built with go-getoptions.
+
+The scripts don't hardcode the options, they run the CLI with the COMP_LINE
+environment variable set to the line being completed, which go-getoptions
+answers with the matching options, so completion stays in sync with the CLI.
+CLIs can complete option arguments themselves by checking COMP_LINE before
+calling Parse, see Words and Filter.
+
+	eval "$(tool --completion bash)"   # ~/.bashrc
+	source <(tool --completion zsh)    # ~/.zshrc, after compinit
+	tool --completion fish | source    # ~/.config/fish/config.fish
+*/
+package completion
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownShell - The shell is not one of Shells.
+var ErrUnknownShell = fmt.Errorf("unknown shell")
+
+// Shells - Shells supported by Script.
+var Shells = []string{"bash", "fish", "zsh"}
+
+var scripts = map[string]string{
+	// With -C bash runs the command with COMP_LINE set and -o default falls
+	// back to file completion when it has no candidates.
+	"bash": `complete -o default -C {{name}} {{name}}
+`,
+	"zsh": `#compdef {{name}}
+_{{fn}}() {
+	local -a candidates
+	candidates=(${(f)"$(COMP_LINE="${BUFFER[1,CURSOR]}" {{name}} 2>/dev/null)"})
+	if (( ${#candidates} )); then
+		compadd -- $candidates
+	else
+		_files
+	fi
+}
+compdef _{{fn}} {{name}}
+`,
+	"fish": `complete -c {{name}} -a '(env COMP_LINE=(commandline -cp) {{name}} 2>/dev/null)'
+`,
+}
+
+// Script - Returns the completion script of the given shell, "bash", "zsh" or
+// "fish", for the CLI called name, which must be in the PATH.
+func Script(shell, name string) (string, error) {
+	script, ok := scripts[shell]
+	if !ok {
+		return "", fmt.Errorf("%w: '%s', use one of %s", ErrUnknownShell, shell, strings.Join(Shells, ", "))
+	}
+	fn := strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	return strings.NewReplacer("{{name}}", name, "{{fn}}", fn).Replace(script), nil
+}
+
+// Words - Splits a COMP_LINE into words, the last one is the word being
+// completed, empty when the line ends in a space.
+// Single and double quotes group words and are removed, a backslash escapes
+// the next character.
+func Words(line string) []string {
+	words := []string{}
+	var word strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	return append(words, word.String())
+}
+
+// Filter - Returns the sorted candidates that start with prefix.
+func Filter(candidates []string, prefix string) []string {
+	matches := []string{}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,78 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package completion
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		shell    string
+		contains []string
+	}{
+		{"bash", []string{"complete -o default -C yaml-parse yaml-parse\n"}},
+		{"zsh", []string{"#compdef yaml-parse\n", "_yaml_parse() {", "COMP_LINE=\"${BUFFER[1,CURSOR]}\" yaml-parse", "compdef _yaml_parse yaml-parse\n"}},
+		{"fish", []string{"complete -c yaml-parse -a '(env COMP_LINE=(commandline -cp) yaml-parse 2>/dev/null)'\n"}},
+	}
+	for _, test := range tests {
+		t.Run(test.shell, func(t *testing.T) {
+			got, err := Script(test.shell, "yaml-parse")
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			for _, s := range test.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("Expected %q in:\n%s\n", s, got)
+				}
+			}
+			if strings.Contains(got, "{{") {
+				t.Errorf("Unexpanded placeholder in:\n%s\n", got)
+			}
+		})
+	}
+	_, err := Script("tcsh", "yaml-parse")
+	if !errors.Is(err, ErrUnknownShell) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"tool", []string{"tool"}},
+		{"tool ", []string{"tool", ""}},
+		{"tool  -k a/b", []string{"tool", "-k", "a/b"}},
+		{`tool -f "my file.yml" -k `, []string{"tool", "-f", "my file.yml", "-k", ""}},
+		{`tool -f my\ file.yml -k 'a b`, []string{"tool", "-f", "my file.yml", "-k", "a b"}},
+	}
+	for _, test := range tests {
+		got := Words(test.line)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, got)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	got := Filter([]string{"world", "again", "west", "x"}, "w")
+	expected := []string{"west", "world"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+	got = Filter(nil, "")
+	if len(got) != 0 {
+		t.Errorf("Unexpected matches: %v\n", got)
+	}
+}
@@ -0,0 +1,154 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package csvutils - CSV writer that produces files spreadsheets open correctly.
+*/
+package csvutils
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// Option - Writer option.
+type Option func(*options)
+
+type options struct {
+	bom            bool
+	crlf           bool
+	delimiter      rune
+	escapeFormulas bool
+}
+
+// BOM - Writes a UTF-8 byte order mark before the first record so
+// spreadsheets detect the encoding.
+func BOM() Option {
+	return func(o *options) {
+		o.bom = true
+	}
+}
+
+// CRLF - Ends records with \r\n instead of \n.
+func CRLF() Option {
+	return func(o *options) {
+		o.crlf = true
+	}
+}
+
+// Delimiter - Sets the field delimiter, defaults to ','.
+// Spreadsheets in locales that use ',' as the decimal separator expect ';'.
+func Delimiter(r rune) Option {
+	return func(o *options) {
+		o.delimiter = r
+	}
+}
+
+// EscapeFormulas - Prefixes fields that a spreadsheet would evaluate as a
+// formula, the ones starting with '=', '+', '-', '@', tab or carriage return,
+// with a single quote to prevent CSV injection.
+// Numbers, like -1.5, are left as is.
+func EscapeFormulas() Option {
+	return func(o *options) {
+		o.escapeFormulas = true
+	}
+}
+
+// Excel - BOM, CRLF and EscapeFormulas, the settings Excel handles best.
+func Excel() Option {
+	return func(o *options) {
+		BOM()(o)
+		CRLF()(o)
+		EscapeFormulas()(o)
+	}
+}
+
+// Writer - encoding/csv Writer with spreadsheet friendly options.
+type Writer struct {
+	w          *csv.Writer
+	out        io.Writer
+	opts       options
+	bomWritten bool
+	err        error
+}
+
+// NewWriter - Returns a Writer that writes to w.
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	o := options{delimiter: ','}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = o.delimiter
+	cw.UseCRLF = o.crlf
+	return &Writer{w: cw, out: w, opts: o}
+}
+
+// Write - Writes a single record, it might be buffered until Flush is called.
+func (w *Writer) Write(record []string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.opts.bom && !w.bomWritten {
+		w.bomWritten = true
+		_, err := w.out.Write([]byte("\xef\xbb\xbf"))
+		if err != nil {
+			w.err = err
+			return err
+		}
+	}
+	if w.opts.escapeFormulas {
+		escaped := make([]string, len(record))
+		for i, field := range record {
+			escaped[i] = escapeFormula(field)
+		}
+		record = escaped
+	}
+	return w.w.Write(record)
+}
+
+// WriteAll - Writes multiple records and flushes.
+func (w *Writer) WriteAll(records [][]string) error {
+	for _, record := range records {
+		err := w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Flush - Writes any buffered data to the underlying writer.
+func (w *Writer) Flush() {
+	w.w.Flush()
+}
+
+// Error - Returns any error from a previous Write or Flush.
+func (w *Writer) Error() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Error()
+}
+
+func escapeFormula(field string) string {
+	if field == "" {
+		return field
+	}
+	switch field[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+	default:
+		return field
+	}
+	if _, err := strconv.ParseFloat(field, 64); err == nil {
+		return field
+	}
+	return "'" + field
+}
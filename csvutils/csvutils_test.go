@@ -0,0 +1,56 @@
+package csvutils
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	records := [][]string{
+		{"name", "value"},
+		{"=1+2", "-1.5"},
+		{"@SUM(A1)", "a;b"},
+		{"+cmd", "-x"},
+	}
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected string
+	}{
+		{"default", nil, "name,value\n=1+2,-1.5\n@SUM(A1),a;b\n+cmd,-x\n"},
+		{"excel", []Option{Excel()}, "\xef\xbb\xbfname,value\r\n'=1+2,-1.5\r\n'@SUM(A1),a;b\r\n'+cmd,'-x\r\n"},
+		{"delimiter", []Option{Delimiter(';'), EscapeFormulas()}, "name;value\n'=1+2;-1.5\n'@SUM(A1);\"a;b\"\n'+cmd;'-x\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewWriter(&buf, test.opts...).WriteAll(records)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if buf.String() != test.expected {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, buf.String())
+			}
+		})
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("write error") }
+
+func TestWriterError(t *testing.T) {
+	w := NewWriter(failWriter{}, BOM())
+	err := w.Write([]string{"a"})
+	if err == nil {
+		t.Errorf("Expected write error\n")
+	}
+	if w.Error() == nil {
+		t.Errorf("Expected write error\n")
+	}
+	err = NewWriter(failWriter{}).WriteAll([][]string{{"a"}})
+	if err == nil {
+		t.Errorf("Expected write error\n")
+	}
+}
@@ -0,0 +1,243 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package envutils
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+)
+
+// ErrInvalidLine - The dotenv line is not of the form 'KEY=value'.
+var ErrInvalidLine = fmt.Errorf("invalid dotenv line")
+
+// ErrUnterminatedQuote - A quoted dotenv value is missing its closing quote.
+var ErrUnterminatedQuote = fmt.Errorf("unterminated quoted value")
+
+// entry - dotenv key/value and the lines it spans in the source.
+type entry struct {
+	key    string
+	value  string
+	export bool
+	start  int
+	end    int
+}
+
+// Parse - Parses dotenv formatted input.
+//
+// Supported syntax:
+//
+//	# comment
+//	KEY=value # inline comment
+//	export KEY=value
+//	KEY='literal $value'
+//	KEY="escaped \"value\"\nwith newline"
+//	KEY="multi
+//	line"
+func Parse(r io.Reader) (map[string]string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parse(splitLines(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(entries))
+	for _, e := range entries {
+		m[e.key] = e.value
+	}
+	return m, nil
+}
+
+// ReadFile - Parses the dotenv file into a map.
+func ReadFile(filename string) (map[string]string, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	m, err := Parse(fh)
+	if err != nil {
+		return nil, fmt.Errorf("'%s': %w", filename, err)
+	}
+	return m, nil
+}
+
+// Load - Reads the dotenv file and sets its variables in the process environment.
+// Variables that are already set are not modified.
+func Load(filename string) error {
+	return load(filename, false)
+}
+
+// Overload - Reads the dotenv file and sets its variables in the process environment.
+// Variables that are already set are overwritten.
+func Overload(filename string) error {
+	return load(filename, true)
+}
+
+func load(filename string, overwrite bool) error {
+	m, err := ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	for k, v := range m {
+		if _, ok := os.LookupEnv(k); ok && !overwrite {
+			continue
+		}
+		err = os.Setenv(k, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateFile - Sets the given keys in the dotenv file.
+// Existing keys are updated in place, preserving comments, blank lines and
+// ordering. New keys are appended in sorted order.
+// The file is created if it doesn't exist and it is written atomically.
+func UpdateFile(filename string, values map[string]string) error {
+	perm := os.FileMode(0644)
+	var lines []string
+	data, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		fInfo, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		perm = fInfo.Mode().Perm()
+		lines = splitLines(string(data))
+	}
+	entries, err := parse(lines)
+	if err != nil {
+		return fmt.Errorf("'%s': %w", filename, err)
+	}
+	updated := map[string]bool{}
+	// Walk backwards so replacing multi-line entries doesn't shift the pending ones.
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		v, ok := values[e.key]
+		if !ok {
+			continue
+		}
+		updated[e.key] = true
+		line := e.key + "=" + formatValue(v)
+		if e.export {
+			line = "export " + line
+		}
+		lines = append(lines[:e.start], append([]string{line}, lines[e.end+1:]...)...)
+	}
+	keys := []string{}
+	for k := range values {
+		if !updated[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lines = append(lines, k+"="+formatValue(values[k]))
+	}
+	out := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		out += "\n"
+	}
+	return fileutils.WriteFileAtomic(filename, []byte(out), perm)
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+func parse(lines []string) ([]entry, error) {
+	entries := []entry{}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e := entry{start: i, end: i}
+		if strings.HasPrefix(line, "export ") {
+			e.export = true
+			line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		}
+		kv := strings.SplitN(line, "=", 2)
+		e.key = strings.TrimSpace(kv[0])
+		if len(kv) != 2 || e.key == "" || strings.ContainsAny(e.key, " \t") {
+			return entries, fmt.Errorf("%w: %d: %s", ErrInvalidLine, i+1, lines[i])
+		}
+		value := strings.TrimSpace(kv[1])
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			value = value[1:]
+			for {
+				end := closingQuote(value, quote)
+				if end >= 0 {
+					value = value[:end]
+					break
+				}
+				if e.end+1 >= len(lines) {
+					return entries, fmt.Errorf("%w: %d: %s", ErrUnterminatedQuote, i+1, lines[i])
+				}
+				e.end++
+				value += "\n" + lines[e.end]
+			}
+			if quote == '"' {
+				value = unescape(value)
+			}
+			i = e.end
+		} else if idx := strings.Index(value, " #"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+		e.value = value
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// closingQuote - Returns the index of the closing quote or -1.
+// Double quotes can be escaped with a backslash.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescape(s string) string {
+	r := strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r", `\t`, "\t")
+	return r.Replace(s)
+}
+
+func formatValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\r\n\"'#\\$") {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(v) + `"`
+}
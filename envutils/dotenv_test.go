@@ -0,0 +1,137 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package envutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const dotenv = `# comment
+A=hello
+export B=world # inline comment
+
+C='single $quoted'
+D="double \"quoted\"\tvalue"
+E="multi
+line"
+F=
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+		err      error
+	}{
+		{"full", dotenv, map[string]string{
+			"A": "hello",
+			"B": "world",
+			"C": "single $quoted",
+			"D": "double \"quoted\"\tvalue",
+			"E": "multi\nline",
+			"F": "",
+		}, nil},
+		{"crlf", "A=1\r\nB=2\r\n", map[string]string{"A": "1", "B": "2"}, nil},
+		{"invalid", "A", nil, ErrInvalidLine},
+		{"invalid key", "A B=c", nil, ErrInvalidLine},
+		{"unterminated", "A=\"hello\nB=2", nil, ErrUnterminatedQuote},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := Parse(strings.NewReader(test.input))
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Unexpected error: %v\n", err)
+			}
+			if test.err == nil && !reflect.DeepEqual(m, test.expected) {
+				t.Errorf("Expected:\n%#v\nGot:\n%#v\n", test.expected, m)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, ".env")
+	err = ioutil.WriteFile(file, []byte("ENVUTILS_LOAD_A=file\nENVUTILS_LOAD_B=file\n"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	os.Setenv("ENVUTILS_LOAD_A", "env")
+	defer os.Unsetenv("ENVUTILS_LOAD_A")
+	defer os.Unsetenv("ENVUTILS_LOAD_B")
+
+	err = Load(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if os.Getenv("ENVUTILS_LOAD_A") != "env" || os.Getenv("ENVUTILS_LOAD_B") != "file" {
+		t.Errorf("Unexpected values: %s, %s\n", os.Getenv("ENVUTILS_LOAD_A"), os.Getenv("ENVUTILS_LOAD_B"))
+	}
+	err = Overload(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if os.Getenv("ENVUTILS_LOAD_A") != "file" {
+		t.Errorf("Unexpected value: %s\n", os.Getenv("ENVUTILS_LOAD_A"))
+	}
+}
+
+func TestUpdateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, ".env")
+	err = ioutil.WriteFile(file, []byte(dotenv), 0600)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = UpdateFile(file, map[string]string{"B": "new world", "E": "single", "Z": "last", "Y": "x"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	b, _ := ioutil.ReadFile(file)
+	expected := `# comment
+A=hello
+export B="new world"
+
+C='single $quoted'
+D="double \"quoted\"\tvalue"
+E=single
+F=
+Y=x
+Z=last
+`
+	if string(b) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, b)
+	}
+	fInfo, _ := os.Stat(file)
+	if fInfo.Mode().Perm() != 0600 {
+		t.Errorf("Unexpected mode: %s\n", fInfo.Mode())
+	}
+	m, err := ReadFile(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if m["B"] != "new world" {
+		t.Errorf("Unexpected value: %s\n", m["B"])
+	}
+}
@@ -0,0 +1,100 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package envutils - Utilities to read configuration from environment variables.
+*/
+package envutils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidValue - The environment variable value can't be parsed into the requested type.
+var ErrInvalidValue = fmt.Errorf("invalid value")
+
+// ErrMissingVariables - Required environment variables are not set.
+var ErrMissingVariables = fmt.Errorf("missing environment variables")
+
+// GetString - Returns the value of the environment variable name or def if it is unset or empty.
+func GetString(name, def string) string {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return def
+	}
+	return v
+}
+
+// GetInt - Returns the value of the environment variable name as an int or def if it is unset or empty.
+// If the value can't be parsed, def is returned together with an ErrInvalidValue error.
+func GetInt(name string, def int) (int, error) {
+	v := GetString(name, "")
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return def, fmt.Errorf("%w for '%s': '%s' is not an int", ErrInvalidValue, name, v)
+	}
+	return i, nil
+}
+
+// GetBool - Returns the value of the environment variable name as a bool or def if it is unset or empty.
+// Besides the values accepted by strconv.ParseBool, yes/no and on/off are accepted.
+// If the value can't be parsed, def is returned together with an ErrInvalidValue error.
+func GetBool(name string, def bool) (bool, error) {
+	v := GetString(name, "")
+	if v == "" {
+		return def, nil
+	}
+	switch strings.ToLower(v) {
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def, fmt.Errorf("%w for '%s': '%s' is not a bool", ErrInvalidValue, name, v)
+	}
+	return b, nil
+}
+
+// GetDuration - Returns the value of the environment variable name as a time.Duration or def if it is unset or empty.
+// The value uses the time.ParseDuration format, for example: "1h30m".
+// If the value can't be parsed, def is returned together with an ErrInvalidValue error.
+func GetDuration(name string, def time.Duration) (time.Duration, error) {
+	v := GetString(name, "")
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def, fmt.Errorf("%w for '%s': '%s' is not a duration", ErrInvalidValue, name, v)
+	}
+	return d, nil
+}
+
+// Require - Checks that all the given environment variables are set and not empty.
+// The returned error lists all the missing variables at once.
+func Require(names ...string) error {
+	missing := []string{}
+	for _, name := range names {
+		if GetString(name, "") == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingVariables, strings.Join(missing, ", "))
+	}
+	return nil
+}
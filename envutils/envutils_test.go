@@ -0,0 +1,78 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package envutils
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetters(t *testing.T) {
+	os.Setenv("ENVUTILS_STRING", "hello")
+	os.Setenv("ENVUTILS_INT", "123")
+	os.Setenv("ENVUTILS_BAD_INT", "abc")
+	os.Setenv("ENVUTILS_BOOL", "yes")
+	os.Setenv("ENVUTILS_DURATION", "1h30m")
+	os.Setenv("ENVUTILS_EMPTY", "")
+	defer func() {
+		for _, e := range []string{"STRING", "INT", "BAD_INT", "BOOL", "DURATION", "EMPTY"} {
+			os.Unsetenv("ENVUTILS_" + e)
+		}
+	}()
+
+	if s := GetString("ENVUTILS_STRING", "def"); s != "hello" {
+		t.Errorf("Unexpected value: %s\n", s)
+	}
+	if s := GetString("ENVUTILS_EMPTY", "def"); s != "def" {
+		t.Errorf("Unexpected value: %s\n", s)
+	}
+	i, err := GetInt("ENVUTILS_INT", 1)
+	if err != nil || i != 123 {
+		t.Errorf("Unexpected value: %d, %v\n", i, err)
+	}
+	i, err = GetInt("ENVUTILS_BAD_INT", 1)
+	if !errors.Is(err, ErrInvalidValue) || i != 1 {
+		t.Errorf("Unexpected value: %d, %v\n", i, err)
+	}
+	i, err = GetInt("ENVUTILS_UNSET", 1)
+	if err != nil || i != 1 {
+		t.Errorf("Unexpected value: %d, %v\n", i, err)
+	}
+	b, err := GetBool("ENVUTILS_BOOL", false)
+	if err != nil || !b {
+		t.Errorf("Unexpected value: %v, %v\n", b, err)
+	}
+	_, err = GetBool("ENVUTILS_STRING", false)
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	d, err := GetDuration("ENVUTILS_DURATION", time.Second)
+	if err != nil || d != 90*time.Minute {
+		t.Errorf("Unexpected value: %v, %v\n", d, err)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	os.Setenv("ENVUTILS_SET", "x")
+	defer os.Unsetenv("ENVUTILS_SET")
+	err := Require("ENVUTILS_SET")
+	if err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+	err = Require("ENVUTILS_A", "ENVUTILS_SET", "ENVUTILS_B")
+	if !errors.Is(err, ErrMissingVariables) {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	expected := "missing environment variables: ENVUTILS_A, ENVUTILS_B"
+	if err.Error() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, err)
+	}
+}
@@ -0,0 +1,129 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package errclass - Classifies file system errors so callers can react to
+them, for example retrying transient errors, re-opening files after a stale
+NFS handle or skipping entries they are not allowed to read.
+
+The errors returned by fileutils, hashdir and the other packages wrap the
+underlying os and syscall errors so they can be classified as they are.
+
+	switch errclass.Classify(err) {
+	case errclass.NotFound:
+		// Removed while walking.
+	case errclass.Transient, errclass.StaleHandle:
+		// Try again.
+	}
+*/
+package errclass
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"syscall"
+)
+
+// Class - Kind of error.
+type Class int
+
+const (
+	// Unknown - nil or an error that doesn't fall in any other class.
+	Unknown Class = iota
+
+	// Transient - The operation might succeed if tried again: EINTR,
+	// EAGAIN, ETIMEDOUT, ECONNRESET, ECONNABORTED, network errors that
+	// report a timeout and, on Windows, sharing and lock violations.
+	Transient
+
+	// Permission - EACCES, EPERM or fs.ErrPermission.
+	Permission
+
+	// NotFound - ENOENT, ENOTDIR or fs.ErrNotExist.
+	NotFound
+
+	// ReadOnly - The file system is mounted read-only, EROFS or, on
+	// Windows, a write protected media.
+	ReadOnly
+
+	// StaleHandle - The file was removed on the NFS server, or the server
+	// restarted, while it was open. Opening the path again usually works.
+	StaleHandle
+)
+
+func (c Class) String() string {
+	switch c {
+	case Unknown:
+		return "unknown"
+	case Transient:
+		return "transient"
+	case Permission:
+		return "permission"
+	case NotFound:
+		return "not-found"
+	case ReadOnly:
+		return "read-only"
+	case StaleHandle:
+		return "stale-handle"
+	}
+	return fmt.Sprintf("Class(%d)", int(c))
+}
+
+// Retryable - Whether errors of the class are worth retrying, Transient
+// and StaleHandle.
+func (c Class) Retryable() bool {
+	return c == Transient || c == StaleHandle
+}
+
+// Classify - Returns the class of err, looking through wrapped errors.
+func Classify(err error) Class {
+	if err == nil {
+		return Unknown
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		if c := classifyErrno(errno); c != Unknown {
+			return c
+		}
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return NotFound
+	case errors.Is(err, fs.ErrPermission):
+		return Permission
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return Transient
+	}
+	return Unknown
+}
+
+// Is - Whether err belongs to class c.
+func Is(err error, c Class) bool {
+	return Classify(err) == c
+}
+
+func classifyErrno(errno syscall.Errno) Class {
+	switch errno {
+	case syscall.EINTR, syscall.EAGAIN, syscall.ETIMEDOUT, syscall.ECONNRESET,
+		syscall.ECONNABORTED:
+		return Transient
+	case syscall.ESTALE:
+		return StaleHandle
+	case syscall.EROFS:
+		return ReadOnly
+	case syscall.ENOENT, syscall.ENOTDIR:
+		return NotFound
+	case syscall.EACCES, syscall.EPERM:
+		return Permission
+	}
+	return platformClass(errno)
+}
@@ -0,0 +1,62 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package errclass
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	_, openErr := os.Open("/does/not/exist")
+	tests := []struct {
+		name     string
+		err      error
+		expected Class
+	}{
+		{"nil", nil, Unknown},
+		{"other", fmt.Errorf("other"), Unknown},
+		{"EINTR", syscall.EINTR, Transient},
+		{"wrapped ETIMEDOUT", fmt.Errorf("read: %w", syscall.ETIMEDOUT), Transient},
+		{"deadline", context.DeadlineExceeded, Transient},
+		{"ESTALE", &os.PathError{Op: "open", Path: "x", Err: syscall.ESTALE}, StaleHandle},
+		{"EROFS", &os.PathError{Op: "open", Path: "x", Err: syscall.EROFS}, ReadOnly},
+		{"EACCES", &os.PathError{Op: "open", Path: "x", Err: syscall.EACCES}, Permission},
+		{"ErrPermission", fmt.Errorf("copy: %w", fs.ErrPermission), Permission},
+		{"open", openErr, NotFound},
+		{"ErrNotExist", fs.ErrNotExist, NotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Classify(test.err)
+			if got != test.expected {
+				t.Errorf("Expected:\n%v\nGot:\n%v\n", test.expected, got)
+			}
+			if !Is(test.err, test.expected) {
+				t.Errorf("Expected Is to match %v\n", test.expected)
+			}
+		})
+	}
+}
+
+func TestClassRetryable(t *testing.T) {
+	for c := Unknown; c <= StaleHandle; c++ {
+		expected := c == Transient || c == StaleHandle
+		if c.Retryable() != expected {
+			t.Errorf("%s: Expected:\n%v\nGot:\n%v\n", c, expected, c.Retryable())
+		}
+	}
+	if Class(42).String() != "Class(42)" {
+		t.Errorf("Unexpected string: %s\n", Class(42))
+	}
+}
@@ -0,0 +1,26 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package errclass
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+func platformClass(errno syscall.Errno) Class {
+	switch errno {
+	case windows.ERROR_SHARING_VIOLATION, windows.ERROR_LOCK_VIOLATION,
+		windows.ERROR_SEM_TIMEOUT:
+		return Transient
+	case windows.ERROR_WRITE_PROTECT:
+		return ReadOnly
+	}
+	return Unknown
+}
@@ -0,0 +1,48 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic - Writes data to a temporary file in the same dir as
+// filename and then renames it into place.
+// Readers of filename see either the old or the new contents, never a partial write.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	if err := checkWritable("WriteFileAtomic", filename); err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(data)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Sync()
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmpFile.Name(), perm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filename)
+}
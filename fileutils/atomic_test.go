@@ -0,0 +1,41 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	for _, data := range []string{"hello\n", "world\n"} {
+		err = WriteFileAtomic(file, []byte(data), 0600)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if string(b) != data {
+			t.Errorf("Expected:\n%s\nGot:\n%s\n", data, b)
+		}
+	}
+	fInfo, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if fInfo.Mode().Perm() != 0600 {
+		t.Errorf("Unexpected mode: %s\n", fInfo.Mode())
+	}
+	list, _ := ioutil.ReadDir(dir)
+	if len(list) != 1 {
+		t.Errorf("Unexpected leftover files: %d\n", len(list))
+	}
+}
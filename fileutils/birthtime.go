@@ -0,0 +1,40 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// BirthTime - Returns the creation time of the file at path, following
+// symlinks, and whether it is available.
+// It comes from statx(2) on Linux, st_birthtime on macOS, FreeBSD and NetBSD
+// and the file creation time on Windows. It is not available on other
+// platforms, on filesystems that don't record it and when path can't be
+// stat'ed.
+func BirthTime(path string) (time.Time, bool) {
+	return birthTime(path)
+}
+
+// entryBirthTime - Returns the birth time of the entry e of dir, falling back
+// to its modification time, used to sort the entries by creation.
+func entryBirthTime(fsys fs.FS, dir string, e fs.DirEntry) time.Time {
+	if fsys == nil {
+		if t, ok := birthTime(filepath.Join(dir, e.Name())); ok {
+			return t
+		}
+	}
+	fInfo, err := e.Info()
+	if err != nil {
+		return time.Time{}
+	}
+	return fInfo.ModTime()
+}
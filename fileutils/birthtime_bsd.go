@@ -0,0 +1,30 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build darwin || freebsd || netbsd
+// +build darwin freebsd netbsd
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func birthTime(path string) (time.Time, bool) {
+	fInfo, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	st, ok := fInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(st.Birthtimespec.Sec), int64(st.Birthtimespec.Nsec)), true
+}
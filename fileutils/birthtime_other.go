@@ -0,0 +1,18 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !darwin && !freebsd && !linux && !netbsd && !windows
+// +build !darwin,!freebsd,!linux,!netbsd,!windows
+
+package fileutils
+
+import "time"
+
+func birthTime(path string) (time.Time, bool) {
+	return time.Time{}, false
+}
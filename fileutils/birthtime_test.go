@@ -0,0 +1,78 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBirthTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, ok := BirthTime(filepath.Join(dir, "missing"))
+	if ok {
+		t.Errorf("Expected no birth time for a missing file\n")
+	}
+
+	start := time.Now().Add(-time.Second)
+	file := filepath.Join(dir, "a")
+	ioutil.WriteFile(file, []byte("a"), 0644)
+	bt, ok := BirthTime(file)
+	if !ok {
+		t.Skipf("Birth time not available in %s\n", dir)
+	}
+	if bt.Before(start) || bt.After(time.Now().Add(time.Second)) {
+		t.Errorf("Unexpected birth time: %s\n", bt)
+	}
+	// Modifying the file doesn't change its birth time.
+	os.Chtimes(file, start, start)
+	got, _ := BirthTime(file)
+	if !got.Equal(bt) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", bt, got)
+	}
+}
+
+func TestListBirthTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"c", "a", "b"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+		time.Sleep(20 * time.Millisecond)
+	}
+	expected := []string{filepath.Join(dir, "c"), filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	got, err := List(dir, ListSortByBirthTime())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+	}
+	got, _ = List(dir, ListSortByBirthTime(), ListReverse())
+	reversed := []string{expected[2], expected[1], expected[0]}
+	if !reflect.DeepEqual(got, reversed) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", reversed, got)
+	}
+
+	from, ok := BirthTime(filepath.Join(dir, "a"))
+	if !ok {
+		t.Skipf("Birth time not available in %s\n", dir)
+	}
+	got, _ = List(dir, ListCreatedBetween(from, time.Time{}), ListSortByBirthTime())
+	if !reflect.DeepEqual(got, expected[1:]) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected[1:], got)
+	}
+	got, _ = List(dir, ListCreatedBetween(time.Time{}, from), ListSortByBirthTime())
+	if !reflect.DeepEqual(got, expected[:2]) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected[:2], got)
+	}
+}
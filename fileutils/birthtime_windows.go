@@ -0,0 +1,27 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func birthTime(path string) (time.Time, bool) {
+	fInfo, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	data, ok := fInfo.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds()), true
+}
@@ -0,0 +1,168 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+)
+
+// Chunk - A content defined chunk.
+type Chunk struct {
+	Offset int64
+	Length int
+
+	// Hash - Hex encoded SHA-256 of the chunk contents.
+	Hash string
+
+	// Data - Chunk contents, only valid until the next call to Next.
+	Data []byte
+}
+
+// CDCOptions - Chunk size limits for ChunkCDC, in bytes.
+// Zero values use the defaults: 2KiB, 8KiB and 64KiB.
+type CDCOptions struct {
+	MinSize int
+	AvgSize int
+	MaxSize int
+}
+
+// ChunkCDC - Splits content into chunks whose boundaries depend on the
+// content itself, using a FastCDC style gear rolling hash with normalized
+// chunking. Inserting or removing bytes only changes the chunks around the
+// edit, so chunk hashes can be used to deduplicate and diff large files.
+type ChunkCDC struct {
+	r      *bufio.Reader
+	opts   CDCOptions
+	maskS  uint64
+	maskL  uint64
+	offset int64
+}
+
+// gear - Random values for each byte, generated with splitmix64 from a
+// fixed seed so boundaries are stable across runs and versions.
+var gear = func() [256]uint64 {
+	var g [256]uint64
+	x := uint64(0x2020)
+	for i := range g {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		g[i] = z ^ (z >> 31)
+	}
+	return g
+}()
+
+// NewChunkCDC - Returns a chunker reading from r.
+func NewChunkCDC(r io.Reader, opts CDCOptions) (*ChunkCDC, error) {
+	if opts.MinSize == 0 {
+		opts.MinSize = 2 << 10
+	}
+	if opts.AvgSize == 0 {
+		opts.AvgSize = 8 << 10
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = 64 << 10
+	}
+	if opts.MinSize < 1 || opts.MinSize > opts.AvgSize || opts.AvgSize > opts.MaxSize {
+		return nil, fmt.Errorf("invalid chunk sizes, expected 0 < min <= avg <= max: %d, %d, %d", opts.MinSize, opts.AvgSize, opts.MaxSize)
+	}
+	// Boundaries are more likely after the average size and less likely before it.
+	n := bits.Len(uint(opts.AvgSize)) - 1
+	c := &ChunkCDC{
+		r:     bufio.NewReaderSize(r, opts.MaxSize),
+		opts:  opts,
+		maskS: highBits(n + 1),
+		maskL: highBits(n - 1),
+	}
+	return c, nil
+}
+
+func highBits(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	return ^uint64(0) << (64 - n)
+}
+
+// Next - Returns the next chunk, io.EOF when there are no more.
+func (c *ChunkCDC) Next() (Chunk, error) {
+	data, err := c.r.Peek(c.opts.MaxSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return Chunk{}, err
+	}
+	if len(data) == 0 {
+		return Chunk{}, io.EOF
+	}
+	n := c.cut(data)
+	data = data[:n]
+	sum := sha256.Sum256(data)
+	chunk := Chunk{Offset: c.offset, Length: n, Hash: hex.EncodeToString(sum[:]), Data: data}
+	c.r.Discard(n)
+	c.offset += int64(n)
+	return chunk, nil
+}
+
+// cut - Returns the length of the chunk at the start of data.
+func (c *ChunkCDC) cut(data []byte) int {
+	n := len(data)
+	if n <= c.opts.MinSize {
+		return n
+	}
+	normal := c.opts.AvgSize
+	if normal > n {
+		normal = n
+	}
+	var fp uint64
+	i := c.opts.MinSize
+	for ; i < normal; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// ChunkFile - Returns the content defined chunks of filename, without their data.
+func ChunkFile(filename string, opts CDCOptions) ([]Chunk, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	c, err := NewChunkCDC(fh, opts)
+	if err != nil {
+		return nil, err
+	}
+	chunks := []Chunk{}
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return chunks, err
+		}
+		chunk.Data = nil
+		chunks = append(chunks, chunk)
+	}
+}
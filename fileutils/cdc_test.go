@@ -0,0 +1,115 @@
+package fileutils
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func chunkAll(t *testing.T, data []byte, opts CDCOptions) []Chunk {
+	t.Helper()
+	c, err := NewChunkCDC(bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	chunks := []Chunk{}
+	var joined []byte
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if chunk.Offset != int64(len(joined)) || chunk.Length != len(chunk.Data) {
+			t.Fatalf("Unexpected chunk: %d %d\n", chunk.Offset, chunk.Length)
+		}
+		if chunk.Length > opts.MaxSize && opts.MaxSize > 0 {
+			t.Fatalf("Chunk bigger than max: %d\n", chunk.Length)
+		}
+		joined = append(joined, chunk.Data...)
+		chunk.Data = nil
+		chunks = append(chunks, chunk)
+	}
+	if !bytes.Equal(joined, data) {
+		t.Fatalf("Chunks don't add up to the data\n")
+	}
+	return chunks
+}
+
+func TestChunkCDC(t *testing.T) {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	opts := CDCOptions{MinSize: 1 << 10, AvgSize: 4 << 10, MaxSize: 16 << 10}
+	chunks := chunkAll(t, data, opts)
+	avg := len(data) / len(chunks)
+	if avg < opts.MinSize || avg > opts.MaxSize {
+		t.Errorf("Unexpected average chunk size: %d\n", avg)
+	}
+
+	// Inserting bytes in the middle only changes the chunks around the edit.
+	edited := append(append(append([]byte{}, data[:500000]...), []byte("inserted")...), data[500000:]...)
+	editedChunks := chunkAll(t, edited, opts)
+	hashes := map[string]bool{}
+	for _, c := range chunks {
+		hashes[c.Hash] = true
+	}
+	changed := 0
+	for _, c := range editedChunks {
+		if !hashes[c.Hash] {
+			changed++
+		}
+	}
+	if changed > 2 {
+		t.Errorf("Expected at most 2 changed chunks, got: %d of %d\n", changed, len(editedChunks))
+	}
+
+	if len(chunkAll(t, nil, CDCOptions{})) != 0 {
+		t.Errorf("Expected no chunks for empty data\n")
+	}
+	small := chunkAll(t, []byte("small"), CDCOptions{})
+	if len(small) != 1 || small[0].Hash != "81db8ebbbbc69c6c6ad4a6aa92b76e0c08af547da236b9e2c9dbe1d8285a8130" {
+		t.Errorf("Unexpected chunks: %v\n", small)
+	}
+
+	_, err := NewChunkCDC(bytes.NewReader(data), CDCOptions{MinSize: 10, AvgSize: 5, MaxSize: 20})
+	if err == nil {
+		t.Errorf("Expected invalid sizes error\n")
+	}
+}
+
+func TestChunkFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	data := make([]byte, 200000)
+	rand.New(rand.NewSource(2)).Read(data)
+	file := filepath.Join(dir, "data")
+	ioutil.WriteFile(file, data, 0644)
+
+	chunks, err := ChunkFile(file, CDCOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := chunkAll(t, data, CDCOptions{})
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d\n", len(expected), len(chunks))
+	}
+	for i := range chunks {
+		if !reflect.DeepEqual(chunks[i], expected[i]) {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", expected[i], chunks[i])
+		}
+	}
+	_, err = ChunkFile(filepath.Join(dir, "missing"), CDCOptions{})
+	if err == nil {
+		t.Errorf("Expected missing file error\n")
+	}
+}
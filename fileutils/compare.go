@@ -0,0 +1,152 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CompareFiles - Whether files a and b have the same contents.
+// Files of different sizes are reported as different without reading them,
+// otherwise both are streamed and compared until the first difference.
+func CompareFiles(a, b string) (bool, error) {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if aInfo.Size() != bInfo.Size() {
+		return false, nil
+	}
+	if os.SameFile(aInfo, bInfo) {
+		return true, nil
+	}
+	aFh, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer aFh.Close()
+	bFh, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer bFh.Close()
+	return sameContents(aFh, bFh)
+}
+
+func sameContents(a, b io.Reader) (bool, error) {
+	aBuf := make([]byte, 32*1024)
+	bBuf := make([]byte, 32*1024)
+	for {
+		an, aErr := io.ReadFull(a, aBuf)
+		bn, bErr := io.ReadFull(b, bBuf)
+		if !bytes.Equal(aBuf[:an], bBuf[:bn]) {
+			return false, nil
+		}
+		aDone := aErr == io.EOF || aErr == io.ErrUnexpectedEOF
+		bDone := bErr == io.EOF || bErr == io.ErrUnexpectedEOF
+		if aErr != nil && !aDone {
+			return false, aErr
+		}
+		if bErr != nil && !bDone {
+			return false, bErr
+		}
+		if aDone || bDone {
+			return aDone == bDone, nil
+		}
+	}
+}
+
+// DirsEqual - Whether the trees under a and b have the same structure and
+// contents: the same relative paths with the same types, files with the
+// same contents and symlinks with the same targets.
+// Modes, owners and times are not compared, symlinks are not followed and
+// special files, like sockets and devices, only need to exist in both.
+func DirsEqual(a, b string) (bool, error) {
+	aEntries, err := treeTypes(a)
+	if err != nil {
+		return false, err
+	}
+	bEntries, err := treeTypes(b)
+	if err != nil {
+		return false, err
+	}
+	if len(aEntries) != len(bEntries) {
+		return false, nil
+	}
+	for rel, kind := range aEntries {
+		if bKind, ok := bEntries[rel]; !ok || bKind != kind {
+			return false, nil
+		}
+	}
+	for rel, kind := range aEntries {
+		aPath, bPath := filepath.Join(a, rel), filepath.Join(b, rel)
+		var same bool
+		switch {
+		case kind == 0:
+			same, err = CompareFiles(aPath, bPath)
+		case kind == fs.ModeSymlink:
+			same, err = sameLink(aPath, bPath)
+		default:
+			continue
+		}
+		if err != nil || !same {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// treeTypes - Returns the type bits of the entries under dir by relative path.
+func treeTypes(dir string) (map[string]fs.FileMode, error) {
+	fInfo, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fInfo.IsDir() {
+		return nil, fmt.Errorf("Provided dir is not a dir: '%s'\n", dir)
+	}
+	entries := map[string]fs.FileMode{}
+	err = Walk(dir, func(path string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		fInfo, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		entries[rel] = fInfo.Mode().Type()
+		return nil
+	})
+	return entries, err
+}
+
+func sameLink(a, b string) (bool, error) {
+	aTarget, err := os.Readlink(a)
+	if err != nil {
+		return false, err
+	}
+	bTarget, err := os.Readlink(b)
+	if err != nil {
+		return false, err
+	}
+	return aTarget == bTarget, nil
+}
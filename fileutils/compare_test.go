@@ -0,0 +1,96 @@
+package fileutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	big := bytes.Repeat([]byte("0123456789"), 10000)
+	changed := append([]byte{}, big...)
+	changed[len(changed)-1] = 'x'
+	files := map[string][]byte{"a": big, "b": big, "c": changed, "d": big[:100], "empty": nil, "empty2": nil}
+	for name, data := range files {
+		ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+	}
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"a", "b", true},
+		{"a", "a", true},
+		{"a", "c", false},
+		{"a", "d", false},
+		{"empty", "empty2", true},
+	}
+	for _, tt := range tests {
+		got, err := CompareFiles(filepath.Join(dir, tt.a), filepath.Join(dir, tt.b))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if got != tt.expected {
+			t.Errorf("%s %s Expected:\n%v\nGot:\n%v\n", tt.a, tt.b, tt.expected, got)
+		}
+	}
+	_, err = CompareFiles(filepath.Join(dir, "a"), filepath.Join(dir, "missing"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
+
+func TestDirsEqual(t *testing.T) {
+	base := copyTree(t)
+	defer os.RemoveAll(base)
+	defer os.Chmod(filepath.Join(base, "src", "a", "b"), 0755)
+	src := filepath.Join(base, "src")
+
+	tests := []struct {
+		name     string
+		change   func(dir string)
+		expected bool
+	}{
+		{"same", func(dir string) {}, true},
+		{"mode", func(dir string) { os.Chmod(filepath.Join(dir, "f"), 0600) }, true},
+		{"content", func(dir string) { ioutil.WriteFile(filepath.Join(dir, "f"), []byte("x"), 0644) }, false},
+		{"extra", func(dir string) { ioutil.WriteFile(filepath.Join(dir, "extra"), nil, 0644) }, false},
+		{"missing", func(dir string) { os.Remove(filepath.Join(dir, "f")) }, false},
+		{"link", func(dir string) {
+			os.Remove(filepath.Join(dir, "link"))
+			os.Symlink("f", filepath.Join(dir, "link"))
+		}, false},
+		{"type", func(dir string) {
+			os.Remove(filepath.Join(dir, "f"))
+			os.Mkdir(filepath.Join(dir, "f"), 0755)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(base, tt.name)
+			err := CopyDir(src, dst)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			defer os.Chmod(filepath.Join(dst, "a", "b"), 0755)
+			tt.change(dst)
+			got, err := DirsEqual(src, dst)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected:\n%v\nGot:\n%v\n", tt.expected, got)
+			}
+		})
+	}
+	_, err := DirsEqual(src, filepath.Join(src, "f"))
+	if err == nil {
+		t.Errorf("Expected not a dir error\n")
+	}
+}
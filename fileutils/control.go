@@ -0,0 +1,102 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ErrCanceled - The operation was stopped with Control.Cancel.
+var ErrCanceled = fmt.Errorf("canceled")
+
+// Control - Pauses, resumes and cancels a long running operation, like
+// ReplaceInTree, from another goroutine.
+//
+// Operations check it between files, so a pause or cancel takes effect once
+// the files in progress are done. To resume a canceled operation, run it
+// again with the same ProcessedCache, or checkpoint, so the files already
+// processed are skipped.
+// A Control can't be reused after being canceled.
+// The zero value is a running Control.
+type Control struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	paused   bool
+	canceled bool
+}
+
+// NewControl - Returns a Control that is running.
+func NewControl() *Control {
+	return &Control{}
+}
+
+// condition - Returns the condition variable, created on first use so the
+// zero value works. Must be called with mu held.
+func (c *Control) condition() *sync.Cond {
+	if c.cond == nil {
+		c.cond = sync.NewCond(&c.mu)
+	}
+	return c.cond
+}
+
+// Pause - Blocks the operation the next time it calls Wait.
+func (c *Control) Pause() {
+	c.mu.Lock()
+	c.paused = true
+	c.mu.Unlock()
+}
+
+// Resume - Unblocks a paused operation.
+func (c *Control) Resume() {
+	c.mu.Lock()
+	c.paused = false
+	c.condition().Broadcast()
+	c.mu.Unlock()
+}
+
+// Cancel - Stops the operation the next time it calls Wait, even if it is paused.
+func (c *Control) Cancel() {
+	c.mu.Lock()
+	c.canceled = true
+	c.condition().Broadcast()
+	c.mu.Unlock()
+}
+
+// Paused - Whether Pause was called without a matching Resume.
+func (c *Control) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// Canceled - Whether Cancel was called.
+func (c *Control) Canceled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.canceled
+}
+
+// Wait - Checkpoint for the operation, called between units of work.
+// Blocks while c is paused and returns ErrCanceled once c is canceled.
+// A nil Control never blocks.
+func (c *Control) Wait() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.paused && !c.canceled {
+		c.condition().Wait()
+	}
+	if c.canceled {
+		return ErrCanceled
+	}
+	return nil
+}
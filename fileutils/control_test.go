@@ -0,0 +1,67 @@
+package fileutils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestControl(t *testing.T) {
+	var nilControl *Control
+	if err := nilControl.Wait(); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+
+	c := NewControl()
+	if err := c.Wait(); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+	c.Pause()
+	if !c.Paused() {
+		t.Errorf("Expected paused\n")
+	}
+	done := make(chan error)
+	go func() { done <- c.Wait() }()
+	select {
+	case <-done:
+		t.Fatalf("Wait returned while paused\n")
+	case <-time.After(20 * time.Millisecond):
+	}
+	c.Resume()
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+
+	c.Pause()
+	go func() { done <- c.Wait() }()
+	c.Cancel()
+	if err := <-done; !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got: %v\n", err)
+	}
+	if !c.Canceled() {
+		t.Errorf("Expected canceled\n")
+	}
+	c.Resume()
+	if err := c.Wait(); !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got: %v\n", err)
+	}
+}
+
+func TestControlZeroValue(t *testing.T) {
+	var c Control
+	if err := c.Wait(); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+	c.Pause()
+	done := make(chan error)
+	go func() { done <- c.Wait() }()
+	select {
+	case <-done:
+		t.Fatalf("Wait returned while paused\n")
+	case <-time.After(20 * time.Millisecond):
+	}
+	c.Cancel()
+	if err := <-done; !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got: %v\n", err)
+	}
+}
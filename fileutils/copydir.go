@@ -0,0 +1,267 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrDestinationExists - The destination exists and the overwrite policy is OverwriteFail.
+var ErrDestinationExists = fmt.Errorf("destination exists")
+
+// Overwrite - What CopyDir does with files that already exist in the destination.
+type Overwrite int
+
+const (
+	// OverwriteAlways replaces existing files, the default.
+	OverwriteAlways Overwrite = iota
+
+	// OverwriteSkip keeps existing files.
+	OverwriteSkip
+
+	// OverwriteIfNewer replaces existing files only when the source was modified after them.
+	OverwriteIfNewer
+
+	// OverwriteFail stops the copy with ErrDestinationExists.
+	OverwriteFail
+)
+
+// CopyOverwrite - Sets the overwrite policy for files that already exist in the destination.
+func CopyOverwrite(policy Overwrite) CopyOption {
+	return func(o *copyOptions) {
+		o.overwrite = policy
+	}
+}
+
+// CopyFollowLinks - Copies the contents symlinks point to instead of recreating the symlinks.
+func CopyFollowLinks() CopyOption {
+	return func(o *copyOptions) {
+		o.followLinks = true
+	}
+}
+
+// CopyDir - Copies the tree under src to dst, creating dst if needed.
+// Dirs are recreated and files copied preserving their permissions, with
+// CopyPreserve their owner, times and extended attributes are preserved as
+// well, and with CopyFlags their file flags.
+// Symlinks are recreated with the same target unless CopyFollowLinks is
+// given, other special files, like sockets and devices, are skipped.
+// The CopyRetry policy applies to each file.
+//
+// dst can't be inside src.
+func CopyDir(src, dst string, opts ...CopyOption) error {
+	if err := checkWritable("CopyDir", dst); err != nil {
+		return err
+	}
+	o := newCopyOptions(opts)
+	if o.spaceCheck || o.onProgress != nil {
+		size, err := treeSize(src)
+		if err != nil {
+			return err
+		}
+		if o.spaceCheck {
+			err = CheckSpace(dst, size)
+			if err != nil {
+				return err
+			}
+		}
+		o.startProgress(size)
+	}
+	return copyDir(src, dst, o, map[string]bool{})
+}
+
+// copyDir - visited has the real paths of the dirs being copied, to detect
+// symlink loops when following links.
+func copyDir(src, dst string, o *copyOptions, visited map[string]bool) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !srcInfo.IsDir() {
+		return fmt.Errorf("Provided dir is not a dir: '%s'\n", src)
+	}
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if absDst == absSrc || strings.HasPrefix(absDst, absSrc+string(os.PathSeparator)) {
+		return fmt.Errorf("destination '%s' is inside the source '%s'", dst, src)
+	}
+	real, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+	if visited[real] {
+		return fmt.Errorf("symlink loop: '%s'", src)
+	}
+	visited[real] = true
+	defer delete(visited, real)
+
+	// Dirs are created writable and get their mode once their contents are copied.
+	type dirMode struct {
+		src   string
+		path  string
+		fInfo fs.FileInfo
+	}
+	dirs := []dirMode{{src, dst, srcInfo}}
+	err = os.MkdirAll(dst, 0700)
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(dst, srcInfo.Mode().Perm()|0700)
+	if err != nil {
+		return err
+	}
+	err = Walk(src, func(path string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		fInfo, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if fInfo.Mode()&fs.ModeSymlink != 0 && o.followLinks {
+			fInfo, err = os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if fInfo.IsDir() {
+				// Walk doesn't descend into links, copy the linked tree on its own.
+				return copyDir(path, target, o, visited)
+			}
+		}
+		switch {
+		case fInfo.IsDir():
+			dirs = append(dirs, dirMode{path, target, fInfo})
+			err := os.Mkdir(target, 0700)
+			if os.IsExist(err) {
+				err = os.Chmod(target, fInfo.Mode().Perm()|0700)
+			}
+			return err
+		case fInfo.Mode()&fs.ModeSymlink != 0:
+			return copySymlink(path, target, o)
+		case fInfo.Mode().IsRegular():
+			return copyRegular(path, target, fInfo, o)
+		}
+		Logger.Printf("CopyDir: skipping special file '%s'", path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Deepest first, so times aren't changed by writes to their children.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		var err error
+		if o.preserve {
+			err = preserveAttributes(dirs[i].src, dirs[i].path, dirs[i].fInfo)
+		} else {
+			err = os.Chmod(dirs[i].path, dirs[i].fInfo.Mode().Perm())
+		}
+		if err != nil {
+			return err
+		}
+		if o.flags {
+			err = copyFlags(dirs[i].src, dirs[i].path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// overwriteTarget - Whether target can be written according to the overwrite policy.
+func (o *copyOptions) overwriteTarget(target string, fInfo fs.FileInfo) (bool, error) {
+	dstInfo, err := os.Lstat(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	switch o.overwrite {
+	case OverwriteSkip:
+		return false, nil
+	case OverwriteIfNewer:
+		return fInfo.ModTime().After(dstInfo.ModTime()), nil
+	case OverwriteFail:
+		return false, fmt.Errorf("%w: '%s'", ErrDestinationExists, target)
+	}
+	if dstInfo.IsDir() {
+		return false, fmt.Errorf("%w: '%s' is a dir", ErrDestinationExists, target)
+	}
+	// Remove links so the file they point to isn't overwritten.
+	if dstInfo.Mode()&fs.ModeSymlink != 0 {
+		return true, os.Remove(target)
+	}
+	return true, nil
+}
+
+func copyRegular(path, target string, fInfo fs.FileInfo, o *copyOptions) error {
+	ok, err := o.overwriteTarget(target, fInfo)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		o.progress.set(o.progress.current() + fInfo.Size())
+		return nil
+	}
+	err = o.copyFile(path, target)
+	if err != nil {
+		return err
+	}
+	if o.preserve {
+		err = preserveAttributes(path, target, fInfo)
+	} else {
+		err = os.Chmod(target, fInfo.Mode().Perm())
+	}
+	if err != nil || !o.flags {
+		return err
+	}
+	return copyFlags(path, target)
+}
+
+func copySymlink(path, target string, o *copyOptions) error {
+	fInfo, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	ok, err := o.overwriteTarget(target, fInfo)
+	if err != nil || !ok {
+		return err
+	}
+	link, err := os.Readlink(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(target); err == nil {
+		err := os.Remove(target)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.Symlink(link, target)
+	if err != nil || !o.preserve {
+		return err
+	}
+	return preserveAttributes(path, target, fInfo)
+}
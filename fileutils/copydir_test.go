@@ -0,0 +1,165 @@
+package fileutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func copyTree(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	src := filepath.Join(dir, "src")
+	os.MkdirAll(filepath.Join(src, "a", "b"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "f"), []byte("f"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "a", "exec"), []byte("exec"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "a", "b", "g"), []byte("g"), 0600)
+	os.Symlink("a/exec", filepath.Join(src, "link"))
+	os.Symlink("a", filepath.Join(src, "dirlink"))
+	os.Chmod(filepath.Join(src, "a", "b"), 0500)
+	return dir
+}
+
+func TestCopyDir(t *testing.T) {
+	dir := copyTree(t)
+	defer os.RemoveAll(dir)
+	defer os.Chmod(filepath.Join(dir, "src", "a", "b"), 0755)
+	defer os.Chmod(filepath.Join(dir, "dst", "a", "b"), 0755)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+
+	err := CopyDir(src, dst)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected, _ := List(src)
+	got, _ := List(dst)
+	for i := range expected {
+		expected[i], _ = filepath.Rel(src, expected[i])
+	}
+	for i := range got {
+		got[i], _ = filepath.Rel(dst, got[i])
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+	}
+	for file, mode := range map[string]os.FileMode{"f": 0644, "a/exec": 0755, "a/b/g": 0600, "a/b": 0500 | os.ModeDir} {
+		fInfo, err := os.Stat(filepath.Join(dst, file))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if fInfo.Mode() != mode {
+			t.Errorf("'%s' Expected:\n%s\nGot:\n%s\n", file, mode, fInfo.Mode())
+		}
+	}
+	link, err := os.Readlink(filepath.Join(dst, "link"))
+	if err != nil || link != "a/exec" {
+		t.Errorf("Expected:\n%s\nGot:\n%s, %v\n", "a/exec", link, err)
+	}
+
+	// Overwrite policies
+	ioutil.WriteFile(filepath.Join(dst, "f"), []byte("changed"), 0644)
+	err = CopyDir(src, dst, CopyOverwrite(OverwriteFail))
+	if !errors.Is(err, ErrDestinationExists) {
+		t.Errorf("Expected ErrDestinationExists, got: %v\n", err)
+	}
+	err = CopyDir(src, dst, CopyOverwrite(OverwriteSkip))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dst, "f")); string(data) != "changed" {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", "changed", data)
+	}
+	err = CopyDir(src, dst, CopyOverwrite(OverwriteIfNewer))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dst, "f")); string(data) != "changed" {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", "changed", data)
+	}
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(src, "f"), future, future)
+	err = CopyDir(src, dst, CopyOverwrite(OverwriteIfNewer))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dst, "f")); string(data) != "f" {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", "f", data)
+	}
+
+	err = CopyDir(src, filepath.Join(src, "a", "copy"))
+	if err == nil {
+		t.Errorf("Expected destination inside source error\n")
+	}
+	err = CopyDir(filepath.Join(src, "f"), filepath.Join(dir, "x"))
+	if err == nil {
+		t.Errorf("Expected not a dir error\n")
+	}
+}
+
+func TestCopyDirFollowLinks(t *testing.T) {
+	dir := copyTree(t)
+	defer os.RemoveAll(dir)
+	defer os.Chmod(filepath.Join(dir, "src", "a", "b"), 0755)
+	defer os.Chmod(filepath.Join(dir, "dst", "a", "b"), 0755)
+	defer os.Chmod(filepath.Join(dir, "dst", "dirlink", "b"), 0755)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+
+	err := CopyDir(src, dst, CopyFollowLinks())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	for _, file := range []string{"link", "dirlink", "dirlink/b/g"} {
+		fInfo, err := os.Lstat(filepath.Join(dst, file))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if fInfo.Mode()&os.ModeSymlink != 0 {
+			t.Errorf("Expected '%s' to be copied, not linked\n", file)
+		}
+	}
+
+	os.Symlink("..", filepath.Join(src, "a", "loop"))
+	err = CopyDir(src, filepath.Join(dir, "dst2"), CopyFollowLinks())
+	os.Chmod(filepath.Join(dir, "dst2", "a", "b"), 0755)
+	os.Chmod(filepath.Join(dir, "dst2", "dirlink", "b"), 0755)
+	if err == nil {
+		t.Errorf("Expected symlink loop error\n")
+	}
+}
+
+func TestCopyDirPreserve(t *testing.T) {
+	dir := copyTree(t)
+	defer os.RemoveAll(dir)
+	defer os.Chmod(filepath.Join(dir, "src", "a", "b"), 0755)
+	defer os.Chmod(filepath.Join(dir, "dst", "a", "b"), 0755)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	mtime := time.Date(2020, 6, 7, 8, 9, 10, 0, time.UTC)
+	for _, file := range []string{"a/b/g", "a/exec", "f", "a/b", "a", ""} {
+		os.Chtimes(filepath.Join(src, file), mtime, mtime)
+	}
+
+	err := CopyDir(src, dst, CopyPreserve())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	for _, file := range []string{"f", "a/exec", "a/b/g", "a/b", "a", ""} {
+		fInfo, err := os.Stat(filepath.Join(dst, file))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if !fInfo.ModTime().Equal(mtime) {
+			t.Errorf("'%s' Expected:\n%v\nGot:\n%v\n", file, mtime, fInfo.ModTime())
+		}
+	}
+	fInfo, _ := os.Stat(filepath.Join(dst, "a", "b"))
+	if fInfo.Mode() != 0500|os.ModeDir {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", 0500|os.ModeDir, fInfo.Mode())
+	}
+}
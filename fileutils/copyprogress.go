@@ -0,0 +1,79 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import "io"
+
+// CopyProgress - Calls fn as the data is copied with the bytes copied so
+// far and the total to copy.
+// fn is called once with 0 copied before copying and then after every
+// read, from the goroutine doing the copy.
+// For CopyDir the total is the size of the regular files in the tree,
+// measured before copying, files skipped by the overwrite policy count as
+// copied. With CopyFollowLinks the linked files are not part of the total.
+// When CopyRetry retries a file, copied goes back to where it was before
+// the failed attempt. ResumeCopy reports the verified prefix as copied.
+func CopyProgress(fn func(copied, total int64)) CopyOption {
+	return func(o *copyOptions) {
+		o.onProgress = fn
+	}
+}
+
+type copyProgress struct {
+	fn     func(copied, total int64)
+	copied int64
+	total  int64
+}
+
+// startProgress - Sets up the progress reporting when CopyProgress is given.
+func (o *copyOptions) startProgress(total int64) {
+	if o.onProgress == nil {
+		return
+	}
+	o.progress = &copyProgress{fn: o.onProgress, total: total}
+	o.onProgress(0, total)
+}
+
+// current - Returns the bytes copied so far.
+func (p *copyProgress) current() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.copied
+}
+
+// set - Sets the bytes copied so far, reporting them if they changed.
+func (p *copyProgress) set(n int64) {
+	if p == nil || p.copied == n {
+		return
+	}
+	p.copied = n
+	p.fn(p.copied, p.total)
+}
+
+// reader - Wraps r to report its reads.
+func (p *copyProgress) reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &progressReader{r: r, p: p}
+}
+
+type progressReader struct {
+	r io.Reader
+	p *copyProgress
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	if n > 0 {
+		pr.p.set(pr.p.copied + int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,95 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type progressCall struct {
+	copied, total int64
+}
+
+func TestCopyProgress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	err = os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	files := map[string]int{
+		"a":     100 * 1024,
+		"sub/b": 10,
+		"sub/c": 0,
+	}
+	for name, size := range files {
+		err = ioutil.WriteFile(filepath.Join(src, name), make([]byte, size), 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	}
+	// check - Verifies the calls start at 0, grow and end at total.
+	check := func(calls []progressCall, total int64) {
+		t.Helper()
+		if len(calls) < 2 {
+			t.Fatalf("Expected at least 2 calls, got: %v\n", calls)
+		}
+		if calls[0] != (progressCall{0, total}) {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", progressCall{0, total}, calls[0])
+		}
+		for i := 1; i < len(calls); i++ {
+			if calls[i].copied <= calls[i-1].copied || calls[i].total != total {
+				t.Errorf("Unexpected call after %v: %v\n", calls[i-1], calls[i])
+			}
+		}
+		if last := calls[len(calls)-1]; last != (progressCall{total, total}) {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", progressCall{total, total}, last)
+		}
+	}
+	var calls []progressCall
+	record := CopyProgress(func(copied, total int64) {
+		calls = append(calls, progressCall{copied, total})
+	})
+
+	err = CopyFile(filepath.Join(src, "a"), filepath.Join(dir, "a"), record)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	check(calls, 100*1024)
+
+	calls = nil
+	err = CopyDir(src, filepath.Join(dir, "dst"), record)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	check(calls, 100*1024+10)
+
+	// Skipped files count as copied.
+	calls = nil
+	err = CopyDir(src, filepath.Join(dir, "dst"), record, CopyOverwrite(OverwriteSkip))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	check(calls, 100*1024+10)
+
+	// The verified prefix is reported at once.
+	partial := filepath.Join(dir, "partial")
+	err = ioutil.WriteFile(partial, make([]byte, 10), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	calls = nil
+	err = ResumeCopy(filepath.Join(src, "a"), partial, record)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	check(calls, 100*1024)
+	if calls[1].copied != 10 {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", 10, calls[1].copied)
+	}
+}
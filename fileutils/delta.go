@@ -0,0 +1,201 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// Delta format, integers are unsigned varints:
+//
+//	delta   = magic op* end
+//	magic   = "BDLT1\n"
+//	op      = 'C' offset length | 'L' length data
+//	end     = 'E' sha256
+//
+// 'C' copies length bytes at offset from the old file, 'L' writes the
+// literal data that follows. The sha256 of the new file is used to verify
+// the result of ApplyBinaryDelta.
+
+// ErrInvalidDelta - The delta is malformed or doesn't apply to the old file.
+var ErrInvalidDelta = fmt.Errorf("invalid delta")
+
+var deltaMagic = []byte("BDLT1\n")
+
+const (
+	deltaCopy    = 'C'
+	deltaLiteral = 'L'
+	deltaEnd     = 'E'
+)
+
+// deltaCDCOptions - Smaller chunks than the CDC defaults, a single changed
+// byte costs a literal chunk in the delta.
+var deltaCDCOptions = CDCOptions{MinSize: 512, AvgSize: 2 << 10, MaxSize: 16 << 10}
+
+// BinaryDiff - Writes to delta the changes required to turn old into new.
+// Both are split into content defined chunks, chunks of new that exist in
+// old are encoded as references to old and the rest as literal data, so
+// the size of the delta is close to the size of the changed portions.
+func BinaryDiff(old, new io.Reader, delta io.Writer) error {
+	index := map[string]Chunk{}
+	c, err := NewChunkCDC(old, deltaCDCOptions)
+	if err != nil {
+		return err
+	}
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := index[chunk.Hash]; !ok {
+			chunk.Data = nil
+			index[chunk.Hash] = chunk
+		}
+	}
+
+	w := &deltaWriter{w: bufio.NewWriter(delta), pendingOffset: -1}
+	w.w.Write(deltaMagic)
+	h := sha256.New()
+	c, err = NewChunkCDC(new, deltaCDCOptions)
+	if err != nil {
+		return err
+	}
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		h.Write(chunk.Data)
+		if ref, ok := index[chunk.Hash]; ok {
+			w.copy(ref.Offset, ref.Length)
+		} else {
+			w.literal(chunk.Data)
+		}
+	}
+	w.flushCopy()
+	w.w.WriteByte(deltaEnd)
+	w.w.Write(h.Sum(nil))
+	return w.w.Flush()
+}
+
+// deltaWriter - Merges copies of contiguous old data into a single op.
+// Write errors are kept by the bufio.Writer and returned by Flush.
+type deltaWriter struct {
+	w             *bufio.Writer
+	pendingOffset int64
+	pendingLength int64
+}
+
+func (w *deltaWriter) copy(offset int64, length int) {
+	if w.pendingOffset >= 0 && w.pendingOffset+w.pendingLength == offset {
+		w.pendingLength += int64(length)
+		return
+	}
+	w.flushCopy()
+	w.pendingOffset, w.pendingLength = offset, int64(length)
+}
+
+func (w *deltaWriter) flushCopy() {
+	if w.pendingOffset < 0 {
+		return
+	}
+	w.w.WriteByte(deltaCopy)
+	w.uvarint(uint64(w.pendingOffset))
+	w.uvarint(uint64(w.pendingLength))
+	w.pendingOffset = -1
+}
+
+func (w *deltaWriter) literal(data []byte) {
+	w.flushCopy()
+	w.w.WriteByte(deltaLiteral)
+	w.uvarint(uint64(len(data)))
+	w.w.Write(data)
+}
+
+func (w *deltaWriter) uvarint(v uint64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	w.w.Write(buf[:binary.PutUvarint(buf, v)])
+}
+
+// ApplyBinaryDelta - Writes to new the result of applying a delta created
+// by BinaryDiff to old.
+// Returns ErrInvalidDelta when the delta is malformed or the result doesn't
+// match the checksum recorded in it, for example when old is not the file
+// the delta was created from.
+func ApplyBinaryDelta(old io.ReaderAt, delta io.Reader, new io.Writer) error {
+	r := bufio.NewReader(delta)
+	magic := make([]byte, len(deltaMagic))
+	_, err := io.ReadFull(r, magic)
+	if err != nil || !bytes.Equal(magic, deltaMagic) {
+		return ErrInvalidDelta
+	}
+	h := sha256.New()
+	w := io.MultiWriter(new, h)
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidDelta, io.ErrUnexpectedEOF)
+		}
+		switch op {
+		case deltaCopy:
+			offset, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidDelta, err)
+			}
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidDelta, err)
+			}
+			n, err := io.Copy(w, io.NewSectionReader(old, int64(offset), int64(length)))
+			if err != nil {
+				return err
+			}
+			if n != int64(length) {
+				return fmt.Errorf("%w: copy past the end of the old file", ErrInvalidDelta)
+			}
+		case deltaLiteral:
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidDelta, err)
+			}
+			_, err = io.CopyN(w, r, int64(length))
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidDelta, err)
+			}
+		case deltaEnd:
+			return checkDeltaSum(r, h)
+		default:
+			return fmt.Errorf("%w: unknown op %q", ErrInvalidDelta, op)
+		}
+	}
+}
+
+func checkDeltaSum(r io.Reader, h hash.Hash) error {
+	sum := make([]byte, sha256.Size)
+	_, err := io.ReadFull(r, sum)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDelta, err)
+	}
+	if !bytes.Equal(sum, h.Sum(nil)) {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidDelta)
+	}
+	return nil
+}
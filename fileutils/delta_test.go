@@ -0,0 +1,84 @@
+package fileutils
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestBinaryDiff(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	old := make([]byte, 256<<10)
+	r.Read(old)
+	edited := append([]byte{}, old[:100<<10]...)
+	edited = append(edited, []byte("inserted")...)
+	edited = append(edited, old[100<<10:200<<10]...)
+	edited = append(edited, old[210<<10:]...)
+	edited[20<<10] ^= 0xff
+
+	tests := []struct {
+		name     string
+		old      []byte
+		new      []byte
+		maxDelta int
+	}{
+		{"edited", old, edited, 32 << 10},
+		{"same", old, old, 64},
+		{"empty old", nil, []byte("new"), 64},
+		{"empty new", old, nil, 64},
+		{"empty", nil, nil, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delta bytes.Buffer
+			err := BinaryDiff(bytes.NewReader(tt.old), bytes.NewReader(tt.new), &delta)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if delta.Len() > tt.maxDelta {
+				t.Errorf("Expected delta size <= %d, got: %d\n", tt.maxDelta, delta.Len())
+			}
+			var got bytes.Buffer
+			err = ApplyBinaryDelta(bytes.NewReader(tt.old), bytes.NewReader(delta.Bytes()), &got)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if !bytes.Equal(got.Bytes(), tt.new) {
+				t.Errorf("Expected %d bytes, got %d different bytes\n", len(tt.new), got.Len())
+			}
+		})
+	}
+}
+
+func TestApplyBinaryDeltaErrors(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	old := make([]byte, 64<<10)
+	r.Read(old)
+	var delta bytes.Buffer
+	err := BinaryDiff(bytes.NewReader(old), bytes.NewReader(append(old, 'x')), &delta)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	other := make([]byte, len(old))
+	r.Read(other)
+	tests := []struct {
+		name  string
+		old   []byte
+		delta []byte
+	}{
+		{"other old", other, delta.Bytes()},
+		{"short old", old[:10], delta.Bytes()},
+		{"truncated", old, delta.Bytes()[:delta.Len()-1]},
+		{"no magic", old, []byte("garbage")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			err := ApplyBinaryDelta(bytes.NewReader(tt.old), bytes.NewReader(tt.delta), &got)
+			if !errors.Is(err, ErrInvalidDelta) {
+				t.Errorf("Expected ErrInvalidDelta, got: %v\n", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DavidGamba/go-utils/errclass"
+)
+
+// DUReport - Disk usage of a dir and its contents, see DiskUsage.
+type DUReport struct {
+	Path string `json:"path"`
+
+	// Size - Bytes allocated on disk, including the dirs themselves, like
+	// du. Where allocation is not available, on Windows, the apparent size.
+	Size int64 `json:"size"`
+
+	// ApparentSize - Sum of the sizes of the files and dirs, like
+	// du --apparent-size.
+	ApparentSize int64 `json:"apparent_size"`
+
+	// Files - Number of files, symlinks and other non dir entries.
+	Files int64 `json:"files"`
+
+	// Dirs - Number of subdirs, not counting the dir itself.
+	Dirs int64 `json:"dirs"`
+
+	// Children - Reports of the subdirs, sorted by name.
+	Children []*DUReport `json:"children,omitempty"`
+}
+
+// DUReportError - A dir report or an error, see DiskUsageStream.
+type DUReportError struct {
+	Report *DUReport
+	Error  error
+}
+
+// ErrorClass - Class of Error, see errclass.Classify.
+func (re DUReportError) ErrorClass() errclass.Class {
+	return errclass.Classify(re.Error)
+}
+
+// DiskUsage - Walks dir and returns its disk usage with a rollup for every
+// subdir.
+// Symlinks are not followed and files with several hard links are counted
+// once.
+// Errors on nested entries don't stop the walk, the report covers what
+// could be read and they are returned joined together.
+func DiskUsage(dir string) (DUReport, error) {
+	var report *DUReport
+	var errs []error
+	for r := range DiskUsageStream(context.Background(), dir) {
+		if r.Error != nil {
+			errs = append(errs, r.Error)
+			continue
+		}
+		report = r.Report
+	}
+	if report == nil {
+		return DUReport{Path: dir}, errors.Join(errs...)
+	}
+	return *report, errors.Join(errs...)
+}
+
+// DiskUsageStream - Same as DiskUsage but sends the report of each dir as
+// soon as its contents are walked, subdirs before their parents and dir
+// last, to show progress on huge trees.
+// Errors are sent and the walk continues, except for errors reading dir
+// itself. Stops walking and closes the channel when ctx is cancelled.
+func DiskUsageStream(ctx context.Context, dir string) <-chan DUReportError {
+	c := make(chan DUReportError)
+	go func() {
+		defer close(c)
+		w := &duWalker{
+			ctx:  ctx,
+			seen: map[[2]uint64]bool{},
+			send: func(r DUReportError) bool {
+				select {
+				case c <- r:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			},
+		}
+		fInfo, err := os.Stat(dir)
+		if err != nil {
+			w.send(DUReportError{Error: err})
+			return
+		}
+		if !fInfo.IsDir() {
+			w.send(DUReportError{Error: fmt.Errorf("Provided dir is not a dir: '%s'", dir)})
+			return
+		}
+		w.dir(dir, fInfo, true)
+	}()
+	return c
+}
+
+type duWalker struct {
+	ctx  context.Context
+	send func(DUReportError) bool
+
+	// seen - Device and inode of the files with several hard links already counted.
+	seen map[[2]uint64]bool
+}
+
+// dir - Walks path and sends its report, returns nil when the walk was stopped.
+func (w *duWalker) dir(path string, fInfo os.FileInfo, root bool) *DUReport {
+	r := &DUReport{Path: path}
+	w.add(r, fInfo)
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		// The report of an unreadable subdir only has the dir itself.
+		if !w.send(DUReportError{Error: err}) || root {
+			return nil
+		}
+	}
+	for _, e := range entries {
+		if w.ctx.Err() != nil {
+			return nil
+		}
+		child := filepath.Join(path, e.Name())
+		info, err := e.Info()
+		if err != nil {
+			if !w.send(DUReportError{Error: err}) {
+				return nil
+			}
+			continue
+		}
+		if !info.IsDir() {
+			r.Files++
+			w.add(r, info)
+			continue
+		}
+		sub := w.dir(child, info, false)
+		if sub == nil {
+			return nil
+		}
+		r.Children = append(r.Children, sub)
+		r.Size += sub.Size
+		r.ApparentSize += sub.ApparentSize
+		r.Files += sub.Files
+		r.Dirs += 1 + sub.Dirs
+	}
+	if !w.send(DUReportError{Report: r}) {
+		return nil
+	}
+	return r
+}
+
+// add - Adds the usage of the entry described by fInfo to r.
+func (w *duWalker) add(r *DUReport, fInfo os.FileInfo) {
+	allocated, links, ok := fileUsage(fInfo)
+	if !ok {
+		allocated = fInfo.Size()
+	}
+	if ok && links > 1 && !fInfo.IsDir() {
+		dev, ino, _ := fileID(fInfo)
+		if w.seen[[2]uint64{dev, ino}] {
+			return
+		}
+		w.seen[[2]uint64{dev, ino}] = true
+	}
+	r.Size += allocated
+	r.ApparentSize += fInfo.Size()
+}
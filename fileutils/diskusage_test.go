@@ -0,0 +1,97 @@
+package fileutils
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	os.MkdirAll(filepath.Join(dir, "c"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "top"), make([]byte, 100), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a", "f"), make([]byte, 10000), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a", "b", "g"), make([]byte, 1), 0644)
+	os.Link(filepath.Join(dir, "a", "f"), filepath.Join(dir, "c", "f-link"))
+
+	report, err := DiskUsage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if report.Path != dir || report.Files != 4 || report.Dirs != 3 {
+		t.Errorf("Unexpected report: %+v\n", report)
+	}
+	if len(report.Children) != 2 || report.Children[0].Path != filepath.Join(dir, "a") || report.Children[1].Path != filepath.Join(dir, "c") {
+		t.Fatalf("Unexpected children: %+v\n", report.Children)
+	}
+	a := report.Children[0]
+	if a.Files != 2 || a.Dirs != 1 || len(a.Children) != 1 || a.Children[0].Files != 1 {
+		t.Errorf("Unexpected report: %+v\n", a)
+	}
+	dirSize := func(path string) int64 {
+		fInfo, _ := os.Lstat(path)
+		return fInfo.Size()
+	}
+	expected := int64(100+10000+1) + dirSize(dir) + dirSize(filepath.Join(dir, "a")) + dirSize(filepath.Join(dir, "a", "b")) + dirSize(filepath.Join(dir, "c"))
+	if runtime.GOOS != "windows" && report.ApparentSize != expected {
+		// The hard link is only counted once.
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", expected, report.ApparentSize)
+	}
+	if a.Size <= 0 || report.Size < a.Size+report.Children[1].Size {
+		t.Errorf("Expected the sizes to roll up, got %d for '%s' and %d for the root\n", a.Size, a.Path, report.Size)
+	}
+
+	var got []string
+	for r := range DiskUsageStream(context.Background(), dir) {
+		if r.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", r.Error)
+		}
+		got = append(got, r.Report.Path)
+	}
+	order := []string{filepath.Join(dir, "a", "b"), filepath.Join(dir, "a"), filepath.Join(dir, "c"), dir}
+	if len(got) != len(order) {
+		t.Fatalf("Expected:\n%q\nGot:\n%q\n", order, got)
+	}
+	for i := range order {
+		if got[i] != order[i] {
+			t.Errorf("Expected:\n%q\nGot:\n%q\n", order, got)
+		}
+	}
+
+	_, err = DiskUsage(filepath.Join(dir, "top"))
+	if err == nil {
+		t.Errorf("Expected error for a file\n")
+	}
+	_, err = DiskUsage(filepath.Join(dir, "missing"))
+	if err == nil {
+		t.Errorf("Expected error for a missing dir\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := DiskUsageStream(ctx, dir)
+	<-c
+	cancel()
+	for range c {
+	}
+}
+
+func TestDUReportJSON(t *testing.T) {
+	r := DUReport{Path: "a", Size: 8192, ApparentSize: 10, Files: 1, Dirs: 1, Children: []*DUReport{{Path: "a/b", Size: 4096}}}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := `{"path":"a","size":8192,"apparent_size":10,"files":1,"dirs":1,"children":[{"path":"a/b","size":4096,"apparent_size":0,"files":0,"dirs":0}]}`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, data)
+	}
+}
@@ -0,0 +1,129 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"iter"
+	"os"
+	"sort"
+)
+
+// DuplicateSet - Files with identical contents.
+type DuplicateSet struct {
+	// Hash - Hex encoded SHA-256 of the contents.
+	Hash string `json:"sha256"`
+
+	// Size - Size of each file.
+	Size int64 `json:"size"`
+
+	// Files - Sorted paths of the files.
+	Files []string `json:"files"`
+}
+
+// FindDuplicates - Returns the sets of files under dir with identical
+// contents, keyed by their hex encoded SHA-256, see Duplicates.
+func FindDuplicates(dir string, opts ...ListOption) (map[string][]string, error) {
+	sets := map[string][]string{}
+	for set, err := range Duplicates(dir, opts...) {
+		if err != nil {
+			return nil, err
+		}
+		sets[set.Hash] = set.Files
+	}
+	return sets, nil
+}
+
+// Duplicates - Yields the sets of files under dir with identical contents.
+// Files are grouped by size first so only files that share their size with
+// another are hashed, and sets are yielded as each size is hashed, largest
+// first.
+//
+// Empty files, symlinks and special files are skipped, and hard links to a
+// file already seen are not reported as duplicates of it.
+// Errors reading dirs or hashing files are yielded and the search
+// continues, breaking out of the loop stops it.
+func Duplicates(dir string, opts ...ListOption) iter.Seq2[DuplicateSet, error] {
+	return func(yield func(DuplicateSet, error) bool) {
+		bySize := map[int64][]string{}
+		seen := map[[2]uint64]bool{}
+		stopped := false
+		err := Walk(dir, func(path string, isDir bool, err error) error {
+			if err != nil {
+				if !yield(DuplicateSet{}, err) {
+					stopped = true
+					return err
+				}
+				return nil
+			}
+			if isDir {
+				return nil
+			}
+			fInfo, err := os.Lstat(path)
+			if err != nil {
+				if !yield(DuplicateSet{}, err) {
+					stopped = true
+					return err
+				}
+				return nil
+			}
+			if !fInfo.Mode().IsRegular() || fInfo.Size() == 0 {
+				return nil
+			}
+			if dev, ino, ok := fileID(fInfo); ok {
+				if seen[[2]uint64{dev, ino}] {
+					return nil
+				}
+				seen[[2]uint64{dev, ino}] = true
+			}
+			bySize[fInfo.Size()] = append(bySize[fInfo.Size()], path)
+			return nil
+		}, opts...)
+		if stopped {
+			return
+		}
+		if err != nil {
+			yield(DuplicateSet{}, err)
+			return
+		}
+		sizes := []int64{}
+		for size, paths := range bySize {
+			if len(paths) > 1 {
+				sizes = append(sizes, size)
+			}
+		}
+		sort.Slice(sizes, func(i, j int) bool { return sizes[i] > sizes[j] })
+		for _, size := range sizes {
+			byHash := map[string][]string{}
+			for _, path := range bySize[size] {
+				sum, err := sha256File(path)
+				if err != nil {
+					if !yield(DuplicateSet{}, err) {
+						return
+					}
+					continue
+				}
+				byHash[sum] = append(byHash[sum], path)
+			}
+			hashes := []string{}
+			for sum, paths := range byHash {
+				if len(paths) > 1 {
+					hashes = append(hashes, sum)
+				}
+			}
+			sort.Strings(hashes)
+			for _, sum := range hashes {
+				files := byHash[sum]
+				sort.Strings(files)
+				if !yield(DuplicateSet{Hash: sum, Size: size, Files: files}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a":       "hello",
+		"b/a":     "hello",
+		"b/c":     "world",
+		"c":       "hola!",
+		"d/e/f":   "hello",
+		"big1":    "0123456789",
+		"d/big2":  "0123456789",
+		"empty":   "",
+		"empty2":  "",
+		"unique":  "unique",
+		".hidden": "world",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(content), 0644)
+	}
+	os.Link(filepath.Join(dir, "unique"), filepath.Join(dir, "hardlink"))
+	os.Symlink("a", filepath.Join(dir, "link"))
+
+	got, err := FindDuplicates(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	join := func(names ...string) []string {
+		for i := range names {
+			names[i] = filepath.Join(dir, filepath.FromSlash(names[i]))
+		}
+		return names
+	}
+	expected := map[string][]string{
+		"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824": join("a", "b/a", "d/e/f"),
+		"486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7": join(".hidden", "b/c"),
+		"84d89877f0d4041efb6bf91a16f0248f2fd573e6af05c19f96bedb9f882f7882": join("big1", "d/big2"),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+
+	// Largest first, break stops.
+	n := 0
+	for set, err := range Duplicates(dir, ListSkipHidden()) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if set.Size != 10 {
+			t.Errorf("Expected:\n%d\nGot:\n%d\n", 10, set.Size)
+		}
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("Expected one set, got: %d\n", n)
+	}
+
+	_, err = FindDuplicates(filepath.Join(dir, "missing"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
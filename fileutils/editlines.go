@@ -0,0 +1,355 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrLineOutOfRange - The line number is past the end of the file.
+var ErrLineOutOfRange = fmt.Errorf("line out of range")
+
+// ErrUnterminatedBlock - The begin marker of a block has no end marker after it.
+var ErrUnterminatedBlock = fmt.Errorf("unterminated block")
+
+// InsertLines - Inserts lines before line number at, counting from 1, so
+// the first inserted line becomes line at. An at of one past the last line
+// appends the lines.
+// Returns the number of lines inserted.
+//
+// The inserted lines get the line ending of the line they are inserted
+// before, or of the last line of the file. Like StringReplace, the file is
+// streamed through a tmp copy and whether it ends with a newline and its
+// mode are preserved.
+func InsertLines(file string, at int, lines []string, bufferSize int) (int, error) {
+	if err := checkWritable("InsertLines", file); err != nil {
+		return 0, err
+	}
+	if at < 1 {
+		return 0, fmt.Errorf("%w: '%s': %d", ErrLineOutOfRange, file, at)
+	}
+	fileEOL, lastEOL := "\n", "\n"
+	// insert - Writes the lines, the last one without a line ending unless terminate is set.
+	insert := func(w *bufio.Writer, terminate bool) (int, error) {
+		for i, line := range lines {
+			if i < len(lines)-1 || terminate {
+				line += fileEOL
+			}
+			_, err := w.WriteString(line)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return len(lines), nil
+	}
+	return rewriteLines(file, bufferSize, func(w *bufio.Writer, n int, line, eol string, eof bool) (int, error) {
+		if eof {
+			if n < at {
+				return 0, fmt.Errorf("%w: '%s': %d, the file has %d lines", ErrLineOutOfRange, file, at, n-1)
+			}
+			if n > at || len(lines) == 0 {
+				return 0, nil
+			}
+			if lastEOL == "" {
+				// Terminate the last line and keep the file without a final newline.
+				_, err := w.WriteString(fileEOL)
+				if err != nil {
+					return 0, err
+				}
+			}
+			return insert(w, lastEOL != "")
+		}
+		if eol != "" {
+			fileEOL = eol
+		}
+		lastEOL = eol
+		changed := 0
+		if n == at {
+			var err error
+			changed, err = insert(w, true)
+			if err != nil {
+				return 0, err
+			}
+		}
+		_, err := w.WriteString(line + eol)
+		return changed, err
+	})
+}
+
+// DeleteLines - Deletes the lines from start to end, counting from 1 and
+// inclusive. An end of 0 deletes up to the end of the file.
+// Returns the number of lines deleted, lines past the end of the file are
+// not counted.
+//
+// Like StringReplace, the file is streamed through a tmp copy and whether
+// it ends with a newline and its mode are preserved.
+func DeleteLines(file string, start, end, bufferSize int) (int, error) {
+	if err := checkWritable("DeleteLines", file); err != nil {
+		return 0, err
+	}
+	if start < 1 || end < 0 || (end > 0 && end < start) {
+		return 0, fmt.Errorf("invalid line range '%s': %d-%d", file, start, end)
+	}
+	// The line ending of the last line written is only written before the
+	// next one, to drop it if the lines after it are deleted and the file
+	// doesn't end with a newline.
+	pending, lastEOL := "", ""
+	return rewriteLines(file, bufferSize, func(w *bufio.Writer, n int, line, eol string, eof bool) (int, error) {
+		if eof {
+			if lastEOL == "" {
+				return 0, nil
+			}
+			_, err := w.WriteString(pending)
+			return 0, err
+		}
+		lastEOL = eol
+		if n >= start && (end == 0 || n <= end) {
+			return 1, nil
+		}
+		_, err := w.WriteString(pending + line)
+		pending = eol
+		return 0, err
+	})
+}
+
+// ReplaceLine - Replaces line number n, counting from 1, keeping its line
+// ending.
+// Returns 1 if the line changed and 0 if it was the same.
+//
+// Like StringReplace, the file is streamed through a tmp copy and its mode
+// is preserved.
+func ReplaceLine(file string, n int, line string, bufferSize int) (int, error) {
+	if err := checkWritable("ReplaceLine", file); err != nil {
+		return 0, err
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("%w: '%s': %d", ErrLineOutOfRange, file, n)
+	}
+	return rewriteLines(file, bufferSize, func(w *bufio.Writer, i int, old, eol string, eof bool) (int, error) {
+		if eof {
+			if i <= n {
+				return 0, fmt.Errorf("%w: '%s': %d, the file has %d lines", ErrLineOutOfRange, file, n, i-1)
+			}
+			return 0, nil
+		}
+		if i != n {
+			_, err := w.WriteString(old + eol)
+			return 0, err
+		}
+		_, err := w.WriteString(line + eol)
+		if err != nil || line == old {
+			return 0, err
+		}
+		return 1, nil
+	})
+}
+
+// LineInFileOption - LineInFile option.
+type LineInFileOption func(*lineInFileOptions)
+
+type lineInFileOptions struct {
+	re         *regexp.Regexp
+	after      *regexp.Regexp
+	before     *regexp.Regexp
+	bufferSize int
+}
+
+// LineRegexp - Lines matching re are replaced by the line, instead of only
+// lines equal to it.
+func LineRegexp(re *regexp.Regexp) LineInFileOption {
+	return func(o *lineInFileOptions) {
+		o.re = re
+	}
+}
+
+// LineInsertAfter - When the line is missing, inserts it after the last line
+// matching re instead of at the end of the file.
+func LineInsertAfter(re *regexp.Regexp) LineInFileOption {
+	return func(o *lineInFileOptions) {
+		o.after = re
+	}
+}
+
+// LineInsertBefore - When the line is missing, inserts it before the first
+// line matching re instead of at the end of the file.
+func LineInsertBefore(re *regexp.Regexp) LineInFileOption {
+	return func(o *lineInFileOptions) {
+		o.before = re
+	}
+}
+
+// LineBufferSize - Buffer size used to read the file, it limits the longest
+// line that can be read. Defaults to 1MiB.
+func LineBufferSize(size int) LineInFileOption {
+	return func(o *lineInFileOptions) {
+		o.bufferSize = size
+	}
+}
+
+// LineInFile - Ensures line is in file exactly once, like the Ansible
+// lineinfile module. Returns whether the file was modified, running it
+// again doesn't modify it.
+//
+// The first line equal to line, or matching LineRegexp, is replaced by line
+// and any other matches are deleted. When there are no matches, line is
+// inserted at the end of the file, or next to the LineInsertAfter or
+// LineInsertBefore markers when they are found.
+//
+// Like StringReplace, the file is streamed through a tmp copy and its line
+// endings and mode are preserved.
+func LineInFile(file, line string, opts ...LineInFileOption) (bool, error) {
+	if err := checkWritable("LineInFile", file); err != nil {
+		return false, err
+	}
+	o := lineInFileOptions{bufferSize: 1024 * 1024}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	matches := func(l string) bool {
+		return l == line || (o.re != nil && o.re.MatchString(l))
+	}
+	first, after, before, count := 0, 0, 0, 0
+	err := eachLine(file, o.bufferSize, func(l, eol string) error {
+		count++
+		if first == 0 && matches(l) {
+			first = count
+		}
+		if o.after != nil && o.after.MatchString(l) {
+			after = count
+		}
+		if before == 0 && o.before != nil && o.before.MatchString(l) {
+			before = count
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if first == 0 {
+		at := count + 1
+		if after > 0 {
+			at = after + 1
+		} else if before > 0 {
+			at = before
+		}
+		n, err := InsertLines(file, at, []string{line}, o.bufferSize)
+		return n > 0, err
+	}
+	// Line endings are written before the next line, like in DeleteLines.
+	pending, lastEOL := "", ""
+	n, err := rewriteLines(file, o.bufferSize, func(w *bufio.Writer, n int, l, eol string, eof bool) (int, error) {
+		if eof {
+			if lastEOL == "" {
+				return 0, nil
+			}
+			_, err := w.WriteString(pending)
+			return 0, err
+		}
+		lastEOL = eol
+		changed := 0
+		switch {
+		case n == first:
+			if l != line {
+				changed = 1
+			}
+			l = line
+		case matches(l):
+			return 1, nil
+		}
+		_, err := w.WriteString(pending + l)
+		pending = eol
+		return changed, err
+	})
+	return n > 0, err
+}
+
+// BlockInFile - Ensures the lines of content are in file between the
+// beginMarker and endMarker lines, so a block of a file can be managed by
+// automation, like the Ansible blockinfile module.
+// Returns whether the file was modified, running it again doesn't modify it.
+//
+//	BlockInFile("/etc/hosts", "# BEGIN managed", "# END managed", "10.0.0.1 db\n")
+//
+// The markers are matched against whole lines. An existing block, from the
+// first beginMarker to the next endMarker, is replaced, otherwise the block
+// is appended to the file. An empty content removes the block, markers
+// included. A beginMarker without an endMarker after it returns
+// ErrUnterminatedBlock.
+//
+// Like StringReplace, the file is streamed through a tmp copy and its line
+// endings and mode are preserved.
+func BlockInFile(file, beginMarker, endMarker, content string) (bool, error) {
+	if err := checkWritable("BlockInFile", file); err != nil {
+		return false, err
+	}
+	bufferSize := 1024 * 1024
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+		for i := range lines {
+			lines[i] = strings.TrimSuffix(lines[i], "\r")
+		}
+	}
+	begin, end, count := 0, 0, 0
+	fileEOL := ""
+	var old []string
+	err := eachLine(file, bufferSize, func(line, eol string) error {
+		count++
+		if fileEOL == "" {
+			fileEOL = eol
+		}
+		switch {
+		case begin == 0 && line == beginMarker:
+			begin = count
+		case begin > 0 && end == 0 && line == endMarker:
+			end = count
+		case begin > 0 && end == 0:
+			old = append(old, line)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if fileEOL == "" {
+		fileEOL = "\n"
+	}
+	if begin > 0 && end == 0 {
+		return false, fmt.Errorf("%w: '%s': '%s' in line %d", ErrUnterminatedBlock, file, beginMarker, begin)
+	}
+	if begin == 0 {
+		if len(lines) == 0 {
+			return false, nil
+		}
+		n, err := InsertLines(file, count+1, append(append([]string{beginMarker}, lines...), endMarker), bufferSize)
+		return n > 0, err
+	}
+	if len(lines) == 0 {
+		n, err := DeleteLines(file, begin, end, bufferSize)
+		return n > 0, err
+	}
+	if equalLines(old, lines) {
+		return false, nil
+	}
+	n, err := rewriteLines(file, bufferSize, func(w *bufio.Writer, n int, line, eol string, eof bool) (int, error) {
+		switch {
+		case eof || n < begin || n >= end:
+			_, err := w.WriteString(line + eol)
+			return 0, err
+		case n == begin:
+			_, err := w.WriteString(line + eol + strings.Join(lines, fileEOL) + fileEOL)
+			return 1, err
+		}
+		return 0, nil
+	})
+	return n > 0, err
+}
@@ -0,0 +1,184 @@
+package fileutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestEditLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a")
+	tests := []struct {
+		name     string
+		content  string
+		fn       func() (int, error)
+		n        int
+		expected string
+	}{
+		{"insert first", "a\nb\n", func() (int, error) { return InsertLines(file, 1, []string{"x", "y"}, 1024) }, 2, "x\ny\na\nb\n"},
+		{"insert middle", "a\r\nb\r\n", func() (int, error) { return InsertLines(file, 2, []string{"x"}, 1024) }, 1, "a\r\nx\r\nb\r\n"},
+		{"insert before last without newline", "a\nb", func() (int, error) { return InsertLines(file, 2, []string{"x"}, 1024) }, 1, "a\nx\nb"},
+		{"append", "a\nb\n", func() (int, error) { return InsertLines(file, 3, []string{"x"}, 1024) }, 1, "a\nb\nx\n"},
+		{"append without newline", "a\r\nb", func() (int, error) { return InsertLines(file, 3, []string{"x", "y"}, 1024) }, 2, "a\r\nb\r\nx\r\ny"},
+		{"append empty file", "", func() (int, error) { return InsertLines(file, 1, []string{"x"}, 1024) }, 1, "x\n"},
+		{"insert nothing", "a\n", func() (int, error) { return InsertLines(file, 1, nil, 1024) }, 0, "a\n"},
+		{"delete range", "a\nb\nc\nd\n", func() (int, error) { return DeleteLines(file, 2, 3, 1024) }, 2, "a\nd\n"},
+		{"delete to end", "a\nb\nc\n", func() (int, error) { return DeleteLines(file, 2, 0, 1024) }, 2, "a\n"},
+		{"delete to end without newline", "a\nb\nc", func() (int, error) { return DeleteLines(file, 2, 0, 1024) }, 2, "a"},
+		{"delete past end", "a\nb\n", func() (int, error) { return DeleteLines(file, 2, 5, 1024) }, 1, "a\n"},
+		{"delete all", "a\nb", func() (int, error) { return DeleteLines(file, 1, 0, 1024) }, 2, ""},
+		{"replace", "a\r\nb\r\nc", func() (int, error) { return ReplaceLine(file, 2, "x", 1024) }, 1, "a\r\nx\r\nc"},
+		{"replace last", "a\nb", func() (int, error) { return ReplaceLine(file, 2, "x", 1024) }, 1, "a\nx"},
+		{"replace same", "a\nb\n", func() (int, error) { return ReplaceLine(file, 1, "a", 1024) }, 0, "a\nb\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ioutil.WriteFile(file, []byte(test.content), 0644)
+			n, err := test.fn()
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if n != test.n {
+				t.Errorf("Expected %d lines, got: %d\n", test.n, n)
+			}
+			b, _ := ioutil.ReadFile(file)
+			if string(b) != test.expected {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, b)
+			}
+		})
+	}
+
+	ioutil.WriteFile(file, []byte("a\nb\n"), 0644)
+	errTests := []struct {
+		name string
+		fn   func() (int, error)
+	}{
+		{"insert past end", func() (int, error) { return InsertLines(file, 4, []string{"x"}, 1024) }},
+		{"insert zero", func() (int, error) { return InsertLines(file, 0, []string{"x"}, 1024) }},
+		{"replace past end", func() (int, error) { return ReplaceLine(file, 3, "x", 1024) }},
+	}
+	for _, test := range errTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.fn()
+			if !errors.Is(err, ErrLineOutOfRange) {
+				t.Errorf("Expected ErrLineOutOfRange, got: %v\n", err)
+			}
+			b, _ := ioutil.ReadFile(file)
+			if string(b) != "a\nb\n" {
+				t.Errorf("File modified: %q\n", b)
+			}
+		})
+	}
+	_, err = DeleteLines(file, 2, 1, 1024)
+	if err == nil {
+		t.Errorf("Expected invalid range error\n")
+	}
+}
+
+func TestLineInFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a")
+	tests := []struct {
+		name     string
+		content  string
+		line     string
+		opts     []LineInFileOption
+		changed  bool
+		expected string
+	}{
+		{"present", "a\nb\n", "b", nil, false, "a\nb\n"},
+		{"append", "a\nb\n", "c", nil, true, "a\nb\nc\n"},
+		{"append without newline", "a\r\nb", "c", nil, true, "a\r\nb\r\nc"},
+		{"empty file", "", "c", nil, true, "c\n"},
+		{"duplicates", "b\na\nb\nb", "b", nil, true, "b\na"},
+		{"regexp", "a\nport=1\nb\n", "port=2", []LineInFileOption{LineRegexp(regexp.MustCompile(`^port=`))}, true, "a\nport=2\nb\n"},
+		{"regexp duplicates", "port=1\na\nport=3\n", "port=2", []LineInFileOption{LineRegexp(regexp.MustCompile(`^port=`))}, true, "port=2\na\n"},
+		{"regexp present", "a\nport=2\n", "port=2", []LineInFileOption{LineRegexp(regexp.MustCompile(`^port=`))}, false, "a\nport=2\n"},
+		{"after", "[a]\nx\n[b]\ny\n[b]\nz\n", "c", []LineInFileOption{LineInsertAfter(regexp.MustCompile(`^\[b\]`))}, true, "[a]\nx\n[b]\ny\n[b]\nc\nz\n"},
+		{"before", "[a]\nx\n[b]\ny\n[b]\n", "c", []LineInFileOption{LineInsertBefore(regexp.MustCompile(`^\[b\]`))}, true, "[a]\nx\nc\n[b]\ny\n[b]\n"},
+		{"missing marker", "a\n", "c", []LineInFileOption{LineInsertAfter(regexp.MustCompile(`^\[b\]`))}, true, "a\nc\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ioutil.WriteFile(file, []byte(test.content), 0644)
+			changed, err := LineInFile(file, test.line, test.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if changed != test.changed {
+				t.Errorf("Expected changed %v, got: %v\n", test.changed, changed)
+			}
+			b, _ := ioutil.ReadFile(file)
+			if string(b) != test.expected {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, b)
+			}
+			changed, err = LineInFile(file, test.line, test.opts...)
+			if err != nil || changed {
+				t.Errorf("Second run not idempotent: %v, %v\n", changed, err)
+			}
+		})
+	}
+}
+
+func TestBlockInFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a")
+	tests := []struct {
+		name     string
+		content  string
+		block    string
+		changed  bool
+		expected string
+	}{
+		{"append", "a\n", "x\ny\n", true, "a\n# BEGIN\nx\ny\n# END\n"},
+		{"append without newline", "a\r\nb", "x", true, "a\r\nb\r\n# BEGIN\r\nx\r\n# END"},
+		{"empty file", "", "x", true, "# BEGIN\nx\n# END\n"},
+		{"replace", "a\n# BEGIN\nx\n# END\nb\n", "y\nz", true, "a\n# BEGIN\ny\nz\n# END\nb\n"},
+		{"replace crlf", "a\r\n# BEGIN\r\nx\r\n# END\r\nb\r\n", "y\r\nz\r\n", true, "a\r\n# BEGIN\r\ny\r\nz\r\n# END\r\nb\r\n"},
+		{"replace empty block", "# BEGIN\n# END", "y", true, "# BEGIN\ny\n# END"},
+		{"present", "a\n# BEGIN\nx\n# END\n", "x\n", false, "a\n# BEGIN\nx\n# END\n"},
+		{"remove", "a\n# BEGIN\nx\n# END\nb\n", "", true, "a\nb\n"},
+		{"remove missing", "a\n", "", false, "a\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ioutil.WriteFile(file, []byte(test.content), 0644)
+			changed, err := BlockInFile(file, "# BEGIN", "# END", test.block)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if changed != test.changed {
+				t.Errorf("Expected changed %v, got: %v\n", test.changed, changed)
+			}
+			b, _ := ioutil.ReadFile(file)
+			if string(b) != test.expected {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, b)
+			}
+			changed, err = BlockInFile(file, "# BEGIN", "# END", test.block)
+			if err != nil || changed {
+				t.Errorf("Second run not idempotent: %v, %v\n", changed, err)
+			}
+		})
+	}
+	ioutil.WriteFile(file, []byte("# BEGIN\nx\n"), 0644)
+	_, err = BlockInFile(file, "# BEGIN", "# END", "y")
+	if !errors.Is(err, ErrUnterminatedBlock) {
+		t.Errorf("Expected ErrUnterminatedBlock, got: %v\n", err)
+	}
+}
@@ -0,0 +1,282 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrEditConflict - A file staged in an EditSession changed before the
+// session was committed.
+var ErrEditConflict = fmt.Errorf("edit conflict")
+
+// EditSession - Stages modifications to the files under a dir in a shadow
+// dir and applies them all together with Commit, or drops them with
+// Discard, giving multi-file edits all or nothing semantics.
+//
+// Edit the staged copies returned by Path with any of the package editing
+// functions, like StringReplace, LineInFile or BlockInFile:
+//
+//	err := fileutils.Edit(dir, func(s *fileutils.EditSession) error {
+//		a, err := s.Path("a.conf")
+//		if err != nil {
+//			return err
+//		}
+//		_, err = fileutils.LineInFile(a, "enabled = true")
+//		return err
+//	})
+//
+// It is safe for concurrent use.
+type EditSession struct {
+	dir    string
+	shadow string
+	mu     sync.Mutex
+	staged map[string]stagedFile
+}
+
+type stagedFile struct {
+	// exists - Whether the original exists, and its size and mtime when staged.
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// NewEditSession - Starts a session to edit the files under dir.
+// Call Commit or Discard when done, to remove the shadow dir.
+func NewEditSession(dir string) (*EditSession, error) {
+	if err := checkWritable("NewEditSession", dir); err != nil {
+		return nil, err
+	}
+	shadow, err := ioutil.TempDir("", "fileutils-edit-")
+	if err != nil {
+		return nil, err
+	}
+	return &EditSession{dir: dir, shadow: shadow, staged: map[string]stagedFile{}}, nil
+}
+
+// Edit - Runs fn with a new EditSession for dir and commits it when fn
+// succeeds, otherwise it is discarded and the error returned.
+func Edit(dir string, fn func(s *EditSession) error) error {
+	s, err := NewEditSession(dir)
+	if err != nil {
+		return err
+	}
+	err = fn(s)
+	if err != nil {
+		s.Discard()
+		return err
+	}
+	return s.Commit()
+}
+
+// Path - Returns the path of the staged copy of file, relative to the
+// session dir, to be modified in place of the original.
+// The original is copied on the first call, files that don't exist yet are
+// created on Commit if the staged copy is written.
+func (s *EditSession) Path(file string) (string, error) {
+	rel := filepath.Clean(file)
+	if filepath.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("'%s' is not a file under the session dir '%s'", file, s.dir)
+	}
+	shadow := filepath.Join(s.shadow, rel)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.staged[rel]; ok {
+		return shadow, nil
+	}
+	err := os.MkdirAll(filepath.Dir(shadow), 0755)
+	if err != nil {
+		return "", err
+	}
+	original := filepath.Join(s.dir, rel)
+	fInfo, err := os.Stat(original)
+	if os.IsNotExist(err) {
+		s.staged[rel] = stagedFile{}
+		return shadow, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !fInfo.Mode().IsRegular() {
+		return "", fmt.Errorf("'%s' is not a regular file", original)
+	}
+	err = CopyFile(original, shadow, CopyPreserve())
+	if err != nil {
+		return "", err
+	}
+	s.staged[rel] = stagedFile{exists: true, size: fInfo.Size(), modTime: fInfo.ModTime()}
+	return shadow, nil
+}
+
+// WriteFile - Stages data as the new contents of file, relative to the
+// session dir.
+func (s *EditSession) WriteFile(file string, data []byte, perm os.FileMode) error {
+	shadow, err := s.Path(file)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(shadow, data, perm)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(shadow, perm)
+}
+
+// Files - Returns the sorted paths, relative to the session dir, of the
+// staged files that differ from their originals.
+func (s *EditSession) Files() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := []string{}
+	for rel, st := range s.staged {
+		changed, err := s.changed(rel, st)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			files = append(files, rel)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// changed - Whether the staged copy of rel differs from the original.
+func (s *EditSession) changed(rel string, st stagedFile) (bool, error) {
+	shadow := filepath.Join(s.shadow, rel)
+	shadowInfo, err := os.Stat(shadow)
+	if os.IsNotExist(err) && !st.exists {
+		return false, nil
+	}
+	if err != nil || !st.exists {
+		return true, err
+	}
+	original := filepath.Join(s.dir, rel)
+	fInfo, err := os.Stat(original)
+	if err != nil {
+		return false, err
+	}
+	if fInfo.Mode() != shadowInfo.Mode() {
+		return true, nil
+	}
+	same, err := CompareFiles(original, shadow)
+	return !same, err
+}
+
+// Commit - Applies the staged changes and removes the shadow dir.
+//
+// All the changed files are first copied next to their originals, checking
+// that the originals didn't change since they were staged, and then renamed
+// into place. If a rename fails, the files already renamed are restored.
+// Returns ErrEditConflict, without applying anything, when an original
+// changed.
+func (s *EditSession) Commit() error {
+	if err := checkWritable("EditSession.Commit", s.dir); err != nil {
+		return err
+	}
+	defer s.Discard()
+	files, err := s.Files()
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		target string
+		tmp    string
+		backup string
+	}
+	prepared := []pending{}
+	defer func() {
+		for _, p := range prepared {
+			os.Remove(p.tmp)
+			if p.backup != "" {
+				os.Remove(p.backup)
+			}
+		}
+	}()
+	for _, rel := range files {
+		st := s.staged[rel]
+		target := filepath.Join(s.dir, rel)
+		fInfo, err := os.Stat(target)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if (err == nil) != st.exists || (st.exists && (fInfo.Size() != st.size || !fInfo.ModTime().Equal(st.modTime))) {
+			return fmt.Errorf("%w: '%s' changed after it was staged", ErrEditConflict, target)
+		}
+		err = os.MkdirAll(filepath.Dir(target), 0755)
+		if err != nil {
+			return err
+		}
+		p := pending{target: target}
+		p.tmp, err = tmpName(target)
+		if err != nil {
+			return err
+		}
+		prepared = append(prepared, p)
+		err = CopyFile(filepath.Join(s.shadow, rel), p.tmp, CopyPreserve())
+		if err != nil {
+			return err
+		}
+		if st.exists {
+			backup, err := tmpName(target)
+			if err != nil {
+				return err
+			}
+			prepared[len(prepared)-1].backup = backup
+			// Hard link the original, it keeps its inode when restored.
+			os.Remove(backup)
+			err = os.Link(target, backup)
+			if err != nil {
+				err = CopyFile(target, backup, CopyPreserve())
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for i, p := range prepared {
+		err := os.Rename(p.tmp, p.target)
+		if err == nil {
+			continue
+		}
+		for _, done := range prepared[:i] {
+			if done.backup != "" {
+				os.Rename(done.backup, done.target)
+			} else {
+				os.Remove(done.target)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// tmpName - Creates an empty tmp file next to target and returns its name.
+func tmpName(target string) (string, error) {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(target), "."+filepath.Base(target)+"-")
+	if err != nil {
+		return "", err
+	}
+	return tmpFile.Name(), tmpFile.Close()
+}
+
+// Discard - Drops the staged changes and removes the shadow dir.
+func (s *EditSession) Discard() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.staged = map[string]stagedFile{}
+	return os.RemoveAll(s.shadow)
+}
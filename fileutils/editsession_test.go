@@ -0,0 +1,142 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEditSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func() {
+		ioutil.WriteFile(filepath.Join(dir, "a"), []byte("a\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "b"), []byte("hello\n"), 0600)
+		os.Remove(filepath.Join(dir, "c"))
+	}
+	check := func(t *testing.T, expected map[string]string) {
+		for name, content := range expected {
+			b, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if content == "" {
+				if !os.IsNotExist(err) {
+					t.Errorf("%s: expected missing file, got: %q, %v\n", name, b, err)
+				}
+				continue
+			}
+			if string(b) != content {
+				t.Errorf("%s: Expected:\n%q\nGot:\n%q\n", name, content, b)
+			}
+		}
+		entries, _ := ioutil.ReadDir(dir)
+		for _, e := range entries {
+			if _, ok := expected[e.Name()]; !ok {
+				t.Errorf("Unexpected file left behind: %s\n", e.Name())
+			}
+		}
+	}
+	edit := func(s *EditSession) error {
+		a, err := s.Path("a")
+		if err != nil {
+			return err
+		}
+		_, err = LineInFile(a, "x")
+		if err != nil {
+			return err
+		}
+		b, err := s.Path("b")
+		if err != nil {
+			return err
+		}
+		_, err = StringReplace(b, "hello", "bye", -1, 1024)
+		if err != nil {
+			return err
+		}
+		// Staged but unchanged.
+		_, err = s.Path("d")
+		if err != nil {
+			return err
+		}
+		return s.WriteFile("c", []byte("c\n"), 0640)
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		write()
+		s, err := NewEditSession(dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		err = edit(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		// Nothing is applied until the commit.
+		check(t, map[string]string{"a": "a\n", "b": "hello\n", "c": ""})
+		files, err := s.Files()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		expected := []string{"a", "b", "c"}
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, files)
+		}
+		err = s.Commit()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		check(t, map[string]string{"a": "a\nx\n", "b": "bye\n", "c": "c\n"})
+		fInfo, _ := os.Stat(filepath.Join(dir, "b"))
+		if fInfo.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600, got: %o\n", fInfo.Mode().Perm())
+		}
+		if _, err := os.Stat(s.shadow); !os.IsNotExist(err) {
+			t.Errorf("Shadow dir not removed: %v\n", err)
+		}
+	})
+
+	t.Run("discard on error", func(t *testing.T) {
+		write()
+		err := Edit(dir, func(s *EditSession) error {
+			err := edit(s)
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("failed")
+		})
+		if err == nil || err.Error() != "failed" {
+			t.Errorf("Unexpected error: %v\n", err)
+		}
+		check(t, map[string]string{"a": "a\n", "b": "hello\n", "c": ""})
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		write()
+		err := Edit(dir, func(s *EditSession) error {
+			err := edit(s)
+			ioutil.WriteFile(filepath.Join(dir, "b"), []byte("changed\n"), 0600)
+			return err
+		})
+		if !errors.Is(err, ErrEditConflict) {
+			t.Errorf("Expected ErrEditConflict, got: %v\n", err)
+		}
+		check(t, map[string]string{"a": "a\n", "b": "changed\n", "c": ""})
+	})
+
+	s, err := NewEditSession(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer s.Discard()
+	for _, file := range []string{"../x", "/x", "."} {
+		_, err = s.Path(file)
+		if err == nil {
+			t.Errorf("Expected error for '%s'\n", file)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EnsureDir - Creates path and any missing parents with the given permissions, like `mkdir -p`.
+// It is not an error if path already exists as a directory.
+func EnsureDir(path string, perm os.FileMode) error {
+	if err := checkWritable("EnsureDir", path); err != nil {
+		return err
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// Touch - Creates an empty file at path, creating any missing parent dirs,
+// or updates the access and modification times to now if it already exists,
+// like `mkdir -p $(dirname path) && touch path`.
+func Touch(path string) error {
+	if err := checkWritable("Touch", path); err != nil {
+		return err
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	fh, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	err = fh.Close()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return os.Chtimes(path, now, now)
+}
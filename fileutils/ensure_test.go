@@ -0,0 +1,86 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnsureDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a", "b", "c")
+	for i := 0; i < 2; i++ {
+		err = EnsureDir(path, 0750)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		fInfo, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if !fInfo.IsDir() {
+			t.Errorf("Expected dir: %s\n", path)
+		}
+	}
+
+	file := filepath.Join(dir, "file")
+	ioutil.WriteFile(file, []byte("x"), 0644)
+	err = EnsureDir(file, 0750)
+	if err == nil {
+		t.Errorf("Expected error when path is a file\n")
+	}
+}
+
+func TestTouch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "a", "b", "file")
+	err = Touch(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	fInfo, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if fInfo.Size() != 0 {
+		t.Errorf("Expected empty file, got size: %d\n", fInfo.Size())
+	}
+
+	// Existing contents are kept and the mtime is updated.
+	err = ioutil.WriteFile(file, []byte("hello\n"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err = os.Chtimes(file, old, old)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = Touch(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	fInfo, err = os.Stat(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !fInfo.ModTime().After(old) {
+		t.Errorf("Expected mtime after %s, got: %s\n", old, fInfo.ModTime())
+	}
+	b, _ := ioutil.ReadFile(file)
+	if string(b) != "hello\n" {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", "hello\n", b)
+	}
+}
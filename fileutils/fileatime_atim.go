@@ -0,0 +1,26 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build aix || dragonfly || linux || openbsd || solaris
+// +build aix dragonfly linux openbsd solaris
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func fileAtime(fInfo os.FileInfo) (time.Time, bool) {
+	st, ok := fInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec)), true
+}
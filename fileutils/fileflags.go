@@ -0,0 +1,96 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrFlagsNotSupported - The platform or filesystem doesn't support the file flag.
+var ErrFlagsNotSupported = fmt.Errorf("file flags not supported")
+
+// FileFlags - File flags and attributes outside of the file mode.
+// Linux and the BSDs support FlagImmutable and FlagAppendOnly, Windows
+// supports FlagHidden and FlagReadOnly.
+type FileFlags uint32
+
+const (
+	// FlagImmutable - The file can't be modified, renamed or removed.
+	FlagImmutable FileFlags = 1 << iota
+
+	// FlagAppendOnly - The file can only be opened for appending.
+	FlagAppendOnly
+
+	// FlagHidden - The file is hidden from normal listings.
+	FlagHidden
+
+	// FlagReadOnly - The file can't be written to.
+	FlagReadOnly
+)
+
+var fileFlagNames = []string{"immutable", "append-only", "hidden", "readonly"}
+
+func (f FileFlags) String() string {
+	names := []string{}
+	for i, name := range fileFlagNames {
+		if f&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// GetFileFlags - Returns the flags of filename that have a FileFlags equivalent.
+func GetFileFlags(filename string) (FileFlags, error) {
+	return getFileFlags(filename)
+}
+
+// SetFileFlags - Sets the flags of filename to flags.
+// Only the flags with a FileFlags equivalent are changed, other platform
+// specific flags are kept.
+// Setting FlagImmutable and FlagAppendOnly usually requires elevated privileges.
+// Returns ErrFlagsNotSupported when flags has flags the platform doesn't support.
+func SetFileFlags(filename string, flags FileFlags) error {
+	if err := checkWritable("SetFileFlags", filename); err != nil {
+		return err
+	}
+	if unsupported := flags &^ supportedFileFlags; unsupported != 0 {
+		return fmt.Errorf("%w: '%s'", ErrFlagsNotSupported, unsupported)
+	}
+	return setFileFlags(filename, flags)
+}
+
+// CopyFlags - Preserves the file flags, see GetFileFlags, of the files and
+// dirs copied by CopyDir and CopyFile.
+// Files whose flags can't be read, because the platform or source
+// filesystem doesn't support them, are copied without flags.
+func CopyFlags() CopyOption {
+	return func(o *copyOptions) {
+		o.flags = true
+	}
+}
+
+// copyFlags - Sets the flags of src on dst. It must be called after any
+// other change to dst, flags like FlagImmutable prevent them.
+func copyFlags(src, dst string) error {
+	flags, err := GetFileFlags(src)
+	if err != nil {
+		if errors.Is(err, ErrFlagsNotSupported) {
+			Logger.Printf("copy flags: %s", err)
+			return nil
+		}
+		return err
+	}
+	if flags == 0 {
+		return nil
+	}
+	return SetFileFlags(dst, flags)
+}
@@ -0,0 +1,68 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+)
+
+const supportedFileFlags = FlagImmutable | FlagAppendOnly
+
+// chflags(2) user and system flags, the system ones can only be set by root.
+const (
+	ufImmutable = 0x00000002
+	ufAppend    = 0x00000004
+	sfImmutable = 0x00020000
+	sfAppend    = 0x00040000
+)
+
+func getFileFlags(filename string) (FileFlags, error) {
+	fInfo, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+	attrs := fInfo.Sys().(*syscall.Stat_t).Flags
+	var flags FileFlags
+	if attrs&(ufImmutable|sfImmutable) != 0 {
+		flags |= FlagImmutable
+	}
+	if attrs&(ufAppend|sfAppend) != 0 {
+		flags |= FlagAppendOnly
+	}
+	return flags, nil
+}
+
+// setFileFlags - Sets the user flags, system flags are only cleared.
+func setFileFlags(filename string, flags FileFlags) error {
+	fInfo, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	attrs := fInfo.Sys().(*syscall.Stat_t).Flags
+	attrs &^= ufImmutable | ufAppend
+	if flags&FlagImmutable != 0 {
+		attrs |= ufImmutable
+	} else {
+		attrs &^= sfImmutable
+	}
+	if flags&FlagAppendOnly != 0 {
+		attrs |= ufAppend
+	} else {
+		attrs &^= sfAppend
+	}
+	err = syscall.Chflags(filename, int(attrs))
+	if err != nil {
+		return &os.PathError{Op: "chflags", Path: filename, Err: err}
+	}
+	return nil
+}
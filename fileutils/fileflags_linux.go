@@ -0,0 +1,88 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const supportedFileFlags = FlagImmutable | FlagAppendOnly
+
+// Inode flags from linux/fs.h, the same on all architectures.
+const (
+	fsImmutableFl = 0x00000010
+	fsAppendFl    = 0x00000020
+)
+
+var linuxFileFlags = map[FileFlags]uint32{
+	FlagImmutable:  fsImmutableFl,
+	FlagAppendOnly: fsAppendFl,
+}
+
+// openFlags - Opens filename for the flags ioctls, which work on read only
+// descriptors of both files and dirs.
+func openFlags(filename string) (*os.File, error) {
+	return os.OpenFile(filename, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+}
+
+func getFileFlags(filename string) (FileFlags, error) {
+	fh, err := openFlags(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+	attrs, err := unix.IoctlGetUint32(int(fh.Fd()), unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		return 0, flagsError(filename, err)
+	}
+	var flags FileFlags
+	for flag, attr := range linuxFileFlags {
+		if attrs&attr != 0 {
+			flags |= flag
+		}
+	}
+	return flags, nil
+}
+
+func setFileFlags(filename string, flags FileFlags) error {
+	fh, err := openFlags(filename)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	attrs, err := unix.IoctlGetUint32(int(fh.Fd()), unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		return flagsError(filename, err)
+	}
+	for flag, attr := range linuxFileFlags {
+		attrs &^= attr
+		if flags&flag != 0 {
+			attrs |= attr
+		}
+	}
+	err = unix.IoctlSetPointerInt(int(fh.Fd()), unix.FS_IOC_SETFLAGS, int(attrs))
+	if err != nil {
+		return flagsError(filename, err)
+	}
+	return nil
+}
+
+// flagsError - Filesystems without flags support, like tmpfs on older
+// kernels, fail the ioctls with ENOTTY or EOPNOTSUPP.
+func flagsError(filename string, err error) error {
+	if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EOPNOTSUPP) {
+		return fmt.Errorf("%w: '%s'", ErrFlagsNotSupported, filename)
+	}
+	return &os.PathError{Op: "ioctl", Path: filename, Err: err}
+}
@@ -0,0 +1,33 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+)
+
+const supportedFileFlags FileFlags = 0
+
+func getFileFlags(filename string) (FileFlags, error) {
+	_, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%w: '%s'", ErrFlagsNotSupported, filename)
+}
+
+// setFileFlags - SetFileFlags only gets here when flags is 0, there is nothing to clear.
+func setFileFlags(filename string, flags FileFlags) error {
+	_, err := os.Stat(filename)
+	return err
+}
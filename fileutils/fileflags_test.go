@@ -0,0 +1,97 @@
+package fileutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileFlagsString(t *testing.T) {
+	tests := []struct {
+		flags    FileFlags
+		expected string
+	}{
+		{0, ""},
+		{FlagImmutable, "immutable"},
+		{FlagAppendOnly | FlagHidden, "append-only|hidden"},
+	}
+	for _, tt := range tests {
+		if got := tt.flags.String(); got != tt.expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s\n", tt.expected, got)
+		}
+	}
+}
+
+// flagsDir - Returns a dir with a src/f file that has flag set, it skips
+// the test when the flag can't be set.
+func flagsDir(t *testing.T, flag FileFlags) (string, string) {
+	t.Helper()
+	if supportedFileFlags&flag == 0 {
+		t.Skipf("%s not supported", flag)
+	}
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	file := filepath.Join(dir, "src", "f")
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	ioutil.WriteFile(file, []byte("f"), 0644)
+	err = SetFileFlags(file, flag)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Skipf("%s can't be set: %s", flag, err)
+	}
+	return dir, file
+}
+
+func TestFileFlags(t *testing.T) {
+	dir, file := flagsDir(t, FlagAppendOnly)
+	defer os.RemoveAll(dir)
+	defer SetFileFlags(file, 0)
+
+	flags, err := GetFileFlags(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if flags != FlagAppendOnly {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", FlagAppendOnly, flags)
+	}
+	err = ioutil.WriteFile(file, []byte("truncated"), 0644)
+	if err == nil {
+		t.Errorf("Expected append-only error\n")
+	}
+	err = SetFileFlags(file, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	flags, _ = GetFileFlags(file)
+	if flags != 0 {
+		t.Errorf("Expected no flags, got: %s\n", flags)
+	}
+	err = SetFileFlags(file, ^supportedFileFlags&(FlagImmutable|FlagAppendOnly|FlagHidden|FlagReadOnly))
+	if !errors.Is(err, ErrFlagsNotSupported) {
+		t.Errorf("Expected ErrFlagsNotSupported, got: %v\n", err)
+	}
+}
+
+func TestCopyFlags(t *testing.T) {
+	dir, file := flagsDir(t, FlagAppendOnly)
+	defer os.RemoveAll(dir)
+	defer SetFileFlags(file, 0)
+	dst := filepath.Join(dir, "dst")
+	defer SetFileFlags(filepath.Join(dst, "f"), 0)
+
+	err := CopyDir(filepath.Dir(file), dst, CopyFlags())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	flags, err := GetFileFlags(filepath.Join(dst, "f"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if flags != FlagAppendOnly {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", FlagAppendOnly, flags)
+	}
+}
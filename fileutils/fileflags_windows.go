@@ -0,0 +1,58 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+)
+
+const supportedFileFlags = FlagHidden | FlagReadOnly
+
+func getFileFlags(filename string) (FileFlags, error) {
+	p, err := syscall.UTF16PtrFromString(filename)
+	if err != nil {
+		return 0, err
+	}
+	attrs, err := syscall.GetFileAttributes(p)
+	if err != nil {
+		return 0, &os.PathError{Op: "GetFileAttributes", Path: filename, Err: err}
+	}
+	var flags FileFlags
+	if attrs&syscall.FILE_ATTRIBUTE_HIDDEN != 0 {
+		flags |= FlagHidden
+	}
+	if attrs&syscall.FILE_ATTRIBUTE_READONLY != 0 {
+		flags |= FlagReadOnly
+	}
+	return flags, nil
+}
+
+func setFileFlags(filename string, flags FileFlags) error {
+	p, err := syscall.UTF16PtrFromString(filename)
+	if err != nil {
+		return err
+	}
+	attrs, err := syscall.GetFileAttributes(p)
+	if err != nil {
+		return &os.PathError{Op: "GetFileAttributes", Path: filename, Err: err}
+	}
+	attrs &^= syscall.FILE_ATTRIBUTE_HIDDEN | syscall.FILE_ATTRIBUTE_READONLY
+	if flags&FlagHidden != 0 {
+		attrs |= syscall.FILE_ATTRIBUTE_HIDDEN
+	}
+	if flags&FlagReadOnly != 0 {
+		attrs |= syscall.FILE_ATTRIBUTE_READONLY
+	}
+	err = syscall.SetFileAttributes(p, attrs)
+	if err != nil {
+		return &os.PathError{Op: "SetFileAttributes", Path: filename, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package fileutils
+
+import (
+	"os"
+)
+
+// fileID - File identity is not available, callers fall back to size checks.
+func fileID(fInfo os.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
+
+func fileOwner(fInfo os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+// fileUsage - Allocation is not available, callers fall back to the apparent size.
+func fileUsage(fInfo os.FileInfo) (allocated int64, links uint64, ok bool) {
+	return 0, 0, false
+}
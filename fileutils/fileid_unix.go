@@ -0,0 +1,44 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID - Returns the device and inode numbers that identify the file.
+func fileID(fInfo os.FileInfo) (dev, ino uint64, ok bool) {
+	st, ok := fInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(st.Dev), uint64(st.Ino), true
+}
+
+func fileOwner(fInfo os.FileInfo) (uid, gid int, ok bool) {
+	st, ok := fInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
+
+// fileUsage - Returns the bytes allocated on disk for the file and its
+// number of hard links.
+func fileUsage(fInfo os.FileInfo) (allocated int64, links uint64, ok bool) {
+	st, ok := fInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int64(st.Blocks) * 512, uint64(st.Nlink), true
+}
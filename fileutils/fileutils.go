@@ -8,27 +8,76 @@
 
 /*
 Package fileutils - file related utilities
+
+# Listing order
+
+All the listing functions follow the same ordering contract, regardless of
+platform or locale:
+
+The entries of each dir are sorted by name comparing bytes, so the order is
+case sensitive with uppercase ASCII letters before lowercase ones and it
+doesn't depend on the locale or on the order the filesystem returns them.
+The NumSort variants compare names with stringutils.NaturalLess instead,
+integers first and numerically, the rest by bytes.
+The VersionSort variants compare names with stringutils.VersionLess, like
+'sort -V', so the numbers within names are compared numerically: "img2.png"
+before "img10.png" and "file-1.2.9.log" before "file-1.2.10.log".
+ListSortBy, ReadDirSorted and ListFilesSorted sort them by a SortKey
+instead: the name, numerically, by version, or by modification time, size or
+creation time, oldest or smallest first, with ties sorted by name.
+ListSortByBirthTime is the same as ListSortBy(SortByBirthTime).
+The reverse flags reverse the order of the entries within each dir.
+
+Recursive listings are depth first. By default each dir is listed before its
+contents (pre-order), ListOrder(PostOrder) lists each dir after its contents.
+
+# Errors
+
+The errors returned by the walkers, listings and copies wrap the underlying
+os and syscall errors, so errclass.Classify tells apart, for example, an
+entry removed while walking from a stale NFS handle or a read-only file
+system. StringError, StringsError and DUReportError expose it with
+ErrorClass. CopyRetry and SyncRetry retry the errclass Transient and
+StaleHandle errors unless the policy sets its own Retryable.
 */
 package fileutils
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
-	"strconv"
 	"strings"
+	"time"
+
+	"github.com/DavidGamba/go-utils/errclass"
+	"github.com/DavidGamba/go-utils/retryutils"
+	"github.com/DavidGamba/go-utils/stringutils"
 )
 
+// Logger - Custom lib logger
+var Logger = log.New(ioutil.Discard, "fileutils ", log.LstdFlags)
+
 // StringError is a struct containing the string `String` and error `Error`.
 type StringError struct {
 	String string
 	Error  error
 }
 
+// ErrorClass - Class of Error, see errclass.Classify.
+func (se StringError) ErrorClass() errclass.Class {
+	return errclass.Classify(se.Error)
+}
+
 // Internal struct used to hold the basedname of a file.
 // Used for sorting purposes.
 type fileParts struct {
@@ -42,15 +91,16 @@ type byName []os.FileInfo
 func (f byName) Len() int      { return len(f) }
 func (f byName) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
 func (f byName) Less(i, j int) bool {
-	nai, err := strconv.Atoi(f[i].Name())
-	if err != nil {
-		return f[i].Name() < f[j].Name()
-	}
-	naj, err := strconv.Atoi(f[j].Name())
-	if err != nil {
-		return f[i].Name() < f[j].Name()
-	}
-	return nai < naj
+	return stringutils.NaturalLess(f[i].Name(), f[j].Name())
+}
+
+// byVersion implements sort.Interface.
+type byVersion []os.FileInfo
+
+func (f byVersion) Len() int      { return len(f) }
+func (f byVersion) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f byVersion) Less(i, j int) bool {
+	return stringutils.VersionLess(f[i].Name(), f[j].Name())
 }
 
 type byBase []fileParts
@@ -58,19 +108,11 @@ type byBase []fileParts
 func (a byBase) Len() int      { return len(a) }
 func (a byBase) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a byBase) Less(i, j int) bool {
-	nai, err := strconv.Atoi(a[i].base)
-	if err != nil {
-		return a[i].base < a[j].base
-	}
-	naj, err := strconv.Atoi(a[j].base)
-	if err != nil {
-		return a[i].base < a[j].base
-	}
-	return nai < naj
+	return stringutils.NaturalLess(a[i].base, a[j].base)
 }
 
 // SortSameDirFilesNumerically - sorts a list of files in the same dir (they all have the same dirname) numerically.
-// The files are only sorted numerically when all element basenames are numbers.
+// Basenames that are integers sort first, numerically, the rest by bytes, see stringutils.NaturalLess.
 func SortSameDirFilesNumerically(fileList []string, reverse bool) []string {
 	var files []fileParts
 	for _, e := range fileList {
@@ -89,11 +131,112 @@ func SortSameDirFilesNumerically(fileList []string, reverse bool) []string {
 	return sortedFileList
 }
 
+// CopyOption - CopyFile option.
+type CopyOption func(*copyOptions)
+
+type copyOptions struct {
+	retry       *retryutils.Policy
+	overwrite   Overwrite
+	followLinks bool
+	preserve    bool
+	flags       bool
+	spaceCheck  bool
+	limiter     *IOLimiter
+	bandwidth   int64
+	throttle    *IOLimiter
+	onProgress  func(copied, total int64)
+	progress    *copyProgress
+}
+
+// newCopyOptions - Applies opts and sets up the state for a single call.
+func newCopyOptions(opts []CopyOption) *copyOptions {
+	o := &copyOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.bandwidth > 0 {
+		o.throttle = NewIOLimiter(o.bandwidth, 0)
+	}
+	return o
+}
+
+// CopyRetry - Retries the copy on transient errors following the given policy.
+func CopyRetry(policy retryutils.Policy) CopyOption {
+	return func(o *copyOptions) {
+		o.retry = &policy
+	}
+}
+
+// CopyPreserve - Replicates the mode, including the setuid, setgid and
+// sticky bits, the access and modification times and the extended
+// attributes of the source, see Metadata.
+// When running as root the owner and group are replicated as well.
+// The access time is only available on unix systems, elsewhere it is set to
+// the modification time.
+func CopyPreserve() CopyOption {
+	return func(o *copyOptions) {
+		o.preserve = true
+	}
+}
+
 // CopyFile copies the contents of the file named src to the file named
 // by dst. The file will be created if it does not already exist. If the
 // destination file exists, all it's contents will be replaced by the contents
 // of the source file.
-func CopyFile(src, dst string) error {
+func CopyFile(src, dst string, opts ...CopyOption) error {
+	if err := checkWritable("CopyFile", dst); err != nil {
+		return err
+	}
+	o := newCopyOptions(opts)
+	// Stat before reading, reading updates the access time.
+	fInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if o.spaceCheck {
+		err = CheckSpace(filepath.Dir(dst), fInfo.Size())
+		if err != nil {
+			return err
+		}
+	}
+	o.startProgress(fInfo.Size())
+	err = o.copyFile(src, dst)
+	if err != nil {
+		return err
+	}
+	if o.preserve {
+		err = preserveAttributes(src, dst, fInfo)
+		if err != nil {
+			return err
+		}
+	}
+	if o.flags {
+		return copyFlags(src, dst)
+	}
+	return nil
+}
+
+// copyFile - Copies src to dst with the retry policy.
+func (o *copyOptions) copyFile(src, dst string) error {
+	if o.retry != nil {
+		start := o.progress.current()
+		return retryutils.Retry(context.Background(), *o.retry, func() error {
+			o.progress.set(start)
+			return copyFile(src, dst, o)
+		})
+	}
+	return copyFile(src, dst, o)
+}
+
+// reader - Wraps r so its reads count against the CopyLimiter and
+// CopyBandwidth rates.
+func (o *copyOptions) reader(r io.Reader) io.Reader {
+	return o.throttle.Reader(o.limiter.Reader(r))
+}
+
+func copyFile(src, dst string, o *copyOptions) error {
+	o.limiter.Acquire()
+	defer o.limiter.Release()
 	in, err := os.Open(src)
 	if err != nil {
 		return err
@@ -109,117 +252,336 @@ func CopyFile(src, dst string) error {
 			err = cerr
 		}
 	}()
-	if _, err = io.Copy(out, in); err != nil {
+	if _, err = io.Copy(out, o.progress.reader(o.reader(in))); err != nil {
 		return err
 	}
 	err = out.Sync()
 	return err
 }
 
-// GetFileList returns a channel with each file (`channel.String`) or an error indicating failure (`channel.Error`).
-func GetFileList(dirname string, ignoreDirs, recursive bool) <-chan StringError {
-	c := make(chan StringError)
-	go func() {
-		fInfo, err := os.Stat(dirname)
-		if err != nil {
-			c <- StringError{"", err}
-			close(c)
-			return
-		}
-		if fInfo.IsDir() {
-			fileSearch := dirname + string(filepath.Separator) + "*"
-			fileMatches, err := filepath.Glob(fileSearch)
-			if err != nil {
-				c <- StringError{"", err}
-				close(c)
-				return
-			}
-			for _, file := range fileMatches {
-				fInfo, err := os.Stat(file)
-				if err != nil {
-					c <- StringError{"", err}
-					continue
-				}
-				if fInfo.IsDir() {
-					if ignoreDirs == false {
-						c <- StringError{file, nil}
-					}
-					if recursive {
-						d := GetFileList(file, ignoreDirs, recursive)
-						for dirFile := range d {
-							c <- dirFile
-						}
-					}
-				} else {
-					c <- StringError{file, nil}
-				}
-			}
-		} else {
-			c <- StringError{"", fmt.Errorf("Provided dir is not a dir: '%s'\n", dirname)}
-			close(c)
-			return
-		}
-		close(c)
-	}()
-	return c
+// StringsError is a struct containing a batch of strings `Strings` or an error `Error`.
+type StringsError struct {
+	Strings []string
+	Error   error
 }
 
-// ListFiles returns []string with a list of files.
-func ListFiles(dirname string, ignoreDirs, recursive bool) ([]string, error) {
-	files := []string{}
-	fInfo, err := os.Stat(dirname)
-	if err != nil {
-		return nil, err
+// ErrorClass - Class of Error, see errclass.Classify.
+func (se StringsError) ErrorClass() errclass.Class {
+	return errclass.Classify(se.Error)
+}
+
+// ListOption - Listing option.
+type ListOption func(*listOptions)
+
+type listOptions struct {
+	bufferSize int
+	statCache  *StatCache
+	order      Order
+	maxDepth   int
+	include    []string
+	exclude    []string
+	extensions []string
+	skipHidden bool
+	ignoreDirs bool
+	fsys       fs.FS
+
+	ignoreFiles    []string
+	ignorePatterns []string
+	sortKey        SortKey
+	reverse        bool
+	sameFS         bool
+	followSet      bool
+	followLinks    bool
+	createdFrom    time.Time
+	createdTo      time.Time
+}
+
+// Order - Traversal order of the recursive listings.
+type Order int
+
+const (
+	// PreOrder lists each dir before its contents, the default.
+	PreOrder Order = iota
+
+	// PostOrder lists each dir after its contents.
+	PostOrder
+)
+
+// ListOrder - Sets the traversal order of recursive listings.
+func ListOrder(order Order) ListOption {
+	return func(o *listOptions) {
+		o.order = order
 	}
-	if fInfo.Mode()&os.ModeSymlink != 0 {
-		rl, err := os.Readlink(dirname)
-		if err != nil {
-			return nil, err
-		}
-		fInfo, err = os.Stat(rl)
-		if err != nil {
-			return nil, err
-		}
+}
+
+// ListBuffer - Sets the buffer size of the returned channel.
+// With a buffer the walk can run ahead of a slow consumer.
+func ListBuffer(size int) ListOption {
+	return func(o *listOptions) {
+		o.bufferSize = size
 	}
-	if fInfo.IsDir() {
-		fileMatches, err := ioutil.ReadDir(dirname)
-		if err != nil {
-			return nil, err
-		}
-		for _, file := range fileMatches {
-			var rlFile os.FileInfo
-			if file.Mode()&os.ModeSymlink != 0 {
-				rl, err := os.Readlink(dirname + string(os.PathSeparator) + file.Name())
-				if err != nil {
-					return files, err
-				}
-				if !filepath.IsAbs(rl) {
-					rl = dirname + string(os.PathSeparator) + rl
-				}
-				rlFile, err = os.Stat(rl)
-				if err != nil {
-					return nil, err
-				}
-			}
-			if (rlFile != nil && rlFile.IsDir()) || file.IsDir() {
-				if !ignoreDirs {
-					files = append(files, dirname+string(os.PathSeparator)+file.Name())
-				}
-				if recursive {
-					fl, err := ListFiles(dirname+string(os.PathSeparator)+file.Name(), ignoreDirs, recursive)
-					if err != nil {
-						return files, err
-					}
-					files = append(files, fl...)
-				}
-			} else {
-				files = append(files, dirname+string(os.PathSeparator)+file.Name())
+}
+
+// ListMaxDepth - Limits how deep recursive listings go.
+// A depth of 1 only lists the entries of the given dir, 0 means no limit.
+func ListMaxDepth(depth int) ListOption {
+	return func(o *listOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// ListInclude - Only lists the entries whose name matches one of the filepath.Match patterns.
+// Patterns with a '/' are matched against the slash separated path relative to
+// dirname instead, where "**" matches any number of dirs, see MatchGlobstar.
+// Dirs that don't match are not listed but their contents still are.
+func ListInclude(patterns ...string) ListOption {
+	return func(o *listOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// ListExclude - Skips the entries whose name matches one of the filepath.Match patterns.
+// Patterns with a '/' are matched against the relative path, like in ListInclude.
+// The contents of excluded dirs are skipped as well.
+func ListExclude(patterns ...string) ListOption {
+	return func(o *listOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// ListExtensions - Only lists the files with one of the given extensions,
+// compared ignoring case, with or without the leading dot: "go" and ".go"
+// are the same.
+// Dirs are listed and walked as usual, use ListIgnoreDirs to only get files.
+func ListExtensions(extensions ...string) ListOption {
+	return func(o *listOptions) {
+		for _, ext := range extensions {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
 			}
+			o.extensions = append(o.extensions, ext)
 		}
-	} else {
-		return nil, fmt.Errorf("Provided dir is not a dir: '%s'\n", dirname)
 	}
-	return files, nil
+}
+
+// ListSkipHidden - Skips the entries whose name starts with a dot, and the contents of hidden dirs.
+func ListSkipHidden() ListOption {
+	return func(o *listOptions) {
+		o.skipHidden = true
+	}
+}
+
+// ListIgnoreDirs - Only lists files, dirs are still walked.
+func ListIgnoreDirs() ListOption {
+	return func(o *listOptions) {
+		o.ignoreDirs = true
+	}
+}
+
+// SortKey - What the entries of each dir are sorted by, see ListSortBy.
+type SortKey int
+
+const (
+	// SortByName compares the names by bytes, the default.
+	SortByName SortKey = iota
+
+	// SortByNumeric compares the names with stringutils.NaturalLess.
+	SortByNumeric
+
+	// SortByVersion compares the names with stringutils.VersionLess.
+	SortByVersion
+
+	// SortByModTime sorts by modification time, oldest first.
+	SortByModTime
+
+	// SortBySize sorts by size, smallest first.
+	SortBySize
+
+	// SortByBirthTime sorts by creation time, oldest first, see ListSortByBirthTime.
+	SortByBirthTime
+)
+
+// ListSortBy - Sorts the entries of each dir by key, entries with the same
+// key are sorted by name. Combine it with ListReverse for the newest or
+// largest entries first.
+func ListSortBy(key SortKey) ListOption {
+	return func(o *listOptions) {
+		o.sortKey = key
+	}
+}
+
+// ListNumSort - Sorts the entries of each dir numerically, see stringutils.NaturalLess.
+func ListNumSort() ListOption {
+	return func(o *listOptions) {
+		o.sortKey = SortByNumeric
+	}
+}
+
+// ListVersionSort - Sorts the entries of each dir in natural order, like
+// 'sort -V', see stringutils.VersionLess.
+func ListVersionSort() ListOption {
+	return func(o *listOptions) {
+		o.sortKey = SortByVersion
+	}
+}
+
+// ListReverse - Reverses the sort order of the entries of each dir.
+func ListReverse() ListOption {
+	return func(o *listOptions) {
+		o.reverse = true
+	}
+}
+
+// ListIgnoreFiles - Honours the gitignore style files with the given names,
+// for example ".gitignore" and ".ignore", found in the walked dirs.
+// Their patterns apply to the dir they are in and its subdirs, ignored dirs
+// are not walked.
+//
+// Only the pattern files are read, git itself isn't consulted: the ".git"
+// dir, global excludes and info/exclude are not ignored unless listed with
+// ListIgnorePatterns or ListExclude.
+func ListIgnoreFiles(names ...string) ListOption {
+	return func(o *listOptions) {
+		o.ignoreFiles = append(o.ignoreFiles, names...)
+	}
+}
+
+// ListIgnorePatterns - Ignores the entries matching the gitignore style
+// patterns, relative to the listed dir, as if they were in an ignore file
+// at its root.
+//
+//	fileutils.ListIgnorePatterns(".git/", "node_modules/", "/vendor/", "*.o", "!keep.o")
+func ListIgnorePatterns(patterns ...string) ListOption {
+	return func(o *listOptions) {
+		o.ignorePatterns = append(o.ignorePatterns, patterns...)
+	}
+}
+
+// ListFileSystem - Lists fsys, for example an embed.FS or fstest.MapFS, instead of the OS file system.
+// Paths follow the fs.FS conventions: slash separated, unrooted and without "." or ".." elements, use "." for the root.
+// ListStatCache is ignored.
+func ListFileSystem(fsys fs.FS) ListOption {
+	return func(o *listOptions) {
+		o.fsys = fsys
+	}
+}
+
+// ListSameFilesystem - Doesn't descend into dirs on a different filesystem
+// than the listed dir, like find -xdev, so listings of / don't walk network
+// or pseudo filesystems. The mount point dirs themselves are still listed.
+// Ignored with ListFileSystem and where device numbers are not available,
+// see IsMountPoint.
+func ListSameFilesystem() ListOption {
+	return func(o *listOptions) {
+		o.sameFS = true
+	}
+}
+
+// ListFollowLinks - Sets whether symlinks to dirs are listed and walked as
+// dirs, the default for the listing functions, or listed as files without
+// being walked, the default for Walk.
+// When following, links to a dir that contains them are listed but not
+// walked and the listing gets ErrSymlinkLoop for them, where device and
+// inode numbers are available, see IsMountPoint.
+// Ignored by ParallelWalk, which never follows symlinks.
+func ListFollowLinks(follow bool) ListOption {
+	return func(o *listOptions) {
+		o.followSet, o.followLinks = true, follow
+	}
+}
+
+// ListSortByBirthTime - Sorts the entries of each dir by creation time,
+// oldest first, see BirthTime. Entries with the same creation time are sorted
+// by name and those without one by modification time.
+func ListSortByBirthTime() ListOption {
+	return func(o *listOptions) {
+		o.sortKey = SortByBirthTime
+	}
+}
+
+// ListCreatedBetween - Only lists the entries created within from and to,
+// both inclusive, see BirthTime. A zero from or to leaves that end open.
+// Entries whose creation time is not available are not listed, dirs are
+// still walked.
+func ListCreatedBetween(from, to time.Time) ListOption {
+	return func(o *listOptions) {
+		o.createdFrom, o.createdTo = from, to
+	}
+}
+
+func newListOptions(opts []ListOption) listOptions {
+	o := listOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// walkOptions - Returns the walk options for the listing options.
+func (lo listOptions) walkOptions(o walkOptions) walkOptions {
+	o.statCache = lo.statCache
+	o.postOrder = lo.order == PostOrder
+	o.maxDepth = lo.maxDepth
+	o.include = lo.include
+	o.exclude = lo.exclude
+	o.extensions = lo.extensions
+	o.skipHidden = lo.skipHidden
+	if lo.sortKey != SortByName {
+		o.sortKey = lo.sortKey
+	}
+	o.reverse = o.reverse || lo.reverse
+	o.fsys = lo.fsys
+	o.ignoreFiles = lo.ignoreFiles
+	o.ignorePatterns = lo.ignorePatterns
+	o.sameFS = lo.sameFS
+	o.createdFrom, o.createdTo = lo.createdFrom, lo.createdTo
+	if lo.followSet {
+		o.followLinks = lo.followLinks
+	}
+	return o
+}
+
+// List - Returns the entries under dirname, recursively.
+// Unlike ListFiles, its behaviour is only controlled through options so new
+// capabilities can be added without breaking callers.
+//
+//	files, err := fileutils.List(dir, fileutils.ListIgnoreDirs(), fileutils.ListMaxDepth(2), fileutils.ListInclude("*.go"))
+func List(dirname string, opts ...ListOption) ([]string, error) {
+	return collect(dirname, walkOptions{recursive: true, followLinks: true}, false, newListOptions(opts))
+}
+
+// GetList - Same as List but returns a channel with each entry (`channel.String`) or an error indicating failure (`channel.Error`).
+// Errors are reported and the listing continues.
+func GetList(dirname string, opts ...ListOption) <-chan StringError {
+	return GetListContext(context.Background(), dirname, opts...)
+}
+
+// GetListContext - Same as GetList but stops walking and closes the channel when ctx is cancelled.
+func GetListContext(ctx context.Context, dirname string, opts ...ListOption) <-chan StringError {
+	return listChan(ctx, dirname, walkOptions{recursive: true, followLinks: true, join: cleanJoin}, true, true, newListOptions(opts))
+}
+
+// GetFileList returns a channel with each file (`channel.String`) or an error indicating failure (`channel.Error`).
+func GetFileList(dirname string, ignoreDirs, recursive bool, opts ...ListOption) <-chan StringError {
+	return GetFileListContext(context.Background(), dirname, ignoreDirs, recursive, opts...)
+}
+
+// GetFileListContext - Same as GetFileList but stops walking and closes the channel when ctx is cancelled.
+// Use it when the consumer might abandon the channel, otherwise the walking goroutine leaks.
+func GetFileListContext(ctx context.Context, dirname string, ignoreDirs, recursive bool, opts ...ListOption) <-chan StringError {
+	return listChan(ctx, dirname, walkOptions{recursive: recursive, followLinks: true, join: cleanJoin}, !ignoreDirs, true, newListOptions(opts))
+}
+
+// GetFileListBatch - Same as GetFileList but sends the files in batches of up to batchSize elements (`channel.Strings`).
+// Reduces the channel synchronization overhead for consumers that process files in bulk.
+// Pending files are sent before an error.
+func GetFileListBatch(dirname string, ignoreDirs, recursive bool, batchSize int, opts ...ListOption) <-chan StringsError {
+	return listBatchChan(dirname, walkOptions{recursive: recursive, followLinks: true, join: cleanJoin}, !ignoreDirs, true, batchSize, newListOptions(opts))
+}
+
+// ListFiles returns []string with a list of files.
+func ListFiles(dirname string, ignoreDirs, recursive bool, opts ...ListOption) ([]string, error) {
+	return collect(dirname, walkOptions{recursive: recursive, followLinks: true}, ignoreDirs, newListOptions(opts))
 }
 
 // ReadDirNumSort - Same as ioutil/ReadDir but uses returns a Numerically
@@ -250,172 +612,224 @@ func ReadDirNumSort(dirname string, reverse bool) ([]os.FileInfo, error) {
 	return list, nil
 }
 
-// ListFilesNumSort returns []string with a numerically sorted list of files.
-func ListFilesNumSort(dirname string, ignoreDirs, recursive, reverse bool) ([]string, error) {
-	files := []string{}
-	fInfo, err := os.Stat(dirname)
+// ReadDirSorted - Same as ReadDirNumSort but sorts the entries by key,
+// entries with the same key are sorted by name.
+//
+//	// Newest file in dir.
+//	fInfos, err := fileutils.ReadDirSorted(dir, fileutils.SortByModTime, true)
+func ReadDirSorted(dirname string, key SortKey, reverse bool) ([]os.FileInfo, error) {
+	entries, err := sortedDirEntries(dirname, walkOptions{sortKey: key, reverse: reverse})
 	if err != nil {
 		return nil, err
 	}
-	if fInfo.IsDir() {
-		fileSearch := dirname
-		fileMatches, err := ReadDirNumSort(fileSearch, reverse)
+	list := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fInfo, err := e.Info()
 		if err != nil {
-			return nil, err
-		}
-		for _, file := range fileMatches {
-			if file.IsDir() {
-				if ignoreDirs == false {
-					files = append(files, dirname+string(os.PathSeparator)+file.Name())
-				}
-				if recursive {
-					fl, err := ListFilesNumSort(dirname+string(os.PathSeparator)+file.Name(), ignoreDirs, recursive, reverse)
-					if err != nil {
-						return files, err
-					}
-					files = append(files, fl...)
-				}
-			} else {
-				files = append(files, dirname+string(os.PathSeparator)+file.Name())
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
 			}
+			return nil, err
 		}
-	} else {
-		return nil, fmt.Errorf("Provided dir is not a dir: '%s'\n", dirname)
+		list = append(list, fInfo)
 	}
-	return files, nil
+	return list, nil
+}
+
+// ListFilesSorted returns []string with a list of files, the entries of each
+// dir sorted by key, see ListSortBy.
+func ListFilesSorted(dirname string, ignoreDirs, recursive bool, key SortKey, reverse bool, opts ...ListOption) ([]string, error) {
+	return collect(dirname, walkOptions{recursive: recursive, sortKey: key, reverse: reverse, followLinks: true}, ignoreDirs, newListOptions(opts))
+}
+
+// ListFilesNumSort returns []string with a numerically sorted list of files.
+func ListFilesNumSort(dirname string, ignoreDirs, recursive, reverse bool, opts ...ListOption) ([]string, error) {
+	return collect(dirname, walkOptions{recursive: recursive, sortKey: SortByNumeric, reverse: reverse}, ignoreDirs, newListOptions(opts))
 }
 
 // GetNumSortFileList - Get Numerically Sorted File List.
 // Returns a channel with each file (`channel.String`) or an error indicating failure (`channel.Error`).
-func GetNumSortFileList(dirname string, ignoreDirs, recursive, reverse bool) <-chan StringError {
-	c := make(chan StringError)
-	go func() {
-		fInfo, err := os.Stat(dirname)
+func GetNumSortFileList(dirname string, ignoreDirs, recursive, reverse bool, opts ...ListOption) <-chan StringError {
+	return GetNumSortFileListContext(context.Background(), dirname, ignoreDirs, recursive, reverse, opts...)
+}
+
+// GetNumSortFileListContext - Same as GetNumSortFileList but stops walking and closes the channel when ctx is cancelled.
+func GetNumSortFileListContext(ctx context.Context, dirname string, ignoreDirs, recursive, reverse bool, opts ...ListOption) <-chan StringError {
+	return listChan(ctx, dirname, walkOptions{recursive: recursive, sortKey: SortByNumeric, reverse: reverse, followLinks: true, join: cleanJoin}, !ignoreDirs, true, newListOptions(opts))
+}
+
+// GetNumSortFileListBatch - Same as GetNumSortFileList but sends the files in batches of up to batchSize elements (`channel.Strings`).
+func GetNumSortFileListBatch(dirname string, ignoreDirs, recursive, reverse bool, batchSize int, opts ...ListOption) <-chan StringsError {
+	return listBatchChan(dirname, walkOptions{recursive: recursive, sortKey: SortByNumeric, reverse: reverse, followLinks: true, join: cleanJoin}, !ignoreDirs, true, batchSize, newListOptions(opts))
+}
+
+// ReadDirVersionSort - Same as ReadDirNumSort but sorts the names in natural
+// order, see stringutils.VersionLess.
+func ReadDirVersionSort(dirname string, reverse bool) ([]os.FileInfo, error) {
+	f, err := os.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	list, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	if reverse {
+		sort.Sort(sort.Reverse(byVersion(list)))
+	} else {
+		sort.Sort(byVersion(list))
+	}
+	return list, nil
+}
+
+// ListFilesVersionSort returns []string with a list of files sorted in natural order, see stringutils.VersionLess.
+func ListFilesVersionSort(dirname string, ignoreDirs, recursive, reverse bool, opts ...ListOption) ([]string, error) {
+	return collect(dirname, walkOptions{recursive: recursive, sortKey: SortByVersion, reverse: reverse}, ignoreDirs, newListOptions(opts))
+}
+
+// GetVersionSortFileList - Same as GetNumSortFileList but sorts in natural order, see stringutils.VersionLess.
+func GetVersionSortFileList(dirname string, ignoreDirs, recursive, reverse bool, opts ...ListOption) <-chan StringError {
+	return GetVersionSortFileListContext(context.Background(), dirname, ignoreDirs, recursive, reverse, opts...)
+}
+
+// GetVersionSortFileListContext - Same as GetVersionSortFileList but stops walking and closes the channel when ctx is cancelled.
+func GetVersionSortFileListContext(ctx context.Context, dirname string, ignoreDirs, recursive, reverse bool, opts ...ListOption) <-chan StringError {
+	return listChan(ctx, dirname, walkOptions{recursive: recursive, sortKey: SortByVersion, reverse: reverse, followLinks: true, join: cleanJoin}, !ignoreDirs, true, newListOptions(opts))
+}
+
+// GetVersionSortFileListBatch - Same as GetVersionSortFileList but sends the files in batches of up to batchSize elements (`channel.Strings`).
+func GetVersionSortFileListBatch(dirname string, ignoreDirs, recursive, reverse bool, batchSize int, opts ...ListOption) <-chan StringsError {
+	return listBatchChan(dirname, walkOptions{recursive: recursive, sortKey: SortByVersion, reverse: reverse, followLinks: true, join: cleanJoin}, !ignoreDirs, true, batchSize, newListOptions(opts))
+}
+
+// GetDirList returns a channel with each file (`channel.String`) or an error indicating failure (`channel.Error`).
+func GetDirList(dirname string, opts ...ListOption) <-chan StringError {
+	return GetDirListContext(context.Background(), dirname, opts...)
+}
+
+// GetDirListContext - Same as GetDirList but stops walking and closes the channel when ctx is cancelled.
+func GetDirListContext(ctx context.Context, dirname string, opts ...ListOption) <-chan StringError {
+	return listChan(ctx, dirname, walkOptions{recursive: true, followLinks: true, join: cleanJoin}, true, false, newListOptions(opts))
+}
+
+// GetNumSortDirList returns a channel with each file (`channel.String`) or an error indicating failure (`channel.Error`).
+func GetNumSortDirList(dirname string, reverse bool, opts ...ListOption) <-chan StringError {
+	return listChan(context.Background(), dirname, walkOptions{recursive: true, sortKey: SortByNumeric, reverse: reverse, followLinks: true, join: cleanJoin}, true, false, newListOptions(opts))
+}
+
+// GetVersionSortDirList - Same as GetNumSortDirList but sorts in natural order, see stringutils.VersionLess.
+func GetVersionSortDirList(dirname string, reverse bool, opts ...ListOption) <-chan StringError {
+	return listChan(context.Background(), dirname, walkOptions{recursive: true, sortKey: SortByVersion, reverse: reverse, followLinks: true, join: cleanJoin}, true, false, newListOptions(opts))
+}
+
+// collect - Returns the entries visited by walk.
+// The walk stops at the first error, returning the entries listed so far.
+func collect(dirname string, o walkOptions, ignoreDirs bool, lo listOptions) ([]string, error) {
+	o = lo.walkOptions(o)
+	fInfo, err := o.stat(dirname)
+	if err != nil {
+		return nil, err
+	}
+	if !fInfo.IsDir() {
+		return nil, fmt.Errorf("Provided dir is not a dir: '%s'\n", dirname)
+	}
+	files := []string{}
+	err = walk(dirname, o, func(path string, isDir bool, err error) error {
 		if err != nil {
-			c <- StringError{"", err}
-			close(c)
-			return
+			return err
 		}
-		if fInfo.IsDir() {
-			fileSearch := dirname + string(filepath.Separator) + "*"
-			fileMatches, err := filepath.Glob(fileSearch)
-			if err != nil {
-				c <- StringError{"", err}
-				close(c)
-				return
+		if !isDir || !ignoreDirs && !lo.ignoreDirs {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// listEach - Calls fn with the entries visited by walk, dirs and/or files.
+// Errors are passed to fn and the walk continues, the walk stops when fn returns false.
+func listEach(dirname string, o walkOptions, dirs, files bool, fn func(StringError) bool) {
+	fInfo, err := o.stat(dirname)
+	if err != nil {
+		fn(StringError{"", err})
+		return
+	}
+	if !fInfo.IsDir() {
+		fn(StringError{"", fmt.Errorf("Provided dir is not a dir: '%s'\n", dirname)})
+		return
+	}
+	err = walk(dirname, o, func(path string, isDir bool, err error) error {
+		if err != nil {
+			if !fn(StringError{"", err}) {
+				return errStopList
 			}
-			fileMatches = SortSameDirFilesNumerically(fileMatches, reverse)
-			for _, file := range fileMatches {
-				fInfo, err := os.Stat(file)
-				if err != nil {
-					c <- StringError{"", err}
-					continue
-				}
-				if fInfo.IsDir() {
-					if ignoreDirs == false {
-						c <- StringError{file, nil}
-					}
-					if recursive {
-						d := GetNumSortFileList(file, ignoreDirs, recursive, reverse)
-						for dirFile := range d {
-							c <- dirFile
-						}
-					}
-				} else {
-					c <- StringError{file, nil}
-				}
+			return nil
+		}
+		if isDir && dirs || !isDir && files {
+			if !fn(StringError{path, nil}) {
+				return errStopList
 			}
-		} else {
-			c <- StringError{"", fmt.Errorf("Provided dir is not a dir: '%s'\n", dirname)}
-			close(c)
-			return
 		}
-		close(c)
-	}()
-	return c
+		return nil
+	})
+	if err != nil && err != errStopList {
+		fn(StringError{"", err})
+	}
 }
 
-// GetDirList returns a channel with each file (`channel.String`) or an error indicating failure (`channel.Error`).
-func GetDirList(dirname string) <-chan StringError {
-	c := make(chan StringError)
+// errStopList - Returned from the walk function to stop listEach.
+var errStopList = fmt.Errorf("stop listing")
+
+// listChan - Sends the entries from listEach to the returned channel.
+// The channel is closed early when ctx is cancelled.
+func listChan(ctx context.Context, dirname string, o walkOptions, dirs, files bool, lo listOptions) <-chan StringError {
+	c := make(chan StringError, lo.bufferSize)
 	go func() {
-		fInfo, err := os.Stat(dirname)
-		if err != nil {
-			c <- StringError{"", err}
-			close(c)
-			return
-		}
-		if fInfo.IsDir() {
-			fileSearch := dirname + string(filepath.Separator) + "*"
-			fileMatches, err := filepath.Glob(fileSearch)
-			if err != nil {
-				c <- StringError{"", err}
-				close(c)
-				return
+		defer close(c)
+		listEach(dirname, lo.walkOptions(o), dirs && !lo.ignoreDirs, files, func(e StringError) bool {
+			if ctx.Err() != nil {
+				return false
 			}
-			for _, file := range fileMatches {
-				fInfo, err := os.Stat(file)
-				if err != nil {
-					c <- StringError{"", err}
-					continue
-				}
-				if fInfo.IsDir() {
-					c <- StringError{file, nil}
-					d := GetDirList(file)
-					for dirFile := range d {
-						c <- dirFile
-					}
-				}
+			select {
+			case c <- e:
+				return true
+			case <-ctx.Done():
+				return false
 			}
-		} else {
-			c <- StringError{"", fmt.Errorf("Provided dir is not a dir: '%s'\n", dirname)}
-			close(c)
-			return
-		}
-		close(c)
+		})
 	}()
 	return c
 }
 
-// GetNumSortDirList returns a channel with each file (`channel.String`) or an error indicating failure (`channel.Error`).
-func GetNumSortDirList(dirname string, reverse bool) <-chan StringError {
-	c := make(chan StringError)
+// listBatchChan - Sends the entries from listEach to the returned channel in batches.
+func listBatchChan(dirname string, o walkOptions, dirs, files bool, batchSize int, lo listOptions) <-chan StringsError {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	c := make(chan StringsError, lo.bufferSize)
 	go func() {
-		fInfo, err := os.Stat(dirname)
-		if err != nil {
-			c <- StringError{"", err}
-			close(c)
-			return
-		}
-		if fInfo.IsDir() {
-			fileSearch := dirname + string(filepath.Separator) + "*"
-			fileMatches, err := filepath.Glob(fileSearch)
-			if err != nil {
-				c <- StringError{"", err}
-				close(c)
-				return
-			}
-			fileMatches = SortSameDirFilesNumerically(fileMatches, reverse)
-			for _, file := range fileMatches {
-				fInfo, err := os.Stat(file)
-				if err != nil {
-					c <- StringError{"", err}
-					continue
-				}
-				if fInfo.IsDir() {
-					c <- StringError{file, nil}
-					d := GetNumSortDirList(file, reverse)
-					for dirFile := range d {
-						c <- dirFile
-					}
+		defer close(c)
+		batch := make([]string, 0, batchSize)
+		listEach(dirname, lo.walkOptions(o), dirs && !lo.ignoreDirs, files, func(e StringError) bool {
+			if e.Error != nil {
+				if len(batch) > 0 {
+					c <- StringsError{batch, nil}
+					batch = make([]string, 0, batchSize)
 				}
+				c <- StringsError{nil, e.Error}
+				return true
 			}
-		} else {
-			c <- StringError{"", fmt.Errorf("Provided dir is not a dir: '%s'\n", dirname)}
-			close(c)
-			return
+			batch = append(batch, e.String)
+			if len(batch) == batchSize {
+				c <- StringsError{batch, nil}
+				batch = make([]string, 0, batchSize)
+			}
+			return true
+		})
+		if len(batch) > 0 {
+			c <- StringsError{batch, nil}
 		}
-		close(c)
 	}()
 	return c
 }
@@ -424,23 +838,94 @@ func GetNumSortDirList(dirname string, reverse bool) <-chan StringError {
 // The file is read line by line to account for large files.
 // The changes are first written to a tmp copy is saved before overwriting the
 // original. The original is only changed if linesChanged > 0.
+// The line endings of each line, LF or CRLF, whether the file ends with a
+// newline and the file mode are preserved.
 func StringReplace(file, old, new string, n, bufferSize int) (int, error) {
-	var tmpFile *os.File
-	linesChanged := 0
+	if err := checkWritable("StringReplace", file); err != nil {
+		return 0, err
+	}
+	return replaceLines(file, bufferSize, func(line string) string {
+		return strings.Replace(line, old, new, n)
+	})
+}
+
+// StringReplaceDiff - Same as StringReplace but only previews the changes,
+// the file is not modified.
+// Returns the unified diff of the changes StringReplace would make, empty
+// when there are none, and the number of lines that would change.
+func StringReplaceDiff(file, old, new string, n, bufferSize int) (string, int, error) {
+	return diffLines(file, bufferSize, func(line string) string {
+		return strings.Replace(line, old, new, n)
+	})
+}
+
+// RegexpReplace - Runs re.ReplaceAllString on each line of the file, so
+// repl can reference capture groups like $1 or ${name}.
+// Like StringReplace, the file is read line by line and only changed if
+// linesChanged > 0.
+func RegexpReplace(file string, re *regexp.Regexp, repl string, bufferSize int) (int, error) {
+	if err := checkWritable("RegexpReplace", file); err != nil {
+		return 0, err
+	}
+	return replaceLines(file, bufferSize, func(line string) string {
+		return re.ReplaceAllString(line, repl)
+	})
+}
+
+// replaceLines - Replaces each line of file with the result of fn.
+// Returns the number of lines changed.
+func replaceLines(file string, bufferSize int, fn func(line string) string) (int, error) {
+	return rewriteLines(file, bufferSize, func(w *bufio.Writer, n int, line, eol string, eof bool) (int, error) {
+		if eof {
+			return 0, nil
+		}
+		newLine := fn(line)
+		_, err := w.WriteString(newLine + eol)
+		if err != nil || newLine == line {
+			return 0, err
+		}
+		return 1, nil
+	})
+}
+
+// rewriteLines - Writes file to a tmp copy through fn, which is called with
+// each line, numbered from 1, and its line ending, and one last time with
+// eof set and n past the last line.
+// fn returns the number of lines it changed, file is only replaced by the
+// copy when there are changes and fn never fails.
+// The line endings, including a missing one at the end of the file, and
+// the file mode are preserved unless fn changes them.
+func rewriteLines(file string, bufferSize int, fn func(w *bufio.Writer, n int, line, eol string, eof bool) (int, error)) (int, error) {
+	fInfo, err := os.Stat(file)
+	if err != nil {
+		return 0, err
+	}
 	tmpFile, err := ioutil.TempFile("", filepath.Base(file)+"-")
 	if err != nil {
-		return 0, fmt.Errorf("cannot open '%s': %s\n", tmpFile.Name(), err)
+		return 0, fmt.Errorf("cannot open tmp file for '%s': %w\n", file, err)
 	}
+	defer os.Remove(tmpFile.Name())
 	defer tmpFile.Close()
-	for d := range ReadLines(file, bufferSize) {
-		if d.Error != nil {
-			return 0, fmt.Errorf("Error reading file '%s': %s\n", file, d.Error)
-		}
-		line := strings.Replace(d.String, old, new, n)
-		if d.String != line {
-			linesChanged++
-		}
-		tmpFile.WriteString(line + "\n")
+	w := bufio.NewWriter(tmpFile)
+	linesChanged := 0
+	n := 0
+	err = eachLine(file, bufferSize, func(line, eol string) error {
+		n++
+		changed, err := fn(w, n, line, eol, false)
+		linesChanged += changed
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Error reading file '%s': %w\n", file, err)
+	}
+	changed, err := fn(w, n+1, "", "", true)
+	if err != nil {
+		return 0, err
+	}
+	linesChanged += changed
+	err = w.Flush()
+	if err != nil {
+		return 0, err
 	}
 	tmpFile.Close()
 	if linesChanged > 0 {
@@ -448,19 +933,170 @@ func StringReplace(file, old, new string, n, bufferSize int) (int, error) {
 		if err != nil {
 			return 0, fmt.Errorf("Couldn't update file: %s. '%s'\n", file, err)
 		}
+		// Restore the mode, umask and setuid clearing writes can change it.
+		err = os.Chmod(file, fInfo.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+		if err != nil {
+			return 0, err
+		}
 	}
-	os.Remove(tmpFile.Name())
 	return linesChanged, nil
 }
 
+// diffLines - Returns the unified diff of replacing each line of file with
+// the result of fn and the number of lines changed.
+func diffLines(file string, bufferSize int, fn func(line string) string) (string, int, error) {
+	d := newLineDiff(file)
+	err := eachLine(file, bufferSize, func(line, eol string) error {
+		d.add(line, fn(line))
+		return nil
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("Error reading file '%s': %w\n", file, err)
+	}
+	return d.String(), d.changed, nil
+}
+
+// eachLine - Calls fn with each line of file and its line ending, "\r\n",
+// "\n", or empty for a last line without one.
+// Lines longer than bufferSize are an error.
+func eachLine(file string, bufferSize int, fn func(line, eol string) error) error {
+	fh, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("Couldn't open file '%s': %w\n", file, err)
+	}
+	defer fh.Close()
+	reader := bufio.NewReaderSize(fh, bufferSize)
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			return fmt.Errorf("%s: buffer size too small\n", file)
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("Read error '%s': %w\n", file, err)
+		}
+		if len(chunk) > 0 {
+			line := string(chunk)
+			eol := ""
+			switch {
+			case strings.HasSuffix(line, "\r\n"):
+				eol = "\r\n"
+			case strings.HasSuffix(line, "\n"):
+				eol = "\n"
+			}
+			ferr := fn(strings.TrimSuffix(line, eol), eol)
+			if ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
 // ReadLines - returns a channel of type StringError with each line of a file.
 func ReadLines(filename string, bufferSize int) <-chan StringError {
+	return ReadLinesContext(context.Background(), filename, bufferSize)
+}
+
+// ReadLinesContext - Same as ReadLines but stops reading and closes the channel when ctx is cancelled.
+func ReadLinesContext(ctx context.Context, filename string, bufferSize int) <-chan StringError {
+	return readLines(ctx, func() (io.ReadCloser, error) { return os.Open(filename) }, filename, bufferSize)
+}
+
+// ReadLinesFS - Same as ReadLines but reads filename from fsys, for example an embed.FS or fstest.MapFS.
+func ReadLinesFS(fsys fs.FS, filename string, bufferSize int) <-chan StringError {
+	return readLines(context.Background(), func() (io.ReadCloser, error) { return fsys.Open(filename) }, filename, bufferSize)
+}
+
+// ReadLineRange - Returns a channel with the lines start to end, 1 based and
+// inclusive, of filename or an error indicating failure (`channel.Error`).
+// An end of 0 reads up to the end of the file.
+// The lines before start are skipped without being copied and reading stops
+// after end, so getting a few lines of a big file is cheap.
+func ReadLineRange(filename string, start, end int) <-chan StringError {
+	return ReadLineRangeContext(context.Background(), filename, start, end)
+}
+
+// ReadLineRangeContext - Same as ReadLineRange but stops reading and closes the channel when ctx is cancelled.
+func ReadLineRangeContext(ctx context.Context, filename string, start, end int) <-chan StringError {
 	c := make(chan StringError)
+	send := func(e StringError) bool {
+		return sendContext(ctx, c, e)
+	}
 	go func() {
+		defer close(c)
+		if start < 1 || end < 0 || (end > 0 && end < start) {
+			send(StringError{"", fmt.Errorf("invalid line range '%s': %d-%d", filename, start, end)})
+			return
+		}
 		file, err := os.Open(filename)
 		if err != nil {
-			c <- StringError{"", fmt.Errorf("Couldn't open file '%s': %s\n", filename, err)}
-			close(c)
+			send(StringError{"", fmt.Errorf("Couldn't open file '%s': %w\n", filename, err)})
+			return
+		}
+		defer file.Close()
+
+		reader := bufio.NewReader(file)
+		for n := 1; end == 0 || n <= end; n++ {
+			var line []byte
+			for {
+				chunk, err := reader.ReadSlice('\n')
+				if n >= start {
+					line = append(line, chunk...)
+				}
+				if err == bufio.ErrBufferFull {
+					continue
+				}
+				if err == io.EOF {
+					if len(line) > 0 {
+						send(StringError{string(bytes.TrimSuffix(line, []byte("\r"))), nil})
+					}
+					return
+				}
+				if err != nil {
+					send(StringError{"", fmt.Errorf("Read error '%s': %w\n", filename, err)})
+					return
+				}
+				break
+			}
+			if n >= start {
+				line = bytes.TrimSuffix(line[:len(line)-1], []byte("\r"))
+				if !send(StringError{string(line), nil}) {
+					return
+				}
+			} else if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// sendContext - Sends e on c unless ctx is cancelled first, returns whether it was sent.
+func sendContext(ctx context.Context, c chan<- StringError, e StringError) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case c <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// readLines - Sends each line of the file returned by open.
+func readLines(ctx context.Context, open func() (io.ReadCloser, error), filename string, bufferSize int) <-chan StringError {
+	c := make(chan StringError)
+	send := func(e StringError) bool {
+		return sendContext(ctx, c, e)
+	}
+	go func() {
+		defer close(c)
+		file, err := open()
+		if err != nil {
+			send(StringError{"", fmt.Errorf("Couldn't open file '%s': %w\n", filename, err)})
 			return
 		}
 		defer file.Close()
@@ -472,19 +1108,20 @@ func ReadLines(filename string, bufferSize int) <-chan StringError {
 			n++
 			line, isPrefix, err := reader.ReadLine()
 			if isPrefix {
-				c <- StringError{"", fmt.Errorf("%s: buffer size too small\n", filename)}
+				send(StringError{"", fmt.Errorf("%s: buffer size too small\n", filename)})
 				break
 			}
 			// stop reading file
 			if err != nil {
 				if err != io.EOF {
-					c <- StringError{"", fmt.Errorf("Read error '%s': %s\n", filename, err)}
+					send(StringError{"", fmt.Errorf("Read error '%s': %w\n", filename, err)})
 				}
 				break
 			}
-			c <- StringError{string(line), nil}
+			if !send(StringError{string(line), nil}) {
+				break
+			}
 		}
-		close(c)
 	}()
 	return c
 }
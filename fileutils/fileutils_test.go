@@ -1,10 +1,38 @@
 package fileutils
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DavidGamba/go-utils/errclass"
+	"github.com/DavidGamba/go-utils/retryutils"
 )
 
+// TestMain - test_tree2 is made of empty dirs that git doesn't track.
+func TestMain(m *testing.M) {
+	for _, d := range []string{"1", "2", "3", "10", "20", "30"} {
+		err := os.MkdirAll("test_tree2/"+d, 0755)
+		if err != nil {
+			panic(err)
+		}
+	}
+	code := m.Run()
+	if benchRoot != "" {
+		os.RemoveAll(benchRoot)
+	}
+	os.Exit(code)
+}
+
 func TestGetFileList(t *testing.T) {
 	cases := []struct {
 		file      string
@@ -79,6 +107,132 @@ func TestGetFileList(t *testing.T) {
 	}
 }
 
+func TestGetFileListPostOrder(t *testing.T) {
+	expected := []string{
+		"test_tree/.A/b/C/d/E",
+		"test_tree/.A/b/C/d",
+		"test_tree/.A/b/C",
+		"test_tree/.A/b",
+		"test_tree/.A",
+		"test_tree/.a/B/c/D/e",
+		"test_tree/.a/B/c/D",
+		"test_tree/.a/B/c",
+		"test_tree/.a/B",
+		"test_tree/.a",
+		"test_tree/.svn/E",
+		"test_tree/.svn/e",
+		"test_tree/.svn",
+		"test_tree/A/b/C/d/E",
+		"test_tree/A/b/C/d",
+		"test_tree/A/b/C",
+		"test_tree/A/b",
+		"test_tree/A",
+		"test_tree/a/B/c/D/e",
+		"test_tree/a/B/c/D",
+		"test_tree/a/B/c",
+		"test_tree/a/B",
+		"test_tree/a",
+		"test_tree/slnA/b/C/d/E",
+		"test_tree/slnA/b/C/d",
+		"test_tree/slnA/b/C",
+		"test_tree/slnA/b",
+		"test_tree/slnA",
+	}
+	tree := []string{}
+	for e := range GetFileList("./test_tree", false, true, ListOrder(PostOrder)) {
+		if e.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", e.Error)
+		}
+		tree = append(tree, e.String)
+	}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, tree)
+	}
+
+	// Without recursion the order doesn't change.
+	list, err := ListFilesNumSort("./test_tree2", false, false, false, ListOrder(PostOrder))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{"./test_tree2/1", "./test_tree2/2", "./test_tree2/3", "./test_tree2/10", "./test_tree2/20", "./test_tree2/30"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+}
+
+func TestGetFileListBatch(t *testing.T) {
+	expected := []string{}
+	for e := range GetFileList("./test_tree", false, true) {
+		expected = append(expected, e.String)
+	}
+	for _, size := range []int{1, 5, 100} {
+		tree := []string{}
+		for e := range GetFileListBatch("./test_tree", false, true, size, ListBuffer(2)) {
+			if e.Error != nil {
+				t.Fatalf("Unexpected error: %s\n", e.Error)
+			}
+			if len(e.Strings) == 0 || len(e.Strings) > size {
+				t.Errorf("Unexpected batch size %d for batches of %d\n", len(e.Strings), size)
+			}
+			tree = append(tree, e.Strings...)
+		}
+		if !reflect.DeepEqual(tree, expected) {
+			t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, tree)
+		}
+	}
+	tree := []string{}
+	for e := range GetNumSortFileListBatch("./test_tree2", false, true, true, 4) {
+		if e.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", e.Error)
+		}
+		tree = append(tree, e.Strings...)
+	}
+	expected = []string{"test_tree2/30", "test_tree2/20", "test_tree2/10", "test_tree2/3", "test_tree2/2", "test_tree2/1"}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, tree)
+	}
+	for e := range GetFileListBatch("./fileutils.go", false, true, 4) {
+		if e.Error == nil {
+			t.Errorf("Expected error, got: %q\n", e.Strings)
+		}
+	}
+}
+
+func TestGetFileListErrorClass(t *testing.T) {
+	for e := range GetFileList("./does-not-exist", false, true) {
+		if e.ErrorClass() != errclass.NotFound {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", errclass.NotFound, e.ErrorClass())
+		}
+	}
+	for e := range GetFileListBatch("./does-not-exist", false, true, 4) {
+		if e.ErrorClass() != errclass.NotFound {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", errclass.NotFound, e.ErrorClass())
+		}
+	}
+}
+
+func TestReadLinesErrorClass(t *testing.T) {
+	for e := range ReadLines("./does-not-exist", 1024) {
+		if e.ErrorClass() != errclass.NotFound {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", errclass.NotFound, e.ErrorClass())
+		}
+	}
+	for e := range ReadLineRange("./does-not-exist", 1, 2) {
+		if e.ErrorClass() != errclass.NotFound {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", errclass.NotFound, e.ErrorClass())
+		}
+	}
+	for e := range ReadLinesReverse("./does-not-exist") {
+		if e.ErrorClass() != errclass.NotFound {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", errclass.NotFound, e.ErrorClass())
+		}
+	}
+	_, err := LineInFile("./does-not-exist", "line")
+	if errclass.Classify(err) != errclass.NotFound {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", errclass.NotFound, errclass.Classify(err))
+	}
+}
+
 func BenchmarkGetFileList(b *testing.B) {
 	cases := []struct {
 		file      string
@@ -273,6 +427,69 @@ func BenchmarkListFiles(b *testing.B) {
 	}
 }
 
+func TestList(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   []ListOption
+		result []string
+	}{
+		{"depth", []ListOption{ListMaxDepth(2), ListSkipHidden()}, []string{
+			"./test_tree/A",
+			"./test_tree/A/b",
+			"./test_tree/a",
+			"./test_tree/a/B",
+			"./test_tree/slnA",
+			"./test_tree/slnA/b",
+		}},
+		{"files", []ListOption{ListIgnoreDirs(), ListExclude(".svn", "slnA")}, []string{
+			"./test_tree/.A/b/C/d/E",
+			"./test_tree/.a/B/c/D/e",
+			"./test_tree/A/b/C/d/E",
+			"./test_tree/a/B/c/D/e",
+		}},
+		{"include", []ListOption{ListInclude("[Ee]", "b"), ListSkipHidden(), ListReverse()}, []string{
+			"./test_tree/slnA/b",
+			"./test_tree/slnA/b/C/d/E",
+			"./test_tree/a/B/c/D/e",
+			"./test_tree/A/b",
+			"./test_tree/A/b/C/d/E",
+		}},
+		{"globstar", []ListOption{ListInclude("**/[Dd]/*"), ListExclude("slnA/**", ".*/**")}, []string{
+			"./test_tree/A/b/C/d/E",
+			"./test_tree/a/B/c/D/e",
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := List("./test_tree", c.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if !reflect.DeepEqual(got, c.result) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", c.result, got)
+			}
+			chGot := []string{}
+			for e := range GetList("./test_tree", c.opts...) {
+				if e.Error != nil {
+					t.Fatalf("Unexpected error: %s\n", e.Error)
+				}
+				chGot = append(chGot, "./"+e.String)
+			}
+			if !reflect.DeepEqual(chGot, c.result) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", c.result, chGot)
+			}
+		})
+	}
+	_, err := List("./test_tree", ListInclude("["))
+	if err == nil {
+		t.Errorf("Expected invalid pattern error\n")
+	}
+	_, err = List("./test_tree", ListExclude("a/["))
+	if err == nil {
+		t.Errorf("Expected invalid pattern error\n")
+	}
+}
+
 func TestGetNumSortFileList(t *testing.T) {
 	cases := []struct {
 		dir       string
@@ -412,6 +629,200 @@ func TestListFilesNumSort(t *testing.T) {
 	}
 }
 
+func TestListFilesVersionSort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	names := []string{"file-1.2.10.log", "img10.png", "file-1.2.9.log", "img2.png", "file-1.10.0.log"}
+	for _, name := range names {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644)
+	}
+	expected := []string{"file-1.2.9.log", "file-1.2.10.log", "file-1.10.0.log", "img2.png", "img10.png"}
+	reversed := []string{"img10.png", "img2.png", "file-1.10.0.log", "file-1.2.10.log", "file-1.2.9.log"}
+	join := func(names []string) []string {
+		var list []string
+		for _, name := range names {
+			list = append(list, filepath.Join(dir, name))
+		}
+		return list
+	}
+
+	list, err := ListFilesVersionSort(dir, true, false, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(list, join(expected)) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", join(expected), list)
+	}
+	list = nil
+	for e := range GetVersionSortFileList(dir, true, false, true) {
+		if e.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", e.Error)
+		}
+		list = append(list, e.String)
+	}
+	if !reflect.DeepEqual(list, join(reversed)) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", join(reversed), list)
+	}
+	fInfos, err := ReadDirVersionSort(dir, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	list = nil
+	for _, fInfo := range fInfos {
+		list = append(list, fInfo.Name())
+	}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+
+	fsys := fstest.MapFS{}
+	for _, name := range names {
+		fsys[name] = &fstest.MapFile{}
+	}
+	list, err = List(".", ListFileSystem(fsys), ListIgnoreDirs(), ListVersionSort())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+}
+
+func TestListExtensions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.go":          &fstest.MapFile{},
+		"b.GO":          &fstest.MapFile{},
+		"c.txt":         &fstest.MapFile{},
+		"go":            &fstest.MapFile{},
+		"sub/d.go":      &fstest.MapFile{},
+		"sub/f.md":      &fstest.MapFile{},
+		"sub/x.go.orig": &fstest.MapFile{},
+		"vendor/e.go":   &fstest.MapFile{},
+	}
+	cases := []struct {
+		name   string
+		opts   []ListOption
+		result []string
+	}{
+		{"extensions", []ListOption{ListExtensions("go", ".md"), ListExclude("vendor")}, []string{
+			"a.go",
+			"b.GO",
+			"sub",
+			"sub/d.go",
+			"sub/f.md",
+		}},
+		{"files", []ListOption{ListExtensions(".go"), ListIgnoreDirs()}, []string{
+			"a.go",
+			"b.GO",
+			"sub/d.go",
+			"vendor/e.go",
+		}},
+		{"globs", []ListOption{ListExtensions("go"), ListInclude("sub/*", "vendor/*"), ListExclude("sub/d.*")}, []string{
+			"vendor/e.go",
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := append([]ListOption{ListFileSystem(fsys)}, c.opts...)
+			got, err := List(".", opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if !reflect.DeepEqual(got, c.result) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", c.result, got)
+			}
+			chGot := []string{}
+			for e := range GetList(".", opts...) {
+				if e.Error != nil {
+					t.Fatalf("Unexpected error: %s\n", e.Error)
+				}
+				chGot = append(chGot, e.String)
+			}
+			if !reflect.DeepEqual(chGot, c.result) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", c.result, chGot)
+			}
+		})
+	}
+}
+
+func TestReadDirSorted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	files := []struct {
+		name  string
+		size  int
+		mtime time.Time
+	}{
+		{"b10", 30, now.Add(-1 * time.Hour)},
+		{"a", 10, now.Add(-3 * time.Hour)},
+		{"b2", 20, now},
+		{"c", 20, now.Add(-2 * time.Hour)},
+	}
+	for _, f := range files {
+		file := filepath.Join(dir, f.name)
+		ioutil.WriteFile(file, make([]byte, f.size), 0644)
+		os.Chtimes(file, f.mtime, f.mtime)
+	}
+	tests := []struct {
+		key      SortKey
+		reverse  bool
+		expected []string
+	}{
+		{SortByName, false, []string{"a", "b10", "b2", "c"}},
+		{SortByName, true, []string{"c", "b2", "b10", "a"}},
+		{SortByNumeric, false, []string{"a", "b10", "b2", "c"}},
+		{SortByVersion, false, []string{"a", "b2", "b10", "c"}},
+		{SortByModTime, false, []string{"a", "c", "b10", "b2"}},
+		{SortByModTime, true, []string{"b2", "b10", "c", "a"}},
+		{SortBySize, false, []string{"a", "b2", "c", "b10"}},
+		{SortBySize, true, []string{"b10", "c", "b2", "a"}},
+	}
+	for _, test := range tests {
+		fInfos, err := ReadDirSorted(dir, test.key, test.reverse)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		var names []string
+		for _, fInfo := range fInfos {
+			names = append(names, fInfo.Name())
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("key %d reverse %v, Expected:\n%q\nGot:\n%q\n", test.key, test.reverse, test.expected, names)
+		}
+		list, err := ListFilesSorted(dir, true, false, test.key, test.reverse)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		for i := range names {
+			names[i] = filepath.Join(dir, names[i])
+		}
+		if !reflect.DeepEqual(list, names) {
+			t.Errorf("key %d reverse %v, Expected:\n%q\nGot:\n%q\n", test.key, test.reverse, names, list)
+		}
+	}
+
+	fsys := fstest.MapFS{
+		"old":   &fstest.MapFile{ModTime: now.Add(-time.Hour)},
+		"new":   &fstest.MapFile{ModTime: now},
+		"older": &fstest.MapFile{ModTime: now.Add(-2 * time.Hour)},
+	}
+	list, err := List(".", ListFileSystem(fsys), ListIgnoreDirs(), ListSortBy(SortByModTime), ListReverse())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []string{"new", "old", "older"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+}
+
 func BenchmarkListFilesNumSort(b *testing.B) {
 	cases := []struct {
 		file      string
@@ -553,6 +964,208 @@ func TestGetNumSortDirList(t *testing.T) {
 	}
 }
 
+func TestGetFileListContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := GetFileListContext(ctx, "./test_tree", false, true)
+	e := <-c
+	if e.Error != nil {
+		t.Fatalf("Unexpected error: %s\n", e.Error)
+	}
+	cancel()
+	// The walk stops and the channel is closed, at most one pending entry can still be received.
+	n := 0
+	for range c {
+		n++
+	}
+	if n > 1 {
+		t.Errorf("Expected the listing to stop, got %d more entries\n", n)
+	}
+
+	cancel()
+	n = 0
+	for range GetDirListContext(ctx, "./test_tree") {
+		n++
+	}
+	if n > 1 {
+		t.Errorf("Expected the listing to stop, got %d entries\n", n)
+	}
+}
+
+func TestReadLinesContext(t *testing.T) {
+	file := filepath.Join(os.TempDir(), "fileutils-readlines")
+	ioutil.WriteFile(file, []byte(strings.Repeat("line\n", 100)), 0644)
+	defer os.Remove(file)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := ReadLinesContext(ctx, file, 64)
+	e := <-c
+	if e.Error != nil || e.String != "line" {
+		t.Fatalf("Unexpected result: %v\n", e)
+	}
+	cancel()
+	n := 0
+	for range c {
+		n++
+	}
+	if n > 1 {
+		t.Errorf("Expected reading to stop, got %d more lines\n", n)
+	}
+
+	n = 0
+	for e := range ReadLines(file, 64) {
+		if e.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", e.Error)
+		}
+		n++
+	}
+	if n != 100 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 100, n)
+	}
+}
+
+func TestReadLineRangeContext(t *testing.T) {
+	file := filepath.Join(os.TempDir(), "fileutils-readlinerangecontext")
+	ioutil.WriteFile(file, []byte(strings.Repeat("line\n", 100)), 0644)
+	defer os.Remove(file)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := ReadLineRangeContext(ctx, file, 10, 0)
+	e := <-c
+	if e.Error != nil || e.String != "line" {
+		t.Fatalf("Unexpected result: %v\n", e)
+	}
+	cancel()
+	n := 0
+	for range c {
+		n++
+	}
+	if n > 1 {
+		t.Errorf("Expected reading to stop, got %d more lines\n", n)
+	}
+
+	// A cancelled context sends nothing, not even the range error.
+	n = 0
+	for range ReadLineRangeContext(ctx, file, 0, 0) {
+		n++
+	}
+	if n != 0 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 0, n)
+	}
+}
+
+func TestReadLineRange(t *testing.T) {
+	file := filepath.Join(os.TempDir(), "fileutils-readlinerange")
+	lines := []string{}
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+	ioutil.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"+strings.Repeat("x", 5000)), 0644)
+	defer os.Remove(file)
+
+	tests := []struct {
+		start, end int
+		expected   []string
+	}{
+		{1, 3, []string{"1", "2", "3"}},
+		{5, 5, []string{"5"}},
+		{9, 12, []string{"9", "10", strings.Repeat("x", 5000)}},
+		{10, 0, []string{"10", strings.Repeat("x", 5000)}},
+		{20, 30, []string{}},
+	}
+	for _, test := range tests {
+		got := []string{}
+		for e := range ReadLineRange(file, test.start, test.end) {
+			if e.Error != nil {
+				t.Fatalf("Unexpected error: %s\n", e.Error)
+			}
+			got = append(got, e.String)
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%d-%d Expected:\n%q\nGot:\n%q\n", test.start, test.end, test.expected, got)
+		}
+	}
+	for _, r := range [][2]int{{0, 3}, {5, 4}, {1, -1}} {
+		e := <-ReadLineRange(file, r[0], r[1])
+		if e.Error == nil {
+			t.Errorf("%v Expected invalid range error\n", r)
+		}
+	}
+}
+
+func TestListFileSystem(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/10":      {Data: []byte("ten")},
+		"a/9":       {Data: []byte("nine")},
+		"a/b/c":     {Data: []byte("c")},
+		".hidden/x": {Data: []byte("x")},
+		"lines":     {Data: []byte("1\n2\n3\n")},
+	}
+	list, err := ListFiles(".", false, true, ListFileSystem(fsys))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []string{".hidden", ".hidden/x", "a", "a/10", "a/9", "a/b", "a/b/c", "lines"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+
+	list, err = ListFilesNumSort("a", true, true, false, ListFileSystem(fsys))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{"a/9", "a/10", "a/b/c"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+
+	list = []string{}
+	for e := range GetFileList(".", true, true, ListFileSystem(fsys), ListSkipHidden()) {
+		if e.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", e.Error)
+		}
+		list = append(list, e.String)
+	}
+	expected = []string{"a/10", "a/9", "a/b/c", "lines"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+
+	list = []string{}
+	err = Walk("a", func(path string, isDir bool, err error) error {
+		list = append(list, path)
+		return err
+	}, ListFileSystem(fsys), ListOrder(PostOrder))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{"a/10", "a/9", "a/b/c", "a/b"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+
+	_, err = ListFiles("lines", false, true, ListFileSystem(fsys))
+	if err == nil {
+		t.Errorf("Expected not a dir error\n")
+	}
+
+	lines := []string{}
+	for e := range ReadLinesFS(fsys, "lines", 64) {
+		if e.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", e.Error)
+		}
+		lines = append(lines, e.String)
+	}
+	expected = []string{"1", "2", "3"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, lines)
+	}
+	for e := range ReadLinesFS(fsys, "missing", 64) {
+		if e.Error == nil {
+			t.Errorf("Expected open error\n")
+		}
+	}
+}
+
 func TestStringReplace(t *testing.T) {
 	n, err := StringReplace("test_tree/A/b/C/d/E", "lorem", "hello", -1, 1024)
 	if err != nil {
@@ -569,3 +1182,154 @@ func TestStringReplace(t *testing.T) {
 		t.Fatalf("Unexpected amount of lines changed: %d\n", n)
 	}
 }
+
+func TestStringReplaceLineEndings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"lf", "hello\nworld\n", "bye\nworld\n"},
+		{"crlf", "hello\r\nworld\r\n", "bye\r\nworld\r\n"},
+		{"no final newline", "world\nhello", "world\nbye"},
+		{"crlf no final newline", "world\r\nhello", "world\r\nbye"},
+		{"mixed", "hello\r\nhello\nhello", "bye\r\nbye\nbye"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(dir, "a")
+			ioutil.WriteFile(file, []byte(test.content), 0644)
+			os.Chmod(file, 0751)
+			_, err := StringReplace(file, "hello", "bye", -1, 1024)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			b, _ := ioutil.ReadFile(file)
+			if string(b) != test.expected {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, b)
+			}
+			fInfo, _ := os.Stat(file)
+			if runtime.GOOS != "windows" && fInfo.Mode().Perm() != 0751 {
+				t.Errorf("Expected mode 0751, got: %o\n", fInfo.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestStringReplaceDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a")
+	content := "hello world\nbye\nhello\n"
+	ioutil.WriteFile(file, []byte(content), 0644)
+	diff, n, err := StringReplaceDiff(file, "hello", "hola", -1, 1024)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if n != 2 {
+		t.Errorf("Unexpected amount of lines changed: %d\n", n)
+	}
+	expected := "--- " + file + "\n+++ " + file + "\n@@ -1,3 +1,3 @@\n-hello world\n+hola world\n bye\n-hello\n+hola\n"
+	if diff != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, diff)
+	}
+	b, _ := ioutil.ReadFile(file)
+	if string(b) != content {
+		t.Errorf("File modified:\n%s\n", b)
+	}
+}
+
+func TestRegexpReplace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a")
+	ioutil.WriteFile(file, []byte("key=value\n# comment\nname = go\n"), 0644)
+	n, err := RegexpReplace(file, regexp.MustCompile(`^(\w+)\s*=\s*(\w+)$`), "${2}: $1", 1024)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if n != 2 {
+		t.Fatalf("Unexpected amount of lines changed: %d\n", n)
+	}
+	b, _ := ioutil.ReadFile(file)
+	expected := "value: key\n# comment\ngo: name\n"
+	if string(b) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, b)
+	}
+	n, err = RegexpReplace(file, regexp.MustCompile(`missing`), "x", 1024)
+	if err != nil || n != 0 {
+		t.Errorf("Unexpected result: %d, %v\n", n, err)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "E")
+	err = CopyFile("test_tree/A/b/C/d/E", dst, CopyRetry(retryutils.DefaultPolicy()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected, _ := ioutil.ReadFile("test_tree/A/b/C/d/E")
+	got, _ := ioutil.ReadFile(dst)
+	if string(got) != string(expected) {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, got)
+	}
+	err = CopyFile("test_tree/missing", dst, CopyRetry(retryutils.DefaultPolicy()))
+	if !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
+
+func TestCopyFilePreserve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	ioutil.WriteFile(src, []byte("src"), 0644)
+	os.Chmod(src, 0750)
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := time.Date(2020, 6, 7, 8, 9, 10, 0, time.UTC)
+	os.Chtimes(src, atime, mtime)
+	err = CopyFile(src, dst, CopyPreserve())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	fInfo, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if fInfo.Mode().Perm() != 0750 {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", os.FileMode(0750), fInfo.Mode().Perm())
+	}
+	if !fInfo.ModTime().Equal(mtime) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", mtime, fInfo.ModTime())
+	}
+	if got, ok := fileAtime(fInfo); ok && !got.Equal(atime) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", atime, got)
+	}
+	srcInfo, _ := os.Stat(src)
+	if uid, gid, ok := fileOwner(srcInfo); ok {
+		dstUID, dstGID, _ := fileOwner(fInfo)
+		if dstUID != uid || dstGID != gid {
+			t.Errorf("Expected:\n%d:%d\nGot:\n%d:%d\n", uid, gid, dstUID, dstGID)
+		}
+	}
+}
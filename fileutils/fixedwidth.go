@@ -0,0 +1,248 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldType - Type a fixed width field is converted to.
+type FieldType int
+
+const (
+	// FieldString - string.
+	FieldString FieldType = iota
+
+	// FieldInt - int64.
+	FieldInt
+
+	// FieldFloat - float64, see FieldSpec.Scale for implied decimals.
+	FieldFloat
+
+	// FieldTime - time.Time, parsed with FieldSpec.Layout.
+	FieldTime
+)
+
+// FieldSpec - Position and type of a fixed width field.
+type FieldSpec struct {
+	Name string
+
+	// Offset - 0 based byte offset of the field within the record.
+	Offset int
+
+	// Width - Width of the field in bytes.
+	Width int
+
+	Type FieldType
+
+	// Layout - time.Parse layout for FieldTime fields.
+	Layout string
+
+	// Scale - Number of implied decimals for FieldFloat fields, as in COBOL
+	// PIC 9(5)V99 where 0012345 is 123.45.
+	Scale int
+}
+
+// FixedWidthRecord - A record read by ReadFixedWidth or an error indicating failure (`Error`).
+//
+// Field values are trimmed of surrounding spaces and converted to the field
+// type. Blank numeric and time fields are nil.
+// Fields past the end of a short record are blank.
+type FixedWidthRecord struct {
+	// Line - 1 based record number.
+	Line int
+
+	Fields map[string]interface{}
+
+	Error error
+}
+
+// FieldError - Position of a field that couldn't be decoded or converted.
+type FieldError struct {
+	Line int
+
+	// Column - 1 based byte column where the field starts.
+	Column int
+
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("line %d, column %d: field '%s': %s", e.Line, e.Column, e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Decoder - Converts the raw bytes of a field to a UTF-8 string.
+// Decoders from golang.org/x/text can be adapted, for example for EBCDIC:
+//
+//	func(b []byte) (string, error) { return charmap.CodePage037.NewDecoder().String(string(b)) }
+type Decoder func([]byte) (string, error)
+
+// DecodeUTF8 - Default decoder, fails on invalid UTF-8.
+func DecodeUTF8(b []byte) (string, error) {
+	if !utf8.Valid(b) {
+		return "", fmt.Errorf("invalid UTF-8")
+	}
+	return string(b), nil
+}
+
+// DecodeLatin1 - ISO-8859-1 decoder.
+func DecodeLatin1(b []byte) (string, error) {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r), nil
+}
+
+// FixedWidthOption - ReadFixedWidth option.
+type FixedWidthOption func(*fixedWidthOptions)
+
+type fixedWidthOptions struct {
+	decoder      Decoder
+	recordLength int
+}
+
+// FixedWidthDecoder - Sets the decoder for the file encoding, defaults to DecodeUTF8.
+func FixedWidthDecoder(d Decoder) FixedWidthOption {
+	return func(o *fixedWidthOptions) {
+		o.decoder = d
+	}
+}
+
+// FixedWidthRecordLength - Reads records of length bytes instead of lines,
+// for exports without line terminators.
+func FixedWidthRecordLength(length int) FixedWidthOption {
+	return func(o *fixedWidthOptions) {
+		o.recordLength = length
+	}
+}
+
+// ReadFixedWidth - Returns a channel with each record of the fixed width
+// file or an error indicating failure (`channel.Error`).
+// Records with field errors are sent with a *FieldError for the first
+// failing field and reading continues.
+func ReadFixedWidth(path string, fields []FieldSpec, opts ...FixedWidthOption) <-chan FixedWidthRecord {
+	o := fixedWidthOptions{decoder: DecodeUTF8}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := make(chan FixedWidthRecord)
+	go func() {
+		defer close(c)
+		for _, f := range fields {
+			if f.Offset < 0 || f.Width < 1 {
+				c <- FixedWidthRecord{Error: fmt.Errorf("field '%s': invalid offset %d or width %d", f.Name, f.Offset, f.Width)}
+				return
+			}
+		}
+		fh, err := os.Open(path)
+		if err != nil {
+			c <- FixedWidthRecord{Error: err}
+			return
+		}
+		defer fh.Close()
+		r := bufio.NewReader(fh)
+		n := 0
+		for {
+			record, err := readRecord(r, o.recordLength)
+			if len(record) > 0 {
+				n++
+				values, ferr := parseRecord(n, record, fields, o.decoder)
+				if ferr != nil {
+					c <- FixedWidthRecord{Line: n, Error: ferr}
+				} else {
+					c <- FixedWidthRecord{Line: n, Fields: values}
+				}
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				c <- FixedWidthRecord{Error: fmt.Errorf("'%s': %w", path, err)}
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// readRecord - Reads a line, without its terminator, or length bytes.
+func readRecord(r *bufio.Reader, length int) ([]byte, error) {
+	if length > 0 {
+		record := make([]byte, length)
+		n, err := io.ReadFull(r, record)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return record[:n], err
+	}
+	line, err := r.ReadBytes('\n')
+	line = []byte(strings.TrimRight(string(line), "\r\n"))
+	return line, err
+}
+
+func parseRecord(n int, record []byte, fields []FieldSpec, decode Decoder) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		var raw []byte
+		if f.Offset < len(record) {
+			end := f.Offset + f.Width
+			if end > len(record) {
+				end = len(record)
+			}
+			raw = record[f.Offset:end]
+		}
+		s, err := decode(raw)
+		if err == nil {
+			values[f.Name], err = convertField(strings.TrimSpace(s), f)
+		}
+		if err != nil {
+			return nil, &FieldError{Line: n, Column: f.Offset + 1, Field: f.Name, Err: err}
+		}
+	}
+	return values, nil
+}
+
+func convertField(s string, f FieldSpec) (interface{}, error) {
+	if f.Type == FieldString {
+		return s, nil
+	}
+	if s == "" {
+		return nil, nil
+	}
+	switch f.Type {
+	case FieldInt:
+		return strconv.ParseInt(s, 10, 64)
+	case FieldFloat:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		if f.Scale > 0 {
+			v /= math.Pow10(f.Scale)
+		}
+		return v, nil
+	case FieldTime:
+		return time.Parse(f.Layout, s)
+	}
+	return nil, fmt.Errorf("unknown field type %d", f.Type)
+}
@@ -0,0 +1,75 @@
+package fileutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadFixedWidth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fields := []FieldSpec{
+		{Name: "name", Offset: 0, Width: 6},
+		{Name: "qty", Offset: 6, Width: 4, Type: FieldInt},
+		{Name: "price", Offset: 10, Width: 7, Type: FieldFloat, Scale: 2},
+		{Name: "date", Offset: 17, Width: 8, Type: FieldTime, Layout: "20060102"},
+	}
+	date := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	data := "apple 0012000125020200301\r\n" +
+		"pear  00x20001250\n" +
+		"caf\xe9 \n" +
+		"plum     30000100\n"
+
+	file := filepath.Join(dir, "records.txt")
+	ioutil.WriteFile(file, []byte(data), 0644)
+	got := []FixedWidthRecord{}
+	for r := range ReadFixedWidth(file, fields) {
+		got = append(got, r)
+	}
+	if len(got) != 4 {
+		t.Fatalf("Expected 4 records, got: %v\n", got)
+	}
+	expected := map[string]interface{}{"name": "apple", "qty": int64(12), "price": 12.5, "date": date}
+	if got[0].Error != nil || !reflect.DeepEqual(got[0].Fields, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v, %v\n", expected, got[0].Fields, got[0].Error)
+	}
+	var fErr *FieldError
+	if !errors.As(got[1].Error, &fErr) || fErr.Line != 2 || fErr.Column != 7 || fErr.Field != "qty" {
+		t.Errorf("Unexpected error: %v\n", got[1].Error)
+	}
+	if !errors.As(got[2].Error, &fErr) || fErr.Line != 3 || fErr.Column != 1 || fErr.Field != "name" {
+		t.Errorf("Unexpected error: %v\n", got[2].Error)
+	}
+	expected = map[string]interface{}{"name": "plum", "qty": int64(3), "price": 1.0, "date": nil}
+	if got[3].Error != nil || !reflect.DeepEqual(got[3].Fields, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v, %v\n", expected, got[3].Fields, got[3].Error)
+	}
+
+	// Latin-1 without line terminators.
+	ioutil.WriteFile(file, []byte("caf\xe9  0001caf\xe9s 0002"), 0644)
+	names := []interface{}{}
+	for r := range ReadFixedWidth(file, fields[:2], FixedWidthRecordLength(10), FixedWidthDecoder(DecodeLatin1)) {
+		if r.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", r.Error)
+		}
+		names = append(names, r.Fields["name"])
+	}
+	if !reflect.DeepEqual(names, []interface{}{"café", "cafés"}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", []string{"café", "cafés"}, names)
+	}
+
+	for r := range ReadFixedWidth(file, []FieldSpec{{Name: "x", Width: 0}}) {
+		if r.Error == nil {
+			t.Errorf("Expected invalid width error\n")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrLocked - The file is locked by another process, or through another FileLock in this one.
+var ErrLocked = fmt.Errorf("locked")
+
+// FileLock - Exclusive advisory lock held on a file, see Lock.
+type FileLock struct {
+	path string
+	fh   *os.File
+}
+
+// Lock - Blocks until it holds an exclusive lock on path, creating the file
+// if needed, flock on Unix and LockFileEx on Windows.
+// Returns ErrNotSupported on other platforms.
+//
+// The lock is advisory on Unix, it only serializes the processes that lock
+// the same file, and is released when the process exits. The file is left
+// in place after Unlock, removing it would let another process lock a new
+// file at the same path while the old one is still held.
+// Locking is allowed in read-only mode, see SetReadOnly.
+func Lock(path string) (*FileLock, error) {
+	return lock(path, true)
+}
+
+// TryLock - Same as Lock but returns ErrLocked instead of blocking when the
+// lock is held elsewhere.
+func TryLock(path string) (*FileLock, error) {
+	return lock(path, false)
+}
+
+// WithLock - Runs fn while holding the lock on path.
+func WithLock(path string, fn func() error) error {
+	l, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	err = fn()
+	uerr := l.Unlock()
+	if err != nil {
+		return err
+	}
+	return uerr
+}
+
+func lock(path string, block bool) (*FileLock, error) {
+	fh, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if os.IsPermission(err) {
+		// Shared files may only be readable, that is enough to lock them.
+		fh, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = lockFile(fh, block)
+	if err != nil {
+		fh.Close()
+		if errors.Is(err, ErrLocked) || errors.Is(err, ErrNotSupported) {
+			return nil, fmt.Errorf("%w: '%s'", err, path)
+		}
+		return nil, err
+	}
+	return &FileLock{path: path, fh: fh}, nil
+}
+
+// Path - Returns the locked file.
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+// Unlock - Releases the lock, calling it again is a no-op.
+func (l *FileLock) Unlock() error {
+	if l == nil || l.fh == nil {
+		return nil
+	}
+	err := unlockFile(l.fh)
+	cerr := l.fh.Close()
+	l.fh = nil
+	if err != nil {
+		return err
+	}
+	return cerr
+}
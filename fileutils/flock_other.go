@@ -0,0 +1,24 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package fileutils
+
+import (
+	"os"
+)
+
+func lockFile(fh *os.File, block bool) error {
+	return ErrNotSupported
+}
+
+func unlockFile(fh *os.File) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,79 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.lock")
+
+	l, err := Lock(path)
+	if errors.Is(err, ErrNotSupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if l.Path() != path {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", path, l.Path())
+	}
+	_, err = TryLock(path)
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked, got: %v\n", err)
+	}
+
+	// Lock blocks until the lock is released.
+	locked := make(chan error)
+	go func() {
+		l2, err := Lock(path)
+		if err == nil {
+			err = l2.Unlock()
+		}
+		locked <- err
+	}()
+	select {
+	case err := <-locked:
+		t.Fatalf("Lock didn't block: %v\n", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	err = l.Unlock()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = <-locked
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = l.Unlock()
+	if err != nil {
+		t.Errorf("Unexpected error on second Unlock: %s\n", err)
+	}
+
+	// WithLock returns the error from fn and releases the lock.
+	err = WithLock(path, func() error {
+		_, err := TryLock(path)
+		if !errors.Is(err, ErrLocked) {
+			t.Errorf("Expected ErrLocked, got: %v\n", err)
+		}
+		return fmt.Errorf("failed")
+	})
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	l, err = TryLock(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	l.Unlock()
+}
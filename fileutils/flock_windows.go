@@ -0,0 +1,34 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// allBytes - Locks the whole file, whatever its size.
+const allBytes = ^uint32(0)
+
+func lockFile(fh *os.File, block bool) error {
+	flags := uint32(windows.LOCKFILE_EXCLUSIVE_LOCK)
+	if !block {
+		flags |= windows.LOCKFILE_FAIL_IMMEDIATELY
+	}
+	err := windows.LockFileEx(windows.Handle(fh.Fd()), flags, 0, allBytes, allBytes, new(windows.Overlapped))
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return ErrLocked
+	}
+	return err
+}
+
+func unlockFile(fh *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(fh.Fd()), 0, allBytes, allBytes, new(windows.Overlapped))
+}
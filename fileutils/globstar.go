@@ -0,0 +1,66 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MatchGlobstar - Reports whether the slash separated name matches pattern.
+// A "**" segment matches zero or more path segments, other segments use the
+// path.Match syntax, so "*" doesn't cross a "/".
+//
+//	MatchGlobstar("src/**/*.go", "src/main.go")     // true
+//	MatchGlobstar("src/**/*.go", "src/a/b/main.go") // true
+//	MatchGlobstar("**/testdata", "a/testdata")      // true
+//	MatchGlobstar("vendor/**", "vendor/a/b")        // true
+//
+// name is converted with filepath.ToSlash. The only possible error is
+// path.ErrBadPattern, when pattern is malformed.
+func MatchGlobstar(pattern, name string) (bool, error) {
+	segments := strings.Split(pattern, "/")
+	for _, s := range segments {
+		if _, err := path.Match(s, ""); err != nil {
+			return false, err
+		}
+	}
+	names := strings.Split(filepath.ToSlash(name), "/")
+	if matchSegments(segments, names) {
+		return true, nil
+	}
+	// matchSegments follows gitignore, where a trailing "**" requires at least one segment.
+	if segments[len(segments)-1] == "**" {
+		return matchSegments(segments[:len(segments)-1], names), nil
+	}
+	return false, nil
+}
+
+// matchGlob - Matches patterns without a "/" against the base name of the
+// slash separated rel and patterns with a "/" against rel itself, with
+// MatchGlobstar. Malformed patterns never match.
+func matchGlob(pattern, rel string) bool {
+	if strings.Contains(pattern, "/") {
+		ok, _ := MatchGlobstar(pattern, rel)
+		return ok
+	}
+	ok, _ := filepath.Match(pattern, path.Base(rel))
+	return ok
+}
+
+// checkGlob - Returns an error if pattern is malformed.
+func checkGlob(pattern string) error {
+	if strings.Contains(pattern, "/") {
+		_, err := MatchGlobstar(pattern, "")
+		return err
+	}
+	_, err := filepath.Match(pattern, "")
+	return err
+}
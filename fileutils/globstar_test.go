@@ -0,0 +1,43 @@
+package fileutils
+
+import (
+	"path"
+	"testing"
+)
+
+func TestMatchGlobstar(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/**/*.go", "src/a/b/main.txt", false},
+		{"src/**/*.go", "lib/src/main.go", false},
+		{"**/testdata", "testdata", true},
+		{"**/testdata", "a/b/testdata", true},
+		{"**/testdata", "a/b/testdata/x", false},
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "vendor/a/b", true},
+		{"vendor/**", "vendors/a", false},
+		{"a/**/b/**/c", "a/x/b/y/z/c", true},
+		{"a/**/b/**/c", "a/c", false},
+		{"a/*/c", "a/b/c", true},
+		{"a/*/c", "a/b/x/c", false},
+		{"**", "a/b/c", true},
+	}
+	for _, test := range tests {
+		got, err := MatchGlobstar(test.pattern, test.name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if got != test.expected {
+			t.Errorf("MatchGlobstar(%q, %q) Expected: %v, Got: %v\n", test.pattern, test.name, test.expected, got)
+		}
+	}
+	_, err := MatchGlobstar("a/**/[", "a/b")
+	if err != path.ErrBadPattern {
+		t.Errorf("Expected: %v, Got: %v\n", path.ErrBadPattern, err)
+	}
+}
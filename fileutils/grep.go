@@ -0,0 +1,176 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// GrepMatch - A line matched by Grep or an error indicating failure (`Error`).
+type GrepMatch struct {
+	// Path - Path of the file, as listed.
+	Path string `json:"path,omitempty"`
+
+	// Line - 1 based line number within the file.
+	Line int `json:"line,omitempty"`
+
+	// Text - Line contents without the line terminator.
+	Text string `json:"text,omitempty"`
+
+	// Submatches - Byte offsets in Text of each match and its submatches,
+	// as returned by regexp.FindAllStringSubmatchIndex.
+	Submatches [][]int `json:"submatches,omitempty"`
+
+	Error error `json:"-"`
+}
+
+// MarshalJSON - Encodes the match with its Error, if any, as an "error" string.
+func (m GrepMatch) MarshalJSON() ([]byte, error) {
+	type match GrepMatch
+	out := struct {
+		match
+		Error string `json:"error,omitempty"`
+	}{match: match(m)}
+	if m.Error != nil {
+		out.Error = m.Error.Error()
+	}
+	return json.Marshal(out)
+}
+
+// Grep - Searches the files under dir, recursively, for lines matching re
+// and sends each of them to the returned channel.
+// When dir is a file, only that file is searched and the listing options
+// don't apply.
+// The files are listed like in List, so the listing options, for example
+// ListInclude, ListExclude and ListIgnoreFiles, select the files searched.
+// Files with a NUL byte in their first 8000 bytes are considered binary,
+// like git does, and skipped.
+// Errors are reported, with the Path of the file when there is one, and the
+// search continues.
+//
+// The channel is closed early when ctx is cancelled.
+func Grep(ctx context.Context, dir string, re *regexp.Regexp, opts ...ListOption) <-chan GrepMatch {
+	lo := newListOptions(opts)
+	c := make(chan GrepMatch, lo.bufferSize)
+	send := func(m GrepMatch) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		select {
+		case c <- m:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	go func() {
+		defer close(c)
+		if isRegularFile(lo, dir) {
+			grepFile(lo, dir, re, send)
+			return
+		}
+		o := lo.walkOptions(walkOptions{recursive: true, followLinks: true, join: cleanJoin})
+		listEach(dir, o, false, true, func(e StringError) bool {
+			if e.Error != nil {
+				return send(GrepMatch{Error: e.Error})
+			}
+			return grepFile(lo, e.String, re, send)
+		})
+	}()
+	return c
+}
+
+// grepFile - Sends the lines of file matching re.
+// Returns false when send does.
+func grepFile(lo listOptions, file string, re *regexp.Regexp, send func(GrepMatch) bool) bool {
+	var fh io.ReadCloser
+	var err error
+	if lo.fsys != nil {
+		fh, err = lo.fsys.Open(file)
+	} else {
+		fh, err = os.Open(file)
+	}
+	if err != nil {
+		return send(GrepMatch{Path: file, Error: err})
+	}
+	defer fh.Close()
+	r := bufio.NewReaderSize(fh, binaryCheckSize)
+	head, err := r.Peek(binaryCheckSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return send(GrepMatch{Path: file, Error: err})
+	}
+	if isBinary(head) {
+		Logger.Printf("Grep: skipping binary file: '%s'", file)
+		return true
+	}
+	for n := 1; ; n++ {
+		line, err := r.ReadString('\n')
+		if len(line) > 0 {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if idx := re.FindAllStringSubmatchIndex(line, -1); idx != nil {
+				if !send(GrepMatch{Path: file, Line: n, Text: line, Submatches: idx}) {
+					return false
+				}
+			}
+		}
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			return send(GrepMatch{Path: file, Error: err})
+		}
+	}
+}
+
+// isRegularFile - Whether path is a regular file, following symlinks.
+func isRegularFile(lo listOptions, path string) bool {
+	var fInfo fs.FileInfo
+	var err error
+	if lo.fsys != nil {
+		fInfo, err = fs.Stat(lo.fsys, path)
+	} else {
+		fInfo, err = os.Stat(path)
+	}
+	return err == nil && fInfo.Mode().IsRegular()
+}
+
+// binaryCheckSize - Number of bytes at the start of a file checked by isBinary.
+const binaryCheckSize = 8000
+
+// isBinary - Whether head, the start of a file, has a NUL byte in its first
+// binaryCheckSize bytes, which is how git detects binary files.
+func isBinary(head []byte) bool {
+	if len(head) > binaryCheckSize {
+		head = head[:binaryCheckSize]
+	}
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// isBinaryFile - Reads the start of filename and checks it with isBinary.
+func isBinaryFile(filename string) (bool, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer fh.Close()
+	head := make([]byte, binaryCheckSize)
+	n, err := io.ReadFull(fh, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return isBinary(head[:n]), nil
+}
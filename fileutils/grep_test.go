@@ -0,0 +1,92 @@
+package fileutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGrep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {}\r\nfunc B() {}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "sub", "b.go"), []byte("func C() {}\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "sub", "c.txt"), []byte("func D() {}\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "bin"), []byte("func E() {}\x00\n"), 0644)
+
+	re := regexp.MustCompile(`func (\w)\(`)
+	expected := []GrepMatch{
+		{Path: filepath.Join(dir, "a.go"), Line: 3, Text: "func A() {}", Submatches: [][]int{{0, 7, 5, 6}}},
+		{Path: filepath.Join(dir, "a.go"), Line: 4, Text: "func B() {}", Submatches: [][]int{{0, 7, 5, 6}}},
+		{Path: filepath.Join(dir, "sub", "b.go"), Line: 1, Text: "func C() {}", Submatches: [][]int{{0, 7, 5, 6}}},
+	}
+	got := []GrepMatch{}
+	for m := range Grep(context.Background(), dir, re, ListInclude("*.go")) {
+		if m.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", m.Error)
+		}
+		got = append(got, m)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+
+	// Binary files are skipped.
+	got = []GrepMatch{}
+	for m := range Grep(context.Background(), dir, re, ListExclude("sub")) {
+		got = append(got, m)
+	}
+	if !reflect.DeepEqual(got, expected[:2]) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected[:2], got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := Grep(ctx, dir, re)
+	<-c
+	cancel()
+	for range c {
+	}
+
+	// A file is searched regardless of the listing options.
+	got = []GrepMatch{}
+	for m := range Grep(context.Background(), filepath.Join(dir, "a.go"), re, ListExclude("*.go")) {
+		if m.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", m.Error)
+		}
+		got = append(got, m)
+	}
+	if !reflect.DeepEqual(got, expected[:2]) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected[:2], got)
+	}
+
+	data, err := json.Marshal([]GrepMatch{expected[0], {Path: "x", Error: fmt.Errorf("boom")}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	path, _ := json.Marshal(expected[0].Path)
+	jsonExpected := `[{"path":` + string(path) + `,"line":3,"text":"func A() {}","submatches":[[0,7,5,6]]},{"path":"x","error":"boom"}]`
+	if string(data) != jsonExpected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", jsonExpected, data)
+	}
+
+	fsys := fstest.MapFS{"x/y.txt": {Data: []byte("one\ntwo\n")}}
+	got = []GrepMatch{}
+	for m := range Grep(context.Background(), ".", regexp.MustCompile("tw"), ListFileSystem(fsys)) {
+		got = append(got, m)
+	}
+	fsExpected := []GrepMatch{{Path: "x/y.txt", Line: 2, Text: "two", Submatches: [][]int{{0, 2}}}}
+	if !reflect.DeepEqual(got, fsExpected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", fsExpected, got)
+	}
+}
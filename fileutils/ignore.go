@@ -0,0 +1,172 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// ignoreRule - A gitignore pattern.
+type ignoreRule struct {
+	// base - Slash separated dir, relative to the walked root, of the ignore file the rule came from.
+	base string
+
+	// segments - Pattern split on "/", "**" matches any number of segments.
+	segments []string
+
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreRules - Rules in effect for a dir, the ones from the root first.
+// It is never modified once built so subdirs can share it.
+type ignoreRules struct {
+	rules []ignoreRule
+}
+
+// parseIgnore - Parses gitignore lines found in the base dir.
+func parseIgnore(base string, lines []string) []ignoreRule {
+	rules := []ignoreRule{}
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasSuffix(line, "\\ ") {
+			line = strings.TrimRight(line[:len(line)-2], " ") + " "
+		} else {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			r.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		r.segments = strings.Split(line, "/")
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// with - Returns the rules extended with more, rules is left untouched.
+func (r *ignoreRules) with(more []ignoreRule) *ignoreRules {
+	if len(more) == 0 {
+		return r
+	}
+	n := &ignoreRules{}
+	if r != nil {
+		n.rules = append(n.rules, r.rules...)
+	}
+	n.rules = append(n.rules, more...)
+	return n
+}
+
+// load - Returns the rules extended with the ignore files in dir.
+func (r *ignoreRules) load(o walkOptions, dir, rel string) (*ignoreRules, error) {
+	more := []ignoreRule{}
+	for _, name := range o.ignoreFiles {
+		var data []byte
+		var err error
+		if o.fsys != nil {
+			data, err = fs.ReadFile(o.fsys, slashJoin(dir, name))
+		} else {
+			data, err = os.ReadFile(cleanJoin(dir, name))
+		}
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return r, err
+		}
+		lines := []string{}
+		s := bufio.NewScanner(bytes.NewReader(data))
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		more = append(more, parseIgnore(rel, lines)...)
+	}
+	return r.with(more), nil
+}
+
+// ignored - Whether the entry at rel, slash separated and relative to the
+// walked root, is ignored. The last matching rule wins.
+func (r *ignoreRules) ignored(rel string, isDir bool) bool {
+	if r == nil {
+		return false
+	}
+	ignored := false
+	for _, rule := range r.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.matches(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func (rule ignoreRule) matches(rel string) bool {
+	if rule.base != "" {
+		if !strings.HasPrefix(rel, rule.base+"/") {
+			return false
+		}
+		rel = rel[len(rule.base)+1:]
+	}
+	if !rule.anchored {
+		ok, _ := path.Match(rule.segments[0], path.Base(rel))
+		return ok
+	}
+	return matchSegments(rule.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments - Matches path segments against pattern segments where
+// "**" matches zero or more segments.
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return len(segments) > 0
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
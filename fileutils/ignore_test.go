@@ -0,0 +1,103 @@
+package fileutils
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{"*.o", "a.o", false, true},
+		{"*.o", "src/deep/a.o", false, true},
+		{"*.o", "a.c", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+		{"/build", "src/build", true, false},
+		{"/build", "build", false, true},
+		{"doc/*.txt", "doc/a.txt", false, true},
+		{"doc/*.txt", "doc/sub/a.txt", false, false},
+		{"doc/*.txt", "src/doc/a.txt", false, false},
+		{"**/logs", "a/b/logs", true, true},
+		{"**/logs", "logs", true, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**", "a/x/y", false, true},
+		{"a/**", "a", true, false},
+		{"# comment", "# comment", false, false},
+		{"\\#file", "#file", false, true},
+		{"trailing   ", "trailing", false, true},
+	}
+	for _, test := range tests {
+		r := (*ignoreRules)(nil).with(parseIgnore("", []string{test.pattern}))
+		if r.ignored(test.rel, test.isDir) != test.ignored {
+			t.Errorf("pattern '%s', path '%s': expected ignored %v\n", test.pattern, test.rel, test.ignored)
+		}
+	}
+
+	r := (*ignoreRules)(nil).with(parseIgnore("", []string{"*.log", "!keep.log"}))
+	r = r.with(parseIgnore("sub", []string{"keep.log", "/local"}))
+	for rel, expected := range map[string]bool{
+		"a.log":        true,
+		"keep.log":     false,
+		"sub/a.log":    true,
+		"sub/keep.log": true,
+		"sub/local":    true,
+		"local":        false,
+		"sub/x/local":  false,
+	} {
+		if r.ignored(rel, false) != expected {
+			t.Errorf("path '%s': expected ignored %v\n", rel, expected)
+		}
+	}
+}
+
+func TestListIgnoreFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":               {Data: []byte("node_modules/\n*.tmp\n!keep.tmp\n")},
+		"a.go":                     {},
+		"a.tmp":                    {},
+		"keep.tmp":                 {},
+		"node_modules/x/index.js":  {},
+		"src/.ignore":              {Data: []byte("/gen\n")},
+		"src/gen/file.go":          {},
+		"src/main.go":              {},
+		"src/lib/gen/file.go":      {},
+		"vendor/github.com/x/x.go": {},
+	}
+	expected := []string{".gitignore", "a.go", "keep.tmp", "src/.ignore", "src/lib/gen/file.go", "src/main.go"}
+	opts := []ListOption{ListFileSystem(fsys), ListIgnoreDirs(), ListIgnoreFiles(".gitignore", ".ignore"), ListIgnorePatterns("vendor/")}
+	got, err := List(".", opts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+	}
+
+	var mu sync.Mutex
+	got = []string{}
+	err = ParallelWalk(".", 4, func(path string, isDir bool, err error) error {
+		if !isDir {
+			mu.Lock()
+			got = append(got, path)
+			mu.Unlock()
+		}
+		return err
+	}, opts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+	}
+}
@@ -0,0 +1,155 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// IOLimiter - Caps the aggregate throughput and the number of files open at
+// the same time across all the operations that share it, for example
+// concurrent CopyFile calls with CopyLimiter and hashdir hashes with
+// hashdir.Options.Limiter.
+//
+// Throughput is limited with a token bucket that holds up to one second
+// worth of bytes, so short bursts go through at full speed.
+// A nil IOLimiter doesn't limit anything.
+type IOLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	slots  chan struct{}
+}
+
+// NewIOLimiter - Returns an IOLimiter that allows bytesPerSecond bytes per
+// second and maxOpen open files.
+// A value of 0 or less leaves the corresponding limit disabled.
+func NewIOLimiter(bytesPerSecond int64, maxOpen int) *IOLimiter {
+	l := &IOLimiter{}
+	if bytesPerSecond > 0 {
+		l.rate = float64(bytesPerSecond)
+		l.tokens = l.rate
+		l.last = time.Now()
+	}
+	if maxOpen > 0 {
+		l.slots = make(chan struct{}, maxOpen)
+	}
+	return l
+}
+
+// Acquire - Blocks until a file slot is available.
+// Every Acquire must be followed by a Release once the file is closed.
+func (l *IOLimiter) Acquire() {
+	if l == nil || l.slots == nil {
+		return
+	}
+	l.slots <- struct{}{}
+}
+
+// Release - Returns the file slot taken by Acquire.
+func (l *IOLimiter) Release() {
+	if l == nil || l.slots == nil {
+		return
+	}
+	<-l.slots
+}
+
+// WaitN - Blocks until n bytes can be transferred without going over the rate.
+// Callers reserve their bytes in order, so a large n delays the calls that
+// come after it rather than starving.
+func (l *IOLimiter) WaitN(n int) {
+	if l == nil || l.rate == 0 || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// Reader - Wraps r so that every read counts against the rate.
+func (l *IOLimiter) Reader(r io.Reader) io.Reader {
+	if l == nil || l.rate == 0 {
+		return r
+	}
+	return &limitedReader{r: r, l: l}
+}
+
+// Open - Opens the named file for reading once a file slot is available.
+// Reads count against the rate and Close releases the slot.
+func (l *IOLimiter) Open(name string) (io.ReadCloser, error) {
+	l.Acquire()
+	fh, err := os.Open(name)
+	if err != nil {
+		l.Release()
+		return nil, err
+	}
+	return &limitedFile{Reader: l.Reader(fh), fh: fh, l: l}, nil
+}
+
+type limitedReader struct {
+	r io.Reader
+	l *IOLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	lr.l.WaitN(n)
+	return n, err
+}
+
+type limitedFile struct {
+	io.Reader
+	fh   *os.File
+	l    *IOLimiter
+	once sync.Once
+}
+
+func (lf *limitedFile) Close() error {
+	err := lf.fh.Close()
+	lf.once.Do(lf.l.Release)
+	return err
+}
+
+// CopyLimiter - Shares l with other operations to cap their combined
+// throughput and open files.
+// Each file copy takes a single file slot for both its source and destination.
+func CopyLimiter(l *IOLimiter) CopyOption {
+	return func(o *copyOptions) {
+		o.limiter = l
+	}
+}
+
+// CopyBandwidth - Caps the throughput of the call to bytesPerSecond, for
+// example to copy onto a slow network mount without saturating it.
+// The limit applies to the call as a whole, CopyDir shares it between all
+// its files, while CopyLimiter shares a limit between calls.
+// Short bursts of up to one second worth of bytes go through at full speed.
+// A value of 0 or less disables the limit.
+func CopyBandwidth(bytesPerSecond int64) CopyOption {
+	return func(o *copyOptions) {
+		o.bandwidth = bytesPerSecond
+	}
+}
@@ -0,0 +1,169 @@
+package fileutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIOLimiterRate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("x"), 96*1024)
+	src := filepath.Join(dir, "src")
+	err = ioutil.WriteFile(src, data, 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	// Two concurrent copies share the rate, the first second worth of bytes
+	// goes through at full speed.
+	l := NewIOLimiter(64*1024, 0)
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = CopyFile(src, filepath.Join(dir, "dst"+string(rune('a'+i))), CopyLimiter(l))
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	}
+	// 192KiB at 64KiB/s with a 64KiB burst.
+	if elapsed < 1500*time.Millisecond {
+		t.Errorf("Expected the copies to be throttled, took: %s\n", elapsed)
+	}
+	for _, name := range []string{"dsta", "dstb"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if !bytes.Equal(b, data) {
+			t.Errorf("%s: contents don't match the source\n", name)
+		}
+	}
+}
+
+func TestCopyBandwidth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("x"), 64*1024)
+	src := filepath.Join(dir, "src")
+	err = os.MkdirAll(src, 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		err = ioutil.WriteFile(filepath.Join(src, name), data, 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	}
+
+	// The files of a CopyDir share the limit.
+	start := time.Now()
+	err = CopyDir(src, filepath.Join(dir, "dst"), CopyBandwidth(64*1024))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	elapsed := time.Since(start)
+	// 128KiB at 64KiB/s with a 64KiB burst.
+	if elapsed < 900*time.Millisecond {
+		t.Errorf("Expected the copy to be throttled, took: %s\n", elapsed)
+	}
+	for _, name := range []string{"a", "b"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "dst", name))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if !bytes.Equal(b, data) {
+			t.Errorf("%s: contents don't match the source\n", name)
+		}
+	}
+}
+
+func TestIOLimiterOpenFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a")
+	err = ioutil.WriteFile(file, []byte("hello"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	l := NewIOLimiter(0, 2)
+	var open, max int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fh, err := l.Open(file)
+			if err != nil {
+				t.Errorf("Unexpected error: %s\n", err)
+				return
+			}
+			n := atomic.AddInt32(&open, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			b, err := ioutil.ReadAll(fh)
+			if err != nil || string(b) != "hello" {
+				t.Errorf("Unexpected read: %q, %v\n", b, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&open, -1)
+			fh.Close()
+		}()
+	}
+	wg.Wait()
+	if max > 2 {
+		t.Errorf("Expected at most 2 open files, got: %d\n", max)
+	}
+
+	// A failed open doesn't leak its slot.
+	for i := 0; i < 3; i++ {
+		_, err = l.Open(filepath.Join(dir, "missing"))
+		if !os.IsNotExist(err) {
+			t.Errorf("Unexpected error: %v\n", err)
+		}
+	}
+	fh, err := l.Open(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	fh.Close()
+
+	var nilLimiter *IOLimiter
+	fh, err = nilLimiter.Open(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	fh.Close()
+}
@@ -0,0 +1,33 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"iter"
+)
+
+// Files - Iterator form of List, yields the entries under dirname, recursively.
+// Errors are yielded with an empty path and the walk continues.
+// Breaking out of the loop stops the walk, no goroutine is involved.
+//
+//	for path, err := range fileutils.Files(dir, fileutils.ListIgnoreDirs()) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func Files(dirname string, opts ...ListOption) iter.Seq2[string, error] {
+	lo := newListOptions(opts)
+	o := lo.walkOptions(walkOptions{recursive: true, followLinks: true})
+	return func(yield func(string, error) bool) {
+		listEach(dirname, o, !lo.ignoreDirs, true, func(e StringError) bool {
+			return yield(e.String, e.Error)
+		})
+	}
+}
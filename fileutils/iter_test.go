@@ -0,0 +1,46 @@
+package fileutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	expected, err := List("./test_tree", ListIgnoreDirs(), ListSkipHidden())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got := []string{}
+	for path, err := range Files("./test_tree", ListIgnoreDirs(), ListSkipHidden()) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		got = append(got, path)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+	}
+
+	got = []string{}
+	for path := range Files("./test_tree") {
+		if len(got) == 2 {
+			break
+		}
+		got = append(got, path)
+	}
+	expected = []string{"./test_tree/.A", "./test_tree/.A/b"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+	}
+
+	n := 0
+	for _, err := range Files("./test_tree/missing") {
+		n++
+		if err == nil {
+			t.Errorf("Expected missing dir error\n")
+		}
+	}
+	if n != 1 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 1, n)
+	}
+}
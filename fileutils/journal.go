@@ -0,0 +1,179 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JournalOp - Kind of change recorded in a Journal.
+type JournalOp string
+
+// Journal operations.
+const (
+	JournalCreate JournalOp = "create"
+	JournalWrite  JournalOp = "write"
+	JournalRemove JournalOp = "remove"
+	JournalRename JournalOp = "rename"
+	JournalChmod  JournalOp = "chmod"
+)
+
+// JournalEvent - A change recorded in a Journal.
+type JournalEvent struct {
+	// Seq - Sequence number, increasing by one with each event.
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Op   JournalOp `json:"op"`
+	Path string    `json:"path"`
+}
+
+// Journal - Append only log of file system events, so tools that don't run
+// continuously can find the paths that changed since their last run with
+// ChangedSince, while a long running process, like a watcher, records the
+// events.
+//
+// The journal file has one JSON encoded JournalEvent per line. Events are
+// identified by their sequence number, a checkpoint is the sequence number
+// of the last event a tool processed.
+// It is safe for concurrent use.
+type Journal struct {
+	mu  sync.Mutex
+	fh  *os.File
+	seq uint64
+}
+
+// OpenJournal - Opens the journal in filename for appending, creating it
+// if needed. The sequence continues from the last event in the file.
+// A last event only partially written, by an interrupted process, is
+// discarded.
+func OpenJournal(filename string) (*Journal, error) {
+	if err := checkWritable("OpenJournal", filename); err != nil {
+		return nil, err
+	}
+	j := &Journal{}
+	size, err := replayJournal(filename, func(e JournalEvent) {
+		j.seq = e.Seq
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	j.fh, err = os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	err = j.fh.Truncate(size)
+	if err != nil {
+		j.fh.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// Append - Records a change to path and returns its sequence number.
+func (j *Journal) Append(op JournalOp, path string) (uint64, error) {
+	if err := checkWritable("Journal.Append", j.fh.Name()); err != nil {
+		return 0, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	e := JournalEvent{Seq: j.seq + 1, Time: time.Now(), Op: op, Path: path}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	_, err = j.fh.Write(append(data, '\n'))
+	if err != nil {
+		return 0, err
+	}
+	j.seq = e.Seq
+	return e.Seq, nil
+}
+
+// Checkpoint - Returns the sequence number of the last event.
+func (j *Journal) Checkpoint() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq
+}
+
+// Sync - Commits the appended events to stable storage.
+func (j *Journal) Sync() error {
+	return j.fh.Sync()
+}
+
+// Close - Closes the journal file.
+func (j *Journal) Close() error {
+	return j.fh.Close()
+}
+
+// ChangedSince - Replays the journal in filename and returns the sorted
+// paths with events after the checkpoint, and the new checkpoint to use in
+// the next call.
+// Removed and renamed paths are included, check whether they still exist.
+// A missing journal has no changes.
+func ChangedSince(filename string, checkpoint uint64) ([]string, uint64, error) {
+	paths := map[string]bool{}
+	last := checkpoint
+	_, err := replayJournal(filename, func(e JournalEvent) {
+		if e.Seq > checkpoint {
+			paths[e.Path] = true
+			last = e.Seq
+		}
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, checkpoint, err
+	}
+	changed := []string{}
+	for path := range paths {
+		changed = append(changed, path)
+	}
+	sort.Strings(changed)
+	return changed, last, nil
+}
+
+// replayJournal - Calls fn with each event in the journal in filename.
+// Returns the size of the complete lines, a last line without a newline,
+// from an interrupted write, is ignored.
+func replayJournal(filename string, fn func(JournalEvent)) (int64, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+	reader := bufio.NewReader(fh)
+	var size int64
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+		size += int64(len(line))
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var e JournalEvent
+		err = json.Unmarshal(line, &e)
+		if err != nil {
+			return size, fmt.Errorf("'%s': invalid journal line %d: %w", filename, n, err)
+		}
+		fn(e)
+	}
+}
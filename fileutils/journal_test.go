@@ -0,0 +1,89 @@
+package fileutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "journal")
+
+	changed, checkpoint, err := ChangedSince(filename, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(changed) != 0 || checkpoint != 0 {
+		t.Errorf("Unexpected changes in missing journal: %v, %d\n", changed, checkpoint)
+	}
+
+	j, err := OpenJournal(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	j.Append(JournalCreate, "b")
+	j.Append(JournalWrite, "a")
+	j.Append(JournalWrite, "b")
+	j.Close()
+
+	changed, checkpoint, err = ChangedSince(filename, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []string{"a", "b"}
+	if !reflect.DeepEqual(changed, expected) || checkpoint != 3 {
+		t.Errorf("Expected:\n%v 3\nGot:\n%v %d\n", expected, changed, checkpoint)
+	}
+
+	// Interrupted write.
+	fh, _ := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+	fh.WriteString(`{"seq":4,"op":"wri`)
+	fh.Close()
+	changed, checkpoint, err = ChangedSince(filename, checkpoint)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(changed) != 0 || checkpoint != 3 {
+		t.Errorf("Unexpected changes: %v, %d\n", changed, checkpoint)
+	}
+
+	// Resumes the sequence after the last complete event.
+	j, err = OpenJournal(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	seq, err := j.Append(JournalRemove, "c")
+	if err != nil || seq != 4 || j.Checkpoint() != 4 {
+		t.Errorf("Unexpected append result: %d, %v\n", seq, err)
+	}
+	j.Close()
+	changed, checkpoint, err = ChangedSince(filename, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{"c"}
+	if !reflect.DeepEqual(changed, expected) || checkpoint != 4 {
+		t.Errorf("Expected:\n%v 4\nGot:\n%v %d\n", expected, changed, checkpoint)
+	}
+
+	j, _ = OpenJournal(filename)
+	defer j.Close()
+	SetReadOnly(true)
+	defer SetReadOnly(false)
+	_, err = j.Append(JournalWrite, "a")
+	if !errors.Is(err, ErrReadOnlyMode) {
+		t.Errorf("Expected ErrReadOnlyMode, got: %v\n", err)
+	}
+	_, err = OpenJournal(filename)
+	if !errors.Is(err, ErrReadOnlyMode) {
+		t.Errorf("Expected ErrReadOnlyMode, got: %v\n", err)
+	}
+}
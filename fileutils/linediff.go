@@ -0,0 +1,127 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// diffContext - Number of unchanged lines shown around each change.
+const diffContext = 3
+
+// lineDiff - Streams a unified diff of two versions of a file that have the
+// same number of lines, where line i of one corresponds to line i of the
+// other, like the output of a line by line replacement.
+// Unchanged lines are buffered only as needed for the context.
+type lineDiff struct {
+	out     bytes.Buffer
+	name    string
+	n       int
+	changed int
+
+	// before - Unchanged lines preceding the next hunk.
+	before []string
+	// hunk - Lines of the open hunk, nil when there is none.
+	hunk  []string
+	start int
+	// minus and plus - The run of changes not yet added to the hunk.
+	minus []string
+	plus  []string
+	// tail - Unchanged lines after the last change of the open hunk.
+	tail []string
+}
+
+func newLineDiff(name string) *lineDiff {
+	return &lineDiff{name: name}
+}
+
+// add - Adds the next line, before and after the change.
+func (d *lineDiff) add(a, b string) {
+	d.n++
+	if a != b {
+		d.changed++
+		if d.hunk == nil {
+			d.start = d.n - len(d.before)
+			d.hunk = prefixLines(" ", d.before)
+			d.before = nil
+		}
+		if len(d.tail) > 0 {
+			d.flush()
+			d.hunk = append(d.hunk, prefixLines(" ", d.tail)...)
+			d.tail = d.tail[:0]
+		}
+		d.minus = append(d.minus, "-"+a)
+		d.plus = append(d.plus, "+"+b)
+		return
+	}
+	if d.hunk == nil {
+		d.before = append(d.before, a)
+		if len(d.before) > diffContext {
+			d.before = d.before[1:]
+		}
+		return
+	}
+	d.tail = append(d.tail, a)
+	if len(d.tail) > 2*diffContext {
+		d.close()
+	}
+}
+
+// flush - Adds the pending run of changes to the hunk.
+func (d *lineDiff) flush() {
+	d.hunk = append(d.hunk, d.minus...)
+	d.hunk = append(d.hunk, d.plus...)
+	d.minus, d.plus = d.minus[:0], d.plus[:0]
+}
+
+// close - Writes the open hunk with the trailing context.
+func (d *lineDiff) close() {
+	d.flush()
+	after := d.tail
+	if len(after) > diffContext {
+		after = after[:diffContext]
+		d.before = append([]string{}, d.tail[len(d.tail)-diffContext:]...)
+	}
+	d.hunk = append(d.hunk, prefixLines(" ", after)...)
+	if d.out.Len() == 0 {
+		fmt.Fprintf(&d.out, "--- %s\n+++ %s\n", d.name, d.name)
+	}
+	count := 0
+	for _, line := range d.hunk {
+		if line[0] != '-' {
+			count++
+		}
+	}
+	r := fmt.Sprintf("%d,%d", d.start, count)
+	if count == 1 {
+		r = fmt.Sprint(d.start)
+	}
+	fmt.Fprintf(&d.out, "@@ -%s +%s @@\n", r, r)
+	for _, line := range d.hunk {
+		d.out.WriteString(line + "\n")
+	}
+	d.hunk, d.tail = nil, d.tail[:0]
+}
+
+// String - Returns the diff, empty when nothing changed.
+func (d *lineDiff) String() string {
+	if d.hunk != nil {
+		d.close()
+	}
+	return d.out.String()
+}
+
+func prefixLines(prefix string, lines []string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = prefix + line
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package fileutils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	lines := func(n int) []string {
+		out := []string{}
+		for i := 1; i <= n; i++ {
+			out = append(out, fmt.Sprintf("%d", i))
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		n        int
+		changed  []int
+		expected string
+	}{
+		{"no changes", 5, nil, ""},
+		{"single line", 1, []int{1}, "--- f\n+++ f\n@@ -1 +1 @@\n-1\n+1x\n"},
+		{"context", 10, []int{5}, "--- f\n+++ f\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+5x\n 6\n 7\n 8\n"},
+		{"run of changes", 4, []int{2, 3}, "--- f\n+++ f\n@@ -1,4 +1,4 @@\n 1\n-2\n-3\n+2x\n+3x\n 4\n"},
+		{"merged hunks", 9, []int{1, 8}, "--- f\n+++ f\n@@ -1,9 +1,9 @@\n-1\n+1x\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+8x\n 9\n"},
+		{"split hunks", 12, []int{1, 9}, "--- f\n+++ f\n@@ -1,4 +1,4 @@\n-1\n+1x\n 2\n 3\n 4\n@@ -6,7 +6,7 @@\n 6\n 7\n 8\n-9\n+9x\n 10\n 11\n 12\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed := map[int]bool{}
+			for _, i := range test.changed {
+				changed[i] = true
+			}
+			d := newLineDiff("f")
+			for i, line := range lines(test.n) {
+				if changed[i+1] {
+					d.add(line, line+"x")
+				} else {
+					d.add(line, line)
+				}
+			}
+			got := d.String()
+			if got != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s\n", test.expected, got)
+			}
+			if d.changed != len(test.changed) {
+				t.Errorf("Expected %d changes, got: %d\n", len(test.changed), d.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Matcher - Matches the entries under a root with the same rules as the
+// listing options ListInclude, ListExclude, ListExtensions, ListSkipHidden,
+// ListIgnoreFiles and ListIgnorePatterns, for paths that don't come from a
+// listing, for example file system notifications.
+// It is safe for concurrent use.
+type Matcher struct {
+	root string
+	o    walkOptions
+
+	mu sync.Mutex
+
+	// ignore - Rules in effect for each dir, keyed by its slash separated
+	// path relative to the root, "" for the root.
+	ignore map[string]*ignoreRules
+}
+
+// NewMatcher - Returns a Matcher for the entries under root.
+// Returns an error if any of the include or exclude patterns is malformed.
+func NewMatcher(root string, opts ...ListOption) (*Matcher, error) {
+	o := newListOptions(opts).walkOptions(walkOptions{})
+	err := o.checkPatterns()
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{root: root, o: o, ignore: map[string]*ignoreRules{}}, nil
+}
+
+// Match - Whether the entry at rel, slash separated and relative to the
+// root, would be listed: it is not Skipped and it is Included.
+func (m *Matcher) Match(rel string, isDir bool) bool {
+	return !m.Skipped(rel, isDir) && m.Included(rel, isDir)
+}
+
+// Skipped - Whether the entry at rel, slash separated and relative to the
+// root, or one of its parent dirs is hidden, excluded or ignored.
+// The contents of skipped dirs are not listed either.
+// The root itself, "" or ".", is never skipped.
+func (m *Matcher) Skipped(rel string, isDir bool) bool {
+	rel = path.Clean(rel)
+	if rel == "." {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i, name := range parts {
+		d := walkDir{rel: strings.Join(parts[:i+1], "/")}
+		if m.o.skipped(d, name) {
+			return true
+		}
+		dir := strings.Join(parts[:i], "/")
+		if m.rules(dir).ignored(d.rel, isDir || i < len(parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// Included - Whether the entry at rel, slash separated and relative to the
+// root, matches the include patterns and extensions.
+// Dirs are still walked when they don't match.
+func (m *Matcher) Included(rel string, isDir bool) bool {
+	rel = path.Clean(rel)
+	d := walkDir{path: m.path(rel), rel: rel}
+	return m.o.included(d, isDir)
+}
+
+// Forget - Drops the ignore rules read from the ignore files of the dir at
+// rel and its subdirs, call it when one of those files changes.
+func (m *Matcher) Forget(rel string) {
+	rel = strings.TrimPrefix(path.Clean(rel), ".")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dir := range m.ignore {
+		if rel == "" || dir == rel || strings.HasPrefix(dir, rel+"/") {
+			delete(m.ignore, dir)
+		}
+	}
+}
+
+// rules - Returns the ignore rules in effect for the entries of the dir at
+// rel, "" for the root, reading the ignore files the first time.
+// Ignore files that can't be read are skipped.
+func (m *Matcher) rules(rel string) *ignoreRules {
+	m.mu.Lock()
+	r, ok := m.ignore[rel]
+	m.mu.Unlock()
+	if ok {
+		return r
+	}
+	if rel == "" {
+		r = r.with(parseIgnore("", m.o.ignorePatterns))
+	} else {
+		parent := ""
+		if i := strings.LastIndex(rel, "/"); i >= 0 {
+			parent = rel[:i]
+		}
+		r = m.rules(parent)
+	}
+	if len(m.o.ignoreFiles) > 0 {
+		r, _ = r.load(m.o, m.path(rel), rel)
+	}
+	m.mu.Lock()
+	m.ignore[rel] = r
+	m.mu.Unlock()
+	return r
+}
+
+// path - Returns the path of the entry at rel.
+func (m *Matcher) path(rel string) string {
+	if m.o.fsys != nil {
+		return slashJoin(m.root, rel)
+	}
+	return cleanJoin(m.root, filepath.FromSlash(rel))
+}
@@ -0,0 +1,122 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	writeTree(t, dir, map[string]string{
+		".gitignore":        "*.log\nbuild/\n",
+		".hidden/a.go":      "",
+		"a.go":              "",
+		"a.log":             "",
+		"b.txt":             "",
+		"build/c.go":        "",
+		"sub/.gitignore":    "!keep.log\n*.txt\n",
+		"sub/keep.log":      "",
+		"sub/d.go":          "",
+		"sub/e.txt":         "",
+		"sub/deep/f.go":     "",
+		"sub/deep/g.log":    "",
+		"vendor/h.go":       "",
+		"vendor/sub/i.go":   "",
+		"vendor/sub/j.txt":  "",
+		"other/vendor/k.go": "",
+	})
+	all, err := List(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	tests := []struct {
+		name string
+		opts []ListOption
+	}{
+		{"none", nil},
+		{"exclude", []ListOption{ListExclude("vendor", "*.txt")}},
+		{"exclude path", []ListOption{ListExclude("/vendor/sub")}},
+		{"hidden", []ListOption{ListSkipHidden()}},
+		{"include", []ListOption{ListInclude("*.go")}},
+		{"extensions", []ListOption{ListExtensions("log")}},
+		{"ignore files", []ListOption{ListIgnoreFiles(".gitignore")}},
+		{"ignore patterns", []ListOption{ListIgnorePatterns("/vendor/", "*.go", "!d.go")}},
+		{"combined", []ListOption{ListIgnoreFiles(".gitignore"), ListIgnorePatterns("vendor/"), ListSkipHidden(), ListInclude("*.go", "*.log")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			listed, err := List(dir, test.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			m, err := NewMatcher(dir, test.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			matched := []string{}
+			for _, p := range all {
+				fInfo, err := os.Stat(p)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s\n", err)
+				}
+				rel, err := filepath.Rel(dir, p)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s\n", err)
+				}
+				if m.Match(filepath.ToSlash(rel), fInfo.IsDir()) {
+					matched = append(matched, p)
+				}
+			}
+			if !reflect.DeepEqual(matched, listed) {
+				t.Errorf("Expected:\n%v\nGot:\n%v\n", listed, matched)
+			}
+		})
+	}
+}
+
+func TestMatcherForget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	writeTree(t, dir, map[string]string{
+		"sub/.gitignore": "*.log\n",
+	})
+	m, err := NewMatcher(dir, ListIgnoreFiles(".gitignore"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if m.Match("sub/deep/a.log", false) {
+		t.Errorf("Expected sub/deep/a.log to be ignored\n")
+	}
+	if !m.Match("sub/deep/a.txt", false) {
+		t.Errorf("Expected sub/deep/a.txt to match\n")
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "sub", ".gitignore"), []byte("*.txt\n"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !m.Match("sub/deep/a.txt", false) {
+		t.Errorf("Expected the cached rules until Forget\n")
+	}
+	m.Forget("sub")
+	if m.Match("sub/deep/a.txt", false) {
+		t.Errorf("Expected sub/deep/a.txt to be ignored after Forget\n")
+	}
+	if !m.Match("sub/deep/a.log", false) {
+		t.Errorf("Expected sub/deep/a.log to match after Forget\n")
+	}
+	if !m.Match("", true) {
+		t.Errorf("Expected the root to match\n")
+	}
+}
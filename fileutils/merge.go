@@ -0,0 +1,246 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// MergeConflict - An entry changed in different ways in both trees merged by MergeTrees.
+type MergeConflict struct {
+	// Path - Slash separated path relative to the trees.
+	Path string `json:"path"`
+
+	// Reason - How the entry changed, for example "modified in both".
+	Reason string `json:"reason"`
+}
+
+// MergeTrees - Three-way merge of the ours and theirs trees, both derived
+// from base, into dst, which must not exist.
+//
+// Each entry, compared like in DirsEqual, is taken from the tree that
+// changed it from base, and entries changed the same way in both trees are
+// taken as is. Text files modified in both trees are merged with MergeFiles
+// and are only conflicts when the merge has conflict markers.
+// Other entries changed differently in both trees are returned as conflicts
+// and dst gets the ours version, when there is one, so they can be resolved
+// in place. Entries whose parent dir became a file or symlink in dst are
+// reported as conflicts and not written.
+//
+// Files are copied with CopyPreserve, symlinks are recreated and special
+// files are skipped. Mode changes alone are not merged.
+func MergeTrees(base, ours, theirs, dst string) ([]MergeConflict, error) {
+	if err := checkWritable("MergeTrees", dst); err != nil {
+		return nil, err
+	}
+	_, err := os.Lstat(dst)
+	if err == nil {
+		return nil, fmt.Errorf("%w: '%s'", fs.ErrExist, dst)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	m := merge{roots: [3]string{base, ours, theirs}}
+	for i, root := range m.roots {
+		m.types[i], err = treeTypes(root)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rels := []string{}
+	for i := range m.types {
+		for rel := range m.types[i] {
+			if (i < 1 || !m.has(0, rel)) && (i < 2 || !m.has(1, rel)) {
+				rels = append(rels, rel)
+			}
+		}
+	}
+	sort.Slice(rels, func(i, j int) bool { return filepath.ToSlash(rels[i]) < filepath.ToSlash(rels[j]) })
+
+	err = os.MkdirAll(dst, 0755)
+	if err != nil {
+		return nil, err
+	}
+	conflicts := []MergeConflict{}
+	// written - Type of the entries written to dst.
+	written := map[string]fs.FileMode{}
+	for _, rel := range rels {
+		from, reason, err := m.resolve(rel)
+		if err != nil {
+			return conflicts, err
+		}
+		if from < 0 {
+			continue
+		}
+		slashRel := filepath.ToSlash(rel)
+		if kind, ok := written[path.Dir(slashRel)]; ok && kind != fs.ModeDir {
+			conflicts = append(conflicts, MergeConflict{Path: slashRel, Reason: "parent is not a dir"})
+			// Not written, so its contents are skipped as well.
+			written[slashRel] = fs.ModeIrregular
+			continue
+		}
+		text, err := m.text(rel)
+		if err != nil {
+			return conflicts, err
+		}
+		if reason == reasonModified && text {
+			n, err := m.mergeFiles(rel, filepath.Join(dst, rel))
+			if err != nil {
+				return conflicts, err
+			}
+			if n == 0 {
+				reason = ""
+			}
+		} else {
+			err = mergeEntry(filepath.Join(m.roots[from], rel), filepath.Join(dst, rel), m.types[from][rel])
+			if err != nil {
+				return conflicts, err
+			}
+		}
+		written[slashRel] = m.types[from][rel]
+		if reason != "" {
+			conflicts = append(conflicts, MergeConflict{Path: slashRel, Reason: reason})
+		}
+	}
+	return conflicts, nil
+}
+
+// merge - Entry types of the base, ours and theirs trees.
+type merge struct {
+	roots [3]string
+	types [3]map[string]fs.FileMode
+}
+
+func (m merge) has(tree int, rel string) bool {
+	_, ok := m.types[tree][rel]
+	return ok
+}
+
+// same - Whether rel is the same in both trees, both can be missing.
+func (m merge) same(a, b int, rel string) (bool, error) {
+	aKind, aOk := m.types[a][rel]
+	bKind, bOk := m.types[b][rel]
+	if aOk != bOk || aKind != bKind {
+		return false, nil
+	}
+	if !aOk {
+		return true, nil
+	}
+	aPath, bPath := filepath.Join(m.roots[a], rel), filepath.Join(m.roots[b], rel)
+	switch aKind {
+	case 0:
+		return CompareFiles(aPath, bPath)
+	case fs.ModeSymlink:
+		return sameLink(aPath, bPath)
+	}
+	return true, nil
+}
+
+// resolve - Returns the tree rel is taken from, -1 when it is removed, and
+// the conflict reason when both trees changed it differently.
+func (m merge) resolve(rel string) (int, string, error) {
+	oursSame, err := m.same(0, 1, rel)
+	if err != nil {
+		return 0, "", err
+	}
+	theirsSame, err := m.same(0, 2, rel)
+	if err != nil {
+		return 0, "", err
+	}
+	from := 1
+	switch {
+	case oursSame && !theirsSame:
+		from = 2
+	case !oursSame && !theirsSame:
+		same, err := m.same(1, 2, rel)
+		if err != nil {
+			return 0, "", err
+		}
+		if !same {
+			if !m.has(1, rel) {
+				return 2, m.conflict(rel), nil
+			}
+			return 1, m.conflict(rel), nil
+		}
+	}
+	if !m.has(from, rel) {
+		return -1, "", nil
+	}
+	return from, "", nil
+}
+
+const reasonModified = "modified in both"
+
+// text - Whether rel is a text file in the three trees, see isBinary.
+func (m merge) text(rel string) (bool, error) {
+	for i := range m.roots {
+		if kind, ok := m.types[i][rel]; !ok || kind != 0 {
+			return false, nil
+		}
+		binary, err := isBinaryFile(filepath.Join(m.roots[i], rel))
+		if err != nil || binary {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// mergeFiles - Merges the three versions of the text file rel into dst
+// with MergeFiles.
+func (m merge) mergeFiles(rel, dst string) (int, error) {
+	err := os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return 0, err
+	}
+	return MergeFiles(filepath.Join(m.roots[0], rel), filepath.Join(m.roots[1], rel), filepath.Join(m.roots[2], rel), dst)
+}
+
+// conflict - Describes how rel changed in both trees.
+func (m merge) conflict(rel string) string {
+	switch {
+	case !m.has(0, rel):
+		return "added in both"
+	case !m.has(1, rel):
+		return "deleted in ours, modified in theirs"
+	case !m.has(2, rel):
+		return "modified in ours, deleted in theirs"
+	}
+	return reasonModified
+}
+
+// mergeEntry - Writes the src entry of type kind to dst.
+func mergeEntry(src, dst string, kind fs.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case fs.ModeDir:
+		fInfo, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		return os.MkdirAll(dst, fInfo.Mode().Perm())
+	case fs.ModeSymlink:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case 0:
+		return CopyFile(src, dst, CopyPreserve())
+	}
+	Logger.Printf("MergeTrees: skipping special file: '%s'", src)
+	return nil
+}
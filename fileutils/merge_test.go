@@ -0,0 +1,143 @@
+package fileutils
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeTrees(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(tree string, files map[string]string) string {
+		root := filepath.Join(dir, tree)
+		os.MkdirAll(root, 0755)
+		for name, content := range files {
+			p := filepath.Join(root, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(p), 0755)
+			if content == "/" {
+				os.MkdirAll(p, 0755)
+				continue
+			}
+			ioutil.WriteFile(p, []byte(content), 0644)
+		}
+		return root
+	}
+	base := write("base", map[string]string{
+		"same":            "same",
+		"ours":            "base",
+		"theirs":          "base",
+		"both":            "base",
+		"conflict":        "base",
+		"merged":          "a\nb\nc\n",
+		"binary":          "base\x00",
+		"deleted":         "base",
+		"deleted-changed": "base",
+		"changed-deleted": "base",
+		"dir/a":           "base",
+		"file-dir":        "base",
+	})
+	ours := write("ours", map[string]string{
+		"same":            "same",
+		"ours":            "ours",
+		"theirs":          "base",
+		"both":            "both",
+		"conflict":        "ours",
+		"merged":          "A\nb\nc\n",
+		"binary":          "ours\x00",
+		"changed-deleted": "ours",
+		"dir/a":           "base",
+		"added-ours":      "ours",
+		"added-both":      "ours",
+		"file-dir":        "ours",
+		"empty":           "/",
+	})
+	theirs := write("theirs", map[string]string{
+		"same":            "same",
+		"ours":            "base",
+		"theirs":          "theirs",
+		"both":            "both",
+		"conflict":        "theirs",
+		"merged":          "a\nb\nC\n",
+		"binary":          "theirs\x00",
+		"deleted-changed": "theirs",
+		"dir/a":           "base",
+		"dir/b":           "theirs",
+		"added-both":      "theirs",
+		"file-dir/x":      "theirs",
+	})
+	os.Symlink("ours", filepath.Join(theirs, "link"))
+
+	dst := filepath.Join(dir, "dst")
+	conflicts, err := MergeTrees(base, ours, theirs, dst)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expectedConflicts := []MergeConflict{
+		{"added-both", "added in both"},
+		{"binary", "modified in both"},
+		{"changed-deleted", "modified in ours, deleted in theirs"},
+		{"conflict", "modified in both"},
+		{"deleted-changed", "deleted in ours, modified in theirs"},
+		{"file-dir", "modified in both"},
+		{"file-dir/x", "parent is not a dir"},
+	}
+	if !reflect.DeepEqual(conflicts, expectedConflicts) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expectedConflicts, conflicts)
+	}
+	expected := map[string]string{
+		"same":   "same",
+		"ours":   "ours",
+		"theirs": "theirs",
+		"both":   "both",
+		"conflict": "<<<<<<< " + filepath.Join(ours, "conflict") + "\nours\n||||||| " + filepath.Join(base, "conflict") +
+			"\nbase\n=======\ntheirs\n>>>>>>> " + filepath.Join(theirs, "conflict") + "\n",
+		"merged":          "A\nb\nC\n",
+		"binary":          "ours\x00",
+		"deleted-changed": "theirs",
+		"changed-deleted": "ours",
+		"dir/a":           "base",
+		"dir/b":           "theirs",
+		"added-ours":      "ours",
+		"added-both":      "ours",
+		"file-dir":        "ours",
+	}
+	got := map[string]string{}
+	err = Walk(dst, func(path string, isDir bool, err error) error {
+		rel, _ := filepath.Rel(dst, path)
+		fInfo, _ := os.Lstat(path)
+		if isDir || fInfo.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		b, err := ioutil.ReadFile(path)
+		got[filepath.ToSlash(rel)] = string(b)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+	if target, _ := os.Readlink(filepath.Join(dst, "link")); target != "ours" {
+		t.Errorf("Expected link to ours, got: '%s'\n", target)
+	}
+	if fInfo, err := os.Stat(filepath.Join(dst, "empty")); err != nil || !fInfo.IsDir() {
+		t.Errorf("Expected empty dir: %v\n", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "deleted")); !os.IsNotExist(err) {
+		t.Errorf("Expected deleted file to be removed: %v\n", err)
+	}
+
+	_, err = MergeTrees(base, ours, theirs, dst)
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
@@ -0,0 +1,200 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// MergeFiles - Three-way merge of the text files ours and theirs, both
+// derived from base, into dst, which can be one of the inputs.
+// Returns the number of conflicts.
+//
+// The files are compared line by line: lines changed in only one of the
+// files are taken from it and lines changed the same way in both are taken
+// as is. Lines changed differently in both are written with diff3 style
+// conflict markers, labeled with the file names:
+//
+//	<<<<<<< ours
+//	ours lines
+//	||||||| base
+//	base lines
+//	=======
+//	theirs lines
+//	>>>>>>> theirs
+//
+// dst gets the mode of ours. The files are loaded in memory.
+func MergeFiles(base, ours, theirs, dst string) (int, error) {
+	if err := checkWritable("MergeFiles", dst); err != nil {
+		return 0, err
+	}
+	var lines [3][]string
+	for i, file := range []string{base, ours, theirs} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return 0, err
+		}
+		lines[i] = splitLines(string(data))
+	}
+	fInfo, err := os.Stat(ours)
+	if err != nil {
+		return 0, err
+	}
+	var out bytes.Buffer
+	conflicts := mergeLines(&out, lines[0], lines[1], lines[2], [3]string{base, ours, theirs})
+	return conflicts, WriteFileAtomic(dst, out.Bytes(), fInfo.Mode().Perm())
+}
+
+// splitLines - Splits s after each "\n", keeping the terminators.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// mergeLines - Writes the merge of ours and theirs to out and returns the
+// number of conflicts. labels are the base, ours and theirs marker labels.
+//
+// Base lines matched in both ours and theirs, at the expected positions,
+// are stable and copied as is. Between stable lines, the chunk is taken
+// from the side that changed it or written as a conflict.
+func mergeLines(out *bytes.Buffer, base, ours, theirs []string, labels [3]string) int {
+	matchOurs := lcsMatches(base, ours)
+	matchTheirs := lcsMatches(base, theirs)
+	conflicts := 0
+	i, a, b := 0, 0, 0
+	for i < len(base) || a < len(ours) || b < len(theirs) {
+		if i < len(base) && matchOurs[i] == a && matchTheirs[i] == b {
+			out.WriteString(base[i])
+			i, a, b = i+1, a+1, b+1
+			continue
+		}
+		// The next base line matched in both ends the unstable chunk.
+		j, aEnd, bEnd := i, len(ours), len(theirs)
+		for ; j < len(base); j++ {
+			if matchOurs[j] >= 0 && matchTheirs[j] >= 0 {
+				aEnd, bEnd = matchOurs[j], matchTheirs[j]
+				break
+			}
+		}
+		baseChunk, oursChunk, theirsChunk := base[i:j], ours[a:aEnd], theirs[b:bEnd]
+		switch {
+		case equalLines(baseChunk, oursChunk):
+			writeLines(out, theirsChunk)
+		case equalLines(baseChunk, theirsChunk), equalLines(oursChunk, theirsChunk):
+			writeLines(out, oursChunk)
+		default:
+			conflicts++
+			out.WriteString("<<<<<<< " + labels[1] + "\n")
+			writeLines(out, terminated(oursChunk))
+			out.WriteString("||||||| " + labels[0] + "\n")
+			writeLines(out, terminated(baseChunk))
+			out.WriteString("=======\n")
+			writeLines(out, terminated(theirsChunk))
+			out.WriteString(">>>>>>> " + labels[2] + "\n")
+		}
+		i, a, b = j, aEnd, bEnd
+	}
+	return conflicts
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeLines(out *bytes.Buffer, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+}
+
+// terminated - Adds the missing line terminator to the last line, so the
+// conflict markers start on their own line.
+func terminated(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	return append(lines[:len(lines)-1:len(lines)-1], lines[len(lines)-1]+"\n")
+}
+
+// lcsMatches - Returns, for each line of a, the index of the line of b it
+// is matched to in a longest common subsequence, or -1.
+// Uses the Myers O(ND) diff algorithm.
+func lcsMatches(a, b []string) []int {
+	n, m := len(a), len(b)
+	// vs[d][k+d] - Furthest x reached on diagonal k = x - y with d edits.
+	vs := [][]int{}
+	for d := 0; ; d++ {
+		v := make([]int, 2*d+1)
+		done := false
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if d > 0 {
+				prev := vs[d-1]
+				if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+					x = prev[k+1+d-1]
+				} else {
+					x = prev[k-1+d-1] + 1
+				}
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[k+d] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		vs = append(vs, v)
+		if done {
+			break
+		}
+	}
+
+	matches := make([]int, n)
+	for i := range matches {
+		matches[i] = -1
+	}
+	x, y := n, m
+	for d := len(vs) - 1; d > 0; d-- {
+		prev := vs[d-1]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			matches[x] = y
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		matches[x] = y
+	}
+	return matches
+}
@@ -0,0 +1,108 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	base, ours, theirs := filepath.Join(dir, "base"), filepath.Join(dir, "ours"), filepath.Join(dir, "theirs")
+	dst := filepath.Join(dir, "dst")
+
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		expected  string
+		conflicts int
+	}{
+		{"unchanged", "a\nb\n", "a\nb\n", "a\nb\n", "a\nb\n", 0},
+		{"ours", "a\nb\nc\n", "a\nB\nc\n", "a\nb\nc\n", "a\nB\nc\n", 0},
+		{"theirs", "a\nb\nc\n", "a\nb\nc\n", "a\nb\nC\n", "a\nb\nC\n", 0},
+		{"both", "a\nb\nc\nd\ne\n", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"same change", "a\nb\nc\n", "a\nB\nc\n", "a\nB\nc\n", "a\nB\nc\n", 0},
+		{"insertions", "a\nb\n", "x\na\nb\n", "a\nb\ny\n", "x\na\nb\ny\n", 0},
+		{"deletions", "a\nb\nc\nd\n", "b\nc\nd\n", "a\nb\nc\n", "b\nc\n", 0},
+		{"conflict", "a\nb\nc\n", "a\nours\nc\n", "a\ntheirs\nc\n",
+			"a\n<<<<<<< " + ours + "\nours\n||||||| " + base + "\nb\n=======\ntheirs\n>>>>>>> " + theirs + "\nc\n", 1},
+		{"no final newline", "a", "b", "c",
+			"<<<<<<< " + ours + "\nb\n||||||| " + base + "\na\n=======\nc\n>>>>>>> " + theirs + "\n", 1},
+		{"two conflicts", "a\nb\nc\n", "1\nb\n3\n", "2\nb\n4\n",
+			"<<<<<<< " + ours + "\n1\n||||||| " + base + "\na\n=======\n2\n>>>>>>> " + theirs + "\nb\n" +
+				"<<<<<<< " + ours + "\n3\n||||||| " + base + "\nc\n=======\n4\n>>>>>>> " + theirs + "\n", 2},
+		{"empty base", "", "a\n", "a\n", "a\n", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ioutil.WriteFile(base, []byte(test.base), 0644)
+			ioutil.WriteFile(ours, []byte(test.ours), 0644)
+			ioutil.WriteFile(theirs, []byte(test.theirs), 0644)
+			n, err := MergeFiles(base, ours, theirs, dst)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if n != test.conflicts {
+				t.Errorf("Expected %d conflicts, got %d\n", test.conflicts, n)
+			}
+			b, _ := ioutil.ReadFile(dst)
+			if string(b) != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s\n", test.expected, b)
+			}
+		})
+	}
+}
+
+func TestLCSMatches(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for n := 0; n < 200; n++ {
+		a, b := random(), random()
+		matches := lcsMatches(a, b)
+		length, last := 0, -1
+		for i, j := range matches {
+			if j < 0 {
+				continue
+			}
+			if j <= last || a[i] != b[j] {
+				t.Fatalf("Invalid match %d -> %d for %q and %q\n", i, j, a, b)
+			}
+			last = j
+			length++
+		}
+		// Longest common subsequence length by dynamic programming.
+		dp := make([][]int, len(a)+1)
+		for i := range dp {
+			dp[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				switch {
+				case a[i] == b[j]:
+					dp[i][j] = dp[i+1][j+1] + 1
+				case dp[i+1][j] > dp[i][j+1]:
+					dp[i][j] = dp[i+1][j]
+				default:
+					dp[i][j] = dp[i][j+1]
+				}
+			}
+		}
+		if length != dp[0][0] {
+			t.Fatalf("Expected LCS of %d, got %d for %q and %q\n", dp[0][0], length, a, b)
+		}
+	}
+}
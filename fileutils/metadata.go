@@ -0,0 +1,151 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Metadata - The attributes of a file other than its contents, as
+// replicated by CopyPreserve.
+// Use Capture and Apply to replicate them on your own, for example when
+// extracting archives or syncing trees.
+type Metadata struct {
+	// Mode - Type and permission bits, including the setuid, setgid and
+	// sticky bits.
+	Mode os.FileMode
+
+	ModTime time.Time
+
+	// AccessTime - Only available on unix systems, elsewhere it is the
+	// modification time.
+	AccessTime time.Time
+
+	// UID and GID - Owner and group, -1 when not available.
+	UID int
+	GID int
+
+	// Xattrs - Extended attributes, only captured on Linux and macOS.
+	Xattrs map[string][]byte
+
+	// LinkTarget - Target of the symlink, empty for other files.
+	LinkTarget string
+}
+
+// Capture - Replaces m with the metadata of path.
+// Symlinks are not followed, their own metadata is captured.
+func (m *Metadata) Capture(path string) error {
+	fInfo, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	return m.capture(path, fInfo)
+}
+
+// capture - Replaces m with the metadata of path, where fInfo is the result
+// of either stating or lstating path.
+func (m *Metadata) capture(path string, fInfo os.FileInfo) error {
+	*m = Metadata{Mode: fInfo.Mode(), ModTime: fInfo.ModTime(), UID: -1, GID: -1}
+	var ok bool
+	m.AccessTime, ok = fileAtime(fInfo)
+	if !ok {
+		m.AccessTime = fInfo.ModTime()
+	}
+	if uid, gid, ok := fileOwner(fInfo); ok {
+		m.UID, m.GID = uid, gid
+	}
+	if fInfo.Mode()&os.ModeSymlink != 0 {
+		var err error
+		m.LinkTarget, err = os.Readlink(path)
+		return err
+	}
+	var err error
+	m.Xattrs, err = getXattrs(path)
+	return err
+}
+
+// Apply - Applies m to path.
+//
+// The owner and group are only applied when running as root, and only the
+// extended attributes in the user namespace on Linux unless running as
+// root. Extended attributes are skipped when the file system of path
+// doesn't support them.
+//
+// When m is a symlink, path is created, or replaced if it is a symlink,
+// pointing to LinkTarget, and only its owner and group are applied.
+// Otherwise path must exist and have the same type.
+func (m *Metadata) Apply(path string) error {
+	if err := checkWritable("Metadata.Apply", path); err != nil {
+		return err
+	}
+	fInfo, err := os.Lstat(path)
+	if err != nil && !(os.IsNotExist(err) && m.Mode&os.ModeSymlink != 0) {
+		return err
+	}
+	if m.Mode&os.ModeSymlink != 0 {
+		if err == nil {
+			if fInfo.Mode()&os.ModeSymlink == 0 {
+				return fmt.Errorf("can't apply symlink metadata to '%s', not a symlink", path)
+			}
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if target != m.LinkTarget {
+				err = os.Remove(path)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		err = os.Symlink(m.LinkTarget, path)
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+	} else if fInfo.Mode().Type() != m.Mode.Type() {
+		return fmt.Errorf("can't apply metadata to '%s', the file type differs", path)
+	}
+	return m.apply(path)
+}
+
+// apply - Applies the owner, mode, extended attributes and times of m to
+// path, which already exists, skipping all but the owner for symlinks.
+func (m *Metadata) apply(path string) error {
+	if m.UID >= 0 && os.Geteuid() == 0 {
+		err := os.Lchown(path, m.UID, m.GID)
+		if err != nil {
+			return err
+		}
+	}
+	if m.Mode&os.ModeSymlink != 0 {
+		return nil
+	}
+	// Set after chown, that clears the setuid and setgid bits.
+	err := os.Chmod(path, m.Mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+	if err != nil {
+		return err
+	}
+	err = setXattrs(path, m.Xattrs)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(path, m.AccessTime, m.ModTime)
+}
+
+// preserveAttributes - Applies the metadata of src, described by fInfo, to dst.
+func preserveAttributes(src, dst string, fInfo os.FileInfo) error {
+	var m Metadata
+	err := m.capture(src, fInfo)
+	if err != nil {
+		return err
+	}
+	return m.apply(dst)
+}
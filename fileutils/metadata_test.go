@@ -0,0 +1,85 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	ioutil.WriteFile(src, []byte("src"), 0644)
+	os.Chmod(src, 0750)
+	setXattrs(src, map[string][]byte{"user.test": []byte("value")})
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(src, mtime, mtime)
+	os.Symlink("src", filepath.Join(dir, "link"))
+	os.Mkdir(filepath.Join(dir, "dir"), 0755)
+
+	tests := []struct {
+		name string
+		src  string
+		dst  string
+		init func(dst string)
+	}{
+		{"file", "src", "dst", func(dst string) { ioutil.WriteFile(dst, []byte("dst"), 0644) }},
+		{"dir", "dir", "dst-dir", func(dst string) { os.Mkdir(dst, 0700) }},
+		{"new link", "link", "dst-link", func(dst string) {}},
+		{"replaced link", "link", "dst-link2", func(dst string) { os.Symlink("other", dst) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := filepath.Join(dir, test.src)
+			dst := filepath.Join(dir, test.dst)
+			test.init(dst)
+			var m Metadata
+			err := m.Capture(src)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			err = m.Apply(dst)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			var got Metadata
+			err = got.Capture(dst)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if m.Mode&os.ModeSymlink != 0 {
+				// Only the target and owner are applied to symlinks.
+				if got.LinkTarget != "src" || got.UID != m.UID || got.GID != m.GID {
+					t.Errorf("Expected:\n%+v\nGot:\n%+v\n", m, got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, m) {
+				t.Errorf("Expected:\n%+v\nGot:\n%+v\n", m, got)
+			}
+		})
+	}
+
+	var m Metadata
+	m.Capture(filepath.Join(dir, "link"))
+	err = m.Apply(src)
+	if err == nil {
+		t.Errorf("Expected error applying symlink metadata to a file\n")
+	}
+	m.Capture(src)
+	err = m.Apply(filepath.Join(dir, "dir"))
+	if err == nil {
+		t.Errorf("Expected error applying file metadata to a dir\n")
+	}
+	err = m.Apply(filepath.Join(dir, "missing"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got: %v\n", err)
+	}
+}
@@ -0,0 +1,64 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotSupported - The operation is not supported on this platform.
+var ErrNotSupported = fmt.Errorf("not supported on this platform")
+
+// IsMountPoint - Whether path is a dir where a filesystem is mounted, that
+// is, it is on a different device than its parent or it is the root.
+// Symlinks are not followed.
+// Returns ErrNotSupported where device numbers are not available, like Windows.
+func IsMountPoint(path string) (bool, error) {
+	fInfo, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+	if !fInfo.IsDir() {
+		return false, nil
+	}
+	parentInfo, err := os.Stat(filepath.Join(path, ".."))
+	if err != nil {
+		return false, err
+	}
+	dev, ino, ok := fileID(fInfo)
+	if !ok {
+		return false, fmt.Errorf("%w: '%s'", ErrNotSupported, path)
+	}
+	parentDev, parentIno, _ := fileID(parentInfo)
+	return dev != parentDev || ino == parentIno, nil
+}
+
+// FilesystemType - Returns the name of the type of the filesystem path is
+// on, for example "ext4", "nfs", "tmpfs" or "apfs".
+// On Linux, types without a known name are returned as their hex magic number.
+// Returns ErrNotSupported on platforms other than Linux, macOS and the BSDs.
+func FilesystemType(path string) (string, error) {
+	return filesystemType(path)
+}
+
+// fileDevice - Returns the device of path, following symlinks when follow is set.
+func fileDevice(path string, follow bool) (uint64, bool) {
+	stat := os.Lstat
+	if follow {
+		stat = os.Stat
+	}
+	fInfo, err := stat(path)
+	if err != nil {
+		return 0, false
+	}
+	dev, _, ok := fileID(fInfo)
+	return dev, ok
+}
@@ -0,0 +1,61 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// filesystemMagic - statfs(2) f_type values, from linux/magic.h.
+var filesystemMagic = map[uint32]string{
+	0x0187:     "autofs",
+	0x00c36400: "ceph",
+	0x27e0eb:   "cgroup",
+	0x63677270: "cgroup2",
+	0xff534d42: "cifs",
+	0x64626720: "debugfs",
+	0x1cd1:     "devpts",
+	0xde5e81e0: "efivarfs",
+	0x2011bab0: "exfat",
+	0xef53:     "ext4",
+	0xf2f52010: "f2fs",
+	0x65735546: "fuse",
+	0x958458f6: "hugetlbfs",
+	0x9660:     "iso9660",
+	0x19800202: "mqueue",
+	0x6969:     "nfs",
+	0x5346544e: "ntfs",
+	0x794c7630: "overlay",
+	0x9fa0:     "proc",
+	0x858458f6: "ramfs",
+	0xfe534d42: "smb2",
+	0x73717368: "squashfs",
+	0x62656572: "sysfs",
+	0x01021994: "tmpfs",
+	0x74726163: "tracefs",
+	0x4d44:     "vfat",
+	0x58465342: "xfs",
+	0x9123683e: "btrfs",
+	0x2fc12fc1: "zfs",
+}
+
+func filesystemType(path string) (string, error) {
+	var st syscall.Statfs_t
+	err := syscall.Statfs(path, &st)
+	if err != nil {
+		return "", &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	magic := uint32(st.Type)
+	if name, ok := filesystemMagic[magic]; ok {
+		return name, nil
+	}
+	return fmt.Sprintf("0x%x", magic), nil
+}
@@ -0,0 +1,30 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+)
+
+func filesystemType(path string) (string, error) {
+	var st syscall.Statfs_t
+	err := syscall.Statfs(path, &st)
+	if err != nil {
+		return "", &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	s := make([]byte, 0, len(st.F_fstypename))
+	for _, c := range st.F_fstypename {
+		if c == 0 {
+			break
+		}
+		s = append(s, byte(c))
+	}
+	return string(s), nil
+}
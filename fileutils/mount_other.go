@@ -0,0 +1,25 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!openbsd
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+)
+
+func filesystemType(path string) (string, error) {
+	_, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%w: '%s'", ErrNotSupported, path)
+}
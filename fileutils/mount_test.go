@@ -0,0 +1,87 @@
+package fileutils
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestIsMountPoint(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		_, err := IsMountPoint(".")
+		if !errors.Is(err, ErrNotSupported) {
+			t.Errorf("Expected ErrNotSupported, got: %v\n", err)
+		}
+		return
+	}
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/", true},
+		{"test_tree/A", false},
+		{"test_tree/A/b/C/d/E", false},
+	}
+	for _, tt := range tests {
+		got, err := IsMountPoint(tt.path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if got != tt.expected {
+			t.Errorf("'%s' Expected:\n%v\nGot:\n%v\n", tt.path, tt.expected, got)
+		}
+	}
+	_, err := IsMountPoint("test_tree/missing")
+	if err == nil {
+		t.Errorf("Expected not exist error\n")
+	}
+}
+
+// procMounted - Skips the test unless /proc is a mounted procfs.
+func procMounted(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("procfs is only checked on linux")
+	}
+	ok, err := IsMountPoint("/proc")
+	if err != nil || !ok {
+		t.Skipf("/proc is not mounted: %v", err)
+	}
+}
+
+func TestFilesystemType(t *testing.T) {
+	procMounted(t)
+	got, err := FilesystemType("/proc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if got != "proc" {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", "proc", got)
+	}
+	_, err = FilesystemType("test_tree/missing")
+	if err == nil {
+		t.Errorf("Expected not exist error\n")
+	}
+}
+
+func TestListSameFilesystem(t *testing.T) {
+	procMounted(t)
+	foundProc := false
+	err := Walk("/", func(path string, isDir bool, err error) error {
+		if path == "/proc" {
+			foundProc = true
+		}
+		if strings.HasPrefix(path, "/proc/") {
+			t.Errorf("Unexpected entry in another filesystem: %s\n", path)
+			return errors.New("stop")
+		}
+		return nil
+	}, ListMaxDepth(2), ListSameFilesystem())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !foundProc {
+		t.Errorf("Expected the /proc mount point to be listed\n")
+	}
+}
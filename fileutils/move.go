@@ -0,0 +1,111 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MoveFile - Renames src to dst. When they are on different filesystems,
+// where rename fails, src is copied to a temporary file next to dst, synced
+// to disk, renamed into place and then removed.
+// The copy preserves the mode, times and, when running as root, the owner
+// of src. Symlinks are recreated, dirs can only be renamed.
+// Names that only differ in case and are the same file, on case-insensitive
+// filesystems, are renamed with RenameCaseOnly.
+func MoveFile(src, dst string) error {
+	if err := checkWritable("MoveFile", src); err != nil {
+		return err
+	}
+	if src != dst && strings.EqualFold(src, dst) && sameFile(src, dst) {
+		return RenameCaseOnly(src, dst)
+	}
+	err := os.Rename(src, dst)
+	if err == nil || !crossDevice(err) {
+		return err
+	}
+	Logger.Printf("MoveFile: '%s' and '%s' are on different devices, copying", src, dst)
+	return moveCopy(src, dst)
+}
+
+// sameFile - Whether a and b exist and are the same file.
+func sameFile(a, b string) bool {
+	aInfo, err := os.Lstat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Lstat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+func moveCopy(src, dst string) error {
+	fInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case fInfo.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		err = os.Remove(dst)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		err = os.Symlink(link, dst)
+		if err != nil {
+			return err
+		}
+		return os.Remove(src)
+	case !fInfo.Mode().IsRegular():
+		return fmt.Errorf("can't move '%s' across devices, not a regular file", src)
+	}
+	fh, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	tmpFile, err := ioutil.TempFile(filepath.Dir(dst), "."+filepath.Base(dst)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = io.Copy(tmpFile, fh)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Sync()
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	err = preserveAttributes(src, tmpFile.Name(), fInfo)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpFile.Name(), dst)
+	if err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
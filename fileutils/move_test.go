@@ -0,0 +1,80 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMoveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	mtime := time.Date(2020, 6, 7, 8, 9, 10, 0, time.UTC)
+
+	// moveCopy is the cross device fallback.
+	for name, move := range map[string]func(src, dst string) error{"rename": MoveFile, "copy": moveCopy} {
+		t.Run(name, func(t *testing.T) {
+			src := filepath.Join(dir, name+"-src")
+			dst := filepath.Join(dir, name+"-dst")
+			ioutil.WriteFile(src, []byte(name), 0640)
+			os.Chmod(src, 0640)
+			os.Chtimes(src, mtime, mtime)
+			ioutil.WriteFile(dst, []byte("overwritten"), 0644)
+			err := move(src, dst)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if _, err := os.Stat(src); !os.IsNotExist(err) {
+				t.Errorf("Expected src to be removed, got: %v\n", err)
+			}
+			data, _ := ioutil.ReadFile(dst)
+			if string(data) != name {
+				t.Errorf("Expected:\n%s\nGot:\n%s\n", name, data)
+			}
+			fInfo, err := os.Stat(dst)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if fInfo.Mode().Perm() != 0640 || !fInfo.ModTime().Equal(mtime) {
+				t.Errorf("Expected:\n%v %v\nGot:\n%v %v\n", os.FileMode(0640), mtime, fInfo.Mode().Perm(), fInfo.ModTime())
+			}
+
+			link := filepath.Join(dir, name+"-link")
+			os.Symlink(name+"-dst", link)
+			err = move(link, link+"-moved")
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			target, err := os.Readlink(link + "-moved")
+			if err != nil || target != name+"-dst" {
+				t.Errorf("Expected:\n%s\nGot:\n%s, %v\n", name+"-dst", target, err)
+			}
+		})
+	}
+	// On case-sensitive filesystems names that only differ in case are
+	// different files and dst is replaced like with any other name.
+	lower := filepath.Join(dir, "case")
+	upper := filepath.Join(dir, "CASE")
+	ioutil.WriteFile(lower, []byte("lower"), 0644)
+	ioutil.WriteFile(upper, []byte("upper"), 0644)
+	if !sameFile(lower, upper) {
+		err = MoveFile(lower, upper)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		data, _ := ioutil.ReadFile(upper)
+		if string(data) != "lower" {
+			t.Errorf("Expected:\n%s\nGot:\n%s\n", "lower", data)
+		}
+	}
+
+	err = moveCopy(dir, filepath.Join(dir, "x"))
+	if err == nil {
+		t.Errorf("Expected not a regular file error\n")
+	}
+}
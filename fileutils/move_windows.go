@@ -0,0 +1,21 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice - ERROR_NOT_SAME_DEVICE.
+const errorNotSameDevice syscall.Errno = 17
+
+func crossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
@@ -0,0 +1,187 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// ParallelWalk - Same as Walk but reads up to workers dirs concurrently,
+// workers < 1 uses one per CPU.
+//
+// fn is called concurrently from the workers so it must be safe for
+// concurrent use. Each dir is passed to fn before its contents, but there
+// is no order between entries of different dirs, ListOrder is ignored.
+// Returning filepath.SkipDir for a dir skips its contents, returning any
+// other error stops scheduling new dirs.
+//
+// The errors returned by fn, and the error reading dirname itself, are
+// sorted by path and joined so the result doesn't depend on scheduling.
+func ParallelWalk(dirname string, workers int, fn WalkFunc, opts ...ListOption) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	w := &parallelWalker{
+		o:       newListOptions(opts).walkOptions(walkOptions{recursive: true, join: cleanJoin}),
+		fn:      fn,
+		pending: 1,
+	}
+	w.queue = []walkDir{w.o.root(dirname)}
+	err := w.o.checkPatterns()
+	if err != nil {
+		return err
+	}
+	w.cond = sync.NewCond(&w.mu)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+	return w.err()
+}
+
+type pathError struct {
+	path string
+	err  error
+}
+
+type parallelWalker struct {
+	o  walkOptions
+	fn WalkFunc
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// queue - Dirs waiting to be read.
+	queue []walkDir
+
+	// pending - Dirs queued or being read, the walk is done when it drops to 0.
+	pending int
+
+	stopped bool
+	errs    []pathError
+}
+
+func (w *parallelWalker) work() {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && w.pending > 0 && !w.stopped {
+			w.cond.Wait()
+		}
+		if len(w.queue) == 0 || w.stopped {
+			w.mu.Unlock()
+			return
+		}
+		d := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+		w.mu.Unlock()
+
+		w.readDir(d)
+
+		w.mu.Lock()
+		w.pending--
+		if w.pending == 0 {
+			w.cond.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// readDir - Passes the entries of d to fn and queues its subdirs.
+func (w *parallelWalker) readDir(d walkDir) {
+	o := w.o
+	entries, d, err := d.readDir(o)
+	if err != nil {
+		if d.depth > 1 {
+			err = w.fn(d.path, true, err)
+		}
+		if err != nil {
+			w.fail(d.path, err)
+		}
+		return
+	}
+	for _, e := range entries {
+		if w.isStopped() {
+			return
+		}
+		path := cleanJoin(d.path, e.Name())
+		if o.fsys != nil {
+			path = slashJoin(d.path, e.Name())
+		}
+		child := d.child(path, e.Name())
+		if o.skipped(child, e.Name()) {
+			continue
+		}
+		isDir := e.IsDir()
+		if child.ignore.ignored(child.rel, isDir) {
+			continue
+		}
+		if o.included(child, isDir) {
+			err := w.fn(path, isDir, nil)
+			if err == filepath.SkipDir && isDir {
+				continue
+			}
+			if err != nil {
+				w.fail(path, err)
+				return
+			}
+		}
+		if isDir && o.descend(d, path) {
+			w.mu.Lock()
+			w.queue = append(w.queue, child)
+			w.pending++
+			w.cond.Signal()
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *parallelWalker) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped
+}
+
+// fail - Records err and stops the walk.
+func (w *parallelWalker) fail(path string, err error) {
+	w.mu.Lock()
+	w.errs = append(w.errs, pathError{path, err})
+	w.stopped = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+func (w *parallelWalker) err() error {
+	return joinPathErrors(w.errs)
+}
+
+// joinPathErrors - Sorts errs by path and joins them so the result doesn't
+// depend on scheduling.
+func joinPathErrors(errs []pathError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) == 1 {
+		return errs[0].err
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].path < errs[j].path })
+	joined := make([]error, len(errs))
+	for i, e := range errs {
+		joined[i] = e.err
+	}
+	return errors.Join(joined...)
+}
@@ -0,0 +1,89 @@
+package fileutils
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelWalk(t *testing.T) {
+	expected := []string{}
+	err := Walk("test_tree", func(path string, isDir bool, err error) error {
+		expected = append(expected, path)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	sort.Strings(expected)
+
+	for _, workers := range []int{0, 1, 4} {
+		var mu sync.Mutex
+		got := []string{}
+		err := ParallelWalk("test_tree", workers, func(path string, isDir bool, err error) error {
+			mu.Lock()
+			got = append(got, path)
+			mu.Unlock()
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+		}
+	}
+
+	var n int32
+	err = ParallelWalk("test_tree", 4, func(path string, isDir bool, err error) error {
+		atomic.AddInt32(&n, 1)
+		if isDir {
+			return filepath.SkipDir
+		}
+		return nil
+	}, ListSkipHidden())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 3, n)
+	}
+
+	errStop := errors.New("stop")
+	err = ParallelWalk("test_tree", 4, func(path string, isDir bool, err error) error {
+		if !isDir {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expected errStop, got: %v\n", err)
+	}
+
+	err = ParallelWalk("test_tree/missing", 4, func(path string, isDir bool, err error) error {
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Expected missing dir error\n")
+	}
+}
+
+func BenchmarkParallelWalk100k(b *testing.B) {
+	root := benchTree(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var count int64
+		err := ParallelWalk(root, 0, func(path string, isDir bool, err error) error {
+			atomic.AddInt64(&count, 1)
+			return err
+		})
+		if err != nil || count != 100100 {
+			b.Fatalf("Unexpected result: %d, %v\n", count, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PathIndex - In memory trie of paths, for example from a List of a large
+// tree, to answer membership, prefix, glob and nearest ancestor queries in
+// time proportional to the length of the path rather than the size of the tree.
+//
+// Paths are converted with filepath.ToSlash and cleaned, so "a/b/", "./a/b"
+// and "a//b" are the same entry.
+// Queries can run concurrently but not while paths are being added.
+type PathIndex struct {
+	root pathNode
+	len  int
+}
+
+type pathNode struct {
+	children map[string]*pathNode
+	// path - The indexed path, empty when the node is only an intermediate dir.
+	path    string
+	present bool
+}
+
+// NewPathIndex - Returns an index of the given paths.
+func NewPathIndex(paths ...string) *PathIndex {
+	x := &PathIndex{}
+	for _, p := range paths {
+		x.Add(p)
+	}
+	return x
+}
+
+// Add - Adds p to the index, adding an existing path is a no-op.
+func (x *PathIndex) Add(p string) {
+	p = cleanIndexPath(p)
+	n := &x.root
+	for _, s := range indexSegments(p) {
+		child, ok := n.children[s]
+		if !ok {
+			if n.children == nil {
+				n.children = map[string]*pathNode{}
+			}
+			child = &pathNode{}
+			n.children[s] = child
+		}
+		n = child
+	}
+	if !n.present {
+		n.present = true
+		n.path = p
+		x.len++
+	}
+}
+
+// Len - Number of indexed paths.
+func (x *PathIndex) Len() int {
+	return x.len
+}
+
+// Contains - Whether p was added to the index.
+func (x *PathIndex) Contains(p string) bool {
+	n := x.find(p)
+	return n != nil && n.present
+}
+
+// HasPrefix - Whether the index has dir or any path under it.
+// The prefix is matched by whole segments, "a/b" is not a prefix of "a/bc".
+func (x *PathIndex) HasPrefix(dir string) bool {
+	n := x.find(dir)
+	return n != nil && (n.present || len(n.children) > 0)
+}
+
+// WithPrefix - Returns dir, if indexed, and all the indexed paths under it, sorted.
+// The prefix is matched by whole segments, "a/b" is not a prefix of "a/bc".
+func (x *PathIndex) WithPrefix(dir string) []string {
+	paths := []string{}
+	n := x.find(dir)
+	if n != nil {
+		n.collect(&paths)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Match - Returns the indexed paths that match pattern with the
+// MatchGlobstar syntax, sorted.
+// Only the branches of the trie that can match are visited, literal
+// segments are looked up directly.
+// The only possible error is path.ErrBadPattern, when pattern is malformed.
+func (x *PathIndex) Match(pattern string) ([]string, error) {
+	segments := strings.Split(pattern, "/")
+	for _, s := range segments {
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, err
+		}
+	}
+	found := map[*pathNode]bool{}
+	x.root.match(segments, found)
+	paths := make([]string, 0, len(found))
+	for n := range found {
+		paths = append(paths, n.path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Ancestor - Returns the deepest indexed path that is p or one of its parent dirs.
+func (x *PathIndex) Ancestor(p string) (string, bool) {
+	n := &x.root
+	ancestor, ok := n.path, n.present
+	for _, s := range indexSegments(cleanIndexPath(p)) {
+		n = n.children[s]
+		if n == nil {
+			break
+		}
+		if n.present {
+			ancestor, ok = n.path, true
+		}
+	}
+	return ancestor, ok
+}
+
+// find - Returns the node for p or nil.
+func (x *PathIndex) find(p string) *pathNode {
+	n := &x.root
+	for _, s := range indexSegments(cleanIndexPath(p)) {
+		n = n.children[s]
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+func (n *pathNode) collect(paths *[]string) {
+	if n.present {
+		*paths = append(*paths, n.path)
+	}
+	for _, child := range n.children {
+		child.collect(paths)
+	}
+}
+
+// match - Adds to found the present nodes under n that match the pattern segments.
+func (n *pathNode) match(segments []string, found map[*pathNode]bool) {
+	if len(segments) == 0 {
+		if n.present {
+			found[n] = true
+		}
+		return
+	}
+	s := segments[0]
+	switch {
+	case s == "**":
+		// Zero segments, or one more segment keeping the "**".
+		n.match(segments[1:], found)
+		for _, child := range n.children {
+			child.match(segments, found)
+		}
+	case !strings.ContainsAny(s, `*?[\`):
+		if child, ok := n.children[s]; ok {
+			child.match(segments[1:], found)
+		}
+	default:
+		for name, child := range n.children {
+			if ok, _ := path.Match(s, name); ok {
+				child.match(segments[1:], found)
+			}
+		}
+	}
+}
+
+func cleanIndexPath(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// indexSegments - Splits the cleaned p, the root "/" is kept as an empty first segment.
+func indexSegments(p string) []string {
+	switch p {
+	case ".":
+		return nil
+	case "/":
+		return []string{""}
+	}
+	return strings.Split(p, "/")
+}
@@ -0,0 +1,120 @@
+package fileutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathIndex(t *testing.T) {
+	x := NewPathIndex(
+		"src",
+		"src/main.go",
+		"src/a/b/util.go",
+		"src/a/b/util_test.go",
+		"./src/a/README.md",
+		"srcx/other.go",
+		"vendor/lib/lib.go",
+		"/abs/file",
+		"src/main.go/",
+	)
+	if x.Len() != 8 {
+		t.Errorf("Expected 8 paths, got: %d\n", x.Len())
+	}
+
+	for p, expected := range map[string]bool{
+		"src":         true,
+		"src/main.go": true,
+		"src/a":       false,
+		"src/a/b/x":   false,
+		"/abs/file":   true,
+		"abs/file":    false,
+		"vendor/lib/": false,
+	} {
+		if got := x.Contains(p); got != expected {
+			t.Errorf("Contains(%q) Expected: %v, Got: %v\n", p, expected, got)
+		}
+	}
+
+	for p, expected := range map[string]bool{
+		"src/a":   true,
+		"src/a/b": true,
+		"sr":      false,
+		"/abs":    true,
+		"vendor":  true,
+		"missing": false,
+	} {
+		if got := x.HasPrefix(p); got != expected {
+			t.Errorf("HasPrefix(%q) Expected: %v, Got: %v\n", p, expected, got)
+		}
+	}
+
+	prefixTests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"src/a", []string{"src/a/README.md", "src/a/b/util.go", "src/a/b/util_test.go"}},
+		{"src", []string{"src", "src/a/README.md", "src/a/b/util.go", "src/a/b/util_test.go", "src/main.go"}},
+		{"sr", []string{}},
+	}
+	for _, test := range prefixTests {
+		got := x.WithPrefix(test.prefix)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("WithPrefix(%q) Expected:\n%v\nGot:\n%v\n", test.prefix, test.expected, got)
+		}
+	}
+
+	matchTests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"src/**/*.go", []string{"src/a/b/util.go", "src/a/b/util_test.go", "src/main.go"}},
+		{"**/*_test.go", []string{"src/a/b/util_test.go"}},
+		{"src*/*.go", []string{"src/main.go", "srcx/other.go"}},
+		{"vendor/**", []string{"vendor/lib/lib.go"}},
+		{"src/a/b/util.go", []string{"src/a/b/util.go"}},
+		{"/abs/*", []string{"/abs/file"}},
+		{"nothing/**", []string{}},
+	}
+	for _, test := range matchTests {
+		got, err := x.Match(test.pattern)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Match(%q) Expected:\n%v\nGot:\n%v\n", test.pattern, test.expected, got)
+		}
+		// Same results as matching every path with MatchGlobstar.
+		linear := []string{}
+		for _, p := range x.WithPrefix(".") {
+			ok, _ := MatchGlobstar(test.pattern, p)
+			if ok {
+				linear = append(linear, p)
+			}
+		}
+		if !reflect.DeepEqual(got, linear) {
+			t.Errorf("Match(%q) MatchGlobstar:\n%v\nGot:\n%v\n", test.pattern, linear, got)
+		}
+	}
+	_, err := x.Match("src/[")
+	if err == nil {
+		t.Errorf("Expected bad pattern error\n")
+	}
+
+	ancestorTests := []struct {
+		p        string
+		expected string
+		ok       bool
+	}{
+		{"src/a/b/c/d.go", "src", true},
+		{"src/main.go/x", "src/main.go", true},
+		{"src/main.go", "src/main.go", true},
+		{"/abs/file/deeper", "/abs/file", true},
+		{"vendor/x", "", false},
+	}
+	for _, test := range ancestorTests {
+		got, ok := x.Ancestor(test.p)
+		if got != test.expected || ok != test.ok {
+			t.Errorf("Ancestor(%q) Expected: %q %v, Got: %q %v\n", test.p, test.expected, test.ok, got, ok)
+		}
+	}
+}
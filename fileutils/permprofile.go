@@ -0,0 +1,294 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PermissionRule - The mode, owner and group enforced on the entries
+// matching Pattern, see ApplyPermissionProfile.
+type PermissionRule struct {
+	// Pattern - Glob matched against the entry name, or with a '/' against
+	// the slash separated path relative to the root, like in RemoveMatching.
+	// Only "." matches the root itself.
+	Pattern string
+
+	// Type - 'f' only matches files, 'd' only dirs and 0 both.
+	Type byte
+
+	// Mode - Unix permission bits, like 0644 or 04755, -1 leaves the mode
+	// unchanged.
+	Mode int
+
+	// UID and GID - Owner and group, -1 leaves them unchanged.
+	UID int
+	GID int
+}
+
+// PermissionProfile - Rules applied in order, later matching rules
+// override the fields set by earlier ones, so general rules go first.
+type PermissionProfile []PermissionRule
+
+// PermissionChange - An entry whose mode, owner or group differs from its
+// profile. The Old fields hold the values found, the others the values
+// required, UID and GID are -1 when not available.
+type PermissionChange struct {
+	Path    string      `json:"path"`
+	Mode    os.FileMode `json:"mode"`
+	OldMode os.FileMode `json:"old_mode"`
+	UID     int         `json:"uid"`
+	OldUID  int         `json:"old_uid"`
+	GID     int         `json:"gid"`
+	OldGID  int         `json:"old_gid"`
+}
+
+// PermissionOption - ApplyPermissionProfile option.
+type PermissionOption func(*permissionOptions)
+
+type permissionOptions struct {
+	dryRun bool
+}
+
+// PermissionDryRun - Only audits the tree, returns the changes required
+// without applying them.
+func PermissionDryRun() PermissionOption {
+	return func(o *permissionOptions) {
+		o.dryRun = true
+	}
+}
+
+// ParsePermissionProfile - Reads a profile, one rule per line, in a
+// simplified tmpfiles.d format:
+//
+//	# type glob mode user group
+//	-  **        0644  root  root
+//	d  **        0755  -     -
+//	f  bin/*     0755  -     -
+//	f  secret.*  0600  app   -
+//
+// The type is 'f', 'd' or '-' for both. The mode is octal, users and groups
+// are names or numeric ids, and '-' leaves the field unchanged.
+// Empty lines and lines starting with '#' are skipped.
+func ParsePermissionProfile(r io.Reader) (PermissionProfile, error) {
+	profile := PermissionProfile{}
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parsePermissionRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		profile = append(profile, rule)
+	}
+	return profile, scanner.Err()
+}
+
+func parsePermissionRule(line string) (PermissionRule, error) {
+	rule := PermissionRule{Mode: -1, UID: -1, GID: -1}
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return rule, fmt.Errorf("expected 5 fields, type glob mode user group: '%s'", line)
+	}
+	switch fields[0] {
+	case "f", "d":
+		rule.Type = fields[0][0]
+	case "-":
+	default:
+		return rule, fmt.Errorf("invalid type '%s', use f, d or -", fields[0])
+	}
+	rule.Pattern = fields[1]
+	if err := checkGlob(rule.Pattern); err != nil {
+		return rule, fmt.Errorf("invalid glob '%s': %w", rule.Pattern, err)
+	}
+	if fields[2] != "-" {
+		mode, err := strconv.ParseUint(fields[2], 8, 32)
+		if err != nil || mode > 07777 {
+			return rule, fmt.Errorf("invalid mode '%s'", fields[2])
+		}
+		rule.Mode = int(mode)
+	}
+	var err error
+	if fields[3] != "-" {
+		rule.UID, err = lookupID(fields[3], func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return rule, err
+		}
+	}
+	if fields[4] != "-" {
+		rule.GID, err = lookupID(fields[4], func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return rule, err
+		}
+	}
+	return rule, nil
+}
+
+// lookupID - Returns the numeric id for name, which can already be one.
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	s, err := lookup(name)
+	if err != nil {
+		return -1, err
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return -1, fmt.Errorf("%w: non numeric id '%s' for '%s'", ErrNotSupported, s, name)
+	}
+	return id, nil
+}
+
+// ApplyPermissionProfile - Enforces profile on root and the entries under
+// it: sets the mode, owner and group of each entry to the ones required by
+// the rules it matches. Entries no rule matches and symlinks are left alone.
+//
+// Returns the entries that were changed, each dir after its contents, or
+// with PermissionDryRun the ones that would be, so the same profile can be
+// used to audit a tree.
+// Changing the owner usually requires running as root, owners are not
+// available on Windows.
+func ApplyPermissionProfile(root string, profile PermissionProfile, opts ...PermissionOption) ([]PermissionChange, error) {
+	o := &permissionOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for _, rule := range profile {
+		if err := checkGlob(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid glob '%s': %w", rule.Pattern, err)
+		}
+	}
+	if !o.dryRun {
+		if err := checkWritable("ApplyPermissionProfile", root); err != nil {
+			return nil, err
+		}
+	}
+	changes := []PermissionChange{}
+	check := func(path, rel string) error {
+		fInfo, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if fInfo.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		c, ok, err := profile.change(path, rel, fInfo)
+		if err != nil || !ok {
+			return err
+		}
+		if !o.dryRun {
+			err = c.apply()
+			if err != nil {
+				return err
+			}
+		}
+		changes = append(changes, c)
+		return nil
+	}
+	// Dirs are changed after their contents, a mode without the execute
+	// bits would stop the walk otherwise.
+	err := Walk(root, func(path string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return check(path, filepath.ToSlash(rel))
+	}, ListOrder(PostOrder))
+	if err != nil {
+		return changes, err
+	}
+	return changes, check(root, ".")
+}
+
+// change - Returns the change required for the entry at path, relative to
+// the root as rel, and whether there is one.
+func (p PermissionProfile) change(path, rel string, fInfo os.FileInfo) (PermissionChange, bool, error) {
+	perm := fInfo.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	c := PermissionChange{Path: path, Mode: perm, OldMode: perm, UID: -1, OldUID: -1, GID: -1, OldGID: -1}
+	uid, gid, owned := fileOwner(fInfo)
+	if owned {
+		c.UID, c.OldUID, c.GID, c.OldGID = uid, uid, gid, gid
+	}
+	for _, rule := range p {
+		if rule.Type == 'f' && fInfo.IsDir() || rule.Type == 'd' && !fInfo.IsDir() {
+			continue
+		}
+		if rel == "." && rule.Pattern != "." || rel != "." && !matchGlob(rule.Pattern, rel) {
+			continue
+		}
+		if rule.Mode >= 0 {
+			c.Mode = unixMode(rule.Mode)
+		}
+		if (rule.UID >= 0 || rule.GID >= 0) && !owned {
+			return c, false, fmt.Errorf("%w: owner of '%s'", ErrNotSupported, path)
+		}
+		if rule.UID >= 0 {
+			c.UID = rule.UID
+		}
+		if rule.GID >= 0 {
+			c.GID = rule.GID
+		}
+	}
+	return c, c.Mode != c.OldMode || c.UID != c.OldUID || c.GID != c.OldGID, nil
+}
+
+// apply - Applies the owner, group and mode of c.
+func (c PermissionChange) apply() error {
+	if c.UID != c.OldUID || c.GID != c.OldGID {
+		err := os.Lchown(c.Path, c.UID, c.GID)
+		if err != nil {
+			return err
+		}
+	}
+	// Set after chown, that clears the setuid and setgid bits.
+	return os.Chmod(c.Path, c.Mode)
+}
+
+// unixMode - Converts unix permission bits, like 04755, to an os.FileMode.
+func unixMode(mode int) os.FileMode {
+	m := os.FileMode(mode) & os.ModePerm
+	if mode&04000 != 0 {
+		m |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		m |= os.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		m |= os.ModeSticky
+	}
+	return m
+}
@@ -0,0 +1,148 @@
+package fileutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestApplyPermissionProfile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	files := map[string]os.FileMode{
+		"bin/run":         0644,
+		"bin/tool.sh":     0755,
+		"data/secret.key": 0644,
+		"readme":          0666,
+	}
+	for name, mode := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0777)
+		ioutil.WriteFile(path, []byte{}, mode)
+		os.Chmod(path, mode)
+	}
+	for _, d := range []string{"", "bin", "data"} {
+		os.Chmod(filepath.Join(root, d), 0777)
+	}
+	os.Symlink("readme", filepath.Join(root, "link"))
+
+	profile, err := ParsePermissionProfile(strings.NewReader(fmt.Sprintf(`
+# type glob mode user group
+-  **        0644  -  -
+d  **        0755  -  -
+f  bin/*     0755  -  -
+f  secret.*  0600  %d  %d
+d  .         0750  -  -
+`, os.Getuid(), os.Getgid())))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := map[string]os.FileMode{
+		"bin/run":         0755,
+		"data/secret.key": 0600,
+		"readme":          0644,
+		"bin":             0755,
+		"data":            0755,
+		".":               0750,
+	}
+	changed := func(changes []PermissionChange) map[string]os.FileMode {
+		got := map[string]os.FileMode{}
+		for _, c := range changes {
+			rel, _ := filepath.Rel(root, c.Path)
+			got[rel] = c.Mode
+		}
+		return got
+	}
+
+	changes, err := ApplyPermissionProfile(root, profile, PermissionDryRun())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if got := changed(changes); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+	if changes[len(changes)-1].Path != root {
+		t.Errorf("Expected the root last, got '%s'\n", changes[len(changes)-1].Path)
+	}
+	fInfo, _ := os.Stat(filepath.Join(root, "readme"))
+	if fInfo.Mode().Perm() != 0666 {
+		t.Errorf("Dry run changed the mode: %s\n", fInfo.Mode())
+	}
+
+	changes, err = ApplyPermissionProfile(root, profile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if got := changed(changes); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+	for rel, mode := range expected {
+		fInfo, err := os.Stat(filepath.Join(root, rel))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if fInfo.Mode().Perm() != mode {
+			t.Errorf("%s: Expected:\n%s\nGot:\n%s\n", rel, mode, fInfo.Mode().Perm())
+		}
+	}
+
+	changes, err = ApplyPermissionProfile(root, profile, PermissionDryRun())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v\n", changes)
+	}
+}
+
+func TestParsePermissionProfile(t *testing.T) {
+	profile, err := ParsePermissionProfile(strings.NewReader("d  a/**  02775  0  -\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := PermissionProfile{{Pattern: "a/**", Type: 'd', Mode: 02775, UID: 0, GID: -1}}
+	if !reflect.DeepEqual(profile, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, profile)
+	}
+	if unixMode(02775) != os.ModeSetgid|0775 {
+		t.Errorf("Unexpected mode: %s\n", unixMode(02775))
+	}
+	for _, line := range []string{
+		"- ** 0644 -",
+		"x ** 0644 - -",
+		"- ** 0999 - -",
+		"- ** 017777 - -",
+		"- [ 0644 - -",
+		"- ** 0644 no-such-user-xyz -",
+	} {
+		_, err := ParsePermissionProfile(strings.NewReader(line))
+		if err == nil {
+			t.Errorf("Expected error for '%s'\n", line)
+		}
+	}
+}
+
+func TestPermissionChangeJSON(t *testing.T) {
+	c := PermissionChange{Path: "a", Mode: 0644, OldMode: 0600, UID: 1000, OldUID: 0, GID: -1, OldGID: -1}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := `{"path":"a","mode":420,"old_mode":384,"uid":1000,"old_uid":0,"gid":-1,"old_gid":-1}`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, data)
+	}
+}
@@ -0,0 +1,164 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// PortabilityIssue - Kind of problem reported by LintPortability.
+type PortabilityIssue int
+
+const (
+	// PortabilityLongPath - The path is longer than the Windows MAX_PATH
+	// limit, see PortabilityMaxPath.
+	PortabilityLongPath PortabilityIssue = iota
+
+	// PortabilityTrailingDotSpace - The name ends with a dot or a space,
+	// which Windows strips.
+	PortabilityTrailingDotSpace
+
+	// PortabilityReservedName - The name is a Windows device name, like
+	// CON or NUL, with or without extension.
+	PortabilityReservedName
+
+	// PortabilityInvalidChar - The name has a character Windows doesn't
+	// allow, one of <>:"\|?* or a control character.
+	PortabilityInvalidChar
+
+	// PortabilityNotUTF8 - The name is not valid UTF-8.
+	PortabilityNotUTF8
+
+	// PortabilityCaseConflict - The name only differs in case from another
+	// one in the same dir, they collide on case-insensitive filesystems.
+	PortabilityCaseConflict
+)
+
+func (i PortabilityIssue) String() string {
+	switch i {
+	case PortabilityLongPath:
+		return "path too long"
+	case PortabilityTrailingDotSpace:
+		return "trailing dot or space"
+	case PortabilityReservedName:
+		return "reserved name"
+	case PortabilityInvalidChar:
+		return "invalid character"
+	case PortabilityNotUTF8:
+		return "not UTF-8"
+	case PortabilityCaseConflict:
+		return "case conflict"
+	}
+	return fmt.Sprintf("PortabilityIssue(%d)", int(i))
+}
+
+// MarshalText - Encodes the issue as its String, so it reads as text in
+// JSON reports.
+func (i PortabilityIssue) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// PortabilityFinding - A path with a portability issue, see LintPortability.
+type PortabilityFinding struct {
+	// Path - Slash separated path relative to the linted root.
+	Path    string           `json:"path"`
+	Issue   PortabilityIssue `json:"issue"`
+	Message string           `json:"message"`
+}
+
+// PortabilityOption - LintPortability option.
+type PortabilityOption func(*portabilityOptions)
+
+type portabilityOptions struct {
+	maxPath int
+}
+
+// PortabilityMaxPath - Sets the longest relative path allowed, in UTF-16
+// code units as Windows counts them, 259 by default: MAX_PATH without the
+// terminating NUL. Subtract the length of the install dir the tree is
+// extracted into.
+func PortabilityMaxPath(n int) PortabilityOption {
+	return func(o *portabilityOptions) {
+		o.maxPath = n
+	}
+}
+
+// windowsReserved - Device names Windows reserves in every dir.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// LintPortability - Reports the entries under root whose paths would break
+// on other platforms, for cross-platform release packaging: paths over the
+// Windows MAX_PATH limit, names ending with a dot or a space, Windows device
+// names and characters, names that are not valid UTF-8 and names that only
+// differ in case.
+// Findings follow the package listing order, a path can have several.
+func LintPortability(root string, opts ...PortabilityOption) ([]PortabilityFinding, error) {
+	o := &portabilityOptions{maxPath: 259}
+	for _, opt := range opts {
+		opt(o)
+	}
+	findings := []PortabilityFinding{}
+	add := func(rel string, issue PortabilityIssue, format string, a ...interface{}) {
+		findings = append(findings, PortabilityFinding{Path: rel, Issue: issue, Message: fmt.Sprintf(format, a...)})
+	}
+	// folded - First path seen for each case folded path.
+	folded := map[string]string{}
+	err := Walk(root, func(p string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		name := path.Base(rel)
+
+		if n := len(utf16.Encode([]rune(rel))); n > o.maxPath {
+			add(rel, PortabilityLongPath, "path is %d characters long, the limit is %d", n, o.maxPath)
+		}
+		if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+			add(rel, PortabilityTrailingDotSpace, "name '%s' ends with a dot or a space", name)
+		}
+		base := strings.TrimRight(name, ". ")
+		if i := strings.IndexByte(base, '.'); i >= 0 {
+			base = base[:i]
+		}
+		if windowsReserved[strings.ToUpper(strings.TrimRight(base, " "))] {
+			add(rel, PortabilityReservedName, "name '%s' is reserved on Windows", name)
+		}
+		if i := strings.IndexFunc(name, invalidWindowsRune); i >= 0 {
+			add(rel, PortabilityInvalidChar, "name '%s' has the invalid character %q", name, name[i])
+		}
+		if !utf8.ValidString(name) {
+			add(rel, PortabilityNotUTF8, "name %q is not valid UTF-8", name)
+		}
+		key := strings.ToLower(path.Dir(rel)) + "/" + strings.ToLower(name)
+		if other, ok := folded[key]; ok {
+			add(rel, PortabilityCaseConflict, "name '%s' conflicts with '%s' on case-insensitive filesystems", name, other)
+		} else {
+			folded[key] = rel
+		}
+		return nil
+	})
+	return findings, err
+}
+
+func invalidWindowsRune(r rune) bool {
+	return r < 32 || strings.ContainsRune(`<>:"\|?*`, r)
+}
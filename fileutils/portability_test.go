@@ -0,0 +1,75 @@
+package fileutils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLintPortability(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("needs a case-sensitive filesystem that allows any name")
+	}
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	long := strings.Repeat("d", 200)
+	os.MkdirAll(filepath.Join(dir, long, long), 0755)
+	for _, name := range []string{"CON.txt", "com1", "console", "file.", "a:b", "bad\xff", "Readme", "README", "ok.txt"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644)
+	}
+
+	findings, err := LintPortability(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got := map[string][]PortabilityIssue{}
+	for _, f := range findings {
+		got[f.Path] = append(got[f.Path], f.Issue)
+		if f.Message == "" {
+			t.Errorf("Expected a message for '%s'\n", f.Path)
+		}
+	}
+	expected := map[string][]PortabilityIssue{
+		"CON.txt":         {PortabilityReservedName},
+		"com1":            {PortabilityReservedName},
+		"file.":           {PortabilityTrailingDotSpace},
+		"a:b":             {PortabilityInvalidChar},
+		"bad\xff":         {PortabilityNotUTF8},
+		"Readme":          {PortabilityCaseConflict},
+		long + "/" + long: {PortabilityLongPath},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+
+	findings, err = LintPortability(filepath.Join(dir, long), PortabilityMaxPath(100))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(findings) != 1 || findings[0].Path != long || findings[0].Issue != PortabilityLongPath {
+		t.Errorf("Unexpected findings: %v\n", findings)
+	}
+	if PortabilityCaseConflict.String() != "case conflict" {
+		t.Errorf("Unexpected issue name: %s\n", PortabilityCaseConflict)
+	}
+}
+
+func TestPortabilityFindingJSON(t *testing.T) {
+	f := PortabilityFinding{Path: "a/con.txt", Issue: PortabilityReservedName, Message: "m"}
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := `{"path":"a/con.txt","issue":"reserved name","message":"m"}`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, data)
+	}
+}
@@ -0,0 +1,194 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings reported by Preview.
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+	EncodingLatin1  = "iso-8859-1"
+)
+
+// FilePreview - The first and last portions of a file, see Preview.
+type FilePreview struct {
+	// Head - The start of the file, the whole file when it fits in the
+	// head and tail sizes.
+	Head string
+	// Tail - The end of the file, empty unless Truncated.
+	Tail string
+	// Size - The size of the file in bytes.
+	Size int64
+	// Truncated - Whether part of the file between Head and Tail was skipped.
+	Truncated bool
+	// Binary - Whether the file is binary, see Grep, Head and Tail are
+	// empty then.
+	Binary bool
+	// Encoding - The encoding the contents were decoded from.
+	Encoding string
+}
+
+// Preview - Returns up to headBytes from the start and tailBytes from the
+// end of path as UTF-8 text, for file previews in listings.
+// A byte order mark selects UTF-8, UTF-16LE or UTF-16BE, and is removed,
+// otherwise the contents are UTF-8 when valid and ISO-8859-1 when not.
+// Characters split by the cuts are dropped, so the portions can be slightly
+// shorter than requested.
+// Binary files, those with a NUL byte near the start, are reported without
+// contents.
+func Preview(path string, headBytes, tailBytes int) (FilePreview, error) {
+	p := FilePreview{}
+	fh, err := os.Open(path)
+	if err != nil {
+		return p, err
+	}
+	defer fh.Close()
+	fInfo, err := fh.Stat()
+	if err != nil {
+		return p, err
+	}
+	p.Size = fInfo.Size()
+	if headBytes < 0 {
+		headBytes = 0
+	}
+	if tailBytes < 0 {
+		tailBytes = 0
+	}
+	p.Truncated = p.Size > int64(headBytes)+int64(tailBytes)
+
+	n := headBytes
+	if !p.Truncated {
+		n = int(p.Size)
+	}
+	if n < binaryCheckSize {
+		n = binaryCheckSize
+	}
+	head := make([]byte, n)
+	n, err = io.ReadFull(fh, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return p, err
+	}
+	head = head[:n]
+
+	bom := 0
+	switch {
+	case bytes.HasPrefix(head, []byte{0xef, 0xbb, 0xbf}):
+		p.Encoding, bom = EncodingUTF8, 3
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		p.Encoding, bom = EncodingUTF16LE, 2
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}):
+		p.Encoding, bom = EncodingUTF16BE, 2
+	}
+	if p.Encoding == "" && isBinary(head) {
+		p.Binary = true
+		return p, nil
+	}
+	if !p.Truncated {
+		if p.Encoding == "" {
+			p.Encoding = EncodingUTF8
+			if !utf8.Valid(head) {
+				p.Encoding = EncodingLatin1
+			}
+		}
+		p.Head = decodePreview(head[bom:], p.Encoding, false, false)
+		return p, nil
+	}
+	if len(head) > headBytes {
+		head = head[:headBytes]
+	}
+	if len(head) < bom {
+		head = head[:0]
+	} else {
+		head = head[bom:]
+	}
+
+	offset := p.Size - int64(tailBytes)
+	if p.Encoding == EncodingUTF16LE || p.Encoding == EncodingUTF16BE {
+		// Keep the code units aligned, the BOM is 2 bytes.
+		offset += offset % 2
+	}
+	tail := make([]byte, p.Size-offset)
+	n, err = fh.ReadAt(tail, offset)
+	if err != nil && err != io.EOF {
+		return p, err
+	}
+	tail = tail[:n]
+
+	if p.Encoding == "" {
+		p.Encoding = EncodingUTF8
+		if !utf8.Valid(trimPartialRunes(head, false, true)) || !utf8.Valid(trimPartialRunes(tail, true, false)) {
+			p.Encoding = EncodingLatin1
+		}
+	}
+	p.Head = decodePreview(head, p.Encoding, false, true)
+	p.Tail = decodePreview(tail, p.Encoding, true, false)
+	return p, nil
+}
+
+// decodePreview - Decodes b from encoding to UTF-8, dropping the characters
+// split at the start or end of b when cutStart or cutEnd are set.
+func decodePreview(b []byte, encoding string, cutStart, cutEnd bool) string {
+	switch encoding {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		if len(b)%2 == 1 {
+			b = b[:len(b)-1]
+		}
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i < len(b); i += 2 {
+			if encoding == EncodingUTF16LE {
+				units = append(units, uint16(b[i])|uint16(b[i+1])<<8)
+			} else {
+				units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+			}
+		}
+		if cutStart && len(units) > 0 && utf16.IsSurrogate(rune(units[0])) && units[0] >= 0xdc00 {
+			units = units[1:]
+		}
+		if cutEnd && len(units) > 0 && utf16.IsSurrogate(rune(units[len(units)-1])) && units[len(units)-1] < 0xdc00 {
+			units = units[:len(units)-1]
+		}
+		return string(utf16.Decode(units))
+	case EncodingLatin1:
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+	return string(trimPartialRunes(b, cutStart, cutEnd))
+}
+
+// trimPartialRunes - Drops the bytes of the UTF-8 characters split at the
+// start or end of b.
+func trimPartialRunes(b []byte, start, end bool) []byte {
+	if start {
+		for i := 0; i < utf8.UTFMax-1 && len(b) > 0 && !utf8.RuneStart(b[0]); i++ {
+			b = b[1:]
+		}
+	}
+	if end {
+		for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(b[i]) {
+				if !utf8.FullRune(b[i:]) {
+					b = b[:i]
+				}
+				break
+			}
+		}
+	}
+	return b
+}
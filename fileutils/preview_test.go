@@ -0,0 +1,57 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	long := strings.Repeat("x", 100)
+	tests := []struct {
+		name     string
+		data     []byte
+		head     int
+		tail     int
+		expected FilePreview
+	}{
+		{"empty", []byte{}, 4, 4, FilePreview{Encoding: EncodingUTF8}},
+		{"fits", []byte("hello\nworld\n"), 8, 8, FilePreview{Head: "hello\nworld\n", Size: 12, Encoding: EncodingUTF8}},
+		{"truncated", []byte("head" + long + "tail"), 4, 4, FilePreview{Head: "head", Tail: "tail", Size: 108, Truncated: true, Encoding: EncodingUTF8}},
+		{"no tail", []byte("head" + long), 4, 0, FilePreview{Head: "head", Size: 104, Truncated: true, Encoding: EncodingUTF8}},
+		{"split runes", []byte("añ" + long + "ña"), 2, 2, FilePreview{Head: "a", Tail: "a", Size: 106, Truncated: true, Encoding: EncodingUTF8}},
+		{"utf-8 bom", []byte("\xef\xbb\xbfhello"), 10, 10, FilePreview{Head: "hello", Size: 8, Encoding: EncodingUTF8}},
+		{"utf-16le", []byte("\xff\xfeh\x00i\x00\x3d\xd8\x00\xde"), 10, 10, FilePreview{Head: "hi😀", Size: 10, Encoding: EncodingUTF16LE}},
+		{"utf-16be truncated", []byte("\xfe\xff\x00h\x00i" + long + "\x00y\x00o"), 6, 3, FilePreview{Head: "hi", Tail: "o", Size: 110, Truncated: true, Encoding: EncodingUTF16BE}},
+		{"latin-1", []byte("caf\xe9"), 10, 10, FilePreview{Head: "café", Size: 4, Encoding: EncodingLatin1}},
+		{"binary", []byte("ELF\x00\x01\x02"), 10, 10, FilePreview{Size: 6, Binary: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(dir, "file")
+			err := ioutil.WriteFile(file, test.data, 0644)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			p, err := Preview(file, test.head, test.tail)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if !reflect.DeepEqual(p, test.expected) {
+				t.Errorf("Expected:\n%#v\nGot:\n%#v\n", test.expected, p)
+			}
+		})
+	}
+	_, err = Preview(filepath.Join(dir, "missing"), 10, 10)
+	if err == nil {
+		t.Errorf("Expected error for a missing file\n")
+	}
+}
@@ -0,0 +1,206 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ProcessedCache - Persists the SHA-256 digest of processed files so
+// incremental re-runs over big trees only process the files that changed.
+//
+// Files are identified by their absolute path. A file whose size and
+// modification time match the recorded ones is considered unchanged without
+// being read, otherwise it is hashed and compared with the recorded digest.
+//
+// Record files with Done after processing them, so a processor that
+// modifies its input, like a search and replace, records its output.
+// Use a different cache file for each kind of processing, or when its
+// parameters change.
+// It is safe for concurrent use.
+type ProcessedCache struct {
+	filename string
+	mu       sync.Mutex
+	files    map[string]processedEntry
+}
+
+type processedEntry struct {
+	Hash    string    `json:"sha256"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// LoadProcessedCache - Loads the cache saved in filename.
+// A missing file results in an empty cache that will be saved to filename.
+func LoadProcessedCache(filename string) (*ProcessedCache, error) {
+	c := &ProcessedCache{filename: filename, files: map[string]processedEntry{}}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, &c.files)
+	if err != nil {
+		return nil, fmt.Errorf("'%s': invalid processed cache: %w", filename, err)
+	}
+	return c, nil
+}
+
+// Save - Writes the cache to its file atomically.
+func (c *ProcessedCache) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.files, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(c.filename, append(data, '\n'), 0644)
+}
+
+// Changed - Whether file changed since it was recorded with Done, files
+// that were never recorded are changed.
+func (c *ProcessedCache) Changed(file string) (bool, error) {
+	abs, fInfo, err := c.stat(file)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	e, ok := c.files[abs]
+	c.mu.Unlock()
+	if !ok {
+		return true, nil
+	}
+	if e.Size == fInfo.Size() && e.ModTime.Equal(fInfo.ModTime()) {
+		return false, nil
+	}
+	if e.Size != fInfo.Size() {
+		return true, nil
+	}
+	sum, err := sha256File(file)
+	if err != nil {
+		return false, err
+	}
+	if sum != e.Hash {
+		return true, nil
+	}
+	// Touched but not modified, refresh the times to avoid hashing it again.
+	c.record(abs, sum, fInfo)
+	return false, nil
+}
+
+// Done - Records the current digest of file as processed.
+func (c *ProcessedCache) Done(file string) error {
+	_, err := c.Hash(file)
+	return err
+}
+
+// Hash - Returns the hex encoded SHA-256 of file and records it as processed.
+// The recorded digest is returned without reading the file when it is unchanged.
+func (c *ProcessedCache) Hash(file string) (string, error) {
+	abs, fInfo, err := c.stat(file)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	e, ok := c.files[abs]
+	c.mu.Unlock()
+	if ok && e.Size == fInfo.Size() && e.ModTime.Equal(fInfo.ModTime()) {
+		return e.Hash, nil
+	}
+	sum, err := sha256File(file)
+	if err != nil {
+		return "", err
+	}
+	c.record(abs, sum, fInfo)
+	return sum, nil
+}
+
+// Forget - Removes file from the cache so it is processed again.
+func (c *ProcessedCache) Forget(file string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	delete(c.files, abs)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *ProcessedCache) stat(file string) (string, os.FileInfo, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", nil, err
+	}
+	fInfo, err := os.Stat(file)
+	if err != nil {
+		return "", nil, err
+	}
+	return abs, fInfo, nil
+}
+
+func (c *ProcessedCache) record(abs, sum string, fInfo os.FileInfo) {
+	c.mu.Lock()
+	c.files[abs] = processedEntry{Hash: sum, Size: fInfo.Size(), ModTime: fInfo.ModTime()}
+	c.mu.Unlock()
+}
+
+func sha256File(filename string) (string, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, fh)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashTreeCached - Same as hashdir.HashTree with crypto.SHA256, but files
+// that are unchanged since the last run are not read, their digest comes
+// from c. Save c afterwards to reuse the digests in the next run.
+func HashTreeCached(dir string, c *ProcessedCache) (map[string]string, error) {
+	sums := map[string]string{}
+	err := Walk(dir, func(path string, isDir bool, err error) error {
+		if err != nil || isDir {
+			return err
+		}
+		fInfo, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if !fInfo.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sums[filepath.ToSlash(rel)], err = c.Hash(path)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
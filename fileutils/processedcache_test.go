@@ -0,0 +1,111 @@
+package fileutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProcessedCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	state := filepath.Join(dir, "state.json")
+	file := filepath.Join(dir, "tree", "a")
+	os.MkdirAll(filepath.Dir(file), 0755)
+	ioutil.WriteFile(file, []byte("hello"), 0644)
+
+	c, err := LoadProcessedCache(state)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	changed, err := c.Changed(file)
+	if err != nil || !changed {
+		t.Errorf("Expected new file to be changed, got: %v, %v\n", changed, err)
+	}
+	err = c.Done(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = c.Save()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	c, err = LoadProcessedCache(state)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	tests := []struct {
+		name     string
+		change   func()
+		expected bool
+	}{
+		{"unchanged", func() {}, false},
+		{"touched", func() {
+			future := time.Now().Add(time.Hour)
+			os.Chtimes(file, future, future)
+		}, false},
+		{"same size", func() { ioutil.WriteFile(file, []byte("hola!"), 0644) }, true},
+		{"size", func() { ioutil.WriteFile(file, []byte("hello world"), 0644) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			changed, err := c.Changed(file)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if changed != tt.expected {
+				t.Errorf("Expected:\n%v\nGot:\n%v\n", tt.expected, changed)
+			}
+			c.Done(file)
+		})
+	}
+	c.Forget(file)
+	if changed, _ := c.Changed(file); !changed {
+		t.Errorf("Expected forgotten file to be changed\n")
+	}
+	_, err = c.Changed(filepath.Join(dir, "missing"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+
+	ioutil.WriteFile(state, []byte("{"), 0644)
+	_, err = LoadProcessedCache(state)
+	if err == nil {
+		t.Errorf("Expected invalid cache error\n")
+	}
+}
+
+func TestHashTreeCached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	c, _ := LoadProcessedCache(filepath.Join(dir, "state.json"))
+	data, _ := ioutil.ReadFile("test_tree/A/b/C/d/E")
+	sum := sha256.Sum256(data)
+	expected, err := HashTreeCached("test_tree", c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if expected["A/b/C/d/E"] != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected:\n%x\nGot:\n%s\n", sum, expected["A/b/C/d/E"])
+	}
+	got, err := HashTreeCached("test_tree", c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+}
@@ -0,0 +1,203 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// HandlerFunc - Processes a file found by ProcessTree.
+// path is the path of the file under the processed dir.
+type HandlerFunc func(ctx context.Context, path string) error
+
+// Registry - Maps globs to the handlers ProcessTree routes files to, the
+// backbone for linters and formatters that handle each file type their own
+// way.
+//
+//	r := fileutils.NewRegistry()
+//	r.Handle("**/testdata/**", nil)
+//	r.HandleExt(gofmt, ".go")
+//
+// Register the handlers before processing, registering is not safe for
+// concurrent use.
+type Registry struct {
+	handlers []registeredHandler
+}
+
+type registeredHandler struct {
+	pattern string
+	fn      HandlerFunc
+}
+
+// NewRegistry - Returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Handle - Routes the files matching the glob to fn.
+// Globs without a '/' are matched against the file name, globs with a '/'
+// against the slash separated path relative to the processed dir, where
+// "**" matches any number of dirs, see MatchGlobstar.
+// When several globs match a file, the first one registered wins, a nil fn
+// skips the files it matches.
+// Returns path.ErrBadPattern when the glob is malformed.
+func (r *Registry) Handle(pattern string, fn HandlerFunc) error {
+	err := checkGlob(pattern)
+	if err != nil {
+		return fmt.Errorf("'%s': %w", pattern, err)
+	}
+	r.handlers = append(r.handlers, registeredHandler{pattern, fn})
+	return nil
+}
+
+// HandleExt - Routes the files with the given extensions, like ".go", to fn.
+func (r *Registry) HandleExt(fn HandlerFunc, exts ...string) error {
+	for _, ext := range exts {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		err := r.Handle("*"+ext, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler - Returns the handler for the slash separated relative path, or
+// nil when no glob matches it.
+func (r *Registry) Handler(rel string) HandlerFunc {
+	for _, h := range r.handlers {
+		if matchGlob(h.pattern, rel) {
+			return h.fn
+		}
+	}
+	return nil
+}
+
+// ProcessOption - ProcessTree option.
+type ProcessOption func(*processOptions)
+
+type processOptions struct {
+	exclude     []string
+	concurrency int
+	control     *Control
+}
+
+// ProcessExclude - Skips the files and dirs that match one of the globs,
+// with the same semantics as ListExclude.
+func ProcessExclude(globs ...string) ProcessOption {
+	return func(o *processOptions) {
+		o.exclude = append(o.exclude, globs...)
+	}
+}
+
+// ProcessConcurrency - Maximum number of files processed at the same time.
+// Defaults to one per CPU.
+func ProcessConcurrency(n int) ProcessOption {
+	return func(o *processOptions) {
+		o.concurrency = n
+	}
+}
+
+// ProcessControl - Lets c pause and cancel the processing between files.
+func ProcessControl(c *Control) ProcessOption {
+	return func(o *processOptions) {
+		o.control = c
+	}
+}
+
+// ProcessTree - Walks dir once and calls, concurrently, the handler
+// registered in r for each regular file.
+// Files without a handler and symlinks are skipped.
+//
+// Handler errors don't stop the other files from being processed, they are
+// sorted by path and joined, along with the walk error, ctx.Err() when ctx is
+// done, or ErrCanceled when the ProcessControl is canceled.
+func ProcessTree(ctx context.Context, dir string, r *Registry, opts ...ProcessOption) error {
+	o := processOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = runtime.NumCPU()
+	}
+	type job struct {
+		path string
+		fn   HandlerFunc
+	}
+	var mu sync.Mutex
+	var errs []pathError
+	fail := func(path string, err error) {
+		mu.Lock()
+		errs = append(errs, pathError{path, err})
+		mu.Unlock()
+	}
+	queue := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				err := j.fn(ctx, j.path)
+				if err != nil {
+					fail(j.path, err)
+				}
+			}
+		}()
+	}
+	err := Walk(dir, func(path string, isDir bool, err error) error {
+		if err != nil || isDir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		fn := r.Handler(filepath.ToSlash(rel))
+		if fn == nil {
+			return nil
+		}
+		fInfo, err := os.Lstat(path)
+		if err != nil {
+			fail(path, err)
+			return nil
+		}
+		if !fInfo.Mode().IsRegular() {
+			return nil
+		}
+		err = o.control.Wait()
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		select {
+		case queue <- job{path, fn}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, ListExclude(o.exclude...))
+	close(queue)
+	wg.Wait()
+	if err != nil {
+		// Sorted first.
+		errs = append(errs, pathError{"", err})
+	}
+	return joinPathErrors(errs)
+}
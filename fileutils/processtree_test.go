@@ -0,0 +1,77 @@
+package fileutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestProcessTree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.go", "b.md", "c.txt", "sub/d.go", "sub/testdata/e.go", "vendor/f.go", "sub/g.yaml", "sub/h.yml"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(name), 0644)
+	}
+	os.Symlink("a.go", filepath.Join(dir, "link.go"))
+
+	var mu sync.Mutex
+	got := []string{}
+	handler := func(kind string) HandlerFunc {
+		return func(ctx context.Context, path string) error {
+			rel, _ := filepath.Rel(dir, path)
+			mu.Lock()
+			got = append(got, kind+" "+filepath.ToSlash(rel))
+			mu.Unlock()
+			if filepath.Base(path) == "b.md" {
+				return fmt.Errorf("bad markdown")
+			}
+			return nil
+		}
+	}
+	r := NewRegistry()
+	err = r.Handle("**/testdata/**", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	r.HandleExt(handler("go"), ".go")
+	r.HandleExt(handler("yaml"), "yaml", "yml")
+	r.Handle("*.md", handler("md"))
+	err = ProcessTree(context.Background(), dir, r, ProcessExclude("vendor"), ProcessConcurrency(2))
+	if err == nil || err.Error() != "bad markdown" {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	sort.Strings(got)
+	expected := []string{"go a.go", "go sub/d.go", "md b.md", "yaml sub/g.yaml", "yaml sub/h.yml"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+
+	c := NewControl()
+	c.Cancel()
+	err = ProcessTree(context.Background(), dir, r, ProcessControl(c))
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got: %v\n", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = ProcessTree(ctx, dir, r)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v\n", err)
+	}
+	err = r.Handle("[", nil)
+	if err == nil {
+		t.Errorf("Expected bad pattern error\n")
+	}
+}
@@ -0,0 +1,51 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrReadOnlyMode - The operation would write to the file system while the
+// package is in read-only mode, see SetReadOnly.
+var ErrReadOnlyMode = fmt.Errorf("read-only mode")
+
+var readOnly atomic.Bool
+
+// SetReadOnly - Enables or disables read-only mode for the whole package.
+// In read-only mode the calls that modify the file system, like CopyFile,
+// ResumeCopy, CopyDir, Sync, MoveFile, RenameCaseOnly, SwapDirs,
+// StringReplace, RegexpReplace, ReplaceInTree, InsertLines, DeleteLines,
+// ReplaceLine, LineInFile, BlockInFile, WriteFileAtomic, EnsureDir, Touch,
+// RemoveMatching, PruneEmptyDirs, Trash, TrimDirToSize,
+// ApplyPermissionProfile, SetFileFlags, Metadata.Apply, NewEditSession and
+// EditSession.Commit, return ErrReadOnlyMode without touching anything, so
+// automation can be run in audit mode.
+// Sync with SyncDryRun, RemoveMatching and PruneEmptyDirs with
+// RemoveDryRun, ReplaceInTree with ReplaceDryRun, ApplyPermissionProfile
+// with PermissionDryRun, StringReplaceDiff and the reading and listing
+// calls work as usual.
+func SetReadOnly(enabled bool) {
+	readOnly.Store(enabled)
+}
+
+// IsReadOnly - Whether read-only mode is enabled, see SetReadOnly.
+func IsReadOnly() bool {
+	return readOnly.Load()
+}
+
+// checkWritable - Returns ErrReadOnlyMode, naming the operation and path,
+// when read-only mode is enabled.
+func checkWritable(op, path string) error {
+	if readOnly.Load() {
+		return fmt.Errorf("%w: %s '%s'", ErrReadOnlyMode, op, path)
+	}
+	return nil
+}
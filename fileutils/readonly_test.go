@@ -0,0 +1,135 @@
+package fileutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSetReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "a"), []byte("hello"), 0644)
+	dst := filepath.Join(dir, "dst")
+
+	SetReadOnly(true)
+	defer SetReadOnly(false)
+	if !IsReadOnly() {
+		t.Fatalf("Expected read-only mode\n")
+	}
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"CopyFile", func() error { return CopyFile(filepath.Join(src, "a"), dst) }},
+		{"ResumeCopy", func() error { return ResumeCopy(filepath.Join(src, "a"), dst) }},
+		{"CopyDir", func() error { return CopyDir(src, dst) }},
+		{"MoveFile", func() error { return MoveFile(filepath.Join(src, "a"), dst) }},
+		{"RenameCaseOnly", func() error { return RenameCaseOnly(filepath.Join(src, "a"), filepath.Join(src, "A")) }},
+		{"SwapDirs", func() error { return SwapDirs(src, filepath.Join(src, "sub")) }},
+		{"Metadata.Apply", func() error {
+			m := Metadata{Mode: 0644, UID: -1, GID: -1}
+			return m.Apply(filepath.Join(src, "a"))
+		}},
+		{"WriteFileAtomic", func() error { return WriteFileAtomic(dst, []byte("x"), 0644) }},
+		{"InsertLines", func() error {
+			_, err := InsertLines(filepath.Join(src, "a"), 1, []string{"x"}, 64)
+			return err
+		}},
+		{"DeleteLines", func() error {
+			_, err := DeleteLines(filepath.Join(src, "a"), 1, 0, 64)
+			return err
+		}},
+		{"ReplaceLine", func() error {
+			_, err := ReplaceLine(filepath.Join(src, "a"), 1, "x", 64)
+			return err
+		}},
+		{"LineInFile", func() error {
+			_, err := LineInFile(filepath.Join(src, "a"), "x")
+			return err
+		}},
+		{"BlockInFile", func() error {
+			_, err := BlockInFile(filepath.Join(src, "a"), "# BEGIN", "# END", "x")
+			return err
+		}},
+		{"ReplaceInTree", func() error {
+			_, err := ReplaceInTree(src, "hello", "bye")
+			return err
+		}},
+		{"StringReplace", func() error {
+			_, err := StringReplace(filepath.Join(src, "a"), "hello", "bye", -1, 64)
+			return err
+		}},
+		{"RegexpReplace", func() error {
+			_, err := RegexpReplace(filepath.Join(src, "a"), regexp.MustCompile("h(ello)"), "j$1", 64)
+			return err
+		}},
+		{"RemoveMatching", func() error {
+			_, err := RemoveMatching(src, []string{"*"})
+			return err
+		}},
+		{"Sync", func() error {
+			_, err := Sync(src, dst)
+			return err
+		}},
+		{"PruneEmptyDirs", func() error {
+			_, err := PruneEmptyDirs(src)
+			return err
+		}},
+		{"Trash", func() error { return Trash(filepath.Join(src, "a")) }},
+		{"ApplyPermissionProfile", func() error {
+			_, err := ApplyPermissionProfile(src, PermissionProfile{{Pattern: "*", Mode: 0600, UID: -1, GID: -1}})
+			return err
+		}},
+		{"TrimDirToSize", func() error {
+			_, err := TrimDirToSize(src, 0, OldestFirst)
+			return err
+		}},
+		{"SetFileFlags", func() error { return SetFileFlags(filepath.Join(src, "a"), 0) }},
+		{"EnsureDir", func() error { return EnsureDir(filepath.Join(src, "d"), 0755) }},
+		{"Touch", func() error { return Touch(filepath.Join(src, "t")) }},
+		{"NewEditSession", func() error {
+			_, err := NewEditSession(src)
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.fn()
+			if !errors.Is(err, ErrReadOnlyMode) {
+				t.Errorf("Unexpected error: %v\n", err)
+			}
+		})
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("Unexpected dst: %v\n", err)
+	}
+	b, _ := ioutil.ReadFile(filepath.Join(src, "a"))
+	if string(b) != "hello" {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", "hello", b)
+	}
+
+	// Dry runs and reads still work.
+	got, err := RemoveMatching(src, []string{"a"}, RemoveDryRun())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, []string{filepath.Join(src, "a")}) {
+		t.Errorf("Unexpected dry run: %q\n", got)
+	}
+
+	SetReadOnly(false)
+	err = CopyFile(filepath.Join(src, "a"), dst)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+}
@@ -0,0 +1,195 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrProtectedPath - The operation would remove a protected path.
+var ErrProtectedPath = fmt.Errorf("protected path")
+
+// RemoveOption - RemoveMatching and PruneEmptyDirs option.
+type RemoveOption func(*removeOptions)
+
+type removeOptions struct {
+	dryRun    bool
+	protected []string
+}
+
+// RemoveDryRun - Returns what would be removed without removing anything.
+func RemoveDryRun() RemoveOption {
+	return func(o *removeOptions) {
+		o.dryRun = true
+	}
+}
+
+// RemoveProtect - Refuses to remove the given paths or any dir that contains them.
+// The filesystem root is always protected.
+func RemoveProtect(paths ...string) RemoveOption {
+	return func(o *removeOptions) {
+		o.protected = append(o.protected, paths...)
+	}
+}
+
+// RemoveMatching - Removes the files and dirs under root that match any of the globs.
+//
+// Globs without a '/' are matched against the entry name, globs with a '/'
+// against the slash separated path relative to root, where "**" matches any
+// number of dirs, see MatchGlobstar. A matching dir is removed with all its
+// contents.
+//
+// All matches are checked against the protected paths before anything is
+// removed. Returns the removed paths, in removal order, or with RemoveDryRun
+// the paths that would be removed.
+func RemoveMatching(root string, includeGlobs []string, opts ...RemoveOption) ([]string, error) {
+	o := &removeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for _, pattern := range includeGlobs {
+		if err := checkGlob(pattern); err != nil {
+			return nil, fmt.Errorf("invalid glob '%s': %w", pattern, err)
+		}
+	}
+	protected := []string{}
+	for _, p := range o.protected {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		protected = append(protected, abs)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if isProtected(absRoot, protected) {
+		return nil, fmt.Errorf("%w: '%s'", ErrProtectedPath, root)
+	}
+
+	matches := []string{}
+	err = Walk(root, func(p string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if !matchAny(includeGlobs, filepath.ToSlash(rel)) {
+			return nil
+		}
+		matches = append(matches, p)
+		if isDir {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range matches {
+		abs, err := filepath.Abs(m)
+		if err != nil {
+			return nil, err
+		}
+		if isProtected(abs, protected) {
+			return nil, fmt.Errorf("%w: '%s'", ErrProtectedPath, m)
+		}
+	}
+	if o.dryRun {
+		return matches, nil
+	}
+	if err := checkWritable("RemoveMatching", root); err != nil {
+		return nil, err
+	}
+	removed := []string{}
+	for _, m := range matches {
+		err := os.RemoveAll(m)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, m)
+	}
+	return removed, nil
+}
+
+// isProtected - Reports whether removing abs would remove the filesystem root or a protected path.
+func isProtected(abs string, protected []string) bool {
+	if filepath.Dir(abs) == abs {
+		return true
+	}
+	for _, p := range protected {
+		if p == abs || strings.HasPrefix(p, abs+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneEmptyDirs - Removes the dirs under dir that contain no files, only
+// other empty dirs, a cleanup step after filtered copies. dir itself is kept.
+// Symlinks count as files, protected dirs and the dirs containing them are
+// kept.
+// Returns the removed dirs, each after its subdirs, or with RemoveDryRun the
+// dirs that would be removed.
+func PruneEmptyDirs(dir string, opts ...RemoveOption) ([]string, error) {
+	o := &removeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.dryRun {
+		if err := checkWritable("PruneEmptyDirs", dir); err != nil {
+			return nil, err
+		}
+	}
+	protected := []string{}
+	for _, p := range o.protected {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		protected = append(protected, abs)
+	}
+
+	// kept - Dirs with a file, a protected path or a kept dir in them.
+	kept := map[string]bool{}
+	removed := []string{}
+	err := Walk(dir, func(p string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		parent := filepath.Dir(p)
+		if !isDir || kept[p] {
+			kept[parent] = true
+			return nil
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		if isProtected(abs, protected) {
+			kept[parent] = true
+			return nil
+		}
+		if !o.dryRun {
+			err = os.Remove(p)
+			if err != nil {
+				return err
+			}
+		}
+		removed = append(removed, p)
+		return nil
+	}, ListOrder(PostOrder))
+	return removed, err
+}
@@ -0,0 +1,148 @@
+package fileutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func removeTree(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	for _, d := range []string{"build/obj", "src/cache", "keep"} {
+		os.MkdirAll(filepath.Join(dir, d), 0755)
+	}
+	for _, f := range []string{"a.tmp", "build/obj/x.o", "src/main.go", "src/y.tmp", "src/cache/z", "keep/k.tmp"} {
+		ioutil.WriteFile(filepath.Join(dir, f), nil, 0644)
+	}
+	return dir
+}
+
+func TestRemoveMatching(t *testing.T) {
+	dir := removeTree(t)
+	defer os.RemoveAll(dir)
+
+	expected := []string{
+		filepath.Join(dir, "a.tmp"),
+		filepath.Join(dir, "build"),
+		filepath.Join(dir, "keep/k.tmp"),
+		filepath.Join(dir, "src/cache"),
+		filepath.Join(dir, "src/y.tmp"),
+	}
+	globs := []string{"*.tmp", "build", "src/cache"}
+	removed, err := RemoveMatching(dir, globs, RemoveDryRun())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "build")); err != nil {
+		t.Errorf("Dry run removed files: %s\n", err)
+	}
+
+	_, err = RemoveMatching(dir, globs, RemoveProtect(filepath.Join(dir, "keep", "k.tmp")))
+	if !errors.Is(err, ErrProtectedPath) {
+		t.Errorf("Expected ErrProtectedPath, got: %v\n", err)
+	}
+	_, err = RemoveMatching(dir, []string{"src"}, RemoveProtect(filepath.Join(dir, "src", "main.go")))
+	if !errors.Is(err, ErrProtectedPath) {
+		t.Errorf("Expected ErrProtectedPath, got: %v\n", err)
+	}
+
+	removed, err = RemoveMatching(dir, globs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, removed)
+	}
+	list, err := ListFiles(dir, false, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	left := []string{dir + "/keep", dir + "/src", dir + "/src/main.go"}
+	if !reflect.DeepEqual(list, left) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", left, list)
+	}
+}
+
+func TestRemoveMatchingRoot(t *testing.T) {
+	_, err := RemoveMatching("/", []string{"*"}, RemoveDryRun())
+	if !errors.Is(err, ErrProtectedPath) {
+		t.Errorf("Expected ErrProtectedPath, got: %v\n", err)
+	}
+	dir := removeTree(t)
+	defer os.RemoveAll(dir)
+	_, err = RemoveMatching(dir, []string{"*"}, RemoveProtect(dir))
+	if !errors.Is(err, ErrProtectedPath) {
+		t.Errorf("Expected ErrProtectedPath, got: %v\n", err)
+	}
+	_, err = RemoveMatching(dir, []string{"["})
+	if err == nil {
+		t.Errorf("Expected invalid glob error\n")
+	}
+}
+
+func TestPruneEmptyDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a/b/c", "a/d", "e/f", "g", "h/i", "j"} {
+		os.MkdirAll(filepath.Join(dir, d), 0755)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "a", "d", "file"), []byte{}, 0644)
+	os.Symlink(filepath.Join("..", "a", "d", "file"), filepath.Join(dir, "g", "link"))
+
+	expected := []string{
+		filepath.Join(dir, "a", "b", "c"),
+		filepath.Join(dir, "a", "b"),
+		filepath.Join(dir, "e", "f"),
+		filepath.Join(dir, "e"),
+		filepath.Join(dir, "j"),
+	}
+	got, err := PruneEmptyDirs(dir, RemoveDryRun(), RemoveProtect(filepath.Join(dir, "h", "i")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a", "b", "c")); err != nil {
+		t.Errorf("Dry run removed a dir: %s\n", err)
+	}
+
+	got, err = PruneEmptyDirs(dir, RemoveProtect(filepath.Join(dir, "h", "i")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, got)
+	}
+	left, err := List(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "a", "d"),
+		filepath.Join(dir, "a", "d", "file"),
+		filepath.Join(dir, "g"),
+		filepath.Join(dir, "g", "link"),
+		filepath.Join(dir, "h"),
+		filepath.Join(dir, "h", "i"),
+	}
+	if !reflect.DeepEqual(left, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, left)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("The root was removed: %s\n", err)
+	}
+}
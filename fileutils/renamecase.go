@@ -0,0 +1,68 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// ErrNotCaseOnly - The old and new names differ in more than their case.
+var ErrNotCaseOnly = fmt.Errorf("not a case-only rename")
+
+// RenameCaseOnly - Renames oldpath to newpath, names that only differ in
+// case, like "readme.md" to "README.md".
+//
+// On case-insensitive filesystems, the default on macOS and Windows, both
+// names are the same file and a direct rename is a no-op or an error, so
+// the file is renamed through a temporary name next to it. If the second
+// rename fails the original name is restored.
+// On case-sensitive filesystems a plain rename is done, and it fails with
+// fs.ErrExist when newpath is a different file.
+func RenameCaseOnly(oldpath, newpath string) error {
+	if err := checkWritable("RenameCaseOnly", newpath); err != nil {
+		return err
+	}
+	if !strings.EqualFold(oldpath, newpath) {
+		return fmt.Errorf("%w: '%s' to '%s'", ErrNotCaseOnly, oldpath, newpath)
+	}
+	if oldpath == newpath {
+		return nil
+	}
+	oldInfo, err := os.Lstat(oldpath)
+	if err != nil {
+		return err
+	}
+	newInfo, err := os.Lstat(newpath)
+	if os.IsNotExist(err) {
+		return os.Rename(oldpath, newpath)
+	}
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(oldInfo, newInfo) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrExist}
+	}
+	return renameViaTemp(oldpath, newpath)
+}
+
+// renameViaTemp - Renames oldpath to newpath through a temporary name next to oldpath.
+func renameViaTemp(oldpath, newpath string) error {
+	tmp, err := renameAside(oldpath, "rename")
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, newpath)
+	if err != nil {
+		return restoreAside(tmp, oldpath, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package fileutils
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenameCaseOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	names := func() []string {
+		entries, _ := os.ReadDir(dir)
+		list := []string{}
+		for _, e := range entries {
+			list = append(list, e.Name())
+		}
+		return list
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "readme.md"), []byte("hello"), 0644)
+	err = RenameCaseOnly(filepath.Join(dir, "readme.md"), filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if got := names(); len(got) != 1 || got[0] != "README.md" {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", []string{"README.md"}, got)
+	}
+
+	// The path taken on case-insensitive filesystems.
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "src", "a"), []byte{}, 0644)
+	err = renameViaTemp(filepath.Join(dir, "src"), filepath.Join(dir, "Src"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if got := names(); len(got) != 2 || got[1] != "Src" {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", []string{"README.md", "Src"}, got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Src", "a")); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+
+	err = RenameCaseOnly(filepath.Join(dir, "README.md"), filepath.Join(dir, "other.md"))
+	if !errors.Is(err, ErrNotCaseOnly) {
+		t.Errorf("Expected ErrNotCaseOnly, got: %v\n", err)
+	}
+	err = RenameCaseOnly(filepath.Join(dir, "README.md"), filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+	err = RenameCaseOnly(filepath.Join(dir, "missing"), filepath.Join(dir, "MISSING"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got: %v\n", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "readme.md"), []byte("other"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(names()) == 3 {
+		// Case-sensitive filesystem, both names are different files.
+		err = RenameCaseOnly(filepath.Join(dir, "README.md"), filepath.Join(dir, "readme.md"))
+		if !errors.Is(err, fs.ErrExist) {
+			t.Errorf("Expected fs.ErrExist, got: %v\n", err)
+		}
+	}
+}
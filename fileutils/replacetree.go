@@ -0,0 +1,298 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ReplaceOption - ReplaceInTree option.
+type ReplaceOption func(*replaceOptions)
+
+type replaceOptions struct {
+	listOpts    []ListOption
+	regexp      bool
+	confirm     func(path string, line int, old, new string) (bool, error)
+	concurrency int
+	bufferSize  int
+	cache       *ProcessedCache
+	dryRun      io.Writer
+	control     *Control
+}
+
+// ReplaceList - Selects the files replaced in with the listing options, for
+// example ListInclude, ListExclude, ListSkipHidden and ListIgnoreFiles.
+func ReplaceList(opts ...ListOption) ReplaceOption {
+	return func(o *replaceOptions) {
+		o.listOpts = append(o.listOpts, opts...)
+	}
+}
+
+// ReplaceRegexp - Treats old as a regular expression, new can reference its
+// capture groups like in regexp.ReplaceAllString.
+func ReplaceRegexp() ReplaceOption {
+	return func(o *replaceOptions) {
+		o.regexp = true
+	}
+}
+
+// ReplaceConfirm - Calls fn before changing each line, with the file path,
+// the 1 based line number and the line before and after the change, without
+// line endings. Only the changes fn accepts are made.
+// Files are processed one at a time, in listing order.
+// An error from fn stops the replacement, the changes accepted in the file
+// so far are still made, and it is returned.
+func ReplaceConfirm(fn func(path string, line int, old, new string) (bool, error)) ReplaceOption {
+	return func(o *replaceOptions) {
+		o.confirm = fn
+	}
+}
+
+// ReplaceConcurrency - Maximum number of files processed at the same time.
+// Defaults to one per CPU.
+func ReplaceConcurrency(n int) ReplaceOption {
+	return func(o *replaceOptions) {
+		o.concurrency = n
+	}
+}
+
+// ReplaceBufferSize - Buffer size passed to StringReplace, it limits the
+// longest line that can be read. Defaults to 1MiB.
+func ReplaceBufferSize(size int) ReplaceOption {
+	return func(o *replaceOptions) {
+		o.bufferSize = size
+	}
+}
+
+// ReplaceProcessedCache - Skips the files that haven't changed since they
+// were last processed and records every processed file in c.
+// The cache is not saved, call c.Save when done.
+// Use a separate cache for each old and new pair, a file processed for one
+// replacement is skipped for any other.
+func ReplaceProcessedCache(c *ProcessedCache) ReplaceOption {
+	return func(o *replaceOptions) {
+		o.cache = c
+	}
+}
+
+// ReplaceDryRun - Writes the unified diff of the changes to w, in listing
+// order, instead of modifying the files.
+// The processed cache, if any, is only used to skip files, nothing is
+// recorded in it.
+func ReplaceDryRun(w io.Writer) ReplaceOption {
+	return func(o *replaceOptions) {
+		o.dryRun = w
+	}
+}
+
+// ReplaceControl - Lets c pause and cancel the replacement between files.
+// With ReplaceProcessedCache, running it again after a cancel resumes from
+// where it stopped.
+func ReplaceControl(c *Control) ReplaceOption {
+	return func(o *replaceOptions) {
+		o.control = c
+	}
+}
+
+// ReplaceInTree - Runs StringReplace, replacing all instances of old with
+// new, on every regular file under dir, recursively.
+// Binary files and symlinks are skipped.
+// When dir is a file, only that file is processed and the listing options
+// don't apply.
+// Hidden files and dirs, including VCS dirs like .git, are processed unless
+// excluded with ReplaceList.
+//
+// Returns the number of lines changed for each file that changed, keyed by
+// its path under dir, or that would change with ReplaceDryRun.
+// Errors, listing errors included, don't stop the other files from being
+// processed, they are sorted by path and joined, along with ErrCanceled if
+// the ReplaceControl was canceled.
+func ReplaceInTree(dir, old, new string, opts ...ReplaceOption) (map[string]int, error) {
+	o := replaceOptions{bufferSize: 1024 * 1024}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = runtime.NumCPU()
+	}
+	if o.confirm != nil {
+		o.concurrency = 1
+	}
+	if o.dryRun == nil {
+		if err := checkWritable("ReplaceInTree", dir); err != nil {
+			return nil, err
+		}
+	}
+	replaceLine := func(line string) string {
+		return strings.Replace(line, old, new, -1)
+	}
+	if o.regexp {
+		re, err := regexp.Compile(old)
+		if err != nil {
+			return nil, err
+		}
+		replaceLine = func(line string) string {
+			return re.ReplaceAllString(line, new)
+		}
+	}
+
+	var errs []pathError
+	var files []string
+	lo := newListOptions(o.listOpts)
+	if isRegularFile(lo, dir) {
+		files = []string{dir}
+	} else {
+		listEach(dir, lo.walkOptions(walkOptions{recursive: true, join: cleanJoin}), false, true, func(e StringError) bool {
+			if e.Error != nil {
+				errs = append(errs, pathError{"", e.Error})
+				return true
+			}
+			files = append(files, e.String)
+			// A cancel is reported below.
+			return o.control.Wait() == nil
+		})
+	}
+
+	var mu sync.Mutex
+	changed := map[string]int{}
+	diffs := map[string]string{}
+	stopped := false
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range queue {
+				n, diff, err := o.replace(path, replaceLine)
+				mu.Lock()
+				if n > 0 {
+					changed[path] = n
+					diffs[path] = diff
+				}
+				var stop confirmError
+				if errors.As(err, &stop) {
+					stopped = true
+					err = stop.err
+				}
+				if err != nil {
+					errs = append(errs, pathError{path, err})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, path := range files {
+		err := o.control.Wait()
+		mu.Lock()
+		if err != nil {
+			// Sorted first.
+			errs = append(errs, pathError{"", err})
+		}
+		stop := err != nil || stopped
+		mu.Unlock()
+		if stop {
+			break
+		}
+		queue <- path
+	}
+	close(queue)
+	wg.Wait()
+	if o.dryRun != nil {
+		for _, path := range files {
+			_, err := io.WriteString(o.dryRun, diffs[path])
+			if err != nil {
+				return changed, err
+			}
+		}
+	}
+	return changed, joinPathErrors(errs)
+}
+
+// confirmError - An error returned by the ReplaceConfirm func.
+type confirmError struct {
+	err error
+}
+
+func (e confirmError) Error() string {
+	return e.err.Error()
+}
+
+// replace - Replaces each line of path with replaceLine, or only reports
+// the diff in dry-run mode, unless it is not a regular text file or the
+// cache reports it as unchanged.
+func (o *replaceOptions) replace(path string, replaceLine func(string) string) (int, string, error) {
+	fInfo, err := os.Lstat(path)
+	if err != nil {
+		return 0, "", err
+	}
+	if !fInfo.Mode().IsRegular() {
+		return 0, "", nil
+	}
+	if o.cache != nil {
+		ok, err := o.cache.Changed(path)
+		if err != nil || !ok {
+			return 0, "", err
+		}
+	}
+	binary, err := isBinaryFile(path)
+	if err != nil {
+		return 0, "", err
+	}
+	var confirmErr error
+	line := 0
+	fn := func(old string) string {
+		line++
+		new := replaceLine(old)
+		if new == old || o.confirm == nil {
+			return new
+		}
+		if confirmErr != nil {
+			return old
+		}
+		ok, err := o.confirm(path, line, old, new)
+		if err != nil {
+			confirmErr = confirmError{err}
+		}
+		if err != nil || !ok {
+			return old
+		}
+		return new
+	}
+	if o.dryRun != nil {
+		if binary {
+			return 0, "", nil
+		}
+		diff, n, err := diffLines(path, o.bufferSize, fn)
+		if err != nil {
+			return 0, "", err
+		}
+		return n, diff, confirmErr
+	}
+	n := 0
+	if !binary {
+		n, err = replaceLines(path, o.bufferSize, fn)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+	if confirmErr != nil {
+		return n, "", confirmErr
+	}
+	if o.cache != nil {
+		return n, "", o.cache.Done(path)
+	}
+	return n, "", nil
+}
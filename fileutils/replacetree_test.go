@@ -0,0 +1,237 @@
+package fileutils
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReplaceInTree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.txt":       "hello world\nhello\nbye\n",
+		"b.txt":       "nothing here\n",
+		"sub/c.txt":   "hello\n",
+		"sub/d.log":   "hello\n",
+		"skip/e.txt":  "hello\n",
+		"bin/f.txt":   "hello\x00\n",
+		"sub/g/h.txt": "say hello hello\n",
+	}
+	write := func() {
+		for name, content := range files {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(path), 0755)
+			ioutil.WriteFile(path, []byte(content), 0644)
+		}
+	}
+	write()
+	os.Symlink("a.txt", filepath.Join(dir, "link.txt"))
+
+	expected := map[string]int{
+		filepath.Join(dir, "a.txt"):             2,
+		filepath.Join(dir, "sub", "c.txt"):      1,
+		filepath.Join(dir, "sub", "g", "h.txt"): 1,
+	}
+
+	// Dry runs only report the diff, even in read-only mode.
+	SetReadOnly(true)
+	var buf bytes.Buffer
+	got, err := ReplaceInTree(dir, "hello", "bye", ReplaceList(ListInclude("*.txt"), ListExclude("skip")), ReplaceDryRun(&buf))
+	SetReadOnly(false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+	a := filepath.Join(dir, "a.txt")
+	c := filepath.Join(dir, "sub", "c.txt")
+	h := filepath.Join(dir, "sub", "g", "h.txt")
+	diff := "--- " + a + "\n+++ " + a + "\n@@ -1,3 +1,3 @@\n-hello world\n-hello\n+bye world\n+bye\n bye\n" +
+		"--- " + c + "\n+++ " + c + "\n@@ -1 +1 @@\n-hello\n+bye\n" +
+		"--- " + h + "\n+++ " + h + "\n@@ -1 +1 @@\n-say hello hello\n+say bye bye\n"
+	if buf.String() != diff {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", diff, buf.String())
+	}
+	data, _ := ioutil.ReadFile(a)
+	if string(data) != files["a.txt"] {
+		t.Errorf("Dry run modified the file:\n%s\n", data)
+	}
+
+	got, err = ReplaceInTree(dir, "hello", "bye", ReplaceList(ListInclude("*.txt"), ListExclude("skip")), ReplaceConcurrency(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+	contents := map[string]string{
+		"a.txt":       "bye world\nbye\nbye\n",
+		"sub/d.log":   "hello\n",
+		"skip/e.txt":  "hello\n",
+		"bin/f.txt":   "hello\x00\n",
+		"sub/g/h.txt": "say bye bye\n",
+	}
+	for name, content := range contents {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if string(data) != content {
+			t.Errorf("%s Expected:\n%q\nGot:\n%q\n", name, content, string(data))
+		}
+	}
+
+	// Cached runs only process the files that changed since the last run.
+	write()
+	cache, err := LoadProcessedCache(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got, err = ReplaceInTree(dir, "hello", "bye", ReplaceList(ListInclude("*.txt")), ReplaceProcessedCache(cache))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(got) != 4 {
+		t.Errorf("Expected 4 changed files, got: %v\n", got)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("hello again\n"), 0644)
+	got, err = ReplaceInTree(dir, "hello", "bye", ReplaceList(ListInclude("*.txt")), ReplaceProcessedCache(cache))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = map[string]int{filepath.Join(dir, "b.txt"): 1}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+}
+
+func TestReplaceInTreeControl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a", "b", "c"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte("hello\n"), 0644)
+	}
+	cache, err := LoadProcessedCache(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	c := NewControl()
+	c.Cancel()
+	got, err := ReplaceInTree(dir, "hello", "bye", ReplaceControl(c), ReplaceProcessedCache(cache))
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got: %v\n", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Unexpected changes: %v\n", got)
+	}
+
+	// Resumes skipping the files already processed.
+	cache.Done(filepath.Join(dir, "a"))
+	got, err = ReplaceInTree(dir, "hello", "bye", ReplaceControl(NewControl()), ReplaceProcessedCache(cache), ReplaceList(ListExclude("cache.json")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := map[string]int{filepath.Join(dir, "b"): 1, filepath.Join(dir, "c"): 1}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+}
+
+func TestReplaceInTreeConfirm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, ".git", "config"), []byte("hello\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a"), []byte("hello\nhallo\nhello\r\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b"), []byte("hello\n"), 0644)
+	os.Symlink("missing", filepath.Join(dir, "dangling"))
+
+	// Listing errors don't stop the replacement.
+	type call struct {
+		Path     string
+		Line     int
+		Old, New string
+	}
+	calls := []call{}
+	got, err := ReplaceInTree(dir, `h(e|a)llo`, "b${1}y", ReplaceRegexp(),
+		ReplaceList(ListSkipHidden(), ListFollowLinks(true)),
+		ReplaceConfirm(func(path string, line int, old, new string) (bool, error) {
+			calls = append(calls, call{path, line, old, new})
+			return line != 2, nil
+		}))
+	if err == nil {
+		t.Errorf("Expected dangling link error\n")
+	}
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	expected := map[string]int{a: 2, b: 1}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+	expectedCalls := []call{
+		{a, 1, "hello", "bey"},
+		{a, 2, "hallo", "bay"},
+		{a, 3, "hello", "bey"},
+		{b, 1, "hello", "bey"},
+	}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expectedCalls, calls)
+	}
+	for name, content := range map[string]string{".git/config": "hello\n", "a": "bey\nhallo\nbey\r\n", "b": "bey\n"} {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if string(data) != content {
+			t.Errorf("%s Expected:\n%q\nGot:\n%q\n", name, content, string(data))
+		}
+	}
+
+	// Errors from the confirm func stop the replacement after writing the
+	// changes already accepted.
+	ioutil.WriteFile(a, []byte("hello\nhello\n"), 0644)
+	ioutil.WriteFile(b, []byte("hello\n"), 0644)
+	errStop := errors.New("stop")
+	got, err = ReplaceInTree(dir, "hello", "bye", ReplaceList(ListSkipHidden()),
+		ReplaceConfirm(func(path string, line int, old, new string) (bool, error) {
+			if line == 2 {
+				return false, errStop
+			}
+			return true, nil
+		}))
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expected errStop, got: %v\n", err)
+	}
+	expected = map[string]int{a: 1}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+	for name, content := range map[string]string{"a": "bye\nhello\n", "b": "hello\n"} {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		if string(data) != content {
+			t.Errorf("%s Expected:\n%q\nGot:\n%q\n", name, content, string(data))
+		}
+	}
+
+	// A file is processed regardless of the listing options.
+	got, err = ReplaceInTree(b, "hello", "bye", ReplaceList(ListExclude("b")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = map[string]int{b: 1}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+}
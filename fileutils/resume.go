@@ -0,0 +1,148 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/DavidGamba/go-utils/retryutils"
+)
+
+// resumeBlockSize - Size of the blocks hashed when verifying a partial copy.
+const resumeBlockSize = 1024 * 1024
+
+// ResumeCopy - Like CopyFile but continues an interrupted copy instead of
+// restarting it, for huge files over unreliable mounts.
+// The prefix of dst that is already in place is verified block by block
+// against src by hashing, the copy continues from the first block that
+// doesn't match and dst is truncated to the size of src.
+// With CopyRetry each attempt resumes from where the previous one failed.
+// CopyPreserve, CopyFlags, CopySpaceCheck and CopyLimiter apply as in CopyFile.
+func ResumeCopy(src, dst string, opts ...CopyOption) error {
+	if err := checkWritable("ResumeCopy", dst); err != nil {
+		return err
+	}
+	o := newCopyOptions(opts)
+	// Stat before reading, reading updates the access time.
+	fInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if o.spaceCheck {
+		var done int64
+		if dInfo, err := os.Stat(dst); err == nil && dInfo.Size() < fInfo.Size() {
+			done = dInfo.Size()
+		}
+		err = CheckSpace(filepath.Dir(dst), fInfo.Size()-done)
+		if err != nil {
+			return err
+		}
+	}
+	o.startProgress(fInfo.Size())
+	if o.retry != nil {
+		err = retryutils.Retry(context.Background(), *o.retry, func() error {
+			return resumeCopy(src, dst, o)
+		})
+	} else {
+		err = resumeCopy(src, dst, o)
+	}
+	if err != nil {
+		return err
+	}
+	if o.preserve {
+		err = preserveAttributes(src, dst, fInfo)
+		if err != nil {
+			return err
+		}
+	}
+	if o.flags {
+		return copyFlags(src, dst)
+	}
+	return nil
+}
+
+func resumeCopy(src, dst string, o *copyOptions) error {
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cerr := out.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+	offset, err := verifiedPrefix(o.reader(in), out)
+	if err != nil {
+		return err
+	}
+	Logger.Printf("ResumeCopy: '%s' resuming at offset %d", dst, offset)
+	if err = out.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err = in.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = out.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	o.progress.set(offset)
+	if _, err = io.Copy(out, o.progress.reader(o.reader(in))); err != nil {
+		return err
+	}
+	err = out.Sync()
+	return err
+}
+
+// verifiedPrefix - Returns the length of the common prefix of src and dst,
+// compared by the hash of each block, up to the first block that differs.
+// A short last block of dst is only verified for the bytes it has.
+func verifiedPrefix(src, dst io.Reader) (int64, error) {
+	srcBuf := make([]byte, resumeBlockSize)
+	dstBuf := make([]byte, resumeBlockSize)
+	var offset int64
+	for {
+		dn, err := io.ReadFull(dst, dstBuf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		if dn == 0 {
+			return offset, nil
+		}
+		sn, err := io.ReadFull(src, srcBuf[:dn])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		if sn != dn {
+			// dst is longer than src, keep the blocks that match.
+			dn = sn
+		}
+		srcSum := sha256.Sum256(srcBuf[:dn])
+		dstSum := sha256.Sum256(dstBuf[:dn])
+		if !bytes.Equal(srcSum[:], dstSum[:]) {
+			return offset, nil
+		}
+		offset += int64(dn)
+		if dn < resumeBlockSize {
+			return offset, nil
+		}
+	}
+}
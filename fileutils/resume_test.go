@@ -0,0 +1,98 @@
+package fileutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResumeCopy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	data := make([]byte, 3*resumeBlockSize+123)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	src := filepath.Join(dir, "src")
+	err = ioutil.WriteFile(src, data, 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	corrupt := func() []byte {
+		b := append([]byte{}, data[:2*resumeBlockSize+10]...)
+		b[resumeBlockSize+5] ^= 0xff
+		return b
+	}
+	tests := []struct {
+		name string
+		dst  []byte
+	}{
+		{"missing", nil},
+		{"empty", []byte{}},
+		{"partial block", data[:resumeBlockSize/2]},
+		{"partial", data[:2*resumeBlockSize+10]},
+		{"complete", data},
+		{"corrupted", corrupt()},
+		{"longer", append(append([]byte{}, data...), []byte("extra")...)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dst := filepath.Join(dir, "dst")
+			os.Remove(dst)
+			if test.dst != nil {
+				err := ioutil.WriteFile(dst, test.dst, 0644)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s\n", err)
+				}
+			}
+			err := ResumeCopy(src, dst)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			got, err := ioutil.ReadFile(dst)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("Expected %d bytes matching src, got %d bytes\n", len(data), len(got))
+			}
+		})
+	}
+}
+
+func TestVerifiedPrefix(t *testing.T) {
+	data := make([]byte, 2*resumeBlockSize+7)
+	for i := range data {
+		data[i] = byte(i % 13)
+	}
+	changed := append([]byte{}, data...)
+	changed[resumeBlockSize+1] ^= 0xff
+	tests := []struct {
+		name     string
+		dst      []byte
+		expected int64
+	}{
+		{"empty", []byte{}, 0},
+		{"short", data[:10], 10},
+		{"block", data[:resumeBlockSize], resumeBlockSize},
+		{"full", data, int64(len(data))},
+		{"changed", changed, resumeBlockSize},
+		{"longer", append(append([]byte{}, data...), 'x'), int64(len(data))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			offset, err := verifiedPrefix(bytes.NewReader(data), bytes.NewReader(test.dst))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if offset != test.expected {
+				t.Errorf("Expected:\n%v\nGot:\n%v\n", test.expected, offset)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// reverseBlockSize - Size of the blocks ReadLinesReverse reads from the end of the file.
+var reverseBlockSize int64 = 64 << 10
+
+// ReadLinesReverse - Returns a channel with each line of filename, last
+// line first, or an error indicating failure (`channel.Error`).
+// The file is read backwards in blocks, so only the lines consumed are read,
+// making it cheap to scan the recent entries of large log files.
+// A final line terminator doesn't produce an empty first line and "\r\n"
+// terminators are supported.
+func ReadLinesReverse(filename string) <-chan StringError {
+	return ReadLinesReverseContext(context.Background(), filename)
+}
+
+// ReadLinesReverseContext - Same as ReadLinesReverse but stops reading and closes the channel when ctx is cancelled.
+func ReadLinesReverseContext(ctx context.Context, filename string) <-chan StringError {
+	c := make(chan StringError)
+	send := func(e StringError) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		select {
+		case c <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	go func() {
+		defer close(c)
+		fh, err := os.Open(filename)
+		if err != nil {
+			send(StringError{"", fmt.Errorf("Couldn't open file '%s': %w\n", filename, err)})
+			return
+		}
+		defer fh.Close()
+		err = readLinesReverse(fh, func(line []byte) bool {
+			return send(StringError{string(bytes.TrimSuffix(line, []byte("\r"))), nil})
+		})
+		if err != nil {
+			send(StringError{"", fmt.Errorf("Read error '%s': %w\n", filename, err)})
+		}
+	}()
+	return c
+}
+
+// readLinesReverse - Calls emit with each line of fh, last line first, until it returns false.
+func readLinesReverse(fh *os.File, emit func(line []byte) bool) error {
+	fInfo, err := fh.Stat()
+	if err != nil {
+		return err
+	}
+	pos := fInfo.Size()
+	if pos == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	_, err = fh.ReadAt(last, pos-1)
+	if err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		pos--
+	}
+	// partial - The end of a line whose start hasn't been read yet.
+	var partial []byte
+	for pos > 0 {
+		n := reverseBlockSize
+		if n > pos {
+			n = pos
+		}
+		pos -= n
+		buf := make([]byte, n, int(n)+len(partial))
+		_, err := fh.ReadAt(buf, pos)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		data := append(buf, partial...)
+		for i := bytes.LastIndexByte(data, '\n'); i >= 0; i = bytes.LastIndexByte(data, '\n') {
+			if !emit(data[i+1:]) {
+				return nil
+			}
+			data = data[:i]
+		}
+		partial = data
+	}
+	emit(partial)
+	return nil
+}
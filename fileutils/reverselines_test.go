@@ -0,0 +1,64 @@
+package fileutils
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadLinesReverse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(size int64) { reverseBlockSize = size }(reverseBlockSize)
+	reverseBlockSize = 4
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{"empty", "", []string{}},
+		{"single", "hello", []string{"hello"}},
+		{"terminated", "hello\n", []string{"hello"}},
+		{"lines", "a\nbb\n\nlonger than a block\nc", []string{"c", "longer than a block", "", "bb", "a"}},
+		{"crlf", "a\r\nb\r\n", []string{"b", "a"}},
+		{"leading newline", "\nabc\n", []string{"abc", ""}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(dir, "lines")
+			ioutil.WriteFile(file, []byte(test.content), 0644)
+			got := []string{}
+			for e := range ReadLinesReverse(file) {
+				if e.Error != nil {
+					t.Fatalf("Unexpected error: %s\n", e.Error)
+				}
+				got = append(got, e.String)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, got)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := ReadLinesReverseContext(ctx, filepath.Join(dir, "lines"))
+	e := <-c
+	if e.String != "abc" {
+		t.Errorf("Expected: abc, Got: %q\n", e.String)
+	}
+	cancel()
+	for range c {
+	}
+
+	e = <-ReadLinesReverse(filepath.Join(dir, "missing"))
+	if e.Error == nil {
+		t.Errorf("Expected open error\n")
+	}
+}
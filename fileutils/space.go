@@ -0,0 +1,102 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrInsufficientSpace - The filesystem doesn't have enough free space for the operation.
+var ErrInsufficientSpace = fmt.Errorf("insufficient space")
+
+// FreeSpace - Returns the bytes available to unprivileged users on the
+// filesystem dir is on. Quotas are not taken into account.
+// Returns ErrNotSupported on platforms other than Linux, macOS, the BSDs and Windows.
+func FreeSpace(dir string) (int64, error) {
+	return freeSpace(dir)
+}
+
+// CheckSpace - Returns ErrInsufficientSpace when the filesystem dir is on
+// doesn't have requiredBytes available.
+// dir doesn't need to exist, its closest existing parent is checked, so
+// the destination of a copy can be checked before it is created.
+// Where the free space is not available the check passes.
+func CheckSpace(dir string, requiredBytes int64) error {
+	if requiredBytes <= 0 {
+		return nil
+	}
+	existing, err := existingParent(dir)
+	if err != nil {
+		return err
+	}
+	free, err := FreeSpace(existing)
+	if err != nil {
+		if errors.Is(err, ErrNotSupported) {
+			Logger.Printf("CheckSpace: %s", err)
+			return nil
+		}
+		return err
+	}
+	if free < requiredBytes {
+		return fmt.Errorf("%w: '%s': %d bytes required, %d available", ErrInsufficientSpace, dir, requiredBytes, free)
+	}
+	return nil
+}
+
+// existingParent - Returns path or its closest parent that exists.
+func existingParent(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		_, err := os.Stat(path)
+		if err == nil || !os.IsNotExist(err) {
+			return path, err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path, err
+		}
+		path = parent
+	}
+}
+
+// CopySpaceCheck - Checks, before copying anything, that the destination
+// of CopyFile or CopyDir has space for all the files to copy, see CheckSpace.
+// The files that will replace existing ones are counted in full and, with
+// CopyFollowLinks, the files behind symlinks are not counted.
+func CopySpaceCheck() CopyOption {
+	return func(o *copyOptions) {
+		o.spaceCheck = true
+	}
+}
+
+// treeSize - Returns the size of the regular files under dir.
+// Symlinks are not followed.
+func treeSize(dir string) (int64, error) {
+	var size int64
+	err := Walk(dir, func(path string, isDir bool, err error) error {
+		if err != nil || isDir {
+			return err
+		}
+		fInfo, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if fInfo.Mode().IsRegular() {
+			size += fInfo.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,25 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!openbsd,!windows
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+)
+
+func freeSpace(dir string) (int64, error) {
+	_, err := os.Stat(dir)
+	if err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%w: '%s'", ErrNotSupported, dir)
+}
@@ -0,0 +1,55 @@
+package fileutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCheckSpace(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "openbsd", "windows":
+	default:
+		t.Skip("free space not supported")
+	}
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	free, err := FreeSpace(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if free <= 0 {
+		t.Errorf("Expected free space, got: %d\n", free)
+	}
+	missing := filepath.Join(dir, "missing", "dir")
+	err = CheckSpace(missing, 1)
+	if err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+	err = CheckSpace(missing, 1<<62)
+	if !errors.Is(err, ErrInsufficientSpace) {
+		t.Errorf("Expected ErrInsufficientSpace, got: %v\n", err)
+	}
+
+	src := filepath.Join(dir, "src")
+	os.Mkdir(src, 0755)
+	ioutil.WriteFile(filepath.Join(src, "f"), []byte("f"), 0644)
+	err = CopyDir(src, filepath.Join(dir, "dst"), CopySpaceCheck())
+	if err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+	err = CopyFile(filepath.Join(src, "f"), filepath.Join(dir, "g"), CopySpaceCheck())
+	if err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+	size, err := treeSize(src)
+	if err != nil || size != 1 {
+		t.Errorf("Expected:\n%d\nGot:\n%d, %v\n", 1, size, err)
+	}
+}
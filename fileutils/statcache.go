@@ -0,0 +1,148 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// StatCache - Caches os.Stat and os.Lstat results, including errors, so a path
+// is only stat'ed once within one logical operation.
+//
+// Listings given a cache with ListStatCache record the dir entries they read,
+// so a later Stat or Lstat of a listed path costs at most one syscall.
+// The cache doesn't notice changes to the filesystem, use Forget after
+// modifying a path or create a new cache for each operation.
+// It is safe for concurrent use.
+type StatCache struct {
+	mu      sync.Mutex
+	stat    map[string]statResult
+	lstat   map[string]statResult
+	entries map[string]fs.DirEntry
+}
+
+type statResult struct {
+	fInfo os.FileInfo
+	err   error
+}
+
+// NewStatCache - Returns an empty StatCache.
+func NewStatCache() *StatCache {
+	return &StatCache{
+		stat:    map[string]statResult{},
+		lstat:   map[string]statResult{},
+		entries: map[string]fs.DirEntry{},
+	}
+}
+
+// ListStatCache - Uses the cache for the stat calls done while listing and
+// records the listed entries in it.
+func ListStatCache(c *StatCache) ListOption {
+	return func(o *listOptions) {
+		o.statCache = c
+	}
+}
+
+// Stat - Cached os.Stat.
+func (c *StatCache) Stat(name string) (os.FileInfo, error) {
+	c.mu.Lock()
+	r, ok := c.stat[name]
+	e, isEntry := c.entries[name]
+	c.mu.Unlock()
+	if ok {
+		return r.fInfo, r.err
+	}
+	// Stat and Lstat only differ for symlinks.
+	if isEntry && e.Type()&fs.ModeSymlink == 0 {
+		return c.Lstat(name)
+	}
+	fInfo, err := os.Stat(name)
+	c.mu.Lock()
+	c.stat[name] = statResult{fInfo, err}
+	c.mu.Unlock()
+	return fInfo, err
+}
+
+// Lstat - Cached os.Lstat.
+func (c *StatCache) Lstat(name string) (os.FileInfo, error) {
+	c.mu.Lock()
+	r, ok := c.lstat[name]
+	e, isEntry := c.entries[name]
+	c.mu.Unlock()
+	if ok {
+		return r.fInfo, r.err
+	}
+	var fInfo os.FileInfo
+	var err error
+	if isEntry {
+		fInfo, err = e.Info()
+	} else {
+		fInfo, err = os.Lstat(name)
+	}
+	c.mu.Lock()
+	c.lstat[name] = statResult{fInfo, err}
+	c.mu.Unlock()
+	return fInfo, err
+}
+
+// Forget - Removes the cached results for the path.
+func (c *StatCache) Forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.stat, name)
+	delete(c.lstat, name)
+	delete(c.entries, name)
+}
+
+// forgetTree - Removes the cached results for the path and the paths under it.
+func (c *StatCache) forgetTree(name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prefix := name + string(os.PathSeparator)
+	for _, m := range []map[string]statResult{c.stat, c.lstat} {
+		for k := range m {
+			if k == name || strings.HasPrefix(k, prefix) {
+				delete(m, k)
+			}
+		}
+	}
+	for k := range c.entries {
+		if k == name || strings.HasPrefix(k, prefix) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+func (c *StatCache) addEntry(name string, e fs.DirEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[name] = e
+}
+
+// cachedStat - os.Stat through the cache when there is one.
+func (c *StatCache) cachedStat(name string) (os.FileInfo, error) {
+	if c == nil {
+		return os.Stat(name)
+	}
+	return c.Stat(name)
+}
+
+// cachedLstat - os.Lstat through the cache when there is one.
+func (c *StatCache) cachedLstat(name string) (os.FileInfo, error) {
+	if c == nil {
+		return os.Lstat(name)
+	}
+	return c.Lstat(name)
+}
@@ -0,0 +1,88 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "f")
+
+	c := NewStatCache()
+	_, err = c.Stat(file)
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected not exist error, got: %v\n", err)
+	}
+	err = ioutil.WriteFile(file, []byte("hello"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	// The error is cached.
+	_, err = c.Stat(file)
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected cached not exist error, got: %v\n", err)
+	}
+	c.Forget(file)
+	fInfo, err := c.Stat(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if fInfo.Size() != 5 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 5, fInfo.Size())
+	}
+	fInfo, err = c.Lstat(file)
+	if err != nil || fInfo.Size() != 5 {
+		t.Errorf("Unexpected Lstat result: %v, %v\n", fInfo, err)
+	}
+}
+
+func TestListStatCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "d"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "d", "f"), []byte("hello"), 0644)
+	os.Symlink("d", filepath.Join(dir, "ld"))
+
+	c := NewStatCache()
+	list, err := ListFiles(dir, true, true, ListStatCache(c))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Unexpected list: %q\n", list)
+	}
+	fInfo, err := c.Lstat(filepath.Join(dir, "ld"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if fInfo.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected symlink info, got: %s\n", fInfo.Mode())
+	}
+	// The symlink was resolved while listing, the result is reused.
+	os.Remove(filepath.Join(dir, "ld"))
+	fInfo, err = c.Stat(filepath.Join(dir, "ld"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !fInfo.IsDir() {
+		t.Errorf("Expected cached dir info for link\n")
+	}
+	// Listed regular entries are resolved from their dir entry.
+	fInfo, err = c.Stat(filepath.Join(dir, "d", "f"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if fInfo.Size() != 5 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 5, fInfo.Size())
+	}
+}
@@ -0,0 +1,101 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// SwapDirs - Replaces the current dir with the staging dir and leaves the
+// previous contents of current in staging, so calling it again rolls back.
+// When current doesn't exist, staging is renamed to current.
+//
+// On Linux, on filesystems that support renameat2 RENAME_EXCHANGE, the swap
+// is atomic: current always points to either version. Elsewhere it is done
+// with three renames and there is a short window where current doesn't
+// exist, if the second rename fails current is restored.
+// Both dirs must be on the same filesystem.
+//
+//	// Deploy
+//	err := fileutils.SwapDirs("/srv/app", "/srv/app.staging")
+//	// Rollback
+//	err = fileutils.SwapDirs("/srv/app", "/srv/app.staging")
+func SwapDirs(current, staging string) error {
+	if err := checkWritable("SwapDirs", current); err != nil {
+		return err
+	}
+	fInfo, err := os.Stat(staging)
+	if err != nil {
+		return err
+	}
+	if !fInfo.IsDir() {
+		return fmt.Errorf("Provided dir is not a dir: '%s'\n", staging)
+	}
+	fInfo, err = os.Stat(current)
+	if os.IsNotExist(err) {
+		return os.Rename(staging, current)
+	}
+	if err != nil {
+		return err
+	}
+	if !fInfo.IsDir() {
+		return fmt.Errorf("Provided dir is not a dir: '%s'\n", current)
+	}
+	ok, err := exchangeDirs(current, staging)
+	if ok || err != nil {
+		return err
+	}
+	Logger.Printf("SwapDirs: atomic exchange not available, renaming")
+	return renameSwap(current, staging)
+}
+
+// renameSwap - Swaps current and staging through a temporary name next to current.
+func renameSwap(current, staging string) error {
+	tmp, err := renameAside(current, "swap")
+	if err != nil {
+		return err
+	}
+	err = os.Rename(staging, current)
+	if err != nil {
+		return restoreAside(tmp, current, err)
+	}
+	return os.Rename(tmp, staging)
+}
+
+// renameAside - Renames path to a free temporary name next to it, tagged
+// with label, and returns that name.
+func renameAside(path, label string) (string, error) {
+	tmp, err := ioutil.TempDir(filepath.Dir(path), "."+filepath.Base(path)+"-"+label+"-")
+	if err != nil {
+		return "", err
+	}
+	// The temporary dir only reserves the name, rename can't replace a dir
+	// on every platform.
+	err = os.Remove(tmp)
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(path, tmp)
+	if err != nil {
+		return "", err
+	}
+	return tmp, nil
+}
+
+// restoreAside - Renames tmp, returned by renameAside, back to path after
+// err made the rename through it fail. Returns err.
+func restoreAside(tmp, path string, err error) error {
+	if rerr := os.Rename(tmp, path); rerr != nil {
+		return fmt.Errorf("%w, and restoring '%s' from '%s' failed: %s", err, path, tmp, rerr)
+	}
+	return err
+}
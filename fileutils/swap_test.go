@@ -0,0 +1,68 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSwapDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	current := filepath.Join(dir, "current")
+	staging := filepath.Join(dir, "staging")
+	version := func(dir string) string {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "version"))
+		return string(data)
+	}
+
+	// renameSwap is the fallback when the atomic exchange is not available.
+	for name, swap := range map[string]func(current, staging string) error{"swap": SwapDirs, "rename": renameSwap} {
+		t.Run(name, func(t *testing.T) {
+			os.RemoveAll(current)
+			os.MkdirAll(staging, 0755)
+			ioutil.WriteFile(filepath.Join(staging, "version"), []byte("v1"), 0644)
+			err := SwapDirs(current, staging)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if _, err := os.Stat(staging); !os.IsNotExist(err) {
+				t.Errorf("Expected staging to be renamed, got: %v\n", err)
+			}
+
+			os.MkdirAll(staging, 0755)
+			ioutil.WriteFile(filepath.Join(staging, "version"), []byte("v2"), 0644)
+			err = swap(current, staging)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if version(current) != "v2" || version(staging) != "v1" {
+				t.Errorf("Expected:\nv2 v1\nGot:\n%s %s\n", version(current), version(staging))
+			}
+			err = swap(current, staging)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if version(current) != "v1" || version(staging) != "v2" {
+				t.Errorf("Expected:\nv1 v2\nGot:\n%s %s\n", version(current), version(staging))
+			}
+			entries, _ := ioutil.ReadDir(dir)
+			if len(entries) != 2 {
+				t.Errorf("Expected no leftover dirs, got: %d entries\n", len(entries))
+			}
+		})
+	}
+
+	err = SwapDirs(current, filepath.Join(dir, "missing"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	err = SwapDirs(current, filepath.Join(staging, "version"))
+	if err == nil {
+		t.Errorf("Expected not a dir error\n")
+	}
+}
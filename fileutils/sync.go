@@ -0,0 +1,590 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/DavidGamba/go-utils/errclass"
+	"github.com/DavidGamba/go-utils/retryutils"
+)
+
+// SyncOp - Kind of change Sync makes to the destination.
+type SyncOp string
+
+const (
+	// SyncOpDelete removes the entry, dirs with their contents.
+	SyncOpDelete SyncOp = "delete"
+
+	// SyncOpMkdir creates the dir.
+	SyncOpMkdir SyncOp = "mkdir"
+
+	// SyncOpCopy copies the file.
+	SyncOpCopy SyncOp = "copy"
+
+	// SyncOpLink creates the symlink with the target of the source one.
+	SyncOpLink SyncOp = "link"
+)
+
+// SyncAction - A change required to make the destination match the source.
+type SyncAction struct {
+	Op SyncOp `json:"action"`
+
+	// Path - Slash separated path relative to the source and destination,
+	// "." for the destination itself.
+	Path string `json:"path"`
+
+	// Size - Size of the file to copy.
+	Size int64 `json:"size,omitempty"`
+
+	// fInfo - Source entry, or destination entry for deletes.
+	fInfo fs.FileInfo
+}
+
+// SyncReport - Result of Sync.
+type SyncReport struct {
+	DryRun bool `json:"dry_run"`
+
+	// Actions - The planned changes, deletes first so entries that change
+	// type can be recreated.
+	Actions []SyncAction `json:"actions"`
+
+	// Applied - Number of Actions applied, all of them unless Sync failed
+	// or was canceled.
+	Applied int `json:"applied"`
+
+	// Copied - Bytes transferred, with SyncDelta the size of the deltas for
+	// the files updated with one.
+	Copied int64 `json:"copied"`
+}
+
+// SyncEvent - Progress of an action, see SyncProgress.
+type SyncEvent struct {
+	Action SyncAction
+
+	// Index - Position of Action in SyncReport.Actions, out of Total.
+	Index int
+	Total int
+
+	// Transferred - Bytes of the action transferred so far, 0 in the first
+	// event of each action.
+	Transferred int64
+
+	// Done - The action is complete, or failed with Err.
+	Done bool
+	Err  error
+}
+
+// SyncOption - Sync option.
+type SyncOption func(*syncOptions)
+
+type syncOptions struct {
+	delete     bool
+	exclude    []string
+	dryRun     bool
+	checksum   bool
+	hash       func(filename string) (string, error)
+	checkpoint *ProcessedCache
+	delta      bool
+	spaceCheck bool
+	control    *Control
+	progress   func(SyncEvent)
+	retry      *retryutils.Policy
+	statCache  *StatCache
+}
+
+// SyncDelete - Deletes the entries in the destination that don't exist in
+// the source.
+func SyncDelete() SyncOption {
+	return func(o *syncOptions) {
+		o.delete = true
+	}
+}
+
+// SyncExclude - Skips the entries that match one of the globs, in both the
+// source and the destination, with the same semantics as ListExclude.
+func SyncExclude(globs ...string) SyncOption {
+	return func(o *syncOptions) {
+		o.exclude = append(o.exclude, globs...)
+	}
+}
+
+// SyncDryRun - Plans the changes without applying them.
+func SyncDryRun() SyncOption {
+	return func(o *syncOptions) {
+		o.dryRun = true
+	}
+}
+
+// SyncChecksum - Compares the contents of the files with the same size,
+// with the hex digests returned by hash, instead of their modification
+// times. A nil hash uses SHA-256.
+func SyncChecksum(hash func(filename string) (string, error)) SyncOption {
+	return func(o *syncOptions) {
+		o.checksum = true
+		o.hash = hash
+	}
+}
+
+// SyncCheckpoint - With SyncChecksum, takes the SHA-256 digests from c, so
+// unchanged files are not read again, and records the new ones.
+// Sync saves c when done, even when it fails or is canceled, so running it
+// again resumes without hashing the files already compared.
+func SyncCheckpoint(c *ProcessedCache) SyncOption {
+	return func(o *syncOptions) {
+		o.checkpoint = c
+	}
+}
+
+// SyncDelta - Updates the files that exist in the destination with a
+// binary delta, see BinaryDiff, so only their changed portions are
+// transferred.
+func SyncDelta() SyncOption {
+	return func(o *syncOptions) {
+		o.delta = true
+	}
+}
+
+// SyncSpaceCheck - Checks that the destination has space for the files to
+// copy before applying any change, see CheckSpace.
+// Files that replace existing ones only count the difference in size.
+func SyncSpaceCheck() SyncOption {
+	return func(o *syncOptions) {
+		o.spaceCheck = true
+	}
+}
+
+// SyncControl - Lets c pause and cancel the sync between entries.
+// Running it again after a cancel resumes from where it stopped, since the
+// applied changes are not planned again.
+func SyncControl(c *Control) SyncOption {
+	return func(o *syncOptions) {
+		o.control = c
+	}
+}
+
+// SyncProgress - Calls fn before applying each action, while copying it and
+// once it is done.
+func SyncProgress(fn func(SyncEvent)) SyncOption {
+	return func(o *syncOptions) {
+		o.progress = fn
+	}
+}
+
+// SyncRetry - Retries each action on transient errors following the given
+// policy, see CopyRetry. A retried copy starts over, so its progress events
+// go back to 0.
+func SyncRetry(policy retryutils.Policy) SyncOption {
+	return func(o *syncOptions) {
+		o.retry = &policy
+	}
+}
+
+// SyncStatCache - Uses the cache for the stat calls done while planning and
+// applying the changes, see ListStatCache. Sync forgets the paths it
+// changes in dst.
+func SyncStatCache(c *StatCache) SyncOption {
+	return func(o *syncOptions) {
+		o.statCache = c
+	}
+}
+
+// Sync - Makes the dst dir match the src dir.
+// Files are copied when they are missing or their size or modification time
+// differ, or with SyncChecksum, when their contents differ. Modes and
+// modification times are preserved. Symlinks are copied as symlinks, other
+// special files, like sockets and devices, are skipped.
+//
+// The changes are planned first and applied in order, stopping at the first
+// error. The returned report has the planned changes and how many of them
+// were applied.
+func Sync(src, dst string, opts ...SyncOption) (SyncReport, error) {
+	o := syncOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	report := SyncReport{DryRun: o.dryRun, Actions: []SyncAction{}}
+	if !o.dryRun {
+		if err := checkWritable("Sync", dst); err != nil {
+			return report, err
+		}
+	}
+	src, dst = filepath.Clean(src), filepath.Clean(dst)
+	srcInfo, err := o.statCache.cachedStat(src)
+	if err != nil {
+		return report, err
+	}
+	if !srcInfo.IsDir() {
+		return report, fmt.Errorf("Provided dir is not a dir: '%s'\n", src)
+	}
+	report.Actions, err = o.plan(src, dst, srcInfo)
+	if err != nil || o.dryRun {
+		return report, err
+	}
+	err = o.apply(src, dst, &report)
+	if o.checkpoint != nil {
+		err = errors.Join(err, o.checkpoint.Save())
+	}
+	return report, err
+}
+
+// plan - Returns the actions required to make dst match src.
+func (o *syncOptions) plan(src, dst string, srcInfo fs.FileInfo) ([]SyncAction, error) {
+	deletes := []SyncAction{}
+	changes := []SyncAction{}
+	add := func(rel string, fInfo fs.FileInfo) error {
+		path, target := filepath.Join(src, rel), filepath.Join(dst, rel)
+		dstInfo, err := o.statCache.cachedLstat(target)
+		// ENOTDIR when a parent dir is a file in dst.
+		if err != nil && !errclass.Is(err, errclass.NotFound) {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if dstInfo != nil && dstInfo.Mode().Type() != fInfo.Mode().Type() {
+			deletes = append(deletes, SyncAction{Op: SyncOpDelete, Path: rel, fInfo: dstInfo})
+			dstInfo = nil
+		}
+		switch {
+		case fInfo.IsDir():
+			if dstInfo == nil {
+				changes = append(changes, SyncAction{Op: SyncOpMkdir, Path: rel, fInfo: fInfo})
+			}
+		case fInfo.Mode()&fs.ModeSymlink != 0:
+			same := false
+			if dstInfo != nil {
+				same, err = sameLink(path, target)
+				if err != nil {
+					return err
+				}
+			}
+			if !same {
+				changes = append(changes, SyncAction{Op: SyncOpLink, Path: rel, fInfo: fInfo})
+			}
+		case fInfo.Mode().IsRegular():
+			same, err := o.sameFile(path, target, fInfo, dstInfo)
+			if err != nil {
+				return err
+			}
+			if !same {
+				changes = append(changes, SyncAction{Op: SyncOpCopy, Path: rel, Size: fInfo.Size(), fInfo: fInfo})
+			}
+		default:
+			Logger.Printf("Sync: skipping special file '%s'", path)
+		}
+		return nil
+	}
+	err := add(".", srcInfo)
+	if err != nil {
+		return nil, err
+	}
+	err = Walk(src, func(path string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		err = o.control.Wait()
+		if err != nil {
+			return err
+		}
+		fInfo, err := o.statCache.cachedLstat(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		return add(rel, fInfo)
+	}, o.listOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	if o.delete {
+		err = Walk(dst, func(path string, isDir bool, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dst, path)
+			if err != nil {
+				return err
+			}
+			srcInfo, err := o.statCache.cachedLstat(filepath.Join(src, rel))
+			if err == nil {
+				// Dirs replaced by another type are already deleted.
+				if isDir && !srcInfo.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// ENOTDIR when a parent dir is a file in src.
+			if !errclass.Is(err, errclass.NotFound) {
+				return err
+			}
+			fInfo, err := o.statCache.cachedLstat(path)
+			if err != nil {
+				return err
+			}
+			deletes = append(deletes, SyncAction{Op: SyncOpDelete, Path: filepath.ToSlash(rel), fInfo: fInfo})
+			if isDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}, o.listOptions()...)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return append(deletes, changes...), nil
+}
+
+// sameFile - Whether the regular file dst, with dstInfo, has the same
+// contents as src. dstInfo is nil when dst doesn't exist.
+func (o *syncOptions) sameFile(src, dst string, srcInfo, dstInfo fs.FileInfo) (bool, error) {
+	if dstInfo == nil || srcInfo.Size() != dstInfo.Size() {
+		return false, nil
+	}
+	if !o.checksum {
+		return srcInfo.ModTime().Equal(dstInfo.ModTime()), nil
+	}
+	hash := o.hash
+	if o.checkpoint != nil {
+		hash = o.checkpoint.Hash
+	} else if hash == nil {
+		hash = sha256File
+	}
+	a, err := hash(src)
+	if err != nil {
+		return false, err
+	}
+	b, err := hash(dst)
+	return a == b, err
+}
+
+// apply - Applies the planned actions and then sets the modification times
+// of the dirs, since changing their contents updates them.
+func (o *syncOptions) apply(src, dst string, report *SyncReport) error {
+	if o.spaceCheck {
+		err := CheckSpace(dst, o.syncRequired(dst, report.Actions))
+		if err != nil {
+			return err
+		}
+	}
+	total := len(report.Actions)
+	for i, a := range report.Actions {
+		err := o.control.Wait()
+		if err != nil {
+			return err
+		}
+		o.event(SyncEvent{Action: a, Index: i, Total: total})
+		n, err := o.applyRetry(src, dst, a, func(copied int64) {
+			if copied > 0 {
+				o.event(SyncEvent{Action: a, Index: i, Total: total, Transferred: copied})
+			}
+		})
+		o.statCache.forgetTree(filepath.Join(dst, filepath.FromSlash(a.Path)))
+		o.event(SyncEvent{Action: a, Index: i, Total: total, Transferred: n, Done: true, Err: err})
+		if err != nil {
+			return err
+		}
+		report.Applied++
+		report.Copied += n
+	}
+	srcInfo, err := o.statCache.cachedStat(src)
+	if err != nil {
+		return err
+	}
+	err = os.Chtimes(dst, srcInfo.ModTime(), srcInfo.ModTime())
+	if err != nil {
+		return err
+	}
+	return Walk(src, func(path string, isDir bool, err error) error {
+		if err != nil || !isDir {
+			return err
+		}
+		fInfo, err := o.statCache.cachedLstat(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		return os.Chtimes(filepath.Join(dst, rel), fInfo.ModTime(), fInfo.ModTime())
+	}, o.listOptions()...)
+}
+
+// listOptions - Options for the walks over src and dst.
+func (o *syncOptions) listOptions() []ListOption {
+	opts := []ListOption{ListExclude(o.exclude...)}
+	if o.statCache != nil {
+		opts = append(opts, ListStatCache(o.statCache))
+	}
+	return opts
+}
+
+func (o *syncOptions) event(e SyncEvent) {
+	if o.progress != nil {
+		o.progress(e)
+	}
+}
+
+// syncRequired - Returns the extra space dst needs for the copies, files
+// that replace existing ones only need the difference.
+func (o *syncOptions) syncRequired(dst string, actions []SyncAction) int64 {
+	var size int64
+	for _, a := range actions {
+		if a.Op != SyncOpCopy {
+			continue
+		}
+		n := a.Size
+		if dstInfo, err := o.statCache.cachedLstat(filepath.Join(dst, filepath.FromSlash(a.Path))); err == nil && dstInfo.Mode().IsRegular() {
+			n -= dstInfo.Size()
+		}
+		if n > 0 {
+			size += n
+		}
+	}
+	return size
+}
+
+// applyRetry - Applies a with the retry policy.
+func (o *syncOptions) applyRetry(src, dst string, a SyncAction, progress func(int64)) (int64, error) {
+	if o.retry == nil {
+		return o.applyAction(src, dst, a, progress)
+	}
+	var n int64
+	err := retryutils.Retry(context.Background(), *o.retry, func() error {
+		var err error
+		n, err = o.applyAction(src, dst, a, progress)
+		return err
+	})
+	return n, err
+}
+
+// applyAction - Applies a and returns the number of bytes transferred,
+// progress gets the bytes copied so far.
+func (o *syncOptions) applyAction(src, dst string, a SyncAction, progress func(int64)) (int64, error) {
+	source := filepath.Join(src, filepath.FromSlash(a.Path))
+	target := filepath.Join(dst, filepath.FromSlash(a.Path))
+	switch a.Op {
+	case SyncOpDelete:
+		return 0, os.RemoveAll(target)
+	case SyncOpMkdir:
+		err := os.MkdirAll(target, a.fInfo.Mode().Perm())
+		if err != nil {
+			return 0, err
+		}
+		return 0, os.Chmod(target, a.fInfo.Mode().Perm())
+	case SyncOpLink:
+		link, err := os.Readlink(source)
+		if err != nil {
+			return 0, err
+		}
+		err = os.Remove(target)
+		if err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		return 0, os.Symlink(link, target)
+	case SyncOpCopy:
+		n := a.Size
+		var err error
+		if dstInfo, statErr := o.statCache.cachedStat(target); o.delta && statErr == nil && dstInfo.Mode().IsRegular() {
+			n, err = syncDelta(source, target)
+		} else {
+			err = CopyFile(source, target, CopyProgress(func(copied, total int64) {
+				progress(copied)
+			}))
+		}
+		if err != nil {
+			return 0, err
+		}
+		if o.checkpoint != nil {
+			// The recorded digest is stale even if the size and times match.
+			err = o.checkpoint.Forget(target)
+			if err != nil {
+				return 0, err
+			}
+		}
+		err = os.Chmod(target, a.fInfo.Mode().Perm())
+		if err != nil {
+			return 0, err
+		}
+		return n, os.Chtimes(target, a.fInfo.ModTime(), a.fInfo.ModTime())
+	}
+	return 0, nil
+}
+
+// syncDelta - Updates dst to match src with a binary delta and returns the
+// delta size.
+// The delta is streamed from BinaryDiff into ApplyBinaryDelta, which writes
+// the result to a temp file next to dst that is only renamed into place
+// once the checksum recorded in the delta matches.
+func syncDelta(src, dst string) (int64, error) {
+	// BinaryDiff reads old sequentially while ApplyBinaryDelta uses ReadAt,
+	// which doesn't move the offset.
+	old, err := os.Open(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer old.Close()
+	fh, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+	tmpFile, err := ioutil.TempFile(filepath.Dir(dst), "."+filepath.Base(dst)+"-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	pr, pw := io.Pipe()
+	delta := &countWriter{w: pw}
+	diffErr := make(chan error, 1)
+	go func() {
+		err := BinaryDiff(old, fh, delta)
+		pw.CloseWithError(err)
+		diffErr <- err
+	}()
+	err = ApplyBinaryDelta(old, pr, tmpFile)
+	// Unblocks BinaryDiff if ApplyBinaryDelta stopped early.
+	pr.Close()
+	if derr := <-diffErr; derr != nil && derr != io.ErrClosedPipe {
+		err = derr
+	}
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if cerr := tmpFile.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	Logger.Printf("Sync: delta '%s': %d bytes", src, delta.n)
+	return delta.n, os.Rename(tmpFile.Name(), dst)
+}
+
+// countWriter - Counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,356 @@
+package fileutils
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DavidGamba/go-utils/retryutils"
+)
+
+// syncTime - Modification time of the files written by writeTree.
+var syncTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// writeTree - Creates the entries in tree under dir, keys ending in "/" are
+// dirs and values starting with "->" symlinks to the rest of the value.
+func writeTree(t *testing.T, dir string, tree map[string]string) {
+	t.Helper()
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	for name, contents := range tree {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		switch {
+		case strings.HasSuffix(name, "/"):
+			err = os.MkdirAll(path, 0755)
+		case strings.HasPrefix(contents, "->"):
+			err = os.Symlink(contents[2:], path)
+		default:
+			err = ioutil.WriteFile(path, []byte(contents), 0644)
+			if err == nil {
+				err = os.Chtimes(path, syncTime, syncTime)
+			}
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	}
+}
+
+// readTree - Returns the entries under dir in the writeTree format.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	tree := map[string]string{}
+	err := Walk(dir, func(path string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		fInfo, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case isDir:
+			tree[rel+"/"] = ""
+		case fInfo.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = "->" + target
+		default:
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = string(b)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	return tree
+}
+
+func actionList(actions []SyncAction) []string {
+	list := []string{}
+	for _, a := range actions {
+		list = append(list, string(a.Op)+" "+a.Path)
+	}
+	return list
+}
+
+func TestSync(t *testing.T) {
+	src := map[string]string{"a": "a", "d/": "", "d/b": "b", "l": "->a"}
+	tests := []struct {
+		name     string
+		src      map[string]string
+		dst      map[string]string
+		opts     []SyncOption
+		actions  []string
+		expected map[string]string
+	}{
+		{"new dst", src, nil, nil,
+			[]string{"mkdir .", "copy a", "mkdir d", "copy d/b", "link l"}, src},
+		{"unchanged", src, src, nil, []string{}, src},
+		{"changed size", src, map[string]string{"a": "old", "d/b": "b", "l": "->a"}, nil,
+			[]string{"copy a"}, src},
+		{"same size and time", src, map[string]string{"a": "x", "d/b": "b", "l": "->a"}, nil,
+			[]string{}, map[string]string{"a": "x", "d/": "", "d/b": "b", "l": "->a"}},
+		{"checksum", src, map[string]string{"a": "x", "d/b": "b", "l": "->a"}, []SyncOption{SyncChecksum(nil)},
+			[]string{"copy a"}, src},
+		{"extra kept", src, map[string]string{"a": "a", "d/b": "b", "l": "->a", "x": "x"}, nil,
+			[]string{}, map[string]string{"a": "a", "d/": "", "d/b": "b", "l": "->a", "x": "x"}},
+		{"extra deleted", src, map[string]string{"a": "a", "d/b": "b", "l": "->a", "e/f": "f", "x": "x"}, []SyncOption{SyncDelete()},
+			[]string{"delete e", "delete x"}, src},
+		{"exclude", map[string]string{"a": "a", "s.tmp": "s"}, map[string]string{"d.tmp": "d"}, []SyncOption{SyncDelete(), SyncExclude("*.tmp")},
+			[]string{"copy a"}, map[string]string{"a": "a", "d.tmp": "d"}},
+		{"file replaces dir", map[string]string{"a": "a"}, map[string]string{"a/x": "x"}, nil,
+			[]string{"delete a", "copy a"}, map[string]string{"a": "a"}},
+		{"file replaces dir with delete", map[string]string{"a": "a"}, map[string]string{"a/x/y": "y"}, []SyncOption{SyncDelete()},
+			[]string{"delete a", "copy a"}, map[string]string{"a": "a"}},
+		{"dir replaces file", map[string]string{"d/b": "b"}, map[string]string{"d": "d"}, nil,
+			[]string{"delete d", "mkdir d", "copy d/b"}, map[string]string{"d/": "", "d/b": "b"}},
+		{"link target", src, map[string]string{"a": "a", "d/b": "b", "l": "->d"}, nil,
+			[]string{"link l"}, src},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "fileutils-")
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			defer os.RemoveAll(dir)
+			srcDir, dstDir := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+			writeTree(t, srcDir, test.src)
+			if test.dst != nil {
+				writeTree(t, dstDir, test.dst)
+			}
+			report, err := Sync(srcDir, dstDir, test.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			got := actionList(report.Actions)
+			if !reflect.DeepEqual(got, test.actions) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.actions, got)
+			}
+			if report.Applied != len(test.actions) {
+				t.Errorf("Expected:\n%d\nGot:\n%d\n", len(test.actions), report.Applied)
+			}
+			expected := map[string]string{}
+			for name, contents := range test.expected {
+				expected[name] = contents
+				// Parent dirs are implied.
+				for d := filepath.ToSlash(filepath.Dir(name)); d != "."; d = filepath.ToSlash(filepath.Dir(d)) {
+					expected[d+"/"] = ""
+				}
+			}
+			tree := readTree(t, dstDir)
+			if !reflect.DeepEqual(tree, expected) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, tree)
+			}
+
+			// A second run has nothing to do.
+			report, err = Sync(srcDir, dstDir, test.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if len(report.Actions) != 0 {
+				t.Errorf("Unexpected actions: %q\n", actionList(report.Actions))
+			}
+		})
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	srcDir, dstDir := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	writeTree(t, srcDir, map[string]string{"a": "a", "d/b": "b"})
+	report, err := Sync(srcDir, dstDir, SyncDryRun())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []string{"mkdir .", "copy a", "mkdir d", "copy d/b"}
+	if !reflect.DeepEqual(actionList(report.Actions), expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, actionList(report.Actions))
+	}
+	if !report.DryRun || report.Applied != 0 || report.Copied != 0 {
+		t.Errorf("Unexpected report: %+v\n", report)
+	}
+	if _, err := os.Stat(dstDir); !os.IsNotExist(err) {
+		t.Errorf("Unexpected dst: %v\n", err)
+	}
+}
+
+func TestSyncDelta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	srcDir, dstDir := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	old := make([]byte, 256*1024)
+	for i := range old {
+		old[i] = byte(i * 7 % 251)
+	}
+	data := append([]byte{}, old...)
+	copy(data[100*1024:], "changed")
+	writeTree(t, srcDir, map[string]string{"f": string(data)})
+	writeTree(t, dstDir, map[string]string{"f": string(old) + "x"})
+
+	report, err := Sync(srcDir, dstDir, SyncDelta())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if report.Copied <= 0 || report.Copied >= int64(len(data))/10 {
+		t.Errorf("Expected a small delta, got: %d\n", report.Copied)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dstDir, "f"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !bytes.Equal(b, data) {
+		t.Errorf("dst doesn't match src\n")
+	}
+	tree := readTree(t, dstDir)
+	if len(tree) != 1 {
+		t.Errorf("Expected the temp file to be removed, got: %q\n", tree)
+	}
+}
+
+func TestSyncControl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	srcDir, dstDir := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	writeTree(t, srcDir, map[string]string{"a": "a", "b": "b", "c": "c"})
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+	cache, err := LoadProcessedCache(checkpoint)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	// Cancel after the first copy.
+	c := NewControl()
+	events := []string{}
+	report, err := Sync(srcDir, dstDir, SyncControl(c), SyncChecksum(nil), SyncCheckpoint(cache), SyncProgress(func(e SyncEvent) {
+		if e.Done {
+			events = append(events, string(e.Action.Op)+" "+e.Action.Path)
+			if e.Action.Path == "a" {
+				c.Cancel()
+			}
+		}
+	}))
+	if !errors.Is(err, ErrCanceled) {
+		t.Fatalf("Expected ErrCanceled, got: %v\n", err)
+	}
+	if report.Applied != 2 || len(report.Actions) != 4 {
+		t.Errorf("Unexpected report: %+v\n", report)
+	}
+	expected := []string{"mkdir .", "copy a"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, events)
+	}
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Errorf("Expected the checkpoint to be saved: %s\n", err)
+	}
+
+	// Running it again resumes.
+	report, err = Sync(srcDir, dstDir, SyncChecksum(nil), SyncCheckpoint(cache))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{"copy b", "copy c"}
+	if !reflect.DeepEqual(actionList(report.Actions), expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, actionList(report.Actions))
+	}
+}
+
+func TestSyncRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	srcDir, dstDir := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	writeTree(t, srcDir, map[string]string{"a": "a"})
+	blocker := filepath.Join(dstDir, "a", "x")
+
+	// A dir in the way of the copy fails it until the retry removes it.
+	block := SyncProgress(func(e SyncEvent) {
+		if e.Action.Path == "a" && !e.Done && e.Transferred == 0 {
+			os.MkdirAll(blocker, 0755)
+		}
+	})
+	policy := retryutils.Policy{MaxAttempts: 2, Retryable: func(err error) bool {
+		return os.RemoveAll(filepath.Dir(blocker)) == nil
+	}}
+	_, err = Sync(srcDir, dstDir, block)
+	if err == nil {
+		t.Fatalf("Expected error without retry\n")
+	}
+	os.RemoveAll(dstDir)
+
+	report, err := Sync(srcDir, dstDir, block, SyncRetry(policy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if report.Applied != 2 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 2, report.Applied)
+	}
+	tree := readTree(t, dstDir)
+	expected := map[string]string{"a": "a"}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, tree)
+	}
+}
+
+func TestSyncStatCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	srcDir, dstDir := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	writeTree(t, srcDir, map[string]string{"a": "a", "d/b": "b"})
+	writeTree(t, dstDir, map[string]string{"d": "d", "x/y": "y"})
+
+	c := NewStatCache()
+	report, err := Sync(srcDir, dstDir, SyncDelete(), SyncStatCache(c))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []string{"delete d", "delete x", "copy a", "mkdir d", "copy d/b"}
+	if !reflect.DeepEqual(actionList(report.Actions), expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, actionList(report.Actions))
+	}
+
+	// The changed dst paths are not served stale from the cache.
+	report, err = Sync(srcDir, dstDir, SyncDelete(), SyncStatCache(c))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(report.Actions) != 0 {
+		t.Errorf("Unexpected actions: %q\n", actionList(report.Actions))
+	}
+}
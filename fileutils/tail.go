@@ -0,0 +1,375 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TailLine - A line read by TailMany or an error indicating failure (`Error`).
+type TailLine struct {
+	// File - Path of the file the line was read from, as matched by the pattern.
+	File string
+
+	// Line - 1 based line number within the file.
+	Line int
+
+	// Text - Line contents without the line terminator.
+	Text string
+
+	Error error
+}
+
+// TailOptions - TailMany options.
+type TailOptions struct {
+	// PollInterval - How often to check for new files and new lines, defaults to 250ms.
+	PollInterval time.Duration
+
+	// FromStart - Read the files that match when TailMany starts from the
+	// beginning instead of only following the lines written afterwards.
+	// Files that show up later are always read from the beginning.
+	FromStart bool
+
+	// State - When set, files with a saved offset are resumed from it and
+	// the offset of each emitted line is recorded. Saving it is left to the caller.
+	State *TailState
+}
+
+// tailFile - A followed file.
+type tailFile struct {
+	path    string
+	fh      *os.File
+	reader  *bufio.Reader
+	offset  int64
+	line    int
+	partial []byte
+}
+
+// TailMany - Follows all the files matching the filepath.Glob patterns and
+// sends their lines, tagged with the file they came from, to the returned
+// channel.
+// Patterns are checked again on every poll so new files are picked up.
+// Rotated files are read to the end before following their replacement and
+// truncated files are followed from the beginning again.
+// Incomplete lines are held until their line terminator is written.
+//
+// The channel is closed when ctx is cancelled.
+func TailMany(ctx context.Context, patterns []string, opts TailOptions) (<-chan TailLine, error) {
+	for _, pattern := range patterns {
+		_, err := filepath.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("%w: '%s'", err, pattern)
+		}
+	}
+	c := make(chan TailLine)
+	go func() {
+		defer close(c)
+		t := newTailer(ctx, opts, func(l TailLine) bool {
+			select {
+			case c <- l:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		t.run(func() []string {
+			matches := []string{}
+			for _, pattern := range patterns {
+				m, _ := filepath.Glob(pattern)
+				matches = append(matches, m...)
+			}
+			sort.Strings(matches)
+			return matches
+		})
+	}()
+	return c, nil
+}
+
+// FollowLines - Sends the lines appended to filename, like tail -F, to the
+// returned channel.
+// When the file is rotated, the rest of the old file is read before the new
+// one is followed from its beginning, and when it is truncated it is
+// followed from the beginning again. A missing file is waited for.
+// Use TailMany to resume from a saved TailState.
+//
+// The channel is closed when ctx is cancelled.
+func FollowLines(ctx context.Context, filename string) <-chan StringError {
+	c := make(chan StringError)
+	go func() {
+		defer close(c)
+		t := newTailer(ctx, TailOptions{PollInterval: followPollInterval}, func(l TailLine) bool {
+			select {
+			case c <- StringError{String: l.Text, Error: l.Error}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		t.run(func() []string {
+			_, err := os.Lstat(filename)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return []string{filename}
+		})
+	}()
+	return c
+}
+
+// followPollInterval - How often FollowLines checks the file.
+var followPollInterval = 250 * time.Millisecond
+
+type tailer struct {
+	ctx   context.Context
+	opts  TailOptions
+	files map[string]*tailFile
+
+	// emit - Delivers a line, returns false when ctx was cancelled.
+	emit func(TailLine) bool
+
+	// failed - Files that couldn't be opened, the error is only reported once.
+	failed map[string]bool
+}
+
+func newTailer(ctx context.Context, opts TailOptions, emit func(TailLine) bool) *tailer {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 250 * time.Millisecond
+	}
+	return &tailer{ctx: ctx, opts: opts, emit: emit, files: map[string]*tailFile{}, failed: map[string]bool{}}
+}
+
+// run - Follows the files returned by match, checked on every poll, until ctx is cancelled.
+func (t *tailer) run(match func() []string) {
+	defer t.closeAll()
+	t.poll(match(), !t.opts.FromStart)
+	ticker := time.NewTicker(t.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+			if !t.poll(match(), false) {
+				return
+			}
+		}
+	}
+}
+
+// send - Returns false when ctx was cancelled.
+func (t *tailer) send(l TailLine) bool {
+	if t.ctx.Err() != nil {
+		return false
+	}
+	return t.emit(l)
+}
+
+// poll - Opens the new matching files and reads the new lines of all of them.
+// Returns false when ctx was cancelled.
+func (t *tailer) poll(matches []string, fromEnd bool) bool {
+	for _, path := range matches {
+		if _, ok := t.files[path]; ok {
+			continue
+		}
+		f, err := t.open(path, fromEnd)
+		if err != nil {
+			if !t.failed[path] {
+				t.failed[path] = true
+				if !t.send(TailLine{File: path, Error: err}) {
+					return false
+				}
+			}
+			continue
+		}
+		if f == nil {
+			continue
+		}
+		delete(t.failed, path)
+		t.files[path] = f
+	}
+	paths := make([]string, 0, len(t.files))
+	for path := range t.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if !t.follow(t.files[path]) {
+			return false
+		}
+	}
+	return true
+}
+
+// open - Opens path at the saved offset, at the end when fromEnd is set, or
+// at the beginning. Returns nil when path is a dir.
+func (t *tailer) open(path string, fromEnd bool) (*tailFile, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fInfo, err := fh.Stat()
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	if fInfo.IsDir() {
+		fh.Close()
+		return nil, nil
+	}
+	var offset int64
+	saved := false
+	if t.opts.State != nil {
+		offset, saved, err = t.opts.State.saved(path, fInfo)
+		if err != nil {
+			fh.Close()
+			return nil, err
+		}
+	}
+	if !saved && fromEnd {
+		offset = fInfo.Size()
+	}
+	line, offset, err := countLines(fh, offset)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	_, err = fh.Seek(offset, io.SeekStart)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	Logger.Printf("TailMany: following '%s' from offset %d", path, offset)
+	return &tailFile{path: path, fh: fh, reader: bufio.NewReader(fh), offset: offset, line: line}, nil
+}
+
+// countLines - Returns the number of complete lines before offset and the
+// offset of the end of the last of them, so reading never starts mid line.
+func countLines(r io.Reader, offset int64) (int, int64, error) {
+	if offset == 0 {
+		return 0, 0, nil
+	}
+	n := 0
+	var end, pos int64
+	buf := make([]byte, 32*1024)
+	for pos < offset {
+		size := int64(len(buf))
+		if offset-pos < size {
+			size = offset - pos
+		}
+		read, err := r.Read(buf[:size])
+		for i, b := range buf[:read] {
+			if b == '\n' {
+				n++
+				end = pos + int64(i) + 1
+			}
+		}
+		pos += int64(read)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return n, end, nil
+}
+
+// follow - Sends the new complete lines of f and handles rotation and truncation.
+// Returns false when ctx was cancelled.
+func (t *tailer) follow(f *tailFile) bool {
+	if !t.readLines(f) {
+		return false
+	}
+	fInfo, err := os.Stat(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			Logger.Printf("TailMany: '%s' was removed", f.path)
+			f.fh.Close()
+			delete(t.files, f.path)
+			return true
+		}
+		return t.send(TailLine{File: f.path, Error: err})
+	}
+	current, err := f.fh.Stat()
+	if err != nil {
+		return t.send(TailLine{File: f.path, Error: err})
+	}
+	if !os.SameFile(fInfo, current) {
+		Logger.Printf("TailMany: '%s' was replaced, reading from the start", f.path)
+		f.fh.Close()
+		delete(t.files, f.path)
+		nf, err := t.open(f.path, false)
+		if err != nil {
+			return t.send(TailLine{File: f.path, Error: err})
+		}
+		if nf != nil {
+			t.files[f.path] = nf
+			return t.readLines(nf)
+		}
+		return true
+	}
+	if current.Size() < f.offset {
+		Logger.Printf("TailMany: '%s' was truncated, reading from the start", f.path)
+		_, err := f.fh.Seek(0, io.SeekStart)
+		if err != nil {
+			return t.send(TailLine{File: f.path, Error: err})
+		}
+		f.reader.Reset(f.fh)
+		f.offset, f.line, f.partial = 0, 0, nil
+		return t.readLines(f)
+	}
+	return true
+}
+
+// readLines - Sends the complete lines available in f.
+// Returns false when ctx was cancelled.
+func (t *tailer) readLines(f *tailFile) bool {
+	if t.opts.State != nil {
+		start := f.offset
+		defer func() {
+			if f.offset != start {
+				t.opts.State.Update(f.fh, f.offset)
+			}
+		}()
+	}
+	for {
+		data, err := f.reader.ReadBytes('\n')
+		if len(data) > 0 && data[len(data)-1] == '\n' {
+			line := append(f.partial, data...)
+			f.partial = nil
+			f.line++
+			if !t.send(TailLine{File: f.path, Line: f.line, Text: strings.TrimRight(string(line), "\r\n")}) {
+				return false
+			}
+			f.offset += int64(len(line))
+			continue
+		}
+		f.partial = append(f.partial, data...)
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			return t.send(TailLine{File: f.path, Error: err})
+		}
+	}
+}
+
+func (t *tailer) closeAll() {
+	for _, f := range t.files {
+		f.fh.Close()
+	}
+}
@@ -0,0 +1,202 @@
+package fileutils
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func appendFile(t *testing.T, file, data string) {
+	t.Helper()
+	fh, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	fh.WriteString(data)
+	fh.Close()
+}
+
+func nextLine(t *testing.T, c <-chan TailLine) TailLine {
+	t.Helper()
+	select {
+	case l := <-c:
+		if l.Error != nil {
+			t.Fatalf("Unexpected error: %s\n", l.Error)
+		}
+		return l
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for a line\n")
+	}
+	return TailLine{}
+}
+
+func TestTailMany(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	appendFile(t, a, "old\n")
+
+	_, err = TailMany(context.Background(), []string{"["}, TailOptions{})
+	if err == nil {
+		t.Errorf("Expected invalid pattern error\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := TailMany(ctx, []string{filepath.Join(dir, "*.log")}, TailOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	// Give the first poll time to open a.log at its end.
+	time.Sleep(50 * time.Millisecond)
+
+	appendFile(t, a, "a1\npar")
+	appendFile(t, b, "b1\r\n")
+	appendFile(t, a, "tial\n")
+
+	expected := []TailLine{
+		{File: a, Line: 2, Text: "a1"},
+		{File: a, Line: 3, Text: "partial"},
+		{File: b, Line: 1, Text: "b1"},
+	}
+	got := map[string][]TailLine{}
+	for i := 0; i < len(expected); i++ {
+		l := nextLine(t, c)
+		got[l.File] = append(got[l.File], l)
+	}
+	all := append(got[a], got[b]...)
+	for i, e := range expected {
+		if i >= len(all) || all[i] != e {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, all)
+			break
+		}
+	}
+
+	// Rotation
+	os.Rename(a, a+".1")
+	appendFile(t, a, "new\n")
+	l := nextLine(t, c)
+	if l.File != a || l.Line != 1 || l.Text != "new" {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", TailLine{File: a, Line: 1, Text: "new"}, l)
+	}
+
+	// Truncation
+	ioutil.WriteFile(b, []byte("t\n"), 0644)
+	l = nextLine(t, c)
+	if l.File != b || l.Line != 1 || l.Text != "t" {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", TailLine{File: b, Line: 1, Text: "t"}, l)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-c:
+		for ok {
+			_, ok = <-c
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the channel to be closed\n")
+	}
+}
+
+func TestTailManyState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a.log")
+	appendFile(t, a, "1\n2\n")
+	s, err := LoadTailState(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c, err := TailMany(ctx, []string{a}, TailOptions{PollInterval: 10 * time.Millisecond, FromStart: true, State: s})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	nextLine(t, c)
+	nextLine(t, c)
+	cancel()
+	for range c {
+	}
+
+	appendFile(t, a, "3\n")
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	c, err = TailMany(ctx, []string{a}, TailOptions{PollInterval: 10 * time.Millisecond, FromStart: true, State: s})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	l := nextLine(t, c)
+	if l.Line != 3 || l.Text != "3" {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", TailLine{File: a, Line: 3, Text: "3"}, l)
+	}
+}
+
+func TestFollowLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a.log")
+	defer func(d time.Duration) { followPollInterval = d }(followPollInterval)
+	followPollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := FollowLines(ctx, file)
+	next := func() string {
+		t.Helper()
+		select {
+		case l := <-c:
+			if l.Error != nil {
+				t.Fatalf("Unexpected error: %s\n", l.Error)
+			}
+			return l.String
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for a line\n")
+		}
+		return ""
+	}
+
+	// Missing files are waited for and read from the start.
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, file, "created\n")
+	appendFile(t, file, "appended\n")
+	for _, expected := range []string{"created", "appended"} {
+		if got := next(); got != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, got)
+		}
+	}
+
+	// Rotation
+	appendFile(t, file, "last\n")
+	os.Rename(file, file+".1")
+	appendFile(t, file, "rotated\n")
+	for _, expected := range []string{"last", "rotated"} {
+		if got := next(); got != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, got)
+		}
+	}
+
+	// Truncation
+	os.Truncate(file, 0)
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, file, "new\n")
+	if got := next(); got != "new" {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", "new", got)
+	}
+
+	cancel()
+	for range c {
+	}
+}
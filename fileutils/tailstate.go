@@ -0,0 +1,168 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// TailState - Persists the last read offset of followed files so a follower
+// can resume exactly where it left off across process restarts.
+//
+// Files are identified by their absolute path and, where the platform
+// supports it, their device and inode numbers. A saved offset is discarded
+// when the file was replaced (log rotation) or truncated, so reading starts
+// again from the beginning of the new file.
+//
+// Offsets should only be updated after the lines up to them have been
+// processed, that way lines are neither lost nor emitted twice.
+// It is safe for concurrent use.
+type TailState struct {
+	filename string
+	mu       sync.Mutex
+	files    map[string]tailEntry
+}
+
+type tailEntry struct {
+	Offset int64  `json:"offset"`
+	Dev    uint64 `json:"dev,omitempty"`
+	Inode  uint64 `json:"inode,omitempty"`
+}
+
+// LoadTailState - Loads the state saved in filename.
+// A missing file results in an empty state that will be saved to filename.
+func LoadTailState(filename string) (*TailState, error) {
+	s := &TailState{filename: filename, files: map[string]tailEntry{}}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, &s.files)
+	if err != nil {
+		return nil, fmt.Errorf("'%s': invalid tail state: %w", filename, err)
+	}
+	return s, nil
+}
+
+// Save - Writes the state to its file atomically.
+func (s *TailState) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.files, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(s.filename, append(data, '\n'), 0644)
+}
+
+// Offset - Returns the offset to resume reading file from.
+// It is 0 when the file has no saved offset, was replaced or was truncated.
+func (s *TailState) Offset(file string) (int64, error) {
+	fInfo, err := os.Stat(file)
+	if err != nil {
+		return 0, err
+	}
+	return s.offset(file, fInfo)
+}
+
+func (s *TailState) offset(file string, fInfo os.FileInfo) (int64, error) {
+	offset, _, err := s.saved(file, fInfo)
+	return offset, err
+}
+
+// saved - Returns the offset to resume reading file from and whether the
+// state had an entry for it.
+func (s *TailState) saved(file string, fInfo os.FileInfo) (int64, bool, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return 0, false, err
+	}
+	s.mu.Lock()
+	e, ok := s.files[abs]
+	s.mu.Unlock()
+	if !ok {
+		return 0, false, nil
+	}
+	if dev, ino, ok := fileID(fInfo); ok && (dev != e.Dev || ino != e.Inode) {
+		Logger.Printf("TailState: '%s' was replaced, reading from the start", file)
+		return 0, true, nil
+	}
+	if fInfo.Size() < e.Offset {
+		Logger.Printf("TailState: '%s' was truncated, reading from the start", file)
+		return 0, true, nil
+	}
+	return e.Offset, true, nil
+}
+
+// Open - Opens file positioned at its saved offset.
+// The offset is checked against the opened file so a rotation between the
+// check and the open can't be missed.
+func (s *TailState) Open(file string) (*os.File, int64, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	fInfo, err := fh.Stat()
+	if err != nil {
+		fh.Close()
+		return nil, 0, err
+	}
+	offset, err := s.offset(file, fInfo)
+	if err != nil {
+		fh.Close()
+		return nil, 0, err
+	}
+	_, err = fh.Seek(offset, io.SeekStart)
+	if err != nil {
+		fh.Close()
+		return nil, 0, err
+	}
+	return fh, offset, nil
+}
+
+// Update - Records offset as the position up to which the open file has been processed.
+// The file identity is taken from the handle, not the path, so it refers to
+// the file that was actually read.
+func (s *TailState) Update(fh *os.File, offset int64) error {
+	fInfo, err := fh.Stat()
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(fh.Name())
+	if err != nil {
+		return err
+	}
+	e := tailEntry{Offset: offset}
+	e.Dev, e.Inode, _ = fileID(fInfo)
+	s.mu.Lock()
+	s.files[abs] = e
+	s.mu.Unlock()
+	return nil
+}
+
+// Forget - Removes the saved offset of file.
+func (s *TailState) Forget(file string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.files, abs)
+	s.mu.Unlock()
+	return nil
+}
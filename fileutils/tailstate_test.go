@@ -0,0 +1,119 @@
+package fileutils
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readRest - Reads the remaining lines and returns them with the offset after the last one.
+func readRest(t *testing.T, fh *os.File, offset int64) ([]string, int64) {
+	t.Helper()
+	lines := []string{}
+	r := bufio.NewReader(fh)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		offset += int64(len(line))
+		lines = append(lines, line)
+	}
+	return lines, offset
+}
+
+func TestTailState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "app.log")
+	stateFile := filepath.Join(dir, "state.json")
+	ioutil.WriteFile(log, []byte("a\nb\n"), 0644)
+
+	s, err := LoadTailState(stateFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	fh, offset, err := s.Open(log)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	lines, offset := readRest(t, fh, offset)
+	if len(lines) != 2 {
+		t.Errorf("Expected 2 lines, got: %q\n", lines)
+	}
+	s.Update(fh, offset)
+	fh.Close()
+	err = s.Save()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	// Restart, only the new line is read.
+	fh2, _ := os.OpenFile(log, os.O_APPEND|os.O_WRONLY, 0644)
+	fh2.WriteString("c\n")
+	fh2.Close()
+	s, err = LoadTailState(stateFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	fh, offset, err = s.Open(log)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if offset != 4 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 4, offset)
+	}
+	lines, offset = readRest(t, fh, offset)
+	if len(lines) != 1 || lines[0] != "c\n" {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", []string{"c\n"}, lines)
+	}
+	s.Update(fh, offset)
+	fh.Close()
+
+	// Truncation restarts from the beginning.
+	ioutil.WriteFile(log, []byte("d\n"), 0644)
+	offset, err = s.Offset(log)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if offset != 0 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 0, offset)
+	}
+
+	// Rotation, a new file with the same name, restarts from the beginning.
+	os.Rename(log, log+".1")
+	ioutil.WriteFile(log, []byte("e\nf\ng\nh\n"), 0644)
+	offset, err = s.Offset(log)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if _, _, ok := fileID(mustStat(t, log)); ok && offset != 0 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 0, offset)
+	}
+
+	s.Forget(log)
+	offset, _ = s.Offset(log)
+	if offset != 0 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 0, offset)
+	}
+
+	ioutil.WriteFile(stateFile, []byte("{"), 0644)
+	_, err = LoadTailState(stateFile)
+	if err == nil {
+		t.Errorf("Expected invalid state error\n")
+	}
+}
+
+func mustStat(t *testing.T, file string) os.FileInfo {
+	t.Helper()
+	fInfo, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	return fInfo
+}
@@ -0,0 +1,193 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FilterLinesByTime - Returns a channel with each line of src whose
+// timestamp is within from and to, both inclusive, or an error indicating
+// failure (`channel.Error`).
+//
+// The timestamp is parsed, with time.ParseInLocation in the location of
+// from, from as many leading space separated fields as layout has.
+// Lines without a timestamp, like stack traces, belong to the previous line
+// that has one.
+//
+// Log files are usually in chronological order, so the first matching line
+// is found with a binary search over the file offsets and reading stops
+// after the last one. When the lines sampled by the search are out of order
+// the whole file is scanned instead.
+func FilterLinesByTime(src, layout string, from, to time.Time) <-chan StringError {
+	c := make(chan StringError)
+	go func() {
+		defer close(c)
+		fh, err := os.Open(src)
+		if err != nil {
+			c <- StringError{"", err}
+			return
+		}
+		defer fh.Close()
+		fInfo, err := fh.Stat()
+		if err != nil {
+			c <- StringError{"", err}
+			return
+		}
+		tf := timeFilter{fh: fh, fields: len(strings.Fields(layout)), layout: layout, loc: from.Location()}
+		start, monotonic, err := tf.search(fInfo.Size(), from)
+		if err != nil {
+			c <- StringError{"", fmt.Errorf("'%s': %w", src, err)}
+			return
+		}
+		if !monotonic {
+			Logger.Printf("FilterLinesByTime: '%s' is not in chronological order, scanning the whole file", src)
+			start = 0
+		}
+		_, err = fh.Seek(start, io.SeekStart)
+		if err != nil {
+			c <- StringError{"", err}
+			return
+		}
+		r := bufio.NewReader(fh)
+		inRange := false
+		for {
+			line, err := r.ReadString('\n')
+			if len(line) > 0 {
+				line = strings.TrimRight(line, "\r\n")
+				if ts, ok := tf.parse(line); ok {
+					if monotonic && ts.After(to) {
+						return
+					}
+					inRange = !ts.Before(from) && !ts.After(to)
+				}
+				if inRange {
+					c <- StringError{line, nil}
+				}
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				c <- StringError{"", err}
+				return
+			}
+		}
+	}()
+	return c
+}
+
+type timeFilter struct {
+	fh     *os.File
+	fields int
+	layout string
+	loc    *time.Location
+}
+
+// parse - Parses the timestamp at the start of line.
+func (tf timeFilter) parse(line string) (time.Time, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < tf.fields || tf.fields == 0 {
+		return time.Time{}, false
+	}
+	ts, err := time.ParseInLocation(tf.layout, strings.Join(fields[:tf.fields], " "), tf.loc)
+	return ts, err == nil
+}
+
+// lineAt - Returns the start offset and timestamp of the first line with a
+// timestamp that starts at or after offset.
+func (tf timeFilter) lineAt(offset int64) (int64, time.Time, bool, error) {
+	_, err := tf.fh.Seek(offset, io.SeekStart)
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+	r := bufio.NewReader(tf.fh)
+	if offset > 0 {
+		// offset is likely mid line, move to the start of the next one.
+		skipped, err := r.ReadString('\n')
+		offset += int64(len(skipped))
+		if err == io.EOF {
+			return 0, time.Time{}, false, nil
+		}
+		if err != nil {
+			return 0, time.Time{}, false, err
+		}
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if ts, ok := tf.parse(strings.TrimRight(line, "\r\n")); ok {
+			return offset, ts, true, nil
+		}
+		offset += int64(len(line))
+		if err == io.EOF {
+			return 0, time.Time{}, false, nil
+		}
+		if err != nil {
+			return 0, time.Time{}, false, err
+		}
+	}
+}
+
+// search - Returns the offset of the first line with a timestamp not before
+// from and whether the timestamps of the sampled lines are in order.
+func (tf timeFilter) search(size int64, from time.Time) (int64, bool, error) {
+	type sample struct {
+		offset int64
+		ts     time.Time
+	}
+	samples := []sample{}
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, ts, ok, err := tf.lineAt(mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			hi = mid
+			continue
+		}
+		samples = append(samples, sample{start, ts})
+		if ts.Before(from) {
+			lo = start + 1
+		} else {
+			hi = mid
+		}
+	}
+	// Include the first line so a file that isn't ordered at all is detected
+	// even when the search only went one way.
+	if start, ts, ok, err := tf.lineAt(0); err != nil {
+		return 0, false, err
+	} else if ok {
+		samples = append(samples, sample{start, ts})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].offset < samples[j].offset })
+	for i := 1; i < len(samples); i++ {
+		if samples[i].ts.Before(samples[i-1].ts) {
+			return 0, false, nil
+		}
+	}
+	if lo == 0 {
+		return 0, true, nil
+	}
+	start, _, ok, err := tf.lineAt(lo)
+	if err != nil {
+		return 0, false, err
+	}
+	if !ok {
+		return size, true, nil
+	}
+	return start, true, nil
+}
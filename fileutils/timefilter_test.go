@@ -0,0 +1,82 @@
+package fileutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterLinesByTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	layout := "2006-01-02 15:04:05"
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	lines := []string{"header without timestamp"}
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, fmt.Sprintf("%s INFO line %d", base.Add(time.Duration(i)*time.Minute).Format(layout), i))
+		if i%100 == 0 {
+			lines = append(lines, "\tstack trace")
+		}
+	}
+	ordered := filepath.Join(dir, "ordered.log")
+	ioutil.WriteFile(ordered, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+
+	// Newest first.
+	unordered := filepath.Join(dir, "unordered.log")
+	reversed := []string{}
+	for i := len(lines) - 1; i >= 0; i-- {
+		reversed = append(reversed, lines[i])
+	}
+	ioutil.WriteFile(unordered, []byte(strings.Join(reversed, "\n")), 0644)
+
+	tests := []struct {
+		name     string
+		file     string
+		from, to time.Time
+		expected []string
+	}{
+		{"range", ordered, base.Add(299 * time.Minute), base.Add(301 * time.Minute), []string{
+			"2020-01-01 04:59:00 INFO line 299",
+			"2020-01-01 05:00:00 INFO line 300",
+			"\tstack trace",
+			"2020-01-01 05:01:00 INFO line 301",
+		}},
+		{"start", ordered, base.Add(-time.Hour), base, []string{"2020-01-01 00:00:00 INFO line 0", "\tstack trace"}},
+		{"end", ordered, base.Add(999 * time.Minute), base.Add(2000 * time.Minute), []string{"2020-01-01 16:39:00 INFO line 999"}},
+		{"between", ordered, base.Add(90 * time.Second), base.Add(100 * time.Second), []string{}},
+		{"after", ordered, base.Add(2000 * time.Minute), base.Add(3000 * time.Minute), []string{}},
+		{"unordered", unordered, base.Add(899 * time.Minute), base.Add(900 * time.Minute), []string{
+			"2020-01-01 15:00:00 INFO line 900",
+			"2020-01-01 14:59:00 INFO line 899",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+			for e := range FilterLinesByTime(test.file, layout, test.from, test.to) {
+				if e.Error != nil {
+					t.Fatalf("Unexpected error: %s\n", e.Error)
+				}
+				got = append(got, e.String)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, got)
+			}
+		})
+	}
+
+	for e := range FilterLinesByTime(filepath.Join(dir, "missing"), layout, base, base) {
+		if e.Error == nil {
+			t.Errorf("Expected open error\n")
+		}
+	}
+}
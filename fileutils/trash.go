@@ -0,0 +1,53 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Trash - Moves path, a file or a dir, to the trash of the current user
+// instead of deleting it, so it can be restored later.
+//
+// On Linux and the BSDs it follows the freedesktop.org Trash specification:
+// path goes to $XDG_DATA_HOME/Trash, or to the .Trash-$uid dir at the top of
+// its filesystem when it is on a different one, along with the .trashinfo
+// file file managers use to restore it.
+// On macOS it goes to ~/.Trash, or .Trashes/$uid on other volumes, without
+// the Put Back information kept by Finder.
+// On Windows it is sent to the Recycle Bin, 32 bit Windows returns ErrNotSupported.
+func Trash(path string) error {
+	if err := checkWritable("Trash", path); err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	_, err = os.Lstat(abs)
+	if err != nil {
+		return err
+	}
+	return trash(abs)
+}
+
+// numberedName - Returns the i-th candidate name for base in the trash,
+// "file.txt", "file 2.txt", "file 3.txt" and so on.
+func numberedName(base string, i int) string {
+	if i <= 1 {
+		return base
+	}
+	ext := filepath.Ext(base)
+	if ext == base {
+		ext = ""
+	}
+	return base[:len(base)-len(ext)] + " " + strconv.Itoa(i) + ext
+}
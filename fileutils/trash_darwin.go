@@ -0,0 +1,29 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// homeTrashCan - Returns ~/.Trash.
+func homeTrashCan() (trashCan, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return trashCan{}, err
+	}
+	return newTrashCan(filepath.Join(home, ".Trash"), "", false)
+}
+
+// topTrashCan - Returns the $top/.Trashes/$uid trash of a volume.
+func topTrashCan(top string) (trashCan, error) {
+	return newTrashCan(filepath.Join(top, ".Trashes", strconv.Itoa(os.Getuid())), top, false)
+}
@@ -0,0 +1,46 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// homeTrashCan - Returns $XDG_DATA_HOME/Trash, by default ~/.local/share/Trash.
+func homeTrashCan() (trashCan, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return trashCan{}, err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return newTrashCan(filepath.Join(dataHome, "Trash"), "", true)
+}
+
+// topTrashCan - Returns the trash at the top of a filesystem, the
+// administrator created $top/.Trash/$uid when $top/.Trash is a sticky dir,
+// or $top/.Trash-$uid otherwise.
+func topTrashCan(top string) (trashCan, error) {
+	uid := strconv.Itoa(os.Getuid())
+	fInfo, err := os.Lstat(filepath.Join(top, ".Trash"))
+	if err == nil && fInfo.IsDir() && fInfo.Mode()&os.ModeSticky != 0 {
+		c, err := newTrashCan(filepath.Join(top, ".Trash", uid), top, true)
+		if err == nil {
+			return c, nil
+		}
+	}
+	return newTrashCan(filepath.Join(top, ".Trash-"+uid), top, true)
+}
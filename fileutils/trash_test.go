@@ -0,0 +1,96 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNumberedName(t *testing.T) {
+	tests := []struct {
+		base     string
+		i        int
+		expected string
+	}{
+		{"file.txt", 1, "file.txt"},
+		{"file.txt", 2, "file 2.txt"},
+		{"file.tar.gz", 3, "file.tar 3.gz"},
+		{"dir", 2, "dir 2"},
+		{".bashrc", 2, ".bashrc 2"},
+	}
+	for _, test := range tests {
+		got := numberedName(test.base, test.i)
+		if got != test.expected {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", test.expected, got)
+		}
+	}
+}
+
+func TestTrash(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("freedesktop.org trash only")
+	}
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	dataHome := filepath.Join(dir, "data")
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	trashDir := filepath.Join(dataHome, "Trash")
+
+	file := filepath.Join(dir, "my file.txt")
+	for i := 0; i < 2; i++ {
+		err = ioutil.WriteFile(file, []byte{byte('a' + i)}, 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		err = Trash(file)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if _, err := os.Lstat(file); !os.IsNotExist(err) {
+			t.Errorf("Expected '%s' to be gone: %v\n", file, err)
+		}
+	}
+	sub := filepath.Join(dir, "sub")
+	err = os.MkdirAll(filepath.Join(sub, "x"), 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = Trash(sub)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	for name, content := range map[string]string{"my file.txt": "a", "my file 2.txt": "b"} {
+		b, err := ioutil.ReadFile(filepath.Join(trashDir, "files", name))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if string(b) != content {
+			t.Errorf("Expected:\n%q\nGot:\n%q\n", content, b)
+		}
+		info, err := ioutil.ReadFile(filepath.Join(trashDir, "info", name+".trashinfo"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		lines := strings.Split(string(info), "\n")
+		if len(lines) != 4 || lines[0] != "[Trash Info]" ||
+			lines[1] != "Path="+filepath.ToSlash(dir)+"/my%20file.txt" ||
+			!strings.HasPrefix(lines[2], "DeletionDate=") {
+			t.Errorf("Unexpected trashinfo:\n%s\n", info)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(trashDir, "files", "sub", "x")); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+
+	err = Trash(filepath.Join(dir, "missing"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
@@ -0,0 +1,136 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !windows
+// +build !windows
+
+package fileutils
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// trashCan - Location of a trash dir.
+type trashCan struct {
+	// files - Dir where trashed files are moved to.
+	files string
+	// info - Dir for the .trashinfo files, empty when not used.
+	info string
+	// top - Top dir of the filesystem, when set the .trashinfo paths are relative to it.
+	top string
+}
+
+func trash(path string) error {
+	c, err := homeTrashCan()
+	if err != nil {
+		return err
+	}
+	dev, ok := fileDevice(filepath.Dir(path), true)
+	homeDev, homeOK := fileDevice(c.files, true)
+	if ok && homeOK && dev != homeDev {
+		top, err := mountRoot(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		c, err = topTrashCan(top)
+		if err != nil {
+			return err
+		}
+	}
+	return c.put(path)
+}
+
+// put - Moves path into the trash under the first free name.
+func (c trashCan) put(path string) error {
+	base := filepath.Base(path)
+	for i := 1; ; i++ {
+		name := numberedName(base, i)
+		target := filepath.Join(c.files, name)
+		_, err := os.Lstat(target)
+		if err == nil {
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if c.info == "" {
+			return os.Rename(path, target)
+		}
+		// The .trashinfo file is created first to reserve the name.
+		infoFile := filepath.Join(c.info, name+".trashinfo")
+		fh, err := os.OpenFile(infoFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(fh, "[Trash Info]\nPath=%s\nDeletionDate=%s\n", c.infoPath(path), time.Now().Format("2006-01-02T15:04:05"))
+		cerr := fh.Close()
+		if err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(path, target)
+		}
+		if err != nil {
+			os.Remove(infoFile)
+			return err
+		}
+		return nil
+	}
+}
+
+// infoPath - Returns the percent encoded path recorded in the .trashinfo file.
+func (c trashCan) infoPath(path string) string {
+	if c.top != "" {
+		rel, err := filepath.Rel(c.top, path)
+		if err == nil {
+			path = rel
+		}
+	}
+	return (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath()
+}
+
+// newTrashCan - Creates, if needed, and returns the trash in dir.
+// With info set the .trashinfo files are kept in dir/info and the trashed files in dir/files.
+func newTrashCan(dir, top string, info bool) (trashCan, error) {
+	c := trashCan{files: dir, top: top}
+	if info {
+		c.files = filepath.Join(dir, "files")
+		c.info = filepath.Join(dir, "info")
+		err := os.MkdirAll(c.info, 0700)
+		if err != nil {
+			return c, err
+		}
+	}
+	return c, os.MkdirAll(c.files, 0700)
+}
+
+// mountRoot - Returns the top dir of the filesystem dir is on.
+func mountRoot(dir string) (string, error) {
+	dev, ok := fileDevice(dir, true)
+	if !ok {
+		return "", fmt.Errorf("%w: '%s'", ErrNotSupported, dir)
+	}
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		parentDev, ok := fileDevice(parent, true)
+		if !ok || parentDev != dev {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,65 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var procSHFileOperationW = syscall.NewLazyDLL("shell32.dll").NewProc("SHFileOperationW")
+
+// shFileOpStruct - SHFILEOPSTRUCTW, only matches the C layout on 64 bit
+// Windows, the 32 bit one is packed.
+type shFileOpStruct struct {
+	hwnd                  uintptr
+	wFunc                 uint32
+	pFrom                 *uint16
+	pTo                   *uint16
+	fFlags                uint16
+	fAnyOperationsAborted int32
+	hNameMappings         uintptr
+	lpszProgressTitle     *uint16
+}
+
+const (
+	foDelete           = 0x3
+	fofSilent          = 0x4
+	fofNoConfirmation  = 0x10
+	fofAllowUndo       = 0x40
+	fofNoErrorUI       = 0x400
+	fofNoConfirmMkdir  = 0x200
+	trashShFileOpFlags = fofSilent | fofNoConfirmation | fofAllowUndo | fofNoErrorUI | fofNoConfirmMkdir
+)
+
+func trash(path string) error {
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		return fmt.Errorf("%w: Trash '%s'", ErrNotSupported, path)
+	}
+	// pFrom is a list of paths terminated by an extra NUL.
+	from, err := syscall.UTF16FromString(path)
+	if err != nil {
+		return err
+	}
+	from = append(from, 0)
+	op := shFileOpStruct{
+		wFunc:  foDelete,
+		pFrom:  &from[0],
+		fFlags: trashShFileOpFlags,
+	}
+	r, _, _ := procSHFileOperationW.Call(uintptr(unsafe.Pointer(&op)))
+	if r != 0 {
+		return fmt.Errorf("failed to move '%s' to the Recycle Bin: SHFileOperation error 0x%x", path, r)
+	}
+	if op.fAnyOperationsAborted != 0 {
+		return fmt.Errorf("failed to move '%s' to the Recycle Bin: aborted", path)
+	}
+	return nil
+}
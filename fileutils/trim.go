@@ -0,0 +1,95 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// TrimPolicy - Order in which TrimDirToSize deletes files.
+type TrimPolicy int
+
+const (
+	// OldestFirst deletes the least recently modified files first.
+	OldestFirst TrimPolicy = iota
+
+	// LargestFirst deletes the biggest files first.
+	LargestFirst
+)
+
+type trimFile struct {
+	path  string
+	size  int64
+	mtime time.Time
+}
+
+// TrimDirToSize - Deletes regular files under dir, recursively, until the
+// total size of the regular files is at most maxBytes.
+// Returns the deleted files in deletion order.
+//
+// Ties are broken by modification time and then by path so the result is
+// deterministic. Symlinks are neither counted nor deleted and emptied dirs
+// are left in place.
+func TrimDirToSize(dir string, maxBytes int64, policy TrimPolicy) ([]string, error) {
+	if policy != OldestFirst && policy != LargestFirst {
+		return nil, fmt.Errorf("invalid trim policy: %d", policy)
+	}
+	if err := checkWritable("TrimDirToSize", dir); err != nil {
+		return nil, err
+	}
+	files := []trimFile{}
+	var total int64
+	err := Walk(dir, func(path string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		if isDir {
+			return nil
+		}
+		fInfo, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if !fInfo.Mode().IsRegular() {
+			return nil
+		}
+		files = append(files, trimFile{path, fInfo.Size(), fInfo.ModTime()})
+		total += fInfo.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if policy == LargestFirst && a.size != b.size {
+			return a.size > b.size
+		}
+		if !a.mtime.Equal(b.mtime) {
+			return a.mtime.Before(b.mtime)
+		}
+		return a.path < b.path
+	})
+	removed := []string{}
+	for _, f := range files {
+		if total <= maxBytes {
+			break
+		}
+		err := os.Remove(f.path)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, f.path)
+		total -= f.size
+	}
+	return removed, nil
+}
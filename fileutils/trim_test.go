@@ -0,0 +1,69 @@
+package fileutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrimDirToSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int64
+		policy   TrimPolicy
+		expected []string
+	}{
+		{"fits", 100, OldestFirst, []string{}},
+		{"oldest first", 60, OldestFirst, []string{"old", "mid2", "sub/mid"}},
+		{"largest first", 60, LargestFirst, []string{"sub/big"}},
+		{"empty", 0, LargestFirst, []string{"sub/big", "mid2", "sub/mid", "old"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "fileutils-")
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			defer os.RemoveAll(dir)
+			os.Mkdir(filepath.Join(dir, "sub"), 0755)
+			now := time.Now()
+			files := []struct {
+				name string
+				size int
+				age  time.Duration
+			}{
+				{"old", 10, 3 * time.Hour},
+				{"sub/mid", 20, 2 * time.Hour},
+				{"mid2", 20, 2 * time.Hour},
+				{"sub/big", 50, time.Hour},
+			}
+			for _, f := range files {
+				path := filepath.Join(dir, f.name)
+				ioutil.WriteFile(path, []byte(strings.Repeat("x", f.size)), 0644)
+				os.Chtimes(path, now.Add(-f.age), now.Add(-f.age))
+			}
+			os.Symlink("sub/big", filepath.Join(dir, "link"))
+
+			removed, err := TrimDirToSize(dir, test.maxBytes, test.policy)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			got := []string{}
+			for _, r := range removed {
+				rel, _ := filepath.Rel(dir, r)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, got)
+			}
+		})
+	}
+	_, err := TrimDirToSize(".", 0, TrimPolicy(5))
+	if err == nil {
+		t.Errorf("Expected invalid policy error\n")
+	}
+}
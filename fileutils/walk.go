@@ -0,0 +1,444 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fileutils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DavidGamba/go-utils/stringutils"
+)
+
+// ErrSymlinkLoop - A followed symlink points to one of the dirs containing it.
+var ErrSymlinkLoop = fmt.Errorf("symlink loop")
+
+// WalkFunc - Called by Walk for each entry.
+// When err is not nil, the entry is a dir that couldn't be read. In
+// pre-order it has already been passed to fn, in post-order it is passed
+// again, without error, right after.
+// Returning filepath.SkipDir for a dir in pre-order skips its contents,
+// returning any other error stops the walk.
+type WalkFunc func(path string, isDir bool, err error) error
+
+// Walk - Calls fn for each entry under dirname, recursively, following the
+// package listing order contract. dirname itself is not passed to fn.
+//
+// Symlinks are reported as files and never followed, unless
+// ListFollowLinks(true) is given. That makes Walk with ListOrder(PostOrder),
+// where every dir is reported after its contents, the building block for
+// recursive deletes, empty dir pruning and bottom-up size aggregation.
+func Walk(dirname string, fn WalkFunc, opts ...ListOption) error {
+	o := newListOptions(opts).walkOptions(walkOptions{recursive: true, join: cleanJoin})
+	return walk(dirname, o, walkFn(fn))
+}
+
+// walkOptions - Controls the order and the entries visited by walk.
+type walkOptions struct {
+	recursive bool
+
+	// sortKey sorts the entries of each dir, by name by default.
+	sortKey SortKey
+	reverse bool
+
+	// followLinks resolves symlinks so links to dirs are reported and walked as dirs.
+	// Links to a dir containing them are not walked, fn gets ErrSymlinkLoop instead.
+	followLinks bool
+
+	// join builds the entry path, defaults to concatenating with the path separator.
+	join func(dir, name string) string
+
+	// statCache, when set, records the visited entries and caches the symlink stats.
+	statCache *StatCache
+
+	// postOrder visits the contents of a dir before the dir itself.
+	postOrder bool
+
+	// maxDepth limits the recursion, 1 only visits the entries of dirname, 0 means no limit.
+	maxDepth int
+
+	// include, when not empty, only passes the entries that match one of the patterns to fn.
+	// Dirs are still walked. See matchGlob.
+	include []string
+
+	// exclude skips the entries that match one of the patterns, dirs are not walked.
+	exclude []string
+
+	// extensions, when not empty, only passes the files with one of the
+	// extensions, with the leading dot, to fn. Dirs are not filtered.
+	extensions []string
+
+	// createdFrom and createdTo, when not zero, only pass the entries created
+	// within them to fn. Dirs are still walked.
+	createdFrom time.Time
+	createdTo   time.Time
+
+	// skipHidden skips the entries whose name starts with a dot, dirs are not walked.
+	skipHidden bool
+
+	// ignoreFiles are the names of the gitignore style files read from each walked dir.
+	ignoreFiles []string
+
+	// ignorePatterns are gitignore style patterns relative to the walked root.
+	ignorePatterns []string
+
+	// fsys, when set, is walked instead of the OS file system. Paths are
+	// always slash separated and join and statCache are ignored.
+	fsys fs.FS
+
+	// sameFS doesn't descend into dirs on a different device than the walked root.
+	sameFS bool
+}
+
+// stat - Stats name on the walked file system.
+func (o walkOptions) stat(name string) (fs.FileInfo, error) {
+	if o.fsys != nil {
+		return fs.Stat(o.fsys, name)
+	}
+	return o.statCache.cachedStat(name)
+}
+
+// checkPatterns - Returns an error if any of the include or exclude patterns is malformed.
+func (o walkOptions) checkPatterns() error {
+	for _, patterns := range [][]string{o.include, o.exclude} {
+		for _, pattern := range patterns {
+			err := checkGlob(pattern)
+			if err != nil {
+				return fmt.Errorf("%w: '%s'", err, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// skipped - Whether the entry d is excluded from the walk.
+func (o walkOptions) skipped(d walkDir, name string) bool {
+	if o.skipHidden && strings.HasPrefix(name, ".") {
+		return true
+	}
+	return matchAny(o.exclude, d.rel)
+}
+
+// included - Whether the entry d is passed to fn.
+func (o walkOptions) included(d walkDir, isDir bool) bool {
+	if len(o.include) > 0 && !matchAny(o.include, d.rel) {
+		return false
+	}
+	if len(o.extensions) > 0 && !isDir && !hasExtension(o.extensions, d.rel) {
+		return false
+	}
+	if o.createdFrom.IsZero() && o.createdTo.IsZero() {
+		return true
+	}
+	if o.fsys != nil {
+		return false
+	}
+	t, ok := birthTime(d.path)
+	return ok && !t.Before(o.createdFrom) && (o.createdTo.IsZero() || !t.After(o.createdTo))
+}
+
+// matchAny - Whether the slash separated rel matches any of the patterns, see matchGlob.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasExtension - Whether the extension of name is one of extensions, ignoring case.
+func hasExtension(extensions []string, name string) bool {
+	ext := path.Ext(name)
+	for _, e := range extensions {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkFn - Called for each entry.
+// When err is not nil, the entry couldn't be resolved or, if isDir, read.
+// Returning an error stops the walk.
+type walkFn func(path string, isDir bool, err error) error
+
+// walk - Visits the entries under dirname in pre-order, each dir before its
+// contents, or in post-order, each dir after its contents.
+//
+// Modeled after filepath.WalkDir: entry types come from the fs.DirEntry
+// returned by os.ReadDir so regular entries are never stat'ed, only symlinks
+// are when following links.
+// Errors reading dirname itself are returned, errors on nested entries are
+// passed to fn.
+//
+// Compared to the previous filepath.Glob/ioutil.ReadDir plus os.Stat per
+// entry implementation, on a tree of 100 dirs with 1000 files each
+// (BenchmarkListFiles100k and friends) ListFiles went from 190ms to 59ms,
+// ListFilesNumSort from 210ms to 93ms and GetFileList from 237ms to 81ms.
+func walk(dirname string, o walkOptions, fn walkFn) error {
+	err := o.checkPatterns()
+	if err != nil {
+		return err
+	}
+	return walkDepth(o.root(dirname), o, fn)
+}
+
+// root - Returns the walkDir of the walked root.
+func (o walkOptions) root(dirname string) walkDir {
+	d := walkDir{path: dirname, depth: 1}
+	if o.followLinks {
+		d.id = &dirID{path: dirname}
+	}
+	d.ignore = d.ignore.with(parseIgnore("", o.ignorePatterns))
+	if o.sameFS && o.fsys == nil {
+		d.dev, d.hasDev = fileDevice(dirname, true)
+	}
+	return d
+}
+
+// descend - Whether the walk goes into the dir at path, a child of d.
+func (o walkOptions) descend(d walkDir, path string) bool {
+	if !o.recursive || (o.maxDepth != 0 && d.depth >= o.maxDepth) {
+		return false
+	}
+	if d.hasDev {
+		dev, ok := fileDevice(path, o.followLinks)
+		return !ok || dev == d.dev
+	}
+	return true
+}
+
+// walkDir - A dir to visit.
+type walkDir struct {
+	path string
+
+	// rel - Slash separated path relative to the walked root, empty for the root.
+	rel string
+
+	depth int
+
+	// ignore - Rules from the ignore patterns and the ignore files of the parent dirs.
+	ignore *ignoreRules
+
+	// dev - Device of the walked root, only set with walkOptions.sameFS.
+	dev    uint64
+	hasDev bool
+
+	// id - Identity of the dir, only set with walkOptions.followLinks.
+	id *dirID
+}
+
+// dirID - Device and inode of a walked dir, linked to its parent dir to
+// detect symlink loops.
+// They are only resolved when a symlink to a dir is found, so walks without
+// symlinks don't stat every dir.
+type dirID struct {
+	path     string
+	parent   *dirID
+	resolved bool
+	ok       bool
+	dev      uint64
+	ino      uint64
+}
+
+// resolve - Stats the dir the first time it is called.
+func (id *dirID) resolve(o walkOptions) bool {
+	if !id.resolved {
+		id.resolved = true
+		fInfo, err := o.stat(id.path)
+		if err == nil {
+			id.dev, id.ino, id.ok = fileID(fInfo)
+		}
+	}
+	return id.ok
+}
+
+// loops - Whether the dir with the given fInfo is d or one of its parents.
+// Always false where device and inode numbers are not available.
+func (d walkDir) loops(o walkOptions, fInfo fs.FileInfo) bool {
+	dev, ino, ok := fileID(fInfo)
+	if !ok {
+		return false
+	}
+	for id := d.id; id != nil; id = id.parent {
+		if id.resolve(o) && id.dev == dev && id.ino == ino {
+			return true
+		}
+	}
+	return false
+}
+
+// child - Returns the walkDir of the entry name.
+func (d walkDir) child(path, name string) walkDir {
+	c := d
+	c.path, c.rel, c.depth = path, strings.TrimPrefix(d.rel+"/"+name, "/"), d.depth+1
+	return c
+}
+
+// readDir - Reads the entries of d and, when ignore files are set, the
+// ignore rules in effect for them.
+func (d walkDir) readDir(o walkOptions) ([]fs.DirEntry, walkDir, error) {
+	entries, err := sortedDirEntries(d.path, o)
+	if err != nil {
+		return nil, d, err
+	}
+	if len(o.ignoreFiles) > 0 {
+		d.ignore, err = d.ignore.load(o, d.path, d.rel)
+	}
+	return entries, d, err
+}
+
+// walkDepth - Visits the entries of dir.
+// Errors reading nested dirs are passed to fn, errors returned by fn are
+// returned as is.
+func walkDepth(dir walkDir, o walkOptions, fn walkFn) error {
+	entries, dir, err := dir.readDir(o)
+	if err != nil {
+		if dir.depth > 1 {
+			return fn(dir.path, true, err)
+		}
+		return err
+	}
+	join := o.join
+	if o.fsys != nil {
+		join = slashJoin
+	} else if join == nil {
+		join = func(dir, name string) string { return dir + string(os.PathSeparator) + name }
+	}
+	for _, e := range entries {
+		path := join(dir.path, e.Name())
+		child := dir.child(path, e.Name())
+		if o.skipped(child, e.Name()) {
+			continue
+		}
+		if o.statCache != nil && o.fsys == nil {
+			o.statCache.addEntry(path, e)
+		}
+		isDir := e.IsDir()
+		loop := false
+		if o.followLinks && e.Type()&fs.ModeSymlink != 0 {
+			fInfo, err := o.stat(path)
+			if err != nil {
+				err = fn(path, false, err)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			isDir = fInfo.IsDir()
+			if isDir {
+				loop = dir.loops(o, fInfo)
+				child.id = &dirID{path: path, parent: dir.id, resolved: true}
+				child.id.dev, child.id.ino, child.id.ok = fileID(fInfo)
+			}
+		} else if o.followLinks && isDir {
+			child.id = &dirID{path: path, parent: dir.id}
+		}
+		if child.ignore.ignored(child.rel, isDir) {
+			continue
+		}
+		report := o.included(child, isDir)
+		if !o.postOrder && report {
+			err := fn(path, isDir, nil)
+			if err == filepath.SkipDir && isDir {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if isDir && o.descend(dir, path) {
+			var err error
+			if loop {
+				err = fn(path, true, fmt.Errorf("%w: '%s'", ErrSymlinkLoop, path))
+			} else {
+				err = walkDepth(child, o, fn)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if o.postOrder && report {
+			err := fn(path, isDir, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedDirEntries - os.ReadDir, or fs.ReadDir when o.fsys is set, sorted as set in o.
+func sortedDirEntries(dirname string, o walkOptions) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	var err error
+	if o.fsys != nil {
+		entries, err = fs.ReadDir(o.fsys, dirname)
+	} else {
+		entries, err = os.ReadDir(dirname)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The entries are sorted by name, the other keys keep that order for ties.
+	switch o.sortKey {
+	case SortByNumeric:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return stringutils.NaturalLess(entries[i].Name(), entries[j].Name())
+		})
+	case SortByVersion:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return stringutils.VersionLess(entries[i].Name(), entries[j].Name())
+		})
+	case SortByModTime, SortByBirthTime:
+		times := map[string]time.Time{}
+		for _, e := range entries {
+			if o.sortKey == SortByBirthTime {
+				times[e.Name()] = entryBirthTime(o.fsys, dirname, e)
+			} else if fInfo, err := e.Info(); err == nil {
+				times[e.Name()] = fInfo.ModTime()
+			}
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return times[entries[i].Name()].Before(times[entries[j].Name()])
+		})
+	case SortBySize:
+		sizes := map[string]int64{}
+		for _, e := range entries {
+			if fInfo, err := e.Info(); err == nil {
+				sizes[e.Name()] = fInfo.Size()
+			}
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return sizes[entries[i].Name()] < sizes[entries[j].Name()]
+		})
+	}
+	if o.reverse {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	return entries, nil
+}
+
+// slashJoin - Builds fs.FS paths.
+func slashJoin(dir, name string) string {
+	return path.Join(dir, name)
+}
+
+// cleanJoin - Builds paths like filepath.Glob does, used by the channel based listings.
+func cleanJoin(dir, name string) string {
+	return filepath.Join(dir, name)
+}
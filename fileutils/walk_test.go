@@ -0,0 +1,304 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestWalkSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "d"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "d", "f"), nil, 0644)
+	os.Symlink("d", filepath.Join(dir, "ld"))
+
+	list, err := ListFiles(dir, false, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []string{dir + "/d", dir + "/d/f", dir + "/ld", dir + "/ld/f"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+	// ListFilesNumSort doesn't follow links.
+	list, err = ListFilesNumSort(dir, false, true, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{dir + "/d", dir + "/d/f", dir + "/ld"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+
+	// A dangling link stops ListFiles but GetFileList reports it and continues.
+	os.Symlink("missing", filepath.Join(dir, "a"))
+	list, err = ListFiles(dir, false, true)
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got: %v\n", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("Expected empty list, got: %q\n", list)
+	}
+	errors := 0
+	files := []string{}
+	for e := range GetFileList(dir, false, true) {
+		if e.Error != nil {
+			errors++
+			continue
+		}
+		files = append(files, e.String)
+	}
+	expected = []string{dir + "/d", dir + "/d/f", dir + "/ld", dir + "/ld/f"}
+	if errors != 1 || !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected:\n1 error, %q\nGot:\n%d errors, %q\n", expected, errors, files)
+	}
+}
+
+func TestWalkSymlinkLoop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("device and inode numbers not available")
+	}
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a", "b", "f"), nil, 0644)
+	os.Symlink("../..", filepath.Join(dir, "a", "b", "up"))
+	os.Symlink("b", filepath.Join(dir, "a", "lb"))
+
+	// The loop is listed but not walked, a link to a sibling dir is walked.
+	list, err := List(dir)
+	if !errors.Is(err, ErrSymlinkLoop) {
+		t.Errorf("Expected ErrSymlinkLoop, got: %v\n", err)
+	}
+	expected := []string{dir + "/a", dir + "/a/b", dir + "/a/b/f", dir + "/a/b/up"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+
+	var errs []error
+	files := []string{}
+	for e := range GetFileList(dir, false, true) {
+		if e.Error != nil {
+			errs = append(errs, e.Error)
+			continue
+		}
+		files = append(files, e.String)
+	}
+	expected = []string{dir + "/a", dir + "/a/b", dir + "/a/b/f", dir + "/a/b/up", dir + "/a/lb", dir + "/a/lb/f", dir + "/a/lb/up"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, files)
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], ErrSymlinkLoop) || !errors.Is(errs[1], ErrSymlinkLoop) {
+		t.Errorf("Expected 2 ErrSymlinkLoop errors, got: %v\n", errs)
+	}
+
+	// Not following links lists them as files.
+	list, err = List(dir, ListFollowLinks(false))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{dir + "/a", dir + "/a/b", dir + "/a/b/f", dir + "/a/b/up", dir + "/a/lb"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+
+	// Walk can follow them as well.
+	walked := []string{}
+	err = Walk(dir, func(path string, isDir bool, err error) error {
+		if errors.Is(err, ErrSymlinkLoop) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		walked = append(walked, fmt.Sprintf("%s %v", path, isDir))
+		return nil
+	}, ListFollowLinks(true), ListOrder(PostOrder))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{
+		dir + "/a/b/f false", dir + "/a/b/up true", dir + "/a/b true",
+		dir + "/a/lb/f false", dir + "/a/lb/up true", dir + "/a/lb true", dir + "/a true",
+	}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, walked)
+	}
+}
+
+func TestWalkPostOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a", "b", "f"), []byte("abc"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a", "g"), []byte("ab"), 0644)
+	os.Symlink("a", filepath.Join(dir, "ln"))
+
+	// Bottom-up size aggregation, links are not followed.
+	sizes := map[string]int64{}
+	visited := []string{}
+	err = Walk(dir, func(path string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		visited = append(visited, rel)
+		if !isDir {
+			fInfo, err := os.Lstat(path)
+			if err != nil {
+				return err
+			}
+			if fInfo.Mode().IsRegular() {
+				sizes[path] = fInfo.Size()
+			}
+		}
+		sizes[filepath.Dir(path)] += sizes[path]
+		return nil
+	}, ListOrder(PostOrder))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []string{"a/b/f", "a/b", "a/g", "a", "ln"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, visited)
+	}
+	if sizes[dir] != 5 || sizes[filepath.Join(dir, "a", "b")] != 3 {
+		t.Errorf("Expected:\n5, 3\nGot:\n%d, %d\n", sizes[dir], sizes[filepath.Join(dir, "a", "b")])
+	}
+
+	// Recursive delete.
+	err = Walk(dir, func(path string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Remove(path)
+	}, ListOrder(PostOrder))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	list, err := ListFiles(dir, false, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("Expected empty dir, got: %q\n", list)
+	}
+}
+
+// benchRoot - Tree with 100 dirs of 1000 files each, shared by the large listing benchmarks.
+// It is created on first use and removed by TestMain.
+var benchRoot string
+
+func benchTree(b *testing.B) string {
+	b.Helper()
+	if benchRoot != "" {
+		return benchRoot
+	}
+	root, err := ioutil.TempDir("", "fileutils-bench-")
+	if err != nil {
+		b.Fatalf("Unexpected error: %s\n", err)
+	}
+	benchRoot = root
+	for d := 0; d < 100; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("%d", d))
+		err := os.Mkdir(dir, 0755)
+		if err != nil {
+			b.Fatalf("Unexpected error: %s\n", err)
+		}
+		for f := 0; f < 1000; f++ {
+			err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%d", f)), nil, 0644)
+			if err != nil {
+				b.Fatalf("Unexpected error: %s\n", err)
+			}
+		}
+	}
+	return root
+}
+
+func BenchmarkListFiles100k(b *testing.B) {
+	root := benchTree(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		list, err := ListFiles(root, false, true)
+		if err != nil || len(list) != 100100 {
+			b.Fatalf("Unexpected result: %d, %v\n", len(list), err)
+		}
+	}
+}
+
+func BenchmarkListFilesNumSort100k(b *testing.B) {
+	root := benchTree(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		list, err := ListFilesNumSort(root, false, true, false)
+		if err != nil || len(list) != 100100 {
+			b.Fatalf("Unexpected result: %d, %v\n", len(list), err)
+		}
+	}
+}
+
+func BenchmarkGetFileList100k(b *testing.B) {
+	root := benchTree(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		count := 0
+		for e := range GetFileList(root, false, true) {
+			if e.Error != nil {
+				b.Fatalf("Unexpected error: %s\n", e.Error)
+			}
+			count++
+		}
+		if count != 100100 {
+			b.Fatalf("Unexpected result: %d\n", count)
+		}
+	}
+}
+
+func BenchmarkGetFileListBuffered100k(b *testing.B) {
+	root := benchTree(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		count := 0
+		for e := range GetFileList(root, false, true, ListBuffer(1024)) {
+			if e.Error != nil {
+				b.Fatalf("Unexpected error: %s\n", e.Error)
+			}
+			count++
+		}
+		if count != 100100 {
+			b.Fatalf("Unexpected result: %d\n", count)
+		}
+	}
+}
+
+func BenchmarkGetFileListBatch100k(b *testing.B) {
+	root := benchTree(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		count := 0
+		for e := range GetFileListBatch(root, false, true, 1024) {
+			if e.Error != nil {
+				b.Fatalf("Unexpected error: %s\n", e.Error)
+			}
+			count += len(e.Strings)
+		}
+		if count != 100100 {
+			b.Fatalf("Unexpected result: %d\n", count)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package fileutils
+
+// getXattrs - Extended attributes are not supported.
+func getXattrs(path string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// setXattrs - Extended attributes are not supported.
+func setXattrs(path string, xattrs map[string][]byte) error {
+	return nil
+}
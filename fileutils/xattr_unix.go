@@ -0,0 +1,91 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build darwin || linux
+// +build darwin linux
+
+package fileutils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// getXattrs - Returns the extended attributes of path, following symlinks.
+// Returns nil when the file system doesn't support them.
+func getXattrs(path string) (map[string][]byte, error) {
+	names, err := xattrCall(func(dest []byte) (int, error) { return unix.Listxattr(path, dest) })
+	if err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil, nil
+		}
+		return nil, &os.PathError{Op: "listxattr", Path: path, Err: err}
+	}
+	var xattrs map[string][]byte
+	for _, name := range bytes.Split(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		value, err := xattrCall(func(dest []byte) (int, error) { return unix.Getxattr(path, string(name), dest) })
+		if err != nil {
+			return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		if xattrs == nil {
+			xattrs = map[string][]byte{}
+		}
+		xattrs[string(name)] = value
+	}
+	return xattrs, nil
+}
+
+// xattrCall - Calls fn with a buffer of the size it reports it needs,
+// retrying while the value grows in between.
+func xattrCall(fn func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := fn(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return []byte{}, nil
+		}
+		dest := make([]byte, size)
+		size, err = fn(dest)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return dest[:size], nil
+	}
+}
+
+// setXattrs - Sets the extended attributes of path, following symlinks.
+// Without root, only the user namespace is set on Linux.
+// Nothing is set when the file system doesn't support them.
+func setXattrs(path string, xattrs map[string][]byte) error {
+	for name, value := range xattrs {
+		if runtime.GOOS == "linux" && os.Geteuid() != 0 && !strings.HasPrefix(name, "user.") {
+			continue
+		}
+		err := unix.Setxattr(path, name, value, 0)
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil
+		}
+		if err != nil {
+			return &os.PathError{Op: "setxattr", Path: path, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package gitutils - Git repository aware file operations.
+
+The file listings shell out to the git binary.
+*/
+package gitutils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+	"github.com/DavidGamba/go-utils/run"
+)
+
+// ErrNotARepo - The dir is not inside a git repository.
+var ErrNotARepo = fmt.Errorf("not a git repository")
+
+// FindRoot - Returns the root of the git repository that contains dir.
+// The root is the first parent dir that contains a '.git' dir or file, the
+// later is used by worktrees and submodules.
+func FindRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for d := abs; ; {
+		_, err := os.Stat(filepath.Join(d, ".git"))
+		if err == nil {
+			return d, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return "", fmt.Errorf("%w: '%s'", ErrNotARepo, dir)
+		}
+		d = parent
+	}
+}
+
+// TrackedFiles - Returns the files under dir tracked by git.
+// Paths are prefixed with dir, the same way fileutils.ListFiles does.
+func TrackedFiles(dir string) ([]string, error) {
+	return lsFiles(dir)
+}
+
+// UntrackedFiles - Returns the files under dir not tracked by git, excluding ignored files.
+// Paths are prefixed with dir, the same way fileutils.ListFiles does.
+func UntrackedFiles(dir string) ([]string, error) {
+	return lsFiles(dir, "--others", "--exclude-standard")
+}
+
+func lsFiles(dir string, args ...string) ([]string, error) {
+	stderr := new(bytes.Buffer)
+	cmd := append([]string{"git", "ls-files", "-z"}, args...)
+	out, err := run.CMD(cmd...).Dir(dir).Stderr(stderr).STDOUTOutput()
+	if err != nil {
+		return nil, fmt.Errorf("'%s': %w: %s", run.ShellQuote(cmd...), err, strings.TrimSpace(stderr.String()))
+	}
+	files := []string{}
+	for _, f := range strings.Split(string(out), "\x00") {
+		if f == "" {
+			continue
+		}
+		files = append(files, dir+string(os.PathSeparator)+filepath.FromSlash(f))
+	}
+	return files, nil
+}
+
+// ListTrackedFiles - Same as fileutils.ListFiles but only returns files
+// tracked by git and the dirs that contain them.
+func ListTrackedFiles(dirname string, ignoreDirs, recursive bool) ([]string, error) {
+	tracked, err := lsFiles(dirname)
+	if err != nil {
+		return nil, err
+	}
+	keep := map[string]bool{}
+	for _, f := range tracked {
+		for p := filepath.Clean(f); p != filepath.Clean(dirname); p = filepath.Dir(p) {
+			keep[p] = true
+			if p == filepath.Dir(p) {
+				break
+			}
+		}
+	}
+	list, err := fileutils.ListFiles(dirname, ignoreDirs, recursive)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, f := range list {
+		if keep[filepath.Clean(f)] {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
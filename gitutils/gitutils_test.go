@@ -0,0 +1,98 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gitutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func setupRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, err := ioutil.TempDir("", "gitutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	os.MkdirAll(filepath.Join(dir, "c"), 0755)
+	for _, f := range []string{"tracked", "untracked", "ignored", "a/b/tracked", "c/untracked"} {
+		ioutil.WriteFile(filepath.Join(dir, f), []byte(f), 0644)
+	}
+	ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("ignored\n"), 0644)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", ".gitignore", "tracked", "a/b/tracked"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s: %s\n", err, out)
+		}
+	}
+	return dir
+}
+
+func TestFindRoot(t *testing.T) {
+	dir := setupRepo(t)
+	defer os.RemoveAll(dir)
+	root, err := FindRoot(filepath.Join(dir, "a", "b"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected, _ := filepath.Abs(dir)
+	if root != expected {
+		t.Errorf("Expected: %s, Got: %s\n", expected, root)
+	}
+	tmp, _ := ioutil.TempDir("", "gitutils-")
+	defer os.RemoveAll(tmp)
+	_, err = FindRoot(tmp)
+	if err != nil && !errors.Is(err, ErrNotARepo) {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := setupRepo(t)
+	defer os.RemoveAll(dir)
+
+	tracked, err := TrackedFiles(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []string{dir + "/.gitignore", dir + "/a/b/tracked", dir + "/tracked"}
+	if !reflect.DeepEqual(tracked, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, tracked)
+	}
+
+	untracked, err := UntrackedFiles(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{dir + "/c/untracked", dir + "/untracked"}
+	if !reflect.DeepEqual(untracked, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, untracked)
+	}
+
+	list, err := ListTrackedFiles(dir, false, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected = []string{dir + "/.gitignore", dir + "/a", dir + "/a/b", dir + "/a/b/tracked", dir + "/tracked"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+}
@@ -1,8 +1,11 @@
 module github.com/DavidGamba/go-utils
 
-go 1.13
+go 1.23
 
 require (
 	github.com/DavidGamba/go-getoptions v0.16.0
+	golang.org/x/sys v0.13.0
 	gopkg.in/yaml.v2 v2.2.4
 )
+
+require github.com/fsnotify/fsnotify v1.9.0
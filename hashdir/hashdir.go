@@ -0,0 +1,345 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package hashdir - Single digest fingerprint of a directory tree, and
+per file digests with HashFile and HashTree.
+
+The HashDir digest is computed over a sorted list of entries, one line per entry:
+
+	<type> NUL <slash separated relative path> NUL [<octal mode> NUL] <content>
+
+Where type is 'd' for dirs, 'f' for files and 'l' for symlinks.
+The content is the hex digest of the file contents, the link target for
+symlinks and empty for dirs.
+This makes the digest independent of the filesystem enumeration order and
+sensitive to renames, empty dirs and content changes.
+
+The HashFileParallel tree digest splits the file into chunks of ChunkSize
+bytes, the last one possibly shorter, and hashes each one on its own. The
+digest is the hash of the chunk size, as a big endian uint64, followed by
+the raw digests of the chunks in file order:
+
+	H(uint64be(ChunkSize) || H(chunk 0) || H(chunk 1) || ... || H(chunk n-1))
+
+An empty file has no chunks. The same file hashed with different chunk sizes
+gives different digests.
+*/
+package hashdir
+
+import (
+	"crypto"
+	// Register the common hash implementations.
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+)
+
+// ErrUnavailableHash - The hash function is not linked into the binary.
+var ErrUnavailableHash = fmt.Errorf("hash function unavailable")
+
+// Options - HashDir options.
+type Options struct {
+	// IncludeMode adds the permission bits of each entry to the digest.
+	IncludeMode bool
+
+	// Exclude skips entries whose base name matches any of the filepath.Match patterns.
+	// Patterns with a '/' are matched against the slash separated relative path
+	// instead, where "**" matches any number of dirs, see fileutils.MatchGlobstar.
+	// Excluded dirs are not descended into.
+	Exclude []string
+
+	// Control pauses and cancels the hashing between entries, a canceled
+	// run returns fileutils.ErrCanceled.
+	Control *fileutils.Control
+
+	// Limiter caps the read throughput and the number of files open at the
+	// same time, it can be shared with other operations.
+	Limiter *fileutils.IOLimiter
+
+	// Jobs is the number of files hashed at the same time by
+	// HashTreeOptions and VerifyManifest, 0 means one.
+	Jobs int
+
+	// Progress, when set, is told about each file hashed by
+	// HashTreeOptions and VerifyManifest, progress.Reporter implements it.
+	Progress Progress
+}
+
+// Progress - Receives the progress of the files being hashed, identified by
+// their slash separated relative path.
+// The methods are called from the hashing goroutines.
+type Progress interface {
+	Start(path string, total int64)
+	Reader(path string, total int64, r io.Reader) io.Reader
+	Done(path string, bytes int64)
+	Error(path string, err error)
+}
+
+type entry struct {
+	kind    byte
+	path    string
+	mode    os.FileMode
+	content string
+}
+
+// HashDir - Returns the hex encoded digest of the root tree.
+func HashDir(root string, algo crypto.Hash, opts Options) (string, error) {
+	if !algo.Available() {
+		return "", fmt.Errorf("%w: %v", ErrUnavailableHash, algo)
+	}
+	entries := []entry{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		err = opts.Control.Wait()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		excluded, err := opts.excluded(rel, info.Name())
+		if err != nil {
+			return err
+		}
+		if excluded {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		e := entry{path: rel, mode: info.Mode().Perm()}
+		switch {
+		case info.IsDir():
+			e.kind = 'd'
+		case info.Mode()&os.ModeSymlink != 0:
+			e.kind = 'l'
+			e.content, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			e.kind = 'f'
+			e.content, err = hashFile(path, algo, opts.Limiter)
+			if err != nil {
+				return err
+			}
+		default:
+			// Devices, sockets and pipes have no stable content.
+			return nil
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+	h := algo.New()
+	for _, e := range entries {
+		if opts.IncludeMode {
+			fmt.Fprintf(h, "%c\x00%s\x00%o\x00%s\n", e.kind, e.path, e.mode, e.content)
+		} else {
+			fmt.Fprintf(h, "%c\x00%s\x00%s\n", e.kind, e.path, e.content)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// excluded - Whether the entry with the given relative path and name matches any of the Exclude patterns.
+func (opts Options) excluded(rel, name string) (bool, error) {
+	for _, pattern := range opts.Exclude {
+		var ok bool
+		var err error
+		if strings.Contains(pattern, "/") {
+			ok, err = fileutils.MatchGlobstar(pattern, rel)
+		} else {
+			ok, err = filepath.Match(pattern, name)
+		}
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// HashFile - Returns the hex encoded digest of the contents of filename.
+// The file is streamed through the hash, it is never fully loaded in memory.
+func HashFile(filename string, algo crypto.Hash) (string, error) {
+	if !algo.Available() {
+		return "", fmt.Errorf("%w: %v", ErrUnavailableHash, algo)
+	}
+	return hashFile(filename, algo, nil)
+}
+
+// HashTree - Returns the hex encoded digest of each regular file under dir,
+// keyed by its slash separated path relative to dir.
+// Symlinks are not followed and, like other special files, are not included.
+func HashTree(dir string, algo crypto.Hash) (map[string]string, error) {
+	return HashTreeOptions(dir, algo, Options{})
+}
+
+// HashTreeOptions - Same as HashTree but honoring the Exclude, Control,
+// Limiter, Jobs and Progress options.
+// When canceled, it returns the digests computed so far along with
+// fileutils.ErrCanceled, to resume without hashing them again use
+// fileutils.HashTreeCached instead.
+func HashTreeOptions(dir string, algo crypto.Hash, opts Options) (map[string]string, error) {
+	if !algo.Available() {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailableHash, algo)
+	}
+	sums := map[string]string{}
+	files := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		err = opts.Control.Wait()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		excluded, err := opts.excluded(rel, info.Name())
+		if err != nil {
+			return err
+		}
+		if excluded && path != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err == nil {
+		err = opts.hashAll(dir, files, algo, func(rel, sum string, err error) error {
+			if err != nil {
+				return err
+			}
+			sums[rel] = sum
+			return nil
+		})
+	}
+	if errors.Is(err, fileutils.ErrCanceled) {
+		return sums, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// hashAll - Hashes the files, slash separated paths relative to dir, with
+// opts.Jobs workers and calls fn, one call at a time, with each result.
+// Stops at the first error returned by fn or by opts.Control.
+func (opts Options) hashAll(dir string, files []string, algo crypto.Hash, fn func(rel, sum string, err error) error) error {
+	jobs := opts.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	var mu sync.Mutex
+	var firstErr error
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rel := range queue {
+				sum, err := opts.hash(filepath.Join(dir, filepath.FromSlash(rel)), rel, algo)
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fn(rel, sum, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, rel := range files {
+		err := opts.Control.Wait()
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		stop := firstErr != nil
+		mu.Unlock()
+		if stop {
+			break
+		}
+		queue <- rel
+	}
+	close(queue)
+	wg.Wait()
+	return firstErr
+}
+
+// hash - Same as hashFile but reporting to opts.Progress, as rel.
+func (opts Options) hash(filename, rel string, algo crypto.Hash) (string, error) {
+	if opts.Progress == nil {
+		return hashFile(filename, algo, opts.Limiter)
+	}
+	var total int64
+	if fInfo, err := os.Stat(filename); err == nil {
+		total = fInfo.Size()
+	}
+	fh, err := opts.Limiter.Open(filename)
+	if err != nil {
+		opts.Progress.Error(rel, err)
+		return "", err
+	}
+	defer fh.Close()
+	opts.Progress.Start(rel, total)
+	h := algo.New()
+	n, err := io.Copy(h, opts.Progress.Reader(rel, total, fh))
+	if err != nil {
+		opts.Progress.Error(rel, err)
+		return "", err
+	}
+	opts.Progress.Done(rel, n)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(filename string, algo crypto.Hash, l *fileutils.IOLimiter) (string, error) {
+	fh, err := l.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+	h := algo.New()
+	_, err = io.Copy(h, fh)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,191 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package hashdir
+
+import (
+	"crypto"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+)
+
+func makeTree(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "hashdir-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if content == "/" {
+			os.MkdirAll(path, 0755)
+			continue
+		}
+		ioutil.WriteFile(path, []byte(content), 0644)
+	}
+	return dir
+}
+
+func TestHashDir(t *testing.T) {
+	base := map[string]string{"a": "hello", "b/c": "world", "d/": "/"}
+	dirA := makeTree(t, base)
+	defer os.RemoveAll(dirA)
+	dirB := makeTree(t, base)
+	defer os.RemoveAll(dirB)
+
+	hashA, err := HashDir(dirA, crypto.SHA256, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	hashB, err := HashDir(dirB, crypto.SHA256, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if hashA != hashB {
+		t.Errorf("Same trees produced different digests: %s != %s\n", hashA, hashB)
+	}
+
+	tests := []struct {
+		name   string
+		change func(dir string)
+	}{
+		{"content", func(dir string) { ioutil.WriteFile(filepath.Join(dir, "a"), []byte("hola"), 0644) }},
+		{"rename", func(dir string) { os.Rename(filepath.Join(dir, "b", "c"), filepath.Join(dir, "b", "e")) }},
+		{"empty dir", func(dir string) { os.Remove(filepath.Join(dir, "d")) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := makeTree(t, base)
+			defer os.RemoveAll(dir)
+			test.change(dir)
+			hash, err := HashDir(dir, crypto.SHA256, Options{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if hash == hashA {
+				t.Errorf("Digest didn't change\n")
+			}
+		})
+	}
+
+	// Excluded entries and modes
+	os.Chmod(filepath.Join(dirB, "a"), 0600)
+	ioutil.WriteFile(filepath.Join(dirB, "ignored.tmp"), []byte("x"), 0644)
+	hash, _ := HashDir(dirB, crypto.SHA256, Options{Exclude: []string{"*.tmp"}})
+	if hash != hashA {
+		t.Errorf("Excluded file or mode changed the digest\n")
+	}
+	os.MkdirAll(filepath.Join(dirB, "b", "x", "y"), 0755)
+	ioutil.WriteFile(filepath.Join(dirB, "b", "x", "y", "z"), []byte("x"), 0644)
+	hash, _ = HashDir(dirB, crypto.SHA256, Options{Exclude: []string{"*.tmp", "b/**/y"}})
+	hashX, _ := HashDir(dirB, crypto.SHA256, Options{Exclude: []string{"*.tmp"}})
+	if hash == hashA || hash == hashX {
+		t.Errorf("Globstar exclude didn't skip only the matching dir\n")
+	}
+	os.RemoveAll(filepath.Join(dirB, "b", "x"))
+	hash, _ = HashDir(dirB, crypto.SHA256, Options{Exclude: []string{"*.tmp"}, IncludeMode: true})
+	hashModeA, _ := HashDir(dirA, crypto.SHA256, Options{IncludeMode: true})
+	if hash == hashModeA {
+		t.Errorf("Mode change didn't change the digest\n")
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	dir := makeTree(t, map[string]string{"a": "hello"})
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		algo     crypto.Hash
+		expected string
+	}{
+		{crypto.MD5, "5d41402abc4b2a76b9719d911017c592"},
+		{crypto.SHA256, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+	}
+	for _, test := range tests {
+		got, err := HashFile(filepath.Join(dir, "a"), test.algo)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if got != test.expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s\n", test.expected, got)
+		}
+	}
+	_, err := HashFile(filepath.Join(dir, "a"), crypto.BLAKE2b_256)
+	if !errors.Is(err, ErrUnavailableHash) {
+		t.Errorf("Expected ErrUnavailableHash, got: %v\n", err)
+	}
+	_, err = HashFile(filepath.Join(dir, "missing"), crypto.SHA256)
+	if !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
+
+func TestHashTree(t *testing.T) {
+	dir := makeTree(t, map[string]string{"a": "hello", "b/c": "hello", "d/": "/"})
+	defer os.RemoveAll(dir)
+	os.Symlink("a", filepath.Join(dir, "link"))
+	got, err := HashTree(dir, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	sum := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	expected := map[string]string{"a": sum, "b/c": sum}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+}
+
+func TestHashTreeOptions(t *testing.T) {
+	dir := makeTree(t, map[string]string{"a": "hello", "b/c": "hello", "b/d.tmp": "x"})
+	defer os.RemoveAll(dir)
+	got, err := HashTreeOptions(dir, crypto.SHA256, Options{Exclude: []string{"*.tmp"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	sum := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	expected := map[string]string{"a": sum, "b/c": sum}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+
+	l := fileutils.NewIOLimiter(1024, 1)
+	got, err = HashTreeOptions(dir, crypto.SHA256, Options{Exclude: []string{"*.tmp"}, Limiter: l})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+
+	got, err = HashTreeOptions(dir, crypto.SHA256, Options{Exclude: []string{"*.tmp"}, Jobs: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+
+	c := fileutils.NewControl()
+	c.Cancel()
+	got, err = HashTreeOptions(dir, crypto.SHA256, Options{Control: c})
+	if !errors.Is(err, fileutils.ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got: %v\n", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Unexpected digests: %v\n", got)
+	}
+	_, err = HashDir(dir, crypto.SHA256, Options{Control: c})
+	if !errors.Is(err, fileutils.ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got: %v\n", err)
+	}
+}
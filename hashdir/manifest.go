@@ -0,0 +1,164 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package hashdir
+
+import (
+	"bufio"
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrVerifyFailed - Some of the manifest entries are not OK.
+var ErrVerifyFailed = fmt.Errorf("verification failed")
+
+// ManifestStatus - Result of verifying a manifest entry.
+type ManifestStatus string
+
+const (
+	// ManifestOK - The file matches its digest.
+	ManifestOK ManifestStatus = "OK"
+
+	// ManifestFailed - The file doesn't match its digest.
+	ManifestFailed ManifestStatus = "FAILED"
+
+	// ManifestMissing - The file doesn't exist.
+	ManifestMissing ManifestStatus = "MISSING"
+
+	// ManifestError - The file couldn't be hashed.
+	ManifestError ManifestStatus = "ERROR"
+)
+
+// ManifestEntry - A manifest line and, after VerifyManifest, its result.
+type ManifestEntry struct {
+	// Path - Slash separated path relative to the manifest dir.
+	Path string `json:"path"`
+
+	// Expected - Hex encoded digest listed in the manifest.
+	Expected string `json:"expected"`
+
+	// Actual - Hex encoded digest of the file, empty when it couldn't be hashed.
+	Actual string `json:"actual,omitempty"`
+
+	Status ManifestStatus `json:"status,omitempty"`
+
+	Err error `json:"-"`
+}
+
+// ReadManifest - Parses a SHA256SUMS style manifest from r, one
+// '<digest>  <path>' line per file, '<digest> *<path>' binary mode lines are
+// also accepted.
+// Empty lines and lines starting with '#' are skipped.
+// The digests must be hex encoded algo digests.
+func ReadManifest(r io.Reader, algo crypto.Hash) ([]ManifestEntry, error) {
+	entries := []ManifestEntry{}
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || len(parts[0]) != algo.Size()*2 || len(parts[1]) < 2 || (parts[1][0] != ' ' && parts[1][0] != '*') {
+			return nil, fmt.Errorf("invalid manifest line %d: %s", n, line)
+		}
+		if _, err := hex.DecodeString(parts[0]); err != nil {
+			return nil, fmt.Errorf("invalid manifest line %d: %s", n, line)
+		}
+		entries = append(entries, ManifestEntry{Path: parts[1][1:], Expected: strings.ToLower(parts[0])})
+	}
+	return entries, scanner.Err()
+}
+
+// WriteManifest - Writes sums, as returned by HashTree, to w as a
+// SHA256SUMS style manifest sorted by path.
+func WriteManifest(w io.Writer, sums map[string]string) error {
+	paths := make([]string, 0, len(sums))
+	for path := range sums {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	bw := bufio.NewWriter(w)
+	for _, path := range paths {
+		_, err := fmt.Fprintf(bw, "%s  %s\n", sums[path], path)
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// VerifyManifest - Hashes the files listed in the manifest file, relative to
+// dir, and sets the Status of each entry.
+// An empty dir means the manifest dir.
+// Honors the Control, Limiter, Jobs and Progress options.
+//
+// Returns the entries, in manifest order, and ErrVerifyFailed when any of
+// them is not OK.
+// When canceled, the entries not verified have no Status and
+// fileutils.ErrCanceled is returned.
+func VerifyManifest(manifest, dir string, algo crypto.Hash, opts Options) ([]ManifestEntry, error) {
+	if !algo.Available() {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailableHash, algo)
+	}
+	fh, err := os.Open(manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	entries, err := ReadManifest(fh, algo)
+	if err != nil {
+		return nil, fmt.Errorf("'%s': %w", manifest, err)
+	}
+	if dir == "" {
+		dir = filepath.Dir(manifest)
+	}
+	index := map[string][]int{}
+	files := []string{}
+	for i, e := range entries {
+		if _, ok := index[e.Path]; !ok {
+			files = append(files, e.Path)
+		}
+		index[e.Path] = append(index[e.Path], i)
+	}
+	err = opts.hashAll(dir, files, algo, func(rel, sum string, err error) error {
+		for _, i := range index[rel] {
+			e := &entries[i]
+			e.Actual, e.Err = sum, err
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				e.Status = ManifestMissing
+			case err != nil:
+				e.Status = ManifestError
+			case sum != e.Expected:
+				e.Status = ManifestFailed
+			default:
+				e.Status = ManifestOK
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return entries, err
+	}
+	for _, e := range entries {
+		if e.Status != ManifestOK {
+			return entries, ErrVerifyFailed
+		}
+	}
+	return entries, nil
+}
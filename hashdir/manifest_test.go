@@ -0,0 +1,153 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package hashdir
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+)
+
+const helloSum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestReadManifest(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected []ManifestEntry
+		err      bool
+	}{
+		{"text and binary", "# comment\n" + helloSum + "  a\r\n\n" + strings.ToUpper(helloSum) + " *b c\n", []ManifestEntry{
+			{Path: "a", Expected: helloSum},
+			{Path: "b c", Expected: helloSum},
+		}, false},
+		{"empty", "", []ManifestEntry{}, false},
+		{"short digest", "abc  a\n", nil, true},
+		{"not hex", strings.Repeat("z", 64) + "  a\n", nil, true},
+		{"no path", helloSum + "  \n", nil, true},
+		{"bad separator", helloSum + " -a\n", nil, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ReadManifest(strings.NewReader(c.input), crypto.SHA256)
+			if c.err {
+				if err == nil {
+					t.Errorf("Expected error\n")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("Expected:\n%v\nGot:\n%v\n", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteManifest(&buf, map[string]string{"b/c": helloSum, "a": helloSum})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := helloSum + "  a\n" + helloSum + "  b/c\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, buf.String())
+	}
+}
+
+// recorder - Records the Progress calls.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) Start(path string, total int64)                          { r.add("start " + path) }
+func (r *recorder) Reader(path string, total int64, rd io.Reader) io.Reader { return rd }
+func (r *recorder) Done(path string, bytes int64)                           { r.add("done " + path) }
+func (r *recorder) Error(path string, err error)                            { r.add("error " + path) }
+
+func TestVerifyManifest(t *testing.T) {
+	dir := makeTree(t, map[string]string{"a": "hello", "b/c": "changed", "d": "hello"})
+	defer os.RemoveAll(dir)
+	manifest := filepath.Join(dir, "SHA256SUMS")
+	ioutil.WriteFile(manifest, []byte(helloSum+"  a\n"+helloSum+"  b/c\n"+helloSum+"  missing\n"+helloSum+"  a\n"), 0644)
+
+	r := &recorder{}
+	got, err := VerifyManifest(manifest, "", crypto.SHA256, Options{Jobs: 2, Progress: r})
+	if !errors.Is(err, ErrVerifyFailed) {
+		t.Errorf("Expected ErrVerifyFailed, got: %v\n", err)
+	}
+	statuses := []ManifestStatus{}
+	for _, e := range got {
+		statuses = append(statuses, e.Status)
+	}
+	expected := []ManifestStatus{ManifestOK, ManifestFailed, ManifestMissing, ManifestOK}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, statuses)
+	}
+	if got[0].Actual != helloSum || got[2].Err == nil {
+		t.Errorf("Unexpected entries: %v\n", got)
+	}
+	sort.Strings(r.events)
+	events := []string{"done a", "done b/c", "error missing", "start a", "start b/c"}
+	if !reflect.DeepEqual(r.events, events) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", events, r.events)
+	}
+
+	// The dir defaults to the manifest dir.
+	other := filepath.Join(dir, "other")
+	ioutil.WriteFile(other, []byte(helloSum+"  d\n"), 0644)
+	got, err = VerifyManifest(other, "", crypto.SHA256, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(got) != 1 || got[0].Status != ManifestOK {
+		t.Errorf("Unexpected entries: %v\n", got)
+	}
+	got, err = VerifyManifest(other, filepath.Join(dir, "b"), crypto.SHA256, Options{})
+	if !errors.Is(err, ErrVerifyFailed) || got[0].Status != ManifestMissing {
+		t.Errorf("Expected missing entry, got: %v, %v\n", got, err)
+	}
+
+	c := fileutils.NewControl()
+	c.Cancel()
+	got, err = VerifyManifest(manifest, "", crypto.SHA256, Options{Control: c})
+	if !errors.Is(err, fileutils.ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got: %v\n", err)
+	}
+	if len(got) != 4 || got[0].Status != "" {
+		t.Errorf("Unexpected entries: %v\n", got)
+	}
+
+	ioutil.WriteFile(other, []byte("bad\n"), 0644)
+	_, err = VerifyManifest(other, "", crypto.SHA256, Options{})
+	if err == nil {
+		t.Errorf("Expected invalid manifest error\n")
+	}
+}
@@ -0,0 +1,99 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package hashdir
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// treeDigest - The documented tree digest scheme, computed sequentially.
+func treeDigest(data []byte, chunkSize int) string {
+	h := sha256.New()
+	binary.Write(h, binary.BigEndian, uint64(chunkSize))
+	for i := 0; i < len(data); i += chunkSize {
+		end := i + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha256.Sum256(data[i:end])
+		h.Write(sum[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestHashFileParallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hashdir-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	data := make([]byte, 3*1024*1024+123)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	file := filepath.Join(dir, "big")
+	ioutil.WriteFile(file, data, 0644)
+	empty := filepath.Join(dir, "empty")
+	ioutil.WriteFile(empty, []byte{}, 0644)
+
+	tests := []struct {
+		name      string
+		file      string
+		data      []byte
+		chunkSize int
+		workers   int
+	}{
+		{"chunks", file, data, 1000 * 1000, 3},
+		{"one worker", file, data, 1000 * 1000, 1},
+		{"single chunk", file, data, DefaultChunkSize, 0},
+		{"empty", empty, []byte{}, 1000, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var last int64
+			got, err := HashFileParallel(test.file, crypto.SHA256, ParallelOptions{
+				ChunkSize: int64(test.chunkSize),
+				Workers:   test.workers,
+				Progress: func(done, total int64) {
+					if done < last || total != int64(len(test.data)) {
+						t.Errorf("Unexpected progress: %d/%d after %d\n", done, total, last)
+					}
+					last = done
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			expected := treeDigest(test.data, test.chunkSize)
+			if got != expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, got)
+			}
+			if last != int64(len(test.data)) {
+				t.Errorf("Expected progress to reach %d, got %d\n", len(test.data), last)
+			}
+		})
+	}
+
+	_, err = HashFileParallel(file, crypto.BLAKE2b_256, ParallelOptions{})
+	if !errors.Is(err, ErrUnavailableHash) {
+		t.Errorf("Expected ErrUnavailableHash, got: %v\n", err)
+	}
+	_, err = HashFileParallel(filepath.Join(dir, "missing"), crypto.SHA256, ParallelOptions{})
+	if !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
@@ -0,0 +1,274 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package httputils - HTTP related utilities.
+*/
+package httputils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+	"github.com/DavidGamba/go-utils/retryutils"
+)
+
+// Logger - Custom lib logger
+var Logger = log.New(ioutil.Discard, "httputils ", log.LstdFlags)
+
+// ErrChecksumMismatch - The downloaded file doesn't match the expected checksum.
+var ErrChecksumMismatch = fmt.Errorf("checksum mismatch")
+
+// ErrUnexpectedStatus - The server returned an unexpected HTTP status code.
+var ErrUnexpectedStatus = fmt.Errorf("unexpected status")
+
+// DownloadOptions - DownloadFile options.
+// The zero value is valid.
+type DownloadOptions struct {
+	// Client used for the requests, defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Retries is the number of extra attempts after a failed one, made with
+	// retryutils.Retry.
+	Retries int
+
+	// Backoff is the wait before the first retry, it doubles on every retry.
+	// Defaults to 1 second.
+	Backoff time.Duration
+
+	// SHA256 is the expected hex encoded checksum of the file.
+	// When empty no verification is done.
+	SHA256 string
+
+	// Progress is called after every write with the number of downloaded bytes and the total size.
+	// The total is -1 when the server doesn't report it.
+	Progress func(downloaded, total int64)
+
+	// Perm is the mode of the final file, defaults to 0644.
+	Perm os.FileMode
+
+	// CheckSpace fails the download with fileutils.ErrInsufficientSpace,
+	// before writing anything, when the server reports a size that doesn't
+	// fit in the filesystem of dst.
+	CheckSpace bool
+}
+
+// DownloadFile - Downloads url into dst.
+//
+// The data is written to 'dst.part' and renamed into dst only after it has
+// been completely downloaded and verified, so an existing dst is never
+// replaced by a partial download.
+// If 'dst.part' exists, the download resumes from its size using a Range request.
+// Returns fileutils.ErrReadOnlyMode when fileutils read-only mode is enabled.
+func DownloadFile(ctx context.Context, url, dst string, opts DownloadOptions) error {
+	if fileutils.IsReadOnly() {
+		return fmt.Errorf("%w: DownloadFile '%s'", fileutils.ErrReadOnlyMode, dst)
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.Perm == 0 {
+		opts.Perm = 0644
+	}
+	part := dst + ".part"
+	policy := retryutils.Policy{
+		MaxAttempts:  opts.Retries + 1,
+		InitialDelay: opts.Backoff,
+		Multiplier:   2,
+		// download tells apart the errors worth retrying.
+		Retryable: func(error) bool { return true },
+	}
+	err := retryutils.Retry(ctx, policy, func() error {
+		retry, err := download(ctx, url, part, opts)
+		if err != nil && !retry {
+			return retryutils.Permanent(err)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if opts.SHA256 != "" {
+		sum, err := sha256File(part)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(sum, opts.SHA256) {
+			os.Remove(part)
+			return fmt.Errorf("%w: '%s': expected %s, got %s", ErrChecksumMismatch, url, opts.SHA256, sum)
+		}
+	}
+	err = os.Chmod(part, opts.Perm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(part, dst)
+}
+
+// download - Downloads url appending to part.
+// Returns whether or not the error is worth retrying.
+func download(ctx context.Context, url, part string, opts DownloadOptions) (bool, error) {
+	var offset int64
+	fInfo, err := os.Stat(part)
+	if err == nil {
+		offset = fInfo.Size()
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusOK:
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusPartialContent:
+		start, ok := contentRangeStart(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			if offset == 0 {
+				return false, fmt.Errorf("%w: '%s': %s with Content-Range '%s'", ErrUnexpectedStatus, url, resp.Status, resp.Header.Get("Content-Range"))
+			}
+			// Appending would corrupt the partial file, start over.
+			Logger.Printf("DownloadFile: '%s': Content-Range '%s' doesn't start at %d, restarting", url, resp.Header.Get("Content-Range"), offset)
+			resp.Body.Close()
+			err = os.Truncate(part, 0)
+			if err != nil {
+				return false, err
+			}
+			return download(ctx, url, part, opts)
+		}
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			return false, fmt.Errorf("%w: '%s': %s", ErrUnexpectedStatus, url, resp.Status)
+		}
+		// The partial file is already complete when it has the whole size.
+		size, ok := contentRangeSize(resp.Header.Get("Content-Range"))
+		if ok && size == offset {
+			return false, nil
+		}
+		Logger.Printf("DownloadFile: '%s': Content-Range '%s' doesn't match the %d bytes downloaded, restarting", url, resp.Header.Get("Content-Range"), offset)
+		resp.Body.Close()
+		err = os.Truncate(part, 0)
+		if err != nil {
+			return false, err
+		}
+		return download(ctx, url, part, opts)
+	default:
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests,
+			fmt.Errorf("%w: '%s': %s", ErrUnexpectedStatus, url, resp.Status)
+	}
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+		if opts.CheckSpace {
+			err := fileutils.CheckSpace(filepath.Dir(part), resp.ContentLength)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	fh, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return false, err
+	}
+	pw := &progressWriter{w: fh, n: offset, total: total, fn: opts.Progress}
+	_, err = io.Copy(pw, resp.Body)
+	cerr := fh.Close()
+	if err != nil {
+		var pathErr *os.PathError
+		// Write errors on the local file are not retried.
+		return !errors.As(err, &pathErr) && ctx.Err() == nil, err
+	}
+	if cerr != nil {
+		return false, cerr
+	}
+	if total >= 0 && pw.n != total {
+		return true, fmt.Errorf("'%s': short read: %d of %d bytes", url, pw.n, total)
+	}
+	return false, nil
+}
+
+// contentRangeStart - Returns the first byte position of a
+// 'bytes first-last/length' Content-Range header.
+func contentRangeStart(header string) (int64, bool) {
+	s := strings.TrimPrefix(header, "bytes ")
+	i := strings.IndexByte(s, '-')
+	if s == header || i < 0 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(s[:i], 10, 64)
+	return start, err == nil
+}
+
+// contentRangeSize - Returns the complete length of a
+// 'bytes first-last/length' or 'bytes */length' Content-Range header.
+func contentRangeSize(header string) (int64, bool) {
+	i := strings.LastIndexByte(header, '/')
+	if !strings.HasPrefix(header, "bytes ") || i < 0 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(header[i+1:], 10, 64)
+	return size, err == nil
+}
+
+type progressWriter struct {
+	w     io.Writer
+	n     int64
+	total int64
+	fn    func(downloaded, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.n += int64(n)
+	if p.fn != nil {
+		p.fn(p.n, p.total)
+	}
+	return n, err
+}
+
+func sha256File(filename string) (string, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, fh)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
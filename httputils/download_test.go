@@ -0,0 +1,237 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package httputils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+)
+
+func newServer(t *testing.T, content []byte, failures int) (*httptest.Server, *[]string) {
+	ranges := []string{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if failures > 0 {
+			failures--
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	})), &ranges
+}
+
+func TestDownloadFile(t *testing.T) {
+	content := []byte(strings.Repeat("hello world\n", 1000))
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	dir, err := ioutil.TempDir("", "httputils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("full", func(t *testing.T) {
+		ts, _ := newServer(t, content, 0)
+		defer ts.Close()
+		dst := filepath.Join(dir, "full")
+		var downloaded, total int64
+		err := DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{
+			SHA256:   checksum,
+			Progress: func(d, t int64) { downloaded, total = d, t },
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		b, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(b, content) {
+			t.Errorf("Unexpected content\n")
+		}
+		if downloaded != int64(len(content)) || total != int64(len(content)) {
+			t.Errorf("Unexpected progress: %d/%d\n", downloaded, total)
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		ts, ranges := newServer(t, content, 0)
+		defer ts.Close()
+		dst := filepath.Join(dir, "resume")
+		err := ioutil.WriteFile(dst+".part", content[:100], 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		err = DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{SHA256: checksum})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if len(*ranges) != 1 || (*ranges)[0] != "bytes=100-" {
+			t.Errorf("Unexpected ranges: %v\n", *ranges)
+		}
+		b, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(b, content) {
+			t.Errorf("Unexpected content\n")
+		}
+		if _, err := os.Stat(dst + ".part"); !os.IsNotExist(err) {
+			t.Errorf("Part file not removed: %v\n", err)
+		}
+	})
+
+	t.Run("resume at wrong offset", func(t *testing.T) {
+		ranges := []string{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ranges = append(ranges, r.Header.Get("Range"))
+			if r.Header.Get("Range") != "" {
+				// Ignores the requested start.
+				w.Header().Set("Content-Range", "bytes 0-"+strconv.Itoa(len(content)-1)+"/"+strconv.Itoa(len(content)))
+				w.WriteHeader(http.StatusPartialContent)
+			}
+			w.Write(content)
+		}))
+		defer ts.Close()
+		dst := filepath.Join(dir, "wrong-offset")
+		err := ioutil.WriteFile(dst+".part", content[:100], 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		err = DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{SHA256: checksum})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		expected := []string{"bytes=100-", ""}
+		if strings.Join(ranges, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, ranges)
+		}
+		b, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(b, content) {
+			t.Errorf("Unexpected content\n")
+		}
+	})
+
+	t.Run("part complete", func(t *testing.T) {
+		ts, ranges := newServer(t, content, 0)
+		defer ts.Close()
+		dst := filepath.Join(dir, "complete")
+		err := ioutil.WriteFile(dst+".part", content, 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		err = DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if len(*ranges) != 1 {
+			t.Errorf("Unexpected ranges: %v\n", *ranges)
+		}
+		b, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(b, content) {
+			t.Errorf("Unexpected content\n")
+		}
+	})
+
+	t.Run("part stale", func(t *testing.T) {
+		ts, ranges := newServer(t, content, 0)
+		defer ts.Close()
+		dst := filepath.Join(dir, "stale")
+		err := ioutil.WriteFile(dst+".part", append(append([]byte{}, content...), "stale"...), 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		err = DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		expected := []string{"bytes=" + strconv.Itoa(len(content)+5) + "-", ""}
+		if strings.Join(*ranges, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, *ranges)
+		}
+		b, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(b, content) {
+			t.Errorf("Unexpected content\n")
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		ts, ranges := newServer(t, content, 2)
+		defer ts.Close()
+		dst := filepath.Join(dir, "retry")
+		err := DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{Retries: 1, Backoff: time.Millisecond})
+		if !errors.Is(err, ErrUnexpectedStatus) {
+			t.Fatalf("Unexpected error: %v\n", err)
+		}
+		err = DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{Retries: 2, Backoff: time.Millisecond})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if len(*ranges) != 3 {
+			t.Errorf("Unexpected number of requests: %d\n", len(*ranges))
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		ts, _ := newServer(t, content, 0)
+		defer ts.Close()
+		dst := filepath.Join(dir, "mismatch")
+		err := ioutil.WriteFile(dst, []byte("good"), 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		err = DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{SHA256: "abc"})
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("Unexpected error: %v\n", err)
+		}
+		b, _ := ioutil.ReadFile(dst)
+		if string(b) != "good" {
+			t.Errorf("Existing file replaced\n")
+		}
+	})
+
+	t.Run("insufficient space", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.FormatInt(1<<62, 10))
+		}))
+		defer ts.Close()
+		dst := filepath.Join(dir, "huge")
+		err := DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{CheckSpace: true})
+		if !errors.Is(err, fileutils.ErrInsufficientSpace) {
+			t.Fatalf("Unexpected error: %v\n", err)
+		}
+		if _, err := os.Stat(dst + ".part"); !os.IsNotExist(err) {
+			t.Errorf("Part file created: %v\n", err)
+		}
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		ts, ranges := newServer(t, content, 0)
+		defer ts.Close()
+		fileutils.SetReadOnly(true)
+		defer fileutils.SetReadOnly(false)
+		dst := filepath.Join(dir, "read-only")
+		err := DownloadFile(context.Background(), ts.URL, dst, DownloadOptions{})
+		if !errors.Is(err, fileutils.ErrReadOnlyMode) {
+			t.Fatalf("Unexpected error: %v\n", err)
+		}
+		if len(*ranges) != 0 {
+			t.Errorf("Unexpected requests: %d\n", len(*ranges))
+		}
+	})
+}
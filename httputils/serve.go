@@ -0,0 +1,338 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package httputils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+)
+
+// ServeOptions - ServeDir options.
+// The zero value serves files only.
+type ServeOptions struct {
+	// Listing enables HTML directory listings.
+	Listing bool
+
+	// Upload enables file uploads with PUT <path> or with a multipart POST to a directory.
+	Upload bool
+
+	// MaxUploadSize limits the size of uploads, 0 means no limit.
+	// Multipart POST bodies are limited to it plus multipartOverhead.
+	MaxUploadSize int64
+
+	// AccessLog receives one line per request, nil disables access logging.
+	AccessLog io.Writer
+}
+
+// multipartOverhead - Room for the multipart headers and boundaries in a
+// POST upload body.
+const multipartOverhead = 64 << 10
+
+// errOutsideRoot - The upload target resolves outside of the served dir.
+var errOutsideRoot = fmt.Errorf("path outside of the served dir")
+
+// ServeDir - Serves the root dir over HTTP on addr until ctx is cancelled.
+func ServeDir(ctx context.Context, root, addr string, opts ServeOptions) error {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: DirHandler(root, opts),
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	Logger.Printf("ServeDir: serving '%s' on %s", root, ln.Addr())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+}
+
+// DirHandler - Returns the http.Handler used by ServeDir.
+func DirHandler(root string, opts ServeOptions) http.Handler {
+	var h http.Handler = &dirHandler{root: root, opts: opts}
+	if opts.AccessLog != nil {
+		h = accessLog(opts.AccessLog, h)
+	}
+	return h
+}
+
+type dirHandler struct {
+	root string
+	opts ServeOptions
+}
+
+func (h *dirHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.URL.Path)
+	fullPath := filepath.Join(h.root, filepath.FromSlash(name))
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		fInfo, err := os.Stat(fullPath)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !fInfo.IsDir() {
+			http.ServeFile(w, r, fullPath)
+			return
+		}
+		// The listing links are relative to the dir.
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target := dirURL(name)
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		if !h.opts.Listing {
+			http.Error(w, "directory listing disabled", http.StatusForbidden)
+			return
+		}
+		h.list(w, name, fullPath)
+	case http.MethodPut:
+		if !h.opts.Upload {
+			http.Error(w, "uploads disabled", http.StatusMethodNotAllowed)
+			return
+		}
+		err := h.save(fullPath, r.Body)
+		if err != nil {
+			uploadError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPost:
+		if !h.opts.Upload {
+			http.Error(w, "uploads disabled", http.StatusMethodNotAllowed)
+			return
+		}
+		if h.opts.MaxUploadSize > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, h.opts.MaxUploadSize+multipartOverhead)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				uploadError(w, err)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		err = h.save(filepath.Join(fullPath, filepath.Base(header.Filename)), file)
+		if err != nil {
+			uploadError(w, err)
+			return
+		}
+		http.Redirect(w, r, dirURL(name), http.StatusSeeOther)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// dirURL - Returns the escaped URL path of the dir name, with a trailing slash.
+func dirURL(name string) string {
+	if name != "/" {
+		name += "/"
+	}
+	return (&url.URL{Path: name}).EscapedPath()
+}
+
+// uploadError - Replies with the status for a failed upload.
+func uploadError(w http.ResponseWriter, err error) {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errOutsideRoot):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &maxErr):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// resolve - Resolves the symlinks in the dir of filename and returns the
+// resolved path, or errOutsideRoot if it isn't under the root.
+func (h *dirHandler) resolve(filename string) (string, error) {
+	root, err := filepath.EvalSymlinks(h.root)
+	if err != nil {
+		return "", err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(filename))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: '%s'", errOutsideRoot, filename)
+	}
+	return filepath.Join(dir, filepath.Base(filename)), nil
+}
+
+// save - Writes the upload to a temp file next to the target and renames it into place.
+// Targets whose dir resolves outside of the root are refused.
+func (h *dirHandler) save(filename string, r io.Reader) error {
+	filename, err := h.resolve(filename)
+	if err != nil {
+		return err
+	}
+	if h.opts.MaxUploadSize > 0 {
+		r = io.LimitReader(r, h.opts.MaxUploadSize+1)
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	n, err := io.Copy(tmpFile, r)
+	cerr := tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	if cerr != nil {
+		return cerr
+	}
+	if h.opts.MaxUploadSize > 0 && n > h.opts.MaxUploadSize {
+		return fmt.Errorf("upload exceeds %d bytes", h.opts.MaxUploadSize)
+	}
+	err = os.Chmod(tmpFile.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filename)
+}
+
+type listEntry struct {
+	Name string
+
+	// Href - Name escaped for the link.
+	Href  string
+	IsDir bool
+	Size  int64
+	Mtime string
+}
+
+var listTemplate = template.Must(template.New("list").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+{{- if ne .Path "/"}}
+<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{- end}}
+{{- range .Entries}}
+<tr><td><a href="./{{.Href}}{{if .IsDir}}/{{end}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td>{{if not .IsDir}}{{.Size}}{{end}}</td><td>{{.Mtime}}</td></tr>
+{{- end}}
+</table>
+{{- if .Upload}}
+<form method="post" enctype="multipart/form-data"><input type="file" name="file"><input type="submit" value="Upload"></form>
+{{- end}}
+</body>
+</html>
+`))
+
+func (h *dirHandler) list(w http.ResponseWriter, name, dir string) {
+	files, err := fileutils.ListFiles(dir, false, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	entries := []listEntry{}
+	for _, file := range files {
+		fInfo, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, listEntry{
+			Name:  filepath.Base(file),
+			Href:  url.PathEscape(filepath.Base(file)),
+			IsDir: fInfo.IsDir(),
+			Size:  fInfo.Size(),
+			Mtime: fInfo.ModTime().Format("2006-01-02 15:04:05"),
+		})
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = listTemplate.Execute(w, struct {
+		Path    string
+		Entries []listEntry
+		Upload  bool
+	}{name, entries, h.opts.Upload})
+	if err != nil {
+		Logger.Printf("list: %s", err)
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusWriter) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.size += n
+	return n, err
+}
+
+// accessLog - Logs requests in Common Log Format.
+func accessLog(out io.Writer, h http.Handler) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, "%s - - [%s] \"%s %s %s\" %d %d\n",
+			host, time.Now().Format("02/Jan/2006:15:04:05 -0700"), r.Method, r.URL.RequestURI(), r.Proto, sw.status, sw.size)
+	})
+}
@@ -0,0 +1,137 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package httputils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httputils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "sub", "a#b?c%d.txt"), []byte("a"), 0644)
+
+	tests := []struct {
+		name     string
+		opts     ServeOptions
+		method   string
+		path     string
+		body     string
+		status   int
+		contains string
+	}{
+		{"file", ServeOptions{}, "GET", "/hello.txt", "", http.StatusOK, "hello"},
+		{"missing", ServeOptions{}, "GET", "/missing", "", http.StatusNotFound, ""},
+		{"traversal", ServeOptions{}, "GET", "/../../etc/passwd", "", http.StatusNotFound, ""},
+		{"no listing", ServeOptions{}, "GET", "/", "", http.StatusForbidden, ""},
+		{"listing", ServeOptions{Listing: true}, "GET", "/", "", http.StatusOK, `<a href="./sub/">sub/</a>`},
+		{"listing escaped", ServeOptions{Listing: true}, "GET", "/sub/", "", http.StatusOK, `<a href="./a%23b%3Fc%25d.txt">a#b?c%d.txt</a>`},
+		{"dir redirect", ServeOptions{Listing: true}, "GET", "/sub?x=1", "", http.StatusMovedPermanently, `<a href="/sub/?x=1">`},
+		{"no upload", ServeOptions{}, "PUT", "/new.txt", "new", http.StatusMethodNotAllowed, ""},
+		{"upload", ServeOptions{Upload: true}, "PUT", "/sub/new.txt", "new", http.StatusCreated, ""},
+		{"upload too big", ServeOptions{Upload: true, MaxUploadSize: 2}, "PUT", "/big.txt", "new", http.StatusInternalServerError, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := new(bytes.Buffer)
+			test.opts.AccessLog = log
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			w := httptest.NewRecorder()
+			DirHandler(dir, test.opts).ServeHTTP(w, req)
+			if w.Code != test.status {
+				t.Errorf("Unexpected status: %d\n", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), test.contains) {
+				t.Errorf("Body doesn't contain '%s':\n%s\n", test.contains, w.Body.String())
+			}
+			if !strings.Contains(log.String(), test.method+" ") {
+				t.Errorf("Unexpected access log: %s\n", log.String())
+			}
+		})
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "sub", "new.txt"))
+	if err != nil || string(b) != "new" {
+		t.Errorf("Upload failed: %s, %v\n", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "big.txt")); !os.IsNotExist(err) {
+		t.Errorf("Oversized upload saved\n")
+	}
+}
+
+func TestDirHandlerUploadLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httputils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	os.Mkdir(root, 0755)
+	os.Mkdir(outside, 0755)
+	os.Mkdir(filepath.Join(root, "sub dir"), 0755)
+	os.Symlink(outside, filepath.Join(root, "out"))
+
+	// post - Returns a multipart upload of content.
+	post := func(path, content string) *http.Request {
+		body := new(bytes.Buffer)
+		mw := multipart.NewWriter(body)
+		fw, _ := mw.CreateFormFile("file", "post.txt")
+		fw.Write([]byte(content))
+		mw.Close()
+		req := httptest.NewRequest("POST", path, body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req
+	}
+	big := strings.Repeat("x", 2*multipartOverhead)
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"post", post("/", "post"), http.StatusSeeOther},
+		{"post too big", post("/", big), http.StatusRequestEntityTooLarge},
+		{"post through symlink", post("/out", "post"), http.StatusForbidden},
+		{"put through symlink", httptest.NewRequest("PUT", "/out/put.txt", strings.NewReader("put")), http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			DirHandler(root, ServeOptions{Upload: true, MaxUploadSize: 10}).ServeHTTP(w, test.req)
+			if w.Code != test.status {
+				t.Errorf("Unexpected status: %d\n%s\n", w.Code, w.Body.String())
+			}
+		})
+	}
+	w := httptest.NewRecorder()
+	DirHandler(root, ServeOptions{Upload: true}).ServeHTTP(w, post("/sub%20dir", "post"))
+	if w.Header().Get("Location") != "/sub%20dir/" {
+		t.Errorf("Unexpected redirect: %s\n", w.Header().Get("Location"))
+	}
+	b, err := ioutil.ReadFile(filepath.Join(root, "post.txt"))
+	if err != nil || string(b) != "post" {
+		t.Errorf("Upload failed: %s, %v\n", b, err)
+	}
+	files, _ := ioutil.ReadDir(outside)
+	if len(files) != 0 {
+		t.Errorf("Upload escaped the root: %v\n", files)
+	}
+}
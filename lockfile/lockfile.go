@@ -0,0 +1,190 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package lockfile - PID lock files to guarantee single instance execution.
+
+	lock, err := lockfile.Acquire("/tmp/tool.lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+A lock file whose owner process is no longer running is stale and can be stolen.
+*/
+package lockfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DavidGamba/go-utils/cleanup"
+	"github.com/DavidGamba/go-utils/processutils"
+)
+
+// Logger - Custom lib logger
+var Logger = log.New(ioutil.Discard, "lockfile ", log.LstdFlags)
+
+// ErrLocked - The lock file is owned by a running process.
+var ErrLocked = fmt.Errorf("locked")
+
+// ErrNotOwner - The lock file is not owned by the current process.
+var ErrNotOwner = fmt.Errorf("not the lock owner")
+
+// incompleteGracePeriod - Time given to an owner to write its PID before an empty or invalid lock file is considered stale.
+const incompleteGracePeriod = 5 * time.Second
+
+// Lock - Acquired lock file.
+type Lock struct {
+	path    string
+	pid     int
+	cleanup *cleanup.Handle
+}
+
+// Option - Acquire option.
+type Option func(*options)
+
+type options struct {
+	confirm func(pid int) bool
+}
+
+// StealConfirm - Calls fn before stealing a stale lock.
+// fn receives the PID of the dead owner, 0 if it couldn't be read.
+// If fn returns false, Acquire returns ErrLocked.
+// Without this option stale locks are stolen automatically.
+func StealConfirm(fn func(pid int) bool) Option {
+	return func(o *options) {
+		o.confirm = fn
+	}
+}
+
+// Acquire - Creates the lock file exclusively and writes the current PID to it.
+// If the lock file exists and its owner is running, it returns ErrLocked.
+// If the owner is not running, the lock is stale and it is stolen.
+func Acquire(path string, opts ...Option) (*Lock, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		lock, err := create(path)
+		if err == nil {
+			return lock, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		pid, stale, err := inspect(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Released in between, try again.
+				continue
+			}
+			return nil, err
+		}
+		if !stale {
+			return nil, fmt.Errorf("%w: '%s' owned by pid %d", ErrLocked, path, pid)
+		}
+		if o.confirm != nil && !o.confirm(pid) {
+			return nil, fmt.Errorf("%w: '%s' stale lock owned by pid %d", ErrLocked, path, pid)
+		}
+		Logger.Printf("Acquire: stealing stale lock '%s' owned by pid %d", path, pid)
+		// Make sure the file wasn't replaced in between.
+		current, _ := readPID(path)
+		if current != pid {
+			continue
+		}
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	pid, _ := readPID(path)
+	return nil, fmt.Errorf("%w: '%s' owned by pid %d", ErrLocked, path, pid)
+}
+
+// Owner - Returns the PID written in the lock file.
+func Owner(path string) (int, error) {
+	return readPID(path)
+}
+
+// Path - Returns the lock file path.
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// Release - Removes the lock file if it is still owned by the current process.
+// The lock is also released if the process receives SIGINT or SIGTERM.
+func (l *Lock) Release() error {
+	return l.cleanup.Run()
+}
+
+func (l *Lock) release() error {
+	pid, err := readPID(l.path)
+	if err != nil {
+		return err
+	}
+	if pid != l.pid {
+		return fmt.Errorf("%w: '%s' owned by pid %d", ErrNotOwner, l.path, pid)
+	}
+	return os.Remove(l.path)
+}
+
+func create(path string) (*Lock, error) {
+	fh, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	pid := os.Getpid()
+	_, err = fmt.Fprintf(fh, "%d\n", pid)
+	cerr := fh.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	l := &Lock{path: path, pid: pid}
+	l.cleanup = cleanup.Register(l.release, cleanup.Name("lock file '"+path+"'"))
+	return l, nil
+}
+
+// inspect - Returns the lock owner and whether or not the lock is stale.
+func inspect(path string) (int, bool, error) {
+	fInfo, err := os.Stat(path)
+	if err != nil {
+		return 0, false, err
+	}
+	pid, err := readPID(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, err
+		}
+		// The owner might still be writing its PID.
+		return 0, time.Since(fInfo.ModTime()) > incompleteGracePeriod, nil
+	}
+	return pid, !processutils.IsAlive(pid), nil
+}
+
+func readPID(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("'%s': invalid pid: '%s'", path, strings.TrimSpace(string(data)))
+	}
+	return pid, nil
+}
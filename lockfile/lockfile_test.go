@@ -0,0 +1,93 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package lockfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lockfile-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.lock")
+
+	lock, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	pid, err := Owner(path)
+	if err != nil || pid != os.Getpid() {
+		t.Errorf("Unexpected owner: %d, %v\n", pid, err)
+	}
+	_, err = Acquire(path)
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	err = lock.Release()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Lock file not removed\n")
+	}
+}
+
+func TestAcquireStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lockfile-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.lock")
+
+	// Get the PID of a process that is no longer running.
+	cmd := exec.Command("true")
+	err = cmd.Run()
+	if err != nil {
+		t.Skipf("Can't run helper process: %s\n", err)
+	}
+	deadPID := cmd.Process.Pid
+	ioutil.WriteFile(path, []byte(strconv.Itoa(deadPID)+"\n"), 0644)
+
+	confirmed := 0
+	_, err = Acquire(path, StealConfirm(func(pid int) bool {
+		confirmed = pid
+		return false
+	}))
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if confirmed != deadPID {
+		t.Errorf("Unexpected confirm pid: %d\n", confirmed)
+	}
+	lock, err := Acquire(path, StealConfirm(func(pid int) bool { return true }))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	pid, _ := Owner(path)
+	if pid != os.Getpid() {
+		t.Errorf("Unexpected owner: %d\n", pid)
+	}
+
+	// Another process took over the lock.
+	ioutil.WriteFile(path, []byte("1\n"), 0644)
+	err = lock.Release()
+	if !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
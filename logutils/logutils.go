@@ -0,0 +1,276 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package logutils - Structured log line parsing.
+
+Parses logfmt, JSON lines and combined access log lines into flat
+map[string]string records so tools can filter on fields instead of raw
+substrings.
+*/
+package logutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+)
+
+// ErrInvalidLine - The line can't be parsed with the given format.
+var ErrInvalidLine = fmt.Errorf("invalid log line")
+
+// ErrUnknownFormat - The format name is not supported.
+var ErrUnknownFormat = fmt.Errorf("unknown log format")
+
+// Format - Log line format.
+type Format int
+
+const (
+	// Logfmt - key=value pairs, values with spaces are double quoted.
+	Logfmt Format = iota
+
+	// JSONLines - One JSON object per line.
+	// Nested objects are flattened with dot separated keys, arrays are kept as JSON.
+	JSONLines
+
+	// CombinedLog - Apache/nginx combined access log. The common log format,
+	// without referer and user agent, is accepted as well.
+	CombinedLog
+)
+
+var formatNames = map[string]Format{
+	"logfmt":   Logfmt,
+	"json":     JSONLines,
+	"combined": CombinedLog,
+}
+
+// ParseFormat - Returns the format for one of the names: logfmt, json or combined.
+func ParseFormat(name string) (Format, error) {
+	f, ok := formatNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("%w: '%s'", ErrUnknownFormat, name)
+	}
+	return f, nil
+}
+
+// Record - A parsed line, or an error indicating failure (`Error`).
+type Record struct {
+	Fields map[string]string
+
+	// Line - 1 based line number of the input.
+	Line int
+
+	// Text - Raw line.
+	Text string
+
+	Error error
+}
+
+// Parse - Pipeline stage that parses each line received from in, for
+// example from fileutils.ReadLines, and sends a record for it.
+// Lines that fail to parse are sent with their error and parsing continues.
+// Input errors are passed through.
+func Parse(in <-chan fileutils.StringError, format Format) <-chan Record {
+	c := make(chan Record)
+	go func() {
+		defer close(c)
+		n := 0
+		for e := range in {
+			if e.Error != nil {
+				c <- Record{Error: e.Error}
+				continue
+			}
+			n++
+			fields, err := ParseLine(format, e.String)
+			if err != nil {
+				err = fmt.Errorf("line %d: %w", n, err)
+			}
+			c <- Record{Fields: fields, Line: n, Text: e.String, Error: err}
+		}
+	}()
+	return c
+}
+
+// ParseLine - Parses a single line.
+func ParseLine(format Format, line string) (map[string]string, error) {
+	switch format {
+	case Logfmt:
+		return parseLogfmt(line)
+	case JSONLines:
+		return parseJSON(line)
+	case CombinedLog:
+		return parseCombined(line)
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownFormat, format)
+}
+
+// parseLogfmt - Bare keys get an empty value.
+func parseLogfmt(line string) (map[string]string, error) {
+	fields := map[string]string{}
+	i := 0
+	for {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i >= len(line) {
+			break
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			if line[i] == '"' {
+				return nil, fmt.Errorf("%w: unexpected quote at column %d", ErrInvalidLine, i+1)
+			}
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, fmt.Errorf("%w: missing key at column %d", ErrInvalidLine, i+1)
+		}
+		if i >= len(line) || line[i] == ' ' {
+			fields[key] = ""
+			continue
+		}
+		// skip '='
+		i++
+		if i < len(line) && line[i] == '"' {
+			value, n, err := unquote(line[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w: key '%s': %s", ErrInvalidLine, key, err)
+			}
+			fields[key] = value
+			i += n
+			continue
+		}
+		start = i
+		for i < len(line) && line[i] != ' ' {
+			i++
+		}
+		fields[key] = line[start:i]
+	}
+	return fields, nil
+}
+
+// unquote - Unquotes the double quoted string at the start of s and returns
+// the number of bytes it took.
+func unquote(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", 0, fmt.Errorf("unterminated quoted value")
+			}
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted value")
+}
+
+func parseJSON(line string) (map[string]string, error) {
+	d := json.NewDecoder(strings.NewReader(line))
+	d.UseNumber()
+	var data map[string]interface{}
+	err := d.Decode(&data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLine, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%w: not a JSON object", ErrInvalidLine)
+	}
+	fields := map[string]string{}
+	err = flatten(fields, "", data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLine, err)
+	}
+	return fields, nil
+}
+
+func flatten(fields map[string]string, prefix string, data map[string]interface{}) error {
+	for k, v := range data {
+		key := prefix + k
+		switch v := v.(type) {
+		case map[string]interface{}:
+			err := flatten(fields, key+".", v)
+			if err != nil {
+				return err
+			}
+		case string:
+			fields[key] = v
+		case nil:
+			fields[key] = ""
+		case json.Number:
+			fields[key] = v.String()
+		case bool:
+			fields[key] = fmt.Sprintf("%t", v)
+		default:
+			var b bytes.Buffer
+			e := json.NewEncoder(&b)
+			e.SetEscapeHTML(false)
+			err := e.Encode(v)
+			if err != nil {
+				return err
+			}
+			fields[key] = strings.TrimSuffix(b.String(), "\n")
+		}
+	}
+	return nil
+}
+
+var combinedRe = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\S+)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+// parseCombined - The request is also split into method, path and protocol.
+// Missing values, logged as "-", are kept as is.
+func parseCombined(line string) (map[string]string, error) {
+	idx := combinedRe.FindStringSubmatchIndex(line)
+	if idx == nil {
+		return nil, fmt.Errorf("%w: not a combined log line", ErrInvalidLine)
+	}
+	m := make([]string, len(idx)/2)
+	for i := range m {
+		if idx[2*i] >= 0 {
+			m[i] = line[idx[2*i]:idx[2*i+1]]
+		}
+	}
+	fields := map[string]string{
+		"remote_host": m[1],
+		"ident":       m[2],
+		"user":        m[3],
+		"time":        m[4],
+		"request":     m[5],
+		"status":      m[6],
+		"bytes":       m[7],
+	}
+	if idx[2*8] >= 0 {
+		fields["referer"] = m[8]
+		fields["user_agent"] = m[9]
+	}
+	if parts := strings.Split(m[5], " "); len(parts) == 3 {
+		fields["method"] = parts[0]
+		fields["path"] = parts[1]
+		fields["protocol"] = parts[2]
+	}
+	return fields, nil
+}
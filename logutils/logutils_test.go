@@ -0,0 +1,93 @@
+package logutils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		line     string
+		expected map[string]string
+		err      error
+	}{
+		{"logfmt", Logfmt, `level=info msg="hello \"world\"" n=3 debug`, map[string]string{"level": "info", "msg": `hello "world"`, "n": "3", "debug": ""}, nil},
+		{"logfmt empty", Logfmt, `a= b=""`, map[string]string{"a": "", "b": ""}, nil},
+		{"logfmt unterminated", Logfmt, `msg="hello`, nil, ErrInvalidLine},
+		{"logfmt missing key", Logfmt, `=x`, nil, ErrInvalidLine},
+		{"json", JSONLines, `{"level":"warn","n":1.50,"ok":true,"err":null,"req":{"id":7,"tags":["a","<b>"]}}`,
+			map[string]string{"level": "warn", "n": "1.50", "ok": "true", "err": "", "req.id": "7", "req.tags": `["a","<b>"]`}, nil},
+		{"json array", JSONLines, `[1]`, nil, ErrInvalidLine},
+		{"json null", JSONLines, `null`, nil, ErrInvalidLine},
+		{"combined", CombinedLog, `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "" "curl/7.0"`,
+			map[string]string{
+				"remote_host": "127.0.0.1", "ident": "-", "user": "frank", "time": "10/Oct/2000:13:55:36 -0700",
+				"request": "GET /a.gif HTTP/1.0", "method": "GET", "path": "/a.gif", "protocol": "HTTP/1.0",
+				"status": "200", "bytes": "2326", "referer": "", "user_agent": "curl/7.0",
+			}, nil},
+		{"common", CombinedLog, `::1 - - [10/Oct/2000:13:55:36 -0700] "-" 408 -`,
+			map[string]string{
+				"remote_host": "::1", "ident": "-", "user": "-", "time": "10/Oct/2000:13:55:36 -0700",
+				"request": "-", "status": "408", "bytes": "-",
+			}, nil},
+		{"combined invalid", CombinedLog, `level=info`, nil, ErrInvalidLine},
+		{"unknown", Format(9), `x`, nil, ErrUnknownFormat},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseLine(test.format, test.line)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Expected error %v, got: %v\n", test.err, err)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected:\n%q\nGot:\n%q\n", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("JSON")
+	if err != nil || f != JSONLines {
+		t.Errorf("Expected:\n%v\nGot:\n%v, %v\n", JSONLines, f, err)
+	}
+	_, err = ParseFormat("xml")
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got: %v\n", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	in := make(chan fileutils.StringError)
+	go func() {
+		in <- fileutils.StringError{String: `a=1`}
+		in <- fileutils.StringError{String: `a="`}
+		in <- fileutils.StringError{Error: errors.New("read error")}
+		in <- fileutils.StringError{String: `a=3`}
+		close(in)
+	}()
+	got := []Record{}
+	for r := range Parse(in, Logfmt) {
+		got = append(got, r)
+	}
+	if len(got) != 4 {
+		t.Fatalf("Expected 4 records, got: %v\n", got)
+	}
+	if got[0].Line != 1 || got[0].Fields["a"] != "1" || got[0].Error != nil {
+		t.Errorf("Unexpected record: %v\n", got[0])
+	}
+	if got[1].Line != 2 || got[1].Text != `a="` || !errors.Is(got[1].Error, ErrInvalidLine) {
+		t.Errorf("Unexpected record: %v\n", got[1])
+	}
+	if got[2].Error == nil || got[2].Line != 0 {
+		t.Errorf("Unexpected record: %v\n", got[2])
+	}
+	if got[3].Line != 3 || got[3].Fields["a"] != "3" {
+		t.Errorf("Unexpected record: %v\n", got[3])
+	}
+}
@@ -0,0 +1,128 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package processutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// clockTicks - USER_HZ, the unit of the /proc/<pid>/stat times.
+// It is 100 on all mainstream Linux architectures.
+const clockTicks = 100
+
+// commLen - Length the kernel truncates the names in /proc/<pid>/comm and
+// /proc/<pid>/stat to, TASK_COMM_LEN without the NUL.
+const commLen = 15
+
+func list() ([]Process, error) {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	procs := []Process{}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		p, err := get(pid)
+		if err != nil {
+			// The process exited while listing.
+			continue
+		}
+		procs = append(procs, *p)
+	}
+	return procs, nil
+}
+
+func get(pid int) (*Process, error) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	data, err := ioutil.ReadFile(dir + "/stat")
+	if err != nil {
+		return nil, err
+	}
+	// Format: pid (comm) state ppid ... The comm can contain spaces and parens.
+	stat := string(data)
+	open := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("invalid stat format")
+	}
+	p := &Process{PID: pid, Name: stat[open+1 : end]}
+	fields := strings.Fields(stat[end+1:])
+	// starttime is field 22, fields starts at field 3.
+	if len(fields) > 19 {
+		ticks, err := strconv.ParseInt(fields[19], 10, 64)
+		if err == nil {
+			if boot, err := bootTime(); err == nil {
+				p.StartTime = boot.Add(time.Duration(ticks) * time.Second / clockTicks)
+			}
+		}
+	}
+	fInfo, err := os.Stat(dir)
+	if err == nil {
+		if st, ok := fInfo.Sys().(*syscall.Stat_t); ok {
+			uid := strconv.Itoa(int(st.Uid))
+			p.User = uid
+			if u, err := user.LookupId(uid); err == nil {
+				p.User = u.Username
+			}
+		}
+	}
+	return p, nil
+}
+
+func bootTime() (time.Time, error) {
+	data, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "btime ") {
+			sec, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "btime ")), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("btime not found in /proc/stat")
+}
+
+// longName - Whether name is longer than what /proc keeps and p, with its
+// name truncated, runs an executable with that name, checked with the
+// base name of /proc/<pid>/exe, only readable for the user's own
+// processes, or of argv[0].
+func longName(p Process, name string) bool {
+	if len(name) <= commLen || p.Name != name[:commLen] {
+		return false
+	}
+	dir := "/proc/" + strconv.Itoa(p.PID)
+	exe, err := os.Readlink(dir + "/exe")
+	if err == nil && filepath.Base(strings.TrimSuffix(exe, " (deleted)")) == name {
+		return true
+	}
+	data, err := ioutil.ReadFile(dir + "/cmdline")
+	if err != nil {
+		return false
+	}
+	argv0 := string(data)
+	if i := strings.IndexByte(argv0, 0); i >= 0 {
+		argv0 = argv0[:i]
+	}
+	return filepath.Base(argv0) == name
+}
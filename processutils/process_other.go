@@ -0,0 +1,32 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !linux && !windows && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!windows,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package processutils
+
+import (
+	"fmt"
+)
+
+// ErrNotSupported - Process listing is not supported on this platform.
+var ErrNotSupported = fmt.Errorf("not supported on this platform")
+
+func list() ([]Process, error) {
+	return nil, ErrNotSupported
+}
+
+func get(pid int) (*Process, error) {
+	return nil, ErrNotSupported
+}
+
+// longName - The names are not truncated.
+func longName(p Process, name string) bool {
+	return false
+}
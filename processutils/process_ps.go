@@ -0,0 +1,78 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package processutils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DavidGamba/go-utils/run"
+)
+
+func list() ([]Process, error) {
+	return ps("-ax")
+}
+
+func get(pid int) (*Process, error) {
+	procs, err := ps("-p", strconv.Itoa(pid))
+	if err != nil {
+		return nil, err
+	}
+	if len(procs) == 0 {
+		return nil, fmt.Errorf("no such process")
+	}
+	return &procs[0], nil
+}
+
+func ps(args ...string) ([]Process, error) {
+	cmd := append([]string{"ps"}, args...)
+	cmd = append(cmd, "-o", "pid=,user=,lstart=,comm=")
+	out, err := run.CMD(cmd...).Env("LC_ALL=C").STDOUTOutput()
+	if err != nil {
+		// ps exits with 1 when -p doesn't match any process.
+		if len(out) == 0 {
+			return []Process{}, nil
+		}
+		return nil, err
+	}
+	procs := []Process{}
+	for _, line := range strings.Split(string(out), "\n") {
+		// Format: pid user Mon Jan  2 15:04:05 2006 comm
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		p := Process{
+			PID:  pid,
+			User: fields[1],
+			Name: filepath.Base(strings.Join(fields[7:], " ")),
+		}
+		start, err := time.ParseInLocation("Mon Jan 2 15:04:05 2006", strings.Join(fields[2:7], " "), time.Local)
+		if err == nil {
+			p.StartTime = start
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+// longName - The names are not truncated.
+func longName(p Process, name string) bool {
+	return false
+}
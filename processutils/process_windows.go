@@ -0,0 +1,68 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package processutils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+
+	"github.com/DavidGamba/go-utils/run"
+)
+
+func list() ([]Process, error) {
+	return tasklist()
+}
+
+func get(pid int) (*Process, error) {
+	procs, err := tasklist("/fi", "PID eq "+strconv.Itoa(pid))
+	if err != nil {
+		return nil, err
+	}
+	if len(procs) == 0 {
+		return nil, fmt.Errorf("no such process")
+	}
+	return &procs[0], nil
+}
+
+// tasklist - Parses the verbose CSV output: "Image Name","PID",...,"User Name",...
+func tasklist(args ...string) ([]Process, error) {
+	cmd := append([]string{"tasklist", "/v", "/fo", "csv", "/nh"}, args...)
+	out, err := run.CMD(cmd...).STDOUTOutput()
+	if err != nil {
+		return nil, err
+	}
+	records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+	if err != nil {
+		// No matches prints an informational line instead of CSV.
+		return []Process{}, nil
+	}
+	procs := []Process{}
+	for _, r := range records {
+		if len(r) < 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(r[1])
+		if err != nil {
+			continue
+		}
+		p := Process{PID: pid, Name: r[0]}
+		if len(r) > 6 {
+			p.User = r[6]
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+// longName - The names are not truncated.
+func longName(p Process, name string) bool {
+	return false
+}
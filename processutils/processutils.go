@@ -0,0 +1,77 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package processutils - Process liveness and lookup helpers.
+
+On Linux the information is read from /proc, on other Unix systems it is
+parsed from ps output and on Windows from tasklist output.
+*/
+package processutils
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErrNotFound - There is no process with the given PID.
+var ErrNotFound = fmt.Errorf("process not found")
+
+// Process - Process information.
+// User and StartTime are left empty when the platform doesn't provide them.
+type Process struct {
+	PID       int
+	Name      string
+	User      string
+	StartTime time.Time
+}
+
+// Uptime - Time since the process started, 0 if the start time is unknown.
+func (p *Process) Uptime() time.Duration {
+	if p.StartTime.IsZero() {
+		return 0
+	}
+	return time.Since(p.StartTime)
+}
+
+// IsAlive - Reports whether a process with the given PID is running.
+func IsAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return isAlive(pid)
+}
+
+// Get - Returns the information of the process with the given PID.
+func Get(pid int) (*Process, error) {
+	p, err := get(pid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %d: %s", ErrNotFound, pid, err)
+	}
+	return p, nil
+}
+
+// List - Returns all running processes.
+func List() ([]Process, error) {
+	return list()
+}
+
+// FindByName - Returns the running processes with the given executable name.
+func FindByName(name string) ([]Process, error) {
+	all, err := list()
+	if err != nil {
+		return nil, err
+	}
+	found := []Process{}
+	for _, p := range all {
+		if p.Name == name || longName(p, name) {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
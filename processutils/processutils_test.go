@@ -0,0 +1,123 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package processutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestIsAlive(t *testing.T) {
+	if !IsAlive(os.Getpid()) {
+		t.Errorf("Current process should be alive\n")
+	}
+	if IsAlive(0) || IsAlive(-1) {
+		t.Errorf("Invalid PIDs should not be alive\n")
+	}
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("Can't run helper process: %s\n", err)
+	}
+	if IsAlive(cmd.Process.Pid) {
+		t.Errorf("Finished process should not be alive\n")
+	}
+}
+
+func TestGet(t *testing.T) {
+	p, err := Get(os.Getpid())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if p.PID != os.Getpid() || p.Name == "" {
+		t.Errorf("Unexpected process: %#v\n", p)
+	}
+	if p.Uptime() < 0 || p.Uptime() > 24*time.Hour {
+		t.Errorf("Unexpected uptime: %s\n", p.Uptime())
+	}
+	found, err := FindByName(p.Name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	ok := false
+	for _, f := range found {
+		if f.PID == p.PID {
+			ok = true
+		}
+	}
+	if !ok {
+		t.Errorf("Current process not found by name '%s'\n", p.Name)
+	}
+	_, err = Get(1 << 30)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
+
+func TestFindByNameLong(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the names are only truncated on linux")
+	}
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skipf("Can't find helper binary: %s\n", err)
+	}
+	dir, err := ioutil.TempDir("", "processutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	data, err := ioutil.ReadFile(sleep)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	name := "sleep-with-a-long-name"
+	err = ioutil.WriteFile(filepath.Join(dir, name), data, 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	cmd := exec.Command(filepath.Join(dir, name), "10")
+	err = cmd.Start()
+	if err != nil {
+		t.Skipf("Can't run helper process: %s\n", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	tests := []struct {
+		name  string
+		found bool
+	}{
+		{name, true},
+		{name[:15], true},
+		{name[:15] + "-other", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found, err := FindByName(test.name)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			ok := false
+			for _, f := range found {
+				if f.PID == cmd.Process.Pid {
+					ok = true
+				}
+			}
+			if ok != test.found {
+				t.Errorf("Expected:\n%v\nGot:\n%v\n", test.found, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package progress - Machine readable progress events written as NDJSON, one
+JSON object per line, so wrappers and GUIs can drive progress bars without
+parsing the human oriented output of the CLIs.
+
+	{"time":"2020-05-01T10:00:00.1Z","event":"start","path":"a.iso","total":4194304}
+	{"time":"2020-05-01T10:00:00.3Z","event":"progress","path":"a.iso","bytes":1048576,"total":4194304}
+	{"time":"2020-05-01T10:00:00.9Z","event":"done","path":"a.iso","bytes":4194304}
+	{"time":"2020-05-01T10:00:01.0Z","event":"error","path":"b.iso","error":"open b.iso: permission denied"}
+	{"time":"2020-05-01T10:00:01.0Z","event":"end","bytes":4194304,"files":1,"errors":1}
+
+Fields that don't apply to an event, or are unknown, like the total size of
+input read from STDIN, are omitted.
+All the Reporter methods are no-ops on a nil Reporter, so callers don't need
+to check whether events were requested.
+*/
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	// EventStart - Processing of path started, total is its size when known.
+	EventStart = "start"
+	// EventProgress - bytes of path have been processed so far.
+	EventProgress = "progress"
+	// EventDone - Processing of path finished after bytes.
+	EventDone = "done"
+	// EventError - Processing of path failed, path is empty for errors not tied to a file.
+	EventError = "error"
+	// EventEnd - Last event, with the number of files done, bytes and errors.
+	EventEnd = "end"
+)
+
+// Event - A line of the NDJSON stream.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Path   string    `json:"path,omitempty"`
+	Bytes  int64     `json:"bytes,omitempty"`
+	Total  int64     `json:"total,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	Files  int64     `json:"files,omitempty"`
+	Errors int64     `json:"errors,omitempty"`
+}
+
+// Reporter - Writes events to a writer, safe for concurrent use.
+type Reporter struct {
+	// Interval - Minimum time between the progress events of a Reader, 200ms by default.
+	Interval time.Duration
+
+	mu     sync.Mutex
+	enc    *json.Encoder
+	files  int64
+	bytes  int64
+	errors int64
+}
+
+// New - Returns a Reporter writing to w, usually os.Stderr.
+func New(w io.Writer) *Reporter {
+	return &Reporter{Interval: 200 * time.Millisecond, enc: json.NewEncoder(w)}
+}
+
+// Emit - Writes e, setting its time to now when zero.
+// Write errors are ignored, progress reporting never stops the work.
+func (r *Reporter) Emit(e Event) {
+	if r == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(e)
+}
+
+// Start - Reports that path started, total is its size or 0 when unknown.
+func (r *Reporter) Start(path string, total int64) {
+	r.Emit(Event{Event: EventStart, Path: path, Total: total})
+}
+
+// Progress - Reports the bytes of path processed so far.
+func (r *Reporter) Progress(path string, bytes, total int64) {
+	r.Emit(Event{Event: EventProgress, Path: path, Bytes: bytes, Total: total})
+}
+
+// Done - Reports that path finished after processing bytes.
+func (r *Reporter) Done(path string, bytes int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.files++
+	r.bytes += bytes
+	r.mu.Unlock()
+	r.Emit(Event{Event: EventDone, Path: path, Bytes: bytes})
+}
+
+// Error - Reports that path failed with err.
+func (r *Reporter) Error(path string, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.errors++
+	r.mu.Unlock()
+	r.Emit(Event{Event: EventError, Path: path, Error: err.Error()})
+}
+
+// End - Reports the totals of the Done and Error calls.
+func (r *Reporter) End() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	e := Event{Event: EventEnd, Files: r.files, Bytes: r.bytes, Errors: r.errors}
+	r.mu.Unlock()
+	r.Emit(e)
+}
+
+// Reader - Wraps rd, the contents of path, to report progress events at most
+// once per Interval while it is read.
+// Start and Done are left to the caller.
+func (r *Reporter) Reader(path string, total int64, rd io.Reader) io.Reader {
+	if r == nil {
+		return rd
+	}
+	return &reader{r: r, rd: rd, path: path, total: total, last: time.Now()}
+}
+
+type reader struct {
+	r     *Reporter
+	rd    io.Reader
+	path  string
+	total int64
+	n     int64
+	last  time.Time
+}
+
+func (pr *reader) Read(p []byte) (int, error) {
+	n, err := pr.rd.Read(p)
+	pr.n += int64(n)
+	if n > 0 && time.Since(pr.last) >= pr.r.Interval {
+		pr.last = time.Now()
+		pr.r.Progress(pr.path, pr.n, pr.total)
+	}
+	return n, err
+}
@@ -0,0 +1,101 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func events(t *testing.T, buf *bytes.Buffer) []Event {
+	t.Helper()
+	list := []Event{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var e Event
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s: %s\n", err, scanner.Text())
+		}
+		if e.Time.IsZero() {
+			t.Errorf("Missing time: %s\n", scanner.Text())
+		}
+		e.Time = time.Time{}
+		list = append(list, e)
+	}
+	return list
+}
+
+func TestReporter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	r := New(buf)
+	r.Start("a", 10)
+	r.Progress("a", 5, 10)
+	r.Done("a", 10)
+	r.Start("b", 0)
+	r.Error("b", fmt.Errorf("failed"))
+	r.Done("c", 2)
+	r.End()
+	expected := []Event{
+		{Event: EventStart, Path: "a", Total: 10},
+		{Event: EventProgress, Path: "a", Bytes: 5, Total: 10},
+		{Event: EventDone, Path: "a", Bytes: 10},
+		{Event: EventStart, Path: "b"},
+		{Event: EventError, Path: "b", Error: "failed"},
+		{Event: EventDone, Path: "c", Bytes: 2},
+		{Event: EventEnd, Files: 2, Bytes: 12, Errors: 1},
+	}
+	got := events(t, buf)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, got)
+	}
+
+	// A nil Reporter does nothing.
+	var nilReporter *Reporter
+	nilReporter.Start("a", 1)
+	nilReporter.Error("a", fmt.Errorf("failed"))
+	nilReporter.End()
+	rd := strings.NewReader("x")
+	if nilReporter.Reader("a", 1, rd) != rd {
+		t.Errorf("Expected the reader as is\n")
+	}
+}
+
+func TestReporterReader(t *testing.T) {
+	buf := new(bytes.Buffer)
+	r := New(buf)
+	r.Interval = 0
+	data := strings.Repeat("x", 10)
+	n, err := io.Copy(ioutil.Discard, r.Reader("a", 10, iotest.OneByteReader(strings.NewReader(data))))
+	if err != nil || n != 10 {
+		t.Fatalf("Unexpected result: %d, %v\n", n, err)
+	}
+	got := events(t, buf)
+	if len(got) == 0 {
+		t.Fatalf("Expected progress events\n")
+	}
+	last := got[len(got)-1]
+	if last.Event != EventProgress || last.Bytes != 10 || last.Total != 10 {
+		t.Errorf("Unexpected last event: %v\n", last)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Bytes <= got[i-1].Bytes {
+			t.Errorf("Progress not increasing: %v\n", got)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package retryutils - Retry operations with exponential backoff.
+
+Meant for IO on flaky storage like NFS or SMB mounts where operations fail
+with transient errors.
+*/
+package retryutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/DavidGamba/go-utils/errclass"
+)
+
+// Logger - Custom lib logger
+var Logger = log.New(ioutil.Discard, "retryutils ", log.LstdFlags)
+
+// Policy - Retry policy.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+
+	// InitialDelay is the wait before the first retry.
+	InitialDelay time.Duration
+
+	// MaxDelay caps the wait between attempts, 0 means no cap.
+	MaxDelay time.Duration
+
+	// Multiplier is applied to the delay after every retry.
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to the given fraction, for example 0.2 means ±20%.
+	Jitter float64
+
+	// Retryable decides if an error is worth retrying, defaults to IsRetryable.
+	Retryable func(error) bool
+}
+
+// DefaultPolicy - 5 attempts starting at 100ms, doubling up to 5s with 20% jitter.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:  5,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     5 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent - Wraps err so Retry stops retrying and returns err right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// Retry - Calls fn until it succeeds, it returns a non retryable error, the
+// policy attempts are exhausted or ctx is done.
+func Retry(ctx context.Context, policy Policy, fn func() error) error {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Multiplier <= 0 {
+		policy.Multiplier = 1
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+	delay := policy.InitialDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if !policy.Retryable(err) {
+			return err
+		}
+		if attempt >= policy.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		wait := jitter(delay, policy.Jitter)
+		Logger.Printf("Retry: attempt %d failed, retrying in %s: %s", attempt, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %s", ctx.Err(), err)
+		case <-timer.C:
+		}
+		delay = time.Duration(float64(delay) * policy.Multiplier)
+		if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+	delta := (rand.Float64()*2 - 1) * fraction * float64(d)
+	return d + time.Duration(delta)
+}
+
+// IsRetryable - Reports whether err is a transient error worth retrying, the
+// errclass Transient and StaleHandle classes.
+//
+// Retryable errors are: EINTR, EAGAIN, ESTALE, ETIMEDOUT, ECONNRESET,
+// ECONNABORTED, network errors that report a timeout and, on Windows,
+// sharing and lock violations.
+func IsRetryable(err error) bool {
+	return errclass.Classify(err).Retryable()
+}
@@ -0,0 +1,79 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package retryutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, InitialDelay: time.Millisecond, Multiplier: 2, Jitter: 0.5}
+	stale := &os.PathError{Op: "open", Path: "x", Err: syscall.ESTALE}
+	errNotRetryable := fmt.Errorf("not retryable")
+
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{"success", []error{nil}, 1, nil},
+		{"transient", []error{stale, syscall.EINTR, nil}, 3, nil},
+		{"exhausted", []error{stale, stale, stale, nil}, 3, syscall.ESTALE},
+		{"not retryable", []error{errNotRetryable, nil}, 1, errNotRetryable},
+		{"permanent", []error{Permanent(stale), nil}, 1, syscall.ESTALE},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := Retry(context.Background(), policy, func() error {
+				err := test.errs[attempts]
+				attempts++
+				return err
+			})
+			if !errors.Is(err, test.err) {
+				t.Errorf("Unexpected error: %v\n", err)
+			}
+			if attempts != test.attempts {
+				t.Errorf("Expected %d attempts, got %d\n", test.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts := 0
+	err := Retry(ctx, Policy{MaxAttempts: 10, InitialDelay: time.Hour}, func() error {
+		attempts++
+		return syscall.EINTR
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Unexpected attempts: %d\n", attempts)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if !IsRetryable(fmt.Errorf("wrapped: %w", syscall.ETIMEDOUT)) {
+		t.Errorf("ETIMEDOUT should be retryable\n")
+	}
+	if IsRetryable(os.ErrNotExist) || IsRetryable(syscall.ENOENT) || IsRetryable(nil) {
+		t.Errorf("Unexpected retryable error\n")
+	}
+}
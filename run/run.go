@@ -0,0 +1,183 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package run - Wrapper around os/exec to run commands with logging and a dry-run mode.
+
+	err := run.CMD("ls", "-l").Dir("/tmp").Log().DryRun(dryRun).Run()
+*/
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Logger - Default logger used to print commands when Log or DryRun are set.
+var Logger = log.New(os.Stderr, "", 0)
+
+// RunInfo - Command information.
+type RunInfo struct {
+	cmd    []string
+	env    []string
+	dir    string
+	log    bool
+	dryRun bool
+	logger *log.Logger
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// CMD - Returns a RunInfo for the given command and its arguments.
+func CMD(cmd ...string) *RunInfo {
+	return &RunInfo{
+		cmd:    cmd,
+		logger: Logger,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+}
+
+// Dir - Sets the working directory of the command.
+func (r *RunInfo) Dir(dir string) *RunInfo {
+	r.dir = dir
+	return r
+}
+
+// Env - Adds environment variables, in the form "key=value", to the ones inherited from the current process.
+func (r *RunInfo) Env(env ...string) *RunInfo {
+	r.env = append(r.env, env...)
+	return r
+}
+
+// Stdin - Sets the reader used as the command's STDIN.
+func (r *RunInfo) Stdin(in io.Reader) *RunInfo {
+	r.stdin = in
+	return r
+}
+
+// Stdout - Sets the writer used for the command's STDOUT when calling Run.
+func (r *RunInfo) Stdout(w io.Writer) *RunInfo {
+	r.stdout = w
+	return r
+}
+
+// Stderr - Sets the writer used for the command's STDERR.
+func (r *RunInfo) Stderr(w io.Writer) *RunInfo {
+	r.stderr = w
+	return r
+}
+
+// Log - Print the command before running it.
+func (r *RunInfo) Log() *RunInfo {
+	r.log = true
+	return r
+}
+
+// DryRun - When set, the command is printed instead of executed.
+func (r *RunInfo) DryRun(b bool) *RunInfo {
+	r.dryRun = b
+	return r
+}
+
+// SetLogger - Sets the logger used to print the command.
+func (r *RunInfo) SetLogger(l *log.Logger) *RunInfo {
+	r.logger = l
+	return r
+}
+
+// String - Returns the shell quoted command.
+// When a working directory is set, it is prefixed as a `cd` call.
+func (r *RunInfo) String() string {
+	s := ShellQuote(r.cmd...)
+	for i := len(r.env) - 1; i >= 0; i-- {
+		kv := strings.SplitN(r.env[i], "=", 2)
+		if len(kv) == 2 {
+			s = kv[0] + "=" + quote(kv[1]) + " " + s
+		} else {
+			s = quote(r.env[i]) + " " + s
+		}
+	}
+	if r.dir != "" {
+		s = "cd " + ShellQuote(r.dir) + " && " + s
+	}
+	return s
+}
+
+// Run - Runs the command sending its output to the configured STDOUT and STDERR.
+func (r *RunInfo) Run() error {
+	return r.run(r.stdout, r.stderr)
+}
+
+// STDOUTOutput - Runs the command and returns its STDOUT.
+// In dry-run mode the output is always empty.
+func (r *RunInfo) STDOUTOutput() ([]byte, error) {
+	var b bytes.Buffer
+	err := r.run(&b, r.stderr)
+	return b.Bytes(), err
+}
+
+// CombinedOutput - Runs the command and returns its combined STDOUT and STDERR.
+// In dry-run mode the output is always empty.
+func (r *RunInfo) CombinedOutput() ([]byte, error) {
+	var b bytes.Buffer
+	err := r.run(&b, &b)
+	return b.Bytes(), err
+}
+
+func (r *RunInfo) run(stdout, stderr io.Writer) error {
+	if len(r.cmd) == 0 {
+		return fmt.Errorf("missing command")
+	}
+	if r.dryRun {
+		r.logger.Printf("DRY RUN: %s", r)
+		return nil
+	}
+	if r.log {
+		r.logger.Printf("%s", r)
+	}
+	c := exec.Command(r.cmd[0], r.cmd[1:]...)
+	c.Dir = r.dir
+	c.Env = append(os.Environ(), r.env...)
+	c.Stdin = r.stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+	return c.Run()
+}
+
+// ShellQuote - Returns the arguments joined by spaces and quoted so they can be pasted into a POSIX shell.
+func ShellQuote(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = quote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, c := range s {
+		if !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:=@%+,", c) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
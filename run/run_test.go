@@ -0,0 +1,72 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package run
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"simple", []string{"ls", "-l"}, "ls -l"},
+		{"empty", []string{"echo", ""}, "echo ''"},
+		{"spaces", []string{"echo", "hello world"}, "echo 'hello world'"},
+		{"single quote", []string{"echo", "it's"}, `echo 'it'\''s'`},
+		{"glob", []string{"ls", "*.go"}, "ls '*.go'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := ShellQuote(test.args...)
+			if output != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s\n", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+	out, err := CMD("false").Dir("/tmp dir").Env("A=b c").SetLogger(logger).DryRun(true).STDOUTOutput()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(out) != 0 {
+		t.Errorf("Unexpected output: %s\n", out)
+	}
+	expected := "DRY RUN: cd '/tmp dir' && A='b c' false\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, buf.String())
+	}
+}
+
+func TestRun(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+	out, err := CMD("echo", "hello world").SetLogger(logger).Log().STDOUTOutput()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if string(out) != "hello world\n" {
+		t.Errorf("Unexpected output: %s\n", out)
+	}
+	if buf.String() != "echo 'hello world'\n" {
+		t.Errorf("Unexpected log: %s\n", buf.String())
+	}
+	err = CMD("false").Run()
+	if err == nil {
+		t.Errorf("Expected error\n")
+	}
+}
@@ -0,0 +1,252 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package semverutils - Semantic version parsing, comparison and versioned file selection.
+*/
+package semverutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidVersion - The string is not a semantic version.
+var ErrInvalidVersion = fmt.Errorf("invalid version")
+
+// ErrNoMatch - No versioned files matched the pattern.
+var ErrNoMatch = fmt.Errorf("no versioned files found")
+
+// Version - Semantic version.
+type Version struct {
+	Major      int64
+	Minor      int64
+	Patch      int64
+	Prerelease []string
+	Build      string
+	Original   string
+}
+
+var versionRe = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// Parse - Parses a semantic version.
+// A leading 'v' is allowed and missing minor or patch components default to 0,
+// for example: "v1.2.3", "1.2.3-rc.1+build.5", "1.2".
+func Parse(s string) (*Version, error) {
+	m := versionRe.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidVersion, s)
+	}
+	v := &Version{Original: s, Build: m[5]}
+	var err error
+	for i, dst := range []*int64{&v.Major, &v.Minor, &v.Patch} {
+		if m[i+1] == "" {
+			continue
+		}
+		if len(m[i+1]) > 1 && m[i+1][0] == '0' {
+			return nil, fmt.Errorf("%w: '%s': leading zero in '%s'", ErrInvalidVersion, s, m[i+1])
+		}
+		*dst, err = strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidVersion, s)
+		}
+	}
+	if m[4] != "" {
+		v.Prerelease = strings.Split(m[4], ".")
+		for _, id := range v.Prerelease {
+			if id == "" {
+				return nil, fmt.Errorf("%w: '%s': empty prerelease identifier", ErrInvalidVersion, s)
+			}
+			if len(id) > 1 && id[0] == '0' && isNumeric(id) {
+				return nil, fmt.Errorf("%w: '%s': leading zero in '%s'", ErrInvalidVersion, s, id)
+			}
+		}
+	}
+	return v, nil
+}
+
+// isNumeric - Whether the identifier only has digits.
+func isNumeric(id string) bool {
+	return strings.Trim(id, "0123456789") == ""
+}
+
+// String - Returns the canonical form of the version, without the 'v' prefix.
+func (v *Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare - Returns -1, 0 or 1 if v has lower, equal or higher precedence than o.
+// Build metadata is ignored as indicated by the semver spec.
+func (v *Version) Compare(o *Version) int {
+	for _, p := range [][2]int64{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if p[0] != p[1] {
+			if p[0] < p[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	// A version without prerelease has higher precedence.
+	if len(v.Prerelease) == 0 || len(o.Prerelease) == 0 {
+		switch {
+		case len(v.Prerelease) == len(o.Prerelease):
+			return 0
+		case len(v.Prerelease) == 0:
+			return 1
+		default:
+			return -1
+		}
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		if c := compareIdentifier(v.Prerelease[i], o.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.Prerelease) < len(o.Prerelease):
+		return -1
+	case len(v.Prerelease) > len(o.Prerelease):
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier - Numeric identifiers are compared numerically and have
+// lower precedence than alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if na < nb {
+			return -1
+		} else if na > nb {
+			return 1
+		}
+		return 0
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Compare - Parses and compares two version strings.
+func Compare(a, b string) (int, error) {
+	va, err := Parse(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := Parse(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+// ByVersion implements sort.Interface.
+type ByVersion []*Version
+
+func (a ByVersion) Len() int           { return len(a) }
+func (a ByVersion) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByVersion) Less(i, j int) bool { return a[i].Compare(a[j]) < 0 }
+
+// LatestVersioned - Returns the file in dir with the highest version that matches pattern.
+//
+// The pattern uses filepath.Match syntax and the first '*' marks the version,
+// for example: "tool-*-linux-amd64.tar.gz". Character classes are negated
+// with '^' or, as in the shell, '!'.
+// Files whose version part isn't a valid semantic version are ignored.
+func LatestVersioned(dir, pattern string) (string, *Version, error) {
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return "", nil, err
+	}
+	list, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	var latest string
+	var latestVersion *Version
+	for _, fInfo := range list {
+		m := re.FindStringSubmatch(fInfo.Name())
+		if m == nil {
+			continue
+		}
+		v, err := Parse(m[1])
+		if err != nil {
+			continue
+		}
+		if latestVersion == nil || v.Compare(latestVersion) > 0 {
+			latest = filepath.Join(dir, fInfo.Name())
+			latestVersion = v
+		}
+	}
+	if latestVersion == nil {
+		return "", nil, fmt.Errorf("%w: '%s' in '%s'", ErrNoMatch, pattern, dir)
+	}
+	return latest, latestVersion, nil
+}
+
+// globToRegexp - Translates the glob into a regexp capturing the first '*'.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	_, err := filepath.Match(pattern, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+	if !strings.Contains(pattern, "*") {
+		return nil, fmt.Errorf("invalid pattern '%s': missing '*' version placeholder", pattern)
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	captured := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if !captured {
+				b.WriteString("(.+?)")
+				captured = true
+			} else {
+				b.WriteString(".*")
+			}
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			class := pattern[i+1 : i+end]
+			// Shell style '[!...]' negation, RE2 only negates with '^'.
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,142 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package semverutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      error
+	}{
+		{"1.2.3", "1.2.3", nil},
+		{"v1.2.3", "1.2.3", nil},
+		{"1.2", "1.2.0", nil},
+		{"1.0.0-rc.1+build.5", "1.0.0-rc.1+build.5", nil},
+		{"1.0.0-", "", ErrInvalidVersion},
+		{"1.0.0-a..b", "", ErrInvalidVersion},
+		{"1.x", "", ErrInvalidVersion},
+		{"01.2.3", "", ErrInvalidVersion},
+		{"1.02", "", ErrInvalidVersion},
+		{"1.2.00", "", ErrInvalidVersion},
+		{"1.0.0-rc.01", "", ErrInvalidVersion},
+		{"1.0.0-0a.0", "1.0.0-0a.0", nil},
+		{"0.0.0", "0.0.0", nil},
+		{"", "", ErrInvalidVersion},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			v, err := Parse(test.input)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Unexpected error: %v\n", err)
+			}
+			if err == nil && v.String() != test.expected {
+				t.Errorf("Expected: %s, Got: %s\n", test.expected, v)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Ordered as in the semver spec precedence example.
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.2.0", "1.10.0", "2.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		c, err := Compare(ordered[i], ordered[i+1])
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if c != -1 {
+			t.Errorf("Expected %s < %s\n", ordered[i], ordered[i+1])
+		}
+		c, _ = Compare(ordered[i+1], ordered[i])
+		if c != 1 {
+			t.Errorf("Expected %s > %s\n", ordered[i+1], ordered[i])
+		}
+	}
+	c, _ := Compare("1.0.0+build.1", "v1.0.0+build.2")
+	if c != 0 {
+		t.Errorf("Build metadata should be ignored\n")
+	}
+	versions := ByVersion{}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		v, _ := Parse(ordered[i])
+		versions = append(versions, v)
+	}
+	sort.Sort(versions)
+	for i, v := range versions {
+		if v.Original != ordered[i] {
+			t.Errorf("Unexpected sort order at %d: %s\n", i, v.Original)
+		}
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{"a-*-[!0].txt", "a-1-x.txt", true},
+		{"a-*-[!0].txt", "a-1-0.txt", false},
+		{"a-*-[!0].txt", "a-1-!.txt", true},
+		{"a-*-[^0].txt", "a-1-0.txt", false},
+		{"a-*-[0-9].txt", "a-1-5.txt", true},
+		{"a-*-[!0-9].txt", "a-1-5.txt", false},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.input, func(t *testing.T) {
+			re, err := globToRegexp(test.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			if re.MatchString(test.input) != test.match {
+				t.Errorf("Expected:\n%v\nGot:\n%v\n", test.match, !test.match)
+			}
+		})
+	}
+}
+
+func TestLatestVersioned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "semverutils-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, f := range []string{
+		"tool-1.2.9-linux.tar.gz",
+		"tool-1.2.10-linux.tar.gz",
+		"tool-1.3.0-rc.1-linux.tar.gz",
+		"tool-2.0.0-darwin.tar.gz",
+		"tool-latest-linux.tar.gz",
+	} {
+		ioutil.WriteFile(filepath.Join(dir, f), []byte{}, 0644)
+	}
+	file, v, err := LatestVersioned(dir, "tool-*-linux.tar.gz")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if file != filepath.Join(dir, "tool-1.3.0-rc.1-linux.tar.gz") || v.String() != "1.3.0-rc.1" {
+		t.Errorf("Unexpected latest: %s, %s\n", file, v)
+	}
+	_, _, err = LatestVersioned(dir, "other-*.zip")
+	if !errors.Is(err, ErrNoMatch) {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
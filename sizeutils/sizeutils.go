@@ -0,0 +1,154 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package sizeutils - Human readable size and duration parsing and formatting.
+
+Used by all size and duration flags so they behave consistently.
+*/
+package sizeutils
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSize - The size string can't be parsed.
+var ErrInvalidSize = fmt.Errorf("invalid size")
+
+// ErrInvalidDuration - The duration string can't be parsed.
+var ErrInvalidDuration = fmt.Errorf("invalid duration")
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kib": 1 << 10,
+	"kb":  1e3,
+	"m":   1 << 20,
+	"mib": 1 << 20,
+	"mb":  1e6,
+	"g":   1 << 30,
+	"gib": 1 << 30,
+	"gb":  1e9,
+	"t":   1 << 40,
+	"tib": 1 << 40,
+	"tb":  1e12,
+	"p":   1 << 50,
+	"pib": 1 << 50,
+	"pb":  1e15,
+}
+
+var sizeRe = regexp.MustCompile(`^([0-9]*\.?[0-9]+)\s*([a-zA-Z]*)$`)
+
+// ParseSize - Parses a human readable size into bytes.
+//
+// Units are case insensitive.
+// K, M, G, T, P and the KiB, MiB, GiB, TiB, PiB forms are powers of 1024.
+// KB, MB, GB, TB, PB are powers of 1000.
+// A number without unit or with the B unit is in bytes.
+// For example: "10MiB", "1.5G", "512", "100KB".
+func ParseSize(s string) (int64, error) {
+	m := sizeRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("%w: '%s'", ErrInvalidSize, s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, fmt.Errorf("%w: '%s': unknown unit '%s'", ErrInvalidSize, s, m[2])
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: '%s'", ErrInvalidSize, s)
+	}
+	size := n * unit
+	// float64(math.MaxInt64) rounds up to 2^63, which doesn't fit.
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w: '%s': overflow", ErrInvalidSize, s)
+	}
+	return int64(size), nil
+}
+
+// FormatSize - Formats bytes into a human readable size using powers of 1024.
+// The output can be parsed back with ParseSize, for example: "512B", "1.5KiB", "10MiB".
+func FormatSize(bytes int64) string {
+	sign := ""
+	size := float64(bytes)
+	if bytes < 0 {
+		sign = "-"
+		// -math.MinInt64 overflows, its float64 doesn't.
+		size = -size
+	}
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	i := 0
+	for size >= 1024 && i < len(units)-1 {
+		size /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", bytes, units[i])
+	}
+	s := strconv.FormatFloat(size, 'f', 1, 64)
+	s = strings.TrimSuffix(s, ".0")
+	return sign + s + units[i]
+}
+
+var durationRe = regexp.MustCompile(`^([0-9]*\.?[0-9]+)([a-zµμ]+)`)
+
+// ParseDuration - Parses a duration string.
+// It accepts the time.ParseDuration units plus d (24h) and w (7d),
+// for example: "1d2h", "2w", "1.5d", "90m".
+func ParseDuration(s string) (time.Duration, error) {
+	str := strings.TrimSpace(s)
+	sign := time.Duration(1)
+	if strings.HasPrefix(str, "-") {
+		sign = -1
+		str = str[1:]
+	} else if strings.HasPrefix(str, "+") {
+		str = str[1:]
+	}
+	if str == "0" {
+		return 0, nil
+	}
+	if str == "" {
+		return 0, fmt.Errorf("%w: '%s'", ErrInvalidDuration, s)
+	}
+	var total time.Duration
+	for str != "" {
+		m := durationRe.FindStringSubmatch(str)
+		if m == nil {
+			return 0, fmt.Errorf("%w: '%s'", ErrInvalidDuration, s)
+		}
+		str = str[len(m[0]):]
+		var d time.Duration
+		switch m[2] {
+		case "d", "w":
+			n, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				return 0, fmt.Errorf("%w: '%s'", ErrInvalidDuration, s)
+			}
+			unit := 24 * time.Hour
+			if m[2] == "w" {
+				unit *= 7
+			}
+			d = time.Duration(n * float64(unit))
+		default:
+			var err error
+			d, err = time.ParseDuration(m[0])
+			if err != nil {
+				return 0, fmt.Errorf("%w: '%s'", ErrInvalidDuration, s)
+			}
+		}
+		total += d
+	}
+	return sign * total, nil
+}
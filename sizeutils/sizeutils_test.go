@@ -0,0 +1,111 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package sizeutils
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		err      error
+	}{
+		{"512", 512, nil},
+		{"512B", 512, nil},
+		{"10MiB", 10 << 20, nil},
+		{"10M", 10 << 20, nil},
+		{"10mb", 10e6, nil},
+		{"1.5K", 1536, nil},
+		{" 2 GiB ", 2 << 30, nil},
+		{"1TB", 1e12, nil},
+		{"", 0, ErrInvalidSize},
+		{"abc", 0, ErrInvalidSize},
+		{"10XB", 0, ErrInvalidSize},
+		{"-1K", 0, ErrInvalidSize},
+		{"8191P", 8191 << 50, nil},
+		{"8192P", 0, ErrInvalidSize},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			output, err := ParseSize(test.input)
+			if !errors.Is(err, test.err) {
+				t.Errorf("Unexpected error: %v\n", err)
+			}
+			if output != test.expected {
+				t.Errorf("Expected: %d, Got: %d\n", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		input    int64
+		expected string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{1024, "1KiB"},
+		{1536, "1.5KiB"},
+		{10 << 20, "10MiB"},
+		{-2048, "-2KiB"},
+		{-512, "-512B"},
+		{math.MinInt64, "-8EiB"},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			output := FormatSize(test.input)
+			if output != test.expected {
+				t.Errorf("Expected: %s, Got: %s\n", test.expected, output)
+			}
+			if test.input >= 0 {
+				n, err := ParseSize(output)
+				if err != nil || n != test.input {
+					t.Errorf("Round trip failed: %d, %v\n", n, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		err      error
+	}{
+		{"0", 0, nil},
+		{"90m", 90 * time.Minute, nil},
+		{"1d2h", 26 * time.Hour, nil},
+		{"2w", 14 * 24 * time.Hour, nil},
+		{"1.5d", 36 * time.Hour, nil},
+		{"-1d", -24 * time.Hour, nil},
+		{"1h30m10s", time.Hour + 30*time.Minute + 10*time.Second, nil},
+		{"", 0, ErrInvalidDuration},
+		{"1x", 0, ErrInvalidDuration},
+		{"d", 0, ErrInvalidDuration},
+		{"10", 0, ErrInvalidDuration},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			output, err := ParseDuration(test.input)
+			if !errors.Is(err, test.err) {
+				t.Errorf("Unexpected error: %v\n", err)
+			}
+			if output != test.expected {
+				t.Errorf("Expected: %s, Got: %s\n", test.expected, output)
+			}
+		})
+	}
+}
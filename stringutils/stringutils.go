@@ -0,0 +1,128 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package stringutils - String comparison and sorting utilities.
+*/
+package stringutils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NaturalLess - Reports whether a sorts before b.
+// Integers sort before any other string and are compared numerically, the
+// rest are compared lexicographically, so the order doesn't depend on the
+// order of the input: "2", "10", "1a".
+// This is the comparison used by the fileutils numerically sorted listings.
+func NaturalLess(a, b string) bool {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return na < nb
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	}
+	return a < b
+}
+
+// NaturalSlice implements sort.Interface using NaturalLess.
+//
+//	sort.Sort(stringutils.NaturalSlice(list))
+type NaturalSlice []string
+
+func (s NaturalSlice) Len() int           { return len(s) }
+func (s NaturalSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s NaturalSlice) Less(i, j int) bool { return NaturalLess(s[i], s[j]) }
+
+// VersionCompare - Compares a and b in natural order, like 'sort -V', and
+// returns -1, 0 or +1.
+// The strings are split into runs of digits and non digits, runs of digits
+// are compared numerically and the rest by bytes, so "img2.png" sorts before
+// "img10.png" and "file-1.2.9.log" before "file-1.2.10.log".
+// Numbers with the same value sort by their leading zeros, fewer first.
+func VersionCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			ai, bj := digitsEnd(a, i), digitsEnd(b, j)
+			if c := compareDigits(a[i:ai], b[j:bj]); c != 0 {
+				return c
+			}
+			i, j = ai, bj
+			continue
+		}
+		if a[i] != b[j] {
+			if a[i] < b[j] {
+				return -1
+			}
+			return 1
+		}
+		i++
+		j++
+	}
+	switch {
+	case len(a)-i < len(b)-j:
+		return -1
+	case len(a)-i > len(b)-j:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// VersionLess - Reports whether a sorts before b, see VersionCompare.
+func VersionLess(a, b string) bool {
+	return VersionCompare(a, b) < 0
+}
+
+// VersionSlice implements sort.Interface using VersionLess.
+//
+//	sort.Sort(stringutils.VersionSlice(list))
+type VersionSlice []string
+
+func (s VersionSlice) Len() int           { return len(s) }
+func (s VersionSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s VersionSlice) Less(i, j int) bool { return VersionLess(s[i], s[j]) }
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// digitsEnd - Returns the index after the run of digits starting at i.
+func digitsEnd(s string, i int) int {
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// compareDigits - Compares two runs of digits by value without parsing
+// them, so they can't overflow.
+func compareDigits(a, b string) int {
+	ta, tb := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	switch {
+	case len(ta) < len(tb):
+		return -1
+	case len(ta) > len(tb):
+		return 1
+	}
+	if c := strings.Compare(ta, tb); c != 0 {
+		return c
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
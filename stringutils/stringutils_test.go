@@ -0,0 +1,99 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package stringutils
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"2", "10", true},
+		{"10", "2", false},
+		{"2", "2", false},
+		{"a", "b", true},
+		{"10", "a", true},
+		{"a", "10", false},
+		{"a10", "a2", true},
+		{"10", "1a", true},
+		{"1a", "2", false},
+	}
+	for _, test := range tests {
+		if NaturalLess(test.a, test.b) != test.expected {
+			t.Errorf("NaturalLess(%s, %s) != %v\n", test.a, test.b, test.expected)
+		}
+	}
+}
+
+func TestNaturalSlice(t *testing.T) {
+	list := []string{"30", "3", "20", "1", "10", "2"}
+	sort.Sort(NaturalSlice(list))
+	expected := []string{"1", "2", "3", "10", "20", "30"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+	sort.Sort(sort.Reverse(NaturalSlice(list)))
+	expected = []string{"30", "20", "10", "3", "2", "1"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+
+	// Mixed input sorts the same regardless of its order.
+	for _, list := range [][]string{{"2", "10", "1a"}, {"1a", "10", "2"}, {"10", "1a", "2"}} {
+		sort.Sort(NaturalSlice(list))
+		expected = []string{"2", "10", "1a"}
+		if !reflect.DeepEqual(list, expected) {
+			t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"img2.png", "img10.png", -1},
+		{"img10.png", "img2.png", 1},
+		{"file-1.2.9.log", "file-1.2.10.log", -1},
+		{"file-1.2.10.log", "file-1.2.10.log", 0},
+		{"1.2", "1.2.1", -1},
+		{"1.10", "1.9", 1},
+		{"a", "b", -1},
+		{"a1", "b", -1},
+		{"7", "007", -1},
+		{"v1.0", "v1.0-rc1", -1},
+		{"99999999999999999999999", "100000000000000000000000", -1},
+		{"", "a", -1},
+		{"", "", 0},
+	}
+	for _, test := range tests {
+		got := VersionCompare(test.a, test.b)
+		if got != test.expected {
+			t.Errorf("VersionCompare(%s, %s) = %d, expected %d\n", test.a, test.b, got, test.expected)
+		}
+		if VersionLess(test.a, test.b) != (test.expected < 0) {
+			t.Errorf("VersionLess(%s, %s) != %v\n", test.a, test.b, test.expected < 0)
+		}
+	}
+}
+
+func TestVersionSlice(t *testing.T) {
+	list := []string{"file-1.2.10.log", "img10.png", "file-1.2.9.log", "img2.png", "file-1.10.0.log", "img1.png"}
+	sort.Sort(VersionSlice(list))
+	expected := []string{"file-1.2.9.log", "file-1.2.10.log", "file-1.10.0.log", "img1.png", "img2.png", "img10.png"}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q\n", expected, list)
+	}
+}
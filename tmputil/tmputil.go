@@ -0,0 +1,79 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package tmputil - Temporary directories that are always cleaned up.
+
+A TempDir is removed when it is closed, when the test that created it
+finishes, or when the process receives SIGINT or SIGTERM through the cleanup
+package registry.
+
+	dir, err := tmputil.NewTempDir("tool-")
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+*/
+package tmputil
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/DavidGamba/go-utils/cleanup"
+)
+
+// Logger - Custom lib logger
+var Logger = log.New(ioutil.Discard, "tmputil ", log.LstdFlags)
+
+// TB - The subset of testing.TB used by NewTestTempDir.
+type TB interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...interface{})
+}
+
+// TempDir - Managed temporary directory.
+type TempDir struct {
+	Path    string
+	cleanup *cleanup.Handle
+}
+
+// NewTempDir - Creates a new temporary directory in the default temp dir
+// whose name starts with prefix.
+func NewTempDir(prefix string) (*TempDir, error) {
+	path, err := ioutil.TempDir("", prefix)
+	if err != nil {
+		return nil, err
+	}
+	d := &TempDir{Path: path}
+	d.cleanup = cleanup.Register(func() error {
+		Logger.Printf("removing '%s'", path)
+		return os.RemoveAll(path)
+	}, cleanup.Name("temp dir '"+path+"'"))
+	return d, nil
+}
+
+// NewTestTempDir - Creates a new temporary directory that is removed when the test finishes.
+// The test fails if the directory can't be created.
+func NewTestTempDir(t TB, prefix string) *TempDir {
+	t.Helper()
+	d, err := NewTempDir(prefix)
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// Close - Removes the temporary directory and all its contents.
+// It is safe to call Close multiple times.
+func (d *TempDir) Close() error {
+	return d.cleanup.Run()
+}
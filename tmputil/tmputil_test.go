@@ -0,0 +1,66 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package tmputil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DavidGamba/go-utils/cleanup"
+)
+
+func TestNewTempDir(t *testing.T) {
+	d, err := NewTempDir("tmputil-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !strings.HasPrefix(filepath.Base(d.Path), "tmputil-") {
+		t.Errorf("Unexpected name: %s\n", d.Path)
+	}
+	ioutil.WriteFile(filepath.Join(d.Path, "file"), []byte("x"), 0644)
+	err = d.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if _, err := os.Stat(d.Path); !os.IsNotExist(err) {
+		t.Errorf("Dir not removed: %v\n", err)
+	}
+	err = d.Close()
+	if err != nil {
+		t.Errorf("Unexpected error on second close: %s\n", err)
+	}
+}
+
+func TestCleanupRun(t *testing.T) {
+	a, _ := NewTempDir("tmputil-")
+	b, _ := NewTempDir("tmputil-")
+	cleanup.Run()
+	for _, d := range []*TempDir{a, b} {
+		if _, err := os.Stat(d.Path); !os.IsNotExist(err) {
+			t.Errorf("Dir not removed: %v\n", err)
+		}
+	}
+}
+
+func TestNewTestTempDir(t *testing.T) {
+	var path string
+	t.Run("sub", func(t *testing.T) {
+		d := NewTestTempDir(t, "tmputil-")
+		path = d.Path
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Dir not removed after test: %v\n", err)
+	}
+}
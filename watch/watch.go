@@ -0,0 +1,469 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+/*
+Package watch - Recursive file system watcher with typed events,
+debouncing and the include, exclude and ignore file filters of the
+fileutils listings, built on fsnotify.
+
+	w, err := watch.New(dir, watch.Debounce(100*time.Millisecond), watch.Exclude(".git", "*.swp"))
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for {
+		select {
+		case e := <-w.Events():
+			fmt.Println(e.Op, e.Path)
+		case err := <-w.Errors():
+			return err
+		}
+	}
+*/
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+	"github.com/DavidGamba/go-utils/retryutils"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Op - Kind of change reported in an Event.
+type Op int
+
+const (
+	// Create - The entry was created, or moved in.
+	Create Op = iota + 1
+
+	// Modify - The contents of the file changed.
+	Modify
+
+	// Delete - The entry was removed.
+	Delete
+
+	// Rename - The entry was moved away from Path, the new name, when
+	// watched, is reported with Create.
+	Rename
+)
+
+func (op Op) String() string {
+	switch op {
+	case Create:
+		return "create"
+	case Modify:
+		return "modify"
+	case Delete:
+		return "delete"
+	case Rename:
+		return "rename"
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// Event - A change to the entry at Path.
+type Event struct {
+	Path string
+	Op   Op
+}
+
+// Option - Watcher option.
+type Option func(*options)
+
+type options struct {
+	debounce time.Duration
+	listOpts []fileutils.ListOption
+
+	// ignoreFiles - Names of the ignore files, changes to them drop the
+	// rules read from them.
+	ignoreFiles []string
+
+	journal *fileutils.Journal
+	retry   *retryutils.Policy
+}
+
+// Debounce - Waits until there are no changes for d before sending the
+// events, coalescing the ones for the same path: a create followed by
+// modifications is a create, a create followed by a delete or rename is
+// dropped and otherwise the last change is sent.
+func Debounce(d time.Duration) Option {
+	return func(o *options) {
+		o.debounce = d
+	}
+}
+
+// Include - Only sends the events for entries whose name matches one of the
+// filepath.Match patterns, see fileutils.ListInclude.
+// Patterns with a '/' are matched against the slash separated path relative
+// to the root instead, where "**" matches any number of dirs.
+// Dirs that don't match are still watched.
+func Include(patterns ...string) Option {
+	return func(o *options) {
+		o.listOpts = append(o.listOpts, fileutils.ListInclude(patterns...))
+	}
+}
+
+// Exclude - Ignores the entries whose name matches one of the patterns,
+// like Include, excluded dirs are not watched, see fileutils.ListExclude.
+func Exclude(patterns ...string) Option {
+	return func(o *options) {
+		o.listOpts = append(o.listOpts, fileutils.ListExclude(patterns...))
+	}
+}
+
+// SkipHidden - Ignores the entries whose name starts with a dot, hidden
+// dirs are not watched.
+func SkipHidden() Option {
+	return func(o *options) {
+		o.listOpts = append(o.listOpts, fileutils.ListSkipHidden())
+	}
+}
+
+// IgnoreFiles - Ignores the entries matching the gitignore style patterns
+// in the files with the given names, see fileutils.ListIgnoreFiles.
+// Ignored dirs are not watched. Edits to the ignore files apply to the
+// events that follow, dirs that become ignored stay watched.
+func IgnoreFiles(names ...string) Option {
+	return func(o *options) {
+		o.listOpts = append(o.listOpts, fileutils.ListIgnoreFiles(names...))
+		o.ignoreFiles = append(o.ignoreFiles, names...)
+	}
+}
+
+// IgnorePatterns - Ignores the entries matching the gitignore style
+// patterns, relative to the root, see fileutils.ListIgnorePatterns.
+func IgnorePatterns(patterns ...string) Option {
+	return func(o *options) {
+		o.listOpts = append(o.listOpts, fileutils.ListIgnorePatterns(patterns...))
+	}
+}
+
+// Journal - Appends each event sent to the journal j before sending it, so
+// tools that don't run continuously can find the changes with
+// fileutils.ChangedSince. Failures to append are sent to Errors.
+// Keep the journal file out of the watched tree, or exclude it, otherwise
+// each append is a new event. The journal is not closed with the Watcher.
+func Journal(j *fileutils.Journal) Option {
+	return func(o *options) {
+		o.journal = j
+	}
+}
+
+// Retry - Retries watching the root and the dirs created later on transient
+// errors, like the ones from NFS or SMB mounts, following the given policy.
+// Entries of a retried new dir may be sent as created more than once.
+func Retry(policy retryutils.Policy) Option {
+	return func(o *options) {
+		o.retry = &policy
+	}
+}
+
+// Watcher - Watches a tree, see New.
+type Watcher struct {
+	root   string
+	o      options
+	m      *fileutils.Matcher
+	fsw    *fsnotify.Watcher
+	events chan Event
+	errors chan error
+	done   chan struct{}
+	once   sync.Once
+
+	// dirs - Watched dirs, only used by the loop goroutine after New.
+	dirs map[string]bool
+
+	// pending - Debounced events and the order their paths arrived in.
+	pending map[string]Op
+	order   []string
+}
+
+// New - Starts watching root and all the dirs under it. Dirs created later
+// are watched as well, and the entries already in them when that happens
+// are sent as created.
+// Symlinks are not followed. Changes to permissions and times are not
+// reported.
+// Call Close to stop watching.
+func New(root string, opts ...Option) (*Watcher, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	root = filepath.Clean(root)
+	m, err := fileutils.NewMatcher(root, o.listOpts...)
+	if err != nil {
+		return nil, err
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		root:    root,
+		o:       o,
+		m:       m,
+		fsw:     fsw,
+		events:  make(chan Event),
+		errors:  make(chan error),
+		done:    make(chan struct{}),
+		dirs:    map[string]bool{},
+		pending: map[string]Op{},
+	}
+	err = w.addTreeRetry(w.root, false)
+	if err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	go w.loop()
+	return w, nil
+}
+
+// Events - The changes, closed after Close.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Errors - Errors from the underlying watcher and from registering new
+// dirs, closed after Close.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Close - Stops watching, pending debounced events are dropped.
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+// addTreeRetry - Calls addTree with the retry policy.
+func (w *Watcher) addTreeRetry(dir string, report bool) error {
+	if w.o.retry == nil {
+		return w.addTree(dir, report)
+	}
+	return retryutils.Retry(context.Background(), *w.o.retry, func() error {
+		return w.addTree(dir, report)
+	})
+}
+
+// addTree - Watches dir and the dirs under it, with report the entries
+// found are sent as created.
+func (w *Watcher) addTree(dir string, report bool) error {
+	err := w.fsw.Add(dir)
+	if err != nil {
+		return err
+	}
+	w.dirs[dir] = true
+	return fileutils.Walk(dir, func(p string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := w.rel(p)
+		if w.m.Skipped(rel, isDir) {
+			if isDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isDir {
+			err = w.fsw.Add(p)
+			if err != nil {
+				return err
+			}
+			w.dirs[p] = true
+		}
+		if report && w.m.Included(rel, isDir) {
+			w.emit(Event{Path: p, Op: Create})
+		}
+		return nil
+	})
+}
+
+// forget - Stops tracking dir and the dirs under it after they are gone.
+func (w *Watcher) forget(dir string) {
+	for d := range w.dirs {
+		if d == dir || strings.HasPrefix(d, dir+string(os.PathSeparator)) {
+			// Removed dirs drop their own watch, renamed ones don't.
+			w.fsw.Remove(d)
+			delete(w.dirs, d)
+		}
+	}
+}
+
+func (w *Watcher) loop() {
+	defer close(w.events)
+	defer close(w.errors)
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	for {
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handle(ev)
+			if w.o.debounce > 0 && len(w.pending) > 0 {
+				timer.Reset(w.o.debounce)
+			}
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.sendError(err)
+		case <-timer.C:
+			w.flush()
+		}
+	}
+}
+
+// handle - Translates and filters an fsnotify event.
+func (w *Watcher) handle(ev fsnotify.Event) {
+	p := filepath.Clean(ev.Name)
+	rel := w.rel(p)
+	if w.isIgnoreFile(rel) {
+		w.m.Forget(path.Dir(rel))
+	}
+	isDir := w.dirs[p]
+	if fInfo, err := os.Lstat(p); err == nil {
+		isDir = fInfo.IsDir()
+	}
+	if w.m.Skipped(rel, isDir) {
+		return
+	}
+	var op Op
+	switch {
+	case ev.Has(fsnotify.Create):
+		op = Create
+		if isDir && !w.dirs[p] {
+			if w.m.Included(rel, true) {
+				w.emit(Event{Path: p, Op: Create})
+			}
+			err := w.addTreeRetry(p, true)
+			if err != nil && !os.IsNotExist(err) {
+				w.sendError(err)
+			}
+			return
+		}
+	case ev.Has(fsnotify.Write):
+		op = Modify
+	case ev.Has(fsnotify.Remove):
+		op = Delete
+		w.forget(p)
+	case ev.Has(fsnotify.Rename):
+		op = Rename
+		w.forget(p)
+	default:
+		return
+	}
+	if w.m.Included(rel, isDir) {
+		w.emit(Event{Path: p, Op: op})
+	}
+}
+
+// emit - Sends e, or queues it when debouncing.
+func (w *Watcher) emit(e Event) {
+	if w.o.debounce <= 0 {
+		w.send(e)
+		return
+	}
+	prev, ok := w.pending[e.Path]
+	switch {
+	case !ok:
+		w.pending[e.Path] = e.Op
+		w.order = append(w.order, e.Path)
+	case prev == Create && e.Op == Modify:
+	case prev == Create && (e.Op == Delete || e.Op == Rename):
+		delete(w.pending, e.Path)
+	default:
+		w.pending[e.Path] = e.Op
+	}
+}
+
+// flush - Sends the debounced events in the order their paths first arrived.
+func (w *Watcher) flush() {
+	order := w.order
+	w.order = nil
+	for _, p := range order {
+		op, ok := w.pending[p]
+		if !ok {
+			continue
+		}
+		delete(w.pending, p)
+		if !w.send(Event{Path: p, Op: op}) {
+			return
+		}
+	}
+}
+
+// send - Records e in the journal and sends it, returns false when the
+// Watcher was closed.
+func (w *Watcher) send(e Event) bool {
+	if w.o.journal != nil {
+		_, err := w.o.journal.Append(journalOps[e.Op], e.Path)
+		if err != nil {
+			w.sendError(err)
+		}
+	}
+	select {
+	case w.events <- e:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// journalOps - The journal operation recorded for each Op.
+var journalOps = map[Op]fileutils.JournalOp{
+	Create: fileutils.JournalCreate,
+	Modify: fileutils.JournalWrite,
+	Delete: fileutils.JournalRemove,
+	Rename: fileutils.JournalRename,
+}
+
+func (w *Watcher) sendError(err error) {
+	select {
+	case w.errors <- err:
+	case <-w.done:
+	}
+}
+
+// rel - Returns the slash separated path of p relative to the root.
+func (w *Watcher) rel(p string) string {
+	rel, err := filepath.Rel(w.root, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// isIgnoreFile - Whether the entry with the relative path rel is one of
+// the ignore files.
+func (w *Watcher) isIgnoreFile(rel string) bool {
+	name := path.Base(rel)
+	for _, n := range w.o.ignoreFiles {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
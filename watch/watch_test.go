@@ -0,0 +1,350 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DavidGamba/go-utils/fileutils"
+	"github.com/DavidGamba/go-utils/retryutils"
+)
+
+// next - Returns the next event or fails after a timeout.
+func next(t *testing.T, w *Watcher) Event {
+	t.Helper()
+	select {
+	case e := <-w.Events():
+		return e
+	case err := <-w.Errors():
+		t.Fatalf("Unexpected error: %s\n", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timeout waiting for event\n")
+	}
+	return Event{}
+}
+
+// none - Fails if an event arrives within d.
+func none(t *testing.T, w *Watcher, d time.Duration) {
+	t.Helper()
+	select {
+	case e := <-w.Events():
+		t.Errorf("Unexpected event: %v\n", e)
+	case <-time.After(d):
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	err = os.MkdirAll(filepath.Join(dir, "skip"), 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	w, err := New(dir, Exclude("skip", "*.swp"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer w.Close()
+
+	file := filepath.Join(dir, "a")
+	err = ioutil.WriteFile(filepath.Join(dir, "skip", "x"), []byte("x"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "a.swp"), []byte("x"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = ioutil.WriteFile(file, []byte("a"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []Event{{file, Create}, {file, Modify}}
+	for _, e := range expected {
+		got := next(t, w)
+		if got != e {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", e, got)
+		}
+	}
+
+	// Files created right after their dir are reported too.
+	sub := filepath.Join(dir, "sub")
+	subFile := filepath.Join(sub, "b")
+	err = os.Mkdir(sub, 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = ioutil.WriteFile(subFile, nil, 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got := next(t, w)
+	if got != (Event{sub, Create}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{sub, Create}, got)
+	}
+	got = next(t, w)
+	if got != (Event{subFile, Create}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{subFile, Create}, got)
+	}
+
+	renamed := filepath.Join(dir, "c")
+	err = os.Rename(file, renamed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	seen := map[Event]bool{}
+	for i := 0; i < 2; i++ {
+		seen[next(t, w)] = true
+	}
+	expectedSeen := map[Event]bool{{file, Rename}: true, {renamed, Create}: true}
+	if !reflect.DeepEqual(seen, expectedSeen) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expectedSeen, seen)
+	}
+
+	err = os.Remove(renamed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got = next(t, w)
+	if got != (Event{renamed, Delete}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{renamed, Delete}, got)
+	}
+	none(t, w, 100*time.Millisecond)
+
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if _, ok := <-w.Events(); ok {
+		t.Errorf("Expected events channel to be closed\n")
+	}
+}
+
+func TestWatchDebounce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := New(dir, Debounce(100*time.Millisecond), Include("*.txt"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer w.Close()
+
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	tmp := filepath.Join(dir, "tmp.txt")
+	for _, file := range []string{a, tmp, b, filepath.Join(dir, "c.log")} {
+		err = ioutil.WriteFile(file, []byte("x"), 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	}
+	err = ioutil.WriteFile(a, []byte("more"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = os.Remove(tmp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []Event{{a, Create}, {b, Create}}
+	for _, e := range expected {
+		got := next(t, w)
+		if got != e {
+			t.Errorf("Expected:\n%v\nGot:\n%v\n", e, got)
+		}
+	}
+	none(t, w, 300*time.Millisecond)
+}
+
+func TestWatchIgnoreFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"build", "sub"} {
+		err = os.Mkdir(filepath.Join(dir, d), 0755)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	}
+	ignore := filepath.Join(dir, ".gitignore")
+	err = ioutil.WriteFile(ignore, []byte("*.log\nbuild/\n"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "sub", ".gitignore"), []byte("!keep.log\n"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	w, err := New(dir, IgnoreFiles(".gitignore"), Debounce(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer w.Close()
+
+	keep := filepath.Join(dir, "sub", "keep.log")
+	for _, name := range []string{filepath.Join(dir, "build", "x"), filepath.Join(dir, "a.log"), keep} {
+		err = ioutil.WriteFile(name, nil, 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	}
+	got := next(t, w)
+	if got != (Event{keep, Create}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{keep, Create}, got)
+	}
+
+	// Edits to the ignore files apply to the events that follow.
+	err = ioutil.WriteFile(ignore, []byte("*.log\n*.txt\nbuild/\n"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got = next(t, w)
+	if got != (Event{ignore, Modify}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{ignore, Modify}, got)
+	}
+	md := filepath.Join(dir, "d.md")
+	for _, name := range []string{filepath.Join(dir, "b.txt"), md} {
+		err = ioutil.WriteFile(name, nil, 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+	}
+	got = next(t, w)
+	if got != (Event{md, Create}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{md, Create}, got)
+	}
+	none(t, w, 200*time.Millisecond)
+}
+
+func TestWatchJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	err = os.Mkdir(root, 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	journalFile := filepath.Join(dir, "journal")
+	j, err := fileutils.OpenJournal(journalFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer j.Close()
+
+	w, err := New(root, Journal(j), Debounce(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer w.Close()
+
+	a := filepath.Join(root, "a")
+	err = ioutil.WriteFile(a, []byte("a"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got := next(t, w)
+	if got != (Event{a, Create}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{a, Create}, got)
+	}
+	checkpoint := j.Checkpoint()
+
+	b := filepath.Join(root, "b")
+	err = ioutil.WriteFile(b, nil, 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got = next(t, w)
+	if got != (Event{b, Create}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{b, Create}, got)
+	}
+	err = os.Remove(a)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got = next(t, w)
+	if got != (Event{a, Delete}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{a, Delete}, got)
+	}
+
+	changed, last, err := fileutils.ChangedSince(journalFile, checkpoint)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := []string{a, b}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", expected, changed)
+	}
+	if last != checkpoint+2 {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", checkpoint+2, last)
+	}
+}
+
+func TestWatchRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+
+	_, err = New(root)
+	if err == nil {
+		t.Fatalf("Expected error for a missing root\n")
+	}
+
+	// The root shows up before the second attempt.
+	attempts := 0
+	policy := retryutils.Policy{MaxAttempts: 2, Retryable: func(err error) bool {
+		attempts++
+		return os.Mkdir(root, 0755) == nil
+	}}
+	w, err := New(root, Retry(policy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	defer w.Close()
+	if attempts != 1 {
+		t.Errorf("Expected:\n%d\nGot:\n%d\n", 1, attempts)
+	}
+	a := filepath.Join(root, "a")
+	err = ioutil.WriteFile(a, nil, 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	got := next(t, w)
+	if got != (Event{a, Create}) {
+		t.Errorf("Expected:\n%v\nGot:\n%v\n", Event{a, Create}, got)
+	}
+}
+
+func TestNewInvalidGlob(t *testing.T) {
+	_, err := New(os.TempDir(), Exclude("[a"))
+	if err == nil {
+		t.Errorf("Expected error for invalid glob\n")
+	}
+}
@@ -0,0 +1,219 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package yamlutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DiffType - Kind of structural difference.
+type DiffType string
+
+// Structural difference kinds.
+const (
+	DiffAdded   DiffType = "added"
+	DiffRemoved DiffType = "removed"
+	DiffChanged DiffType = "changed"
+)
+
+// Difference - Structural difference between two trees.
+// Path uses the same elements as NavigateTree, map keys and list indexes.
+type Difference struct {
+	Path []string
+	Type DiffType
+	Old  interface{}
+	New  interface{}
+}
+
+// String - Returns the difference as '+ path: new', '- path: old' or '~ path: old -> new'.
+// Complex values are printed as indented YAML blocks.
+func (d Difference) String() string {
+	path := "/" + strings.Join(d.Path, "/")
+	switch d.Type {
+	case DiffAdded:
+		return fmt.Sprintf("+ %s:%s", path, formatValue(d.New))
+	case DiffRemoved:
+		return fmt.Sprintf("- %s:%s", path, formatValue(d.Old))
+	default:
+		return fmt.Sprintf("~ %s:%s ->%s", path, formatValue(d.Old), formatValue(d.New))
+	}
+}
+
+// MarshalJSON - Encodes the difference as an object with the path, type,
+// and the old and new values when they apply to the type.
+// YAML maps are encoded as JSON objects with their keys formatted as strings.
+func (d Difference) MarshalJSON() ([]byte, error) {
+	var err error
+	out := struct {
+		Path []string        `json:"path"`
+		Type DiffType        `json:"type"`
+		Old  json.RawMessage `json:"old,omitempty"`
+		New  json.RawMessage `json:"new,omitempty"`
+	}{Path: d.Path, Type: d.Type}
+	if out.Path == nil {
+		out.Path = []string{}
+	}
+	if d.Type != DiffAdded {
+		out.Old, err = json.Marshal(jsonValue(d.Old))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if d.Type != DiffRemoved {
+		out.New, err = json.Marshal(jsonValue(d.New))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(out)
+}
+
+// jsonValue - Converts the YAML maps in v, which can have keys of any type,
+// to maps with string keys that encoding/json supports.
+func jsonValue(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(tv))
+		for k, e := range tv {
+			m[fmt.Sprintf("%v", k)] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(tv))
+		for i, e := range tv {
+			l[i] = jsonValue(e)
+		}
+		return l
+	}
+	return v
+}
+
+func formatValue(v interface{}) string {
+	switch v.(type) {
+	case map[interface{}]interface{}, []interface{}:
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf(" %v", v)
+		}
+		return "\n    " + strings.ReplaceAll(strings.TrimSuffix(string(out), "\n"), "\n", "\n    ")
+	case nil:
+		return " null"
+	}
+	return fmt.Sprintf(" %v", v)
+}
+
+// Diff - Returns the structural differences between the a and b trees.
+// Map keys are compared regardless of order and are reported sorted, lists
+// are compared index by index.
+func Diff(a, b interface{}) []Difference {
+	return diff([]string{}, a, b)
+}
+
+func diff(path []string, a, b interface{}) []Difference {
+	switch ta := a.(type) {
+	case map[interface{}]interface{}:
+		tb, ok := b.(map[interface{}]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]interface{}{}
+		for k := range ta {
+			keys[fmt.Sprintf("%v", k)] = k
+		}
+		for k := range tb {
+			keys[fmt.Sprintf("%v", k)] = k
+		}
+		names := []string{}
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		diffs := []Difference{}
+		for _, name := range names {
+			k := keys[name]
+			p := appendPath(path, name)
+			va, okA := ta[k]
+			vb, okB := tb[k]
+			switch {
+			case !okA:
+				diffs = append(diffs, Difference{Path: p, Type: DiffAdded, New: vb})
+			case !okB:
+				diffs = append(diffs, Difference{Path: p, Type: DiffRemoved, Old: va})
+			default:
+				diffs = append(diffs, diff(p, va, vb)...)
+			}
+		}
+		return diffs
+	case []interface{}:
+		tb, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		diffs := []Difference{}
+		for i := 0; i < len(ta) || i < len(tb); i++ {
+			p := appendPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(ta):
+				diffs = append(diffs, Difference{Path: p, Type: DiffAdded, New: tb[i]})
+			case i >= len(tb):
+				diffs = append(diffs, Difference{Path: p, Type: DiffRemoved, Old: ta[i]})
+			default:
+				diffs = append(diffs, diff(p, ta[i], tb[i])...)
+			}
+		}
+		return diffs
+	}
+	if reflect.DeepEqual(a, b) {
+		return []Difference{}
+	}
+	return []Difference{{Path: path, Type: DiffChanged, Old: a, New: b}}
+}
+
+// appendPath - Returns a copy of path with name appended so the differences don't share backing arrays.
+func appendPath(path []string, name string) []string {
+	p := make([]string, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, name)
+}
+
+// NewListFromReader - Returns a YML object for each document in a multi document stream.
+func NewListFromReader(reader io.Reader) ([]*YML, error) {
+	list := []*YML{}
+	dec := yaml.NewDecoder(reader)
+	for {
+		var tree interface{}
+		err := dec.Decode(&tree)
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return list, err
+		}
+		list = append(list, &YML{Tree: tree})
+	}
+}
+
+// NewListFromFile - Returns a YML object for each document in a multi document file.
+func NewListFromFile(filename string) ([]*YML, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return NewListFromReader(fh)
+}
@@ -0,0 +1,101 @@
+// This file is part of go-utils.
+//
+// Copyright (C) 2020  David Gamba Rios
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package yamlutils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected []string
+	}{
+		{"equal", "a: 1\nb: [1, 2]", "b: [1, 2]\na: 1", []string{}},
+		{"scalar", "hello", "world", []string{"~ /: hello -> world"}},
+		{"changed key", "a: {b: 1}", "a: {b: 2}", []string{"~ /a/b: 1 -> 2"}},
+		{"added and removed keys", "a: 1\nc: 3", "a: 1\nb: 2", []string{"+ /b: 2", "- /c: 3"}},
+		{"list", "a: [1, 2, 3]", "a: [1, 4]", []string{"~ /a/1: 2 -> 4", "- /a/2: 3"}},
+		{"type change", "a: 1", "a: [1]", []string{"~ /a: 1 ->\n    - 1"}},
+		{"complex added", "a: 1", "a: 1\nb: {c: d}", []string{"+ /b:\n    c: d"}},
+		{"null", "a: null", "a: 1", []string{"~ /a: null -> 1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := NewFromString(test.a)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			b, err := NewFromString(test.b)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			got := []string{}
+			for _, d := range Diff(a.Tree, b.Tree) {
+				got = append(got, d.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("Expected:\n%s\nGot:\n%s\n", strings.Join(test.expected, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestDiffPath(t *testing.T) {
+	a, _ := NewFromString("a: {b: 1, c: 1}")
+	b, _ := NewFromString("a: {b: 2, c: 2}")
+	diffs := Diff(a.Tree, b.Tree)
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 differences, got %d\n", len(diffs))
+	}
+	if strings.Join(diffs[0].Path, "/") != "a/b" || strings.Join(diffs[1].Path, "/") != "a/c" {
+		t.Errorf("Expected:\na/b, a/c\nGot:\n%v, %v\n", diffs[0].Path, diffs[1].Path)
+	}
+}
+
+func TestDifferenceMarshalJSON(t *testing.T) {
+	a, _ := NewFromString("a: 1\nb: {c: [1, 2]}\ne: null")
+	b, _ := NewFromString("a: 2\nb: {c: [1]}\nd: {1: x}\ne: 3")
+	data, err := json.Marshal(Diff(a.Tree, b.Tree))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	expected := `[{"path":["a"],"type":"changed","old":1,"new":2},` +
+		`{"path":["b","c","1"],"type":"removed","old":2},` +
+		`{"path":["d"],"type":"added","new":{"1":"x"}},` +
+		`{"path":["e"],"type":"changed","old":null,"new":3}]`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, data)
+	}
+}
+
+func TestNewListFromReader(t *testing.T) {
+	list, err := NewListFromReader(strings.NewReader("a: 1\n---\nb: 2\n---\n- 3\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("Expected 3 documents, got %d\n", len(list))
+	}
+	str, err := list[1].GetString(false, []string{"b"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if str != "2" {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", "2", str)
+	}
+	_, err = NewListFromReader(strings.NewReader("a: 1\n---\na: [\n"))
+	if err == nil {
+		t.Errorf("Expected error, got nil\n")
+	}
+}
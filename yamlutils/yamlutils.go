@@ -18,6 +18,7 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"sort"
 
 	// "reflect"
 	"strconv"
@@ -100,6 +101,30 @@ func (y *YML) GetString(include bool, keys []string) (string, error) {
 	return string(out), nil
 }
 
+// Keys returns the children of the element designated by keys, the sorted
+// map keys for a map or the indexes for an array, for example to complete a
+// path.
+// Single elements have no children.
+func (y *YML) Keys(keys []string) ([]string, error) {
+	target, _, err := NavigateTree(false, y.Tree, keys)
+	if err != nil {
+		return nil, fmt.Errorf("yaml path '%s': %w", strings.Join(keys, ","), err)
+	}
+	children := []string{}
+	switch o := target.(type) {
+	case map[interface{}]interface{}:
+		for k := range o {
+			children = append(children, fmt.Sprintf("%v", k))
+		}
+		sort.Strings(children)
+	case []interface{}:
+		for i := range o {
+			children = append(children, strconv.Itoa(i))
+		}
+	}
+	return children, nil
+}
+
 func (y *YML) AddString(keys []string, input string) (string, error) {
 	path := strings.Join(keys, ",")
 	errPath := AddChildToTree(&y.Tree, &y.Tree, keys, input)
@@ -216,7 +241,6 @@ func AddChild(m *interface{}, child string) error {
 		Logger.Printf("AddChild: single element type")
 		return fmt.Errorf("%w", ErrInvalidParentType)
 	}
-	return nil
 }
 
 func AddChildToTree(parent *interface{}, current *interface{}, p []string, child string) error {
@@ -214,3 +214,40 @@ func TestAddChildToTree(t *testing.T) {
 		})
 	}
 }
+
+func TestKeys(t *testing.T) {
+	input := `hello:
+  - one
+  - world: 1
+    again: 2
+  - three
+bye: x
+1: y`
+	tests := []struct {
+		name     string
+		path     []string
+		expected []string
+		err      error
+	}{
+		{"root", []string{}, []string{"1", "bye", "hello"}, nil},
+		{"array", []string{"hello"}, []string{"0", "1", "2"}, nil},
+		{"map in array", []string{"hello", "1"}, []string{"again", "world"}, nil},
+		{"single element", []string{"bye"}, []string{}, nil},
+		{"missing", []string{"x"}, nil, ErrMapKeyNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			yml, err := NewFromString(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			got, err := yml.Keys(test.path)
+			if !errors.Is(err, test.err) {
+				t.Errorf("Unexpected error: %s\n", err)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected:\n%v\nGot:\n%v\n", test.expected, got)
+			}
+		})
+	}
+}